@@ -2,60 +2,159 @@ package app
 
 import (
 	"task-manager-app/config"
-	"task-manager-app/controller"
-	"task-manager-app/network/userManager"
-	"task-manager-app/repo"
-	"task-manager-app/services/taskManagerService"
-	"task-manager-app/services/userManagerServices"
-	"task-manager-app/services/validationService"
+	"task-manager-app/constants"
+	"task-manager-app/secrets"
+	"task-manager-app/tracing"
 	"task-manager-app/utils"
-	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
-var (
-	appName string
-	version string
-	router  = gin.Default()
-)
-
+// StartApplication runs the service configured by the command line, the config file, the .env
+// file and the environment until SIGINT or SIGTERM
 func StartApplication() {
 
-	// Load environment variables from .env file
-	err := godotenv.Load("resources/.env")
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+	sources, cfg, refresher := loadConfig(os.Args[1:])
+
+	// Tracing starts before the database so that its queries are traced too
+	shutdownTracing, err := tracing.Init(tracing.Options{
+		Exporter:       config.ApplicationConfig.TracingExporter,
+		ServiceName:    config.ApplicationConfig.AppName,
+		ServiceVersion: config.ApplicationConfig.AppVersion,
+	})
 	if err != nil {
-		log.Printf("Warning: Error loading .env file: %v", err)
+		utils.Sugar.Fatal(constants.ErrFailedToInitTracing+": ", err)
 	}
 
-	utils.InitLogger()
+	// Initialize database using GORM
+	config.InitDB(refresher)
+	config.InitRedis(refresher)
 
-	config.ApplicationConfig.SetAwsSecretValues()
+	utils.Sugar.Infow("Starting application: ", config.ApplicationConfig.AppName, config.ApplicationConfig.AppVersion)
 
-	// Initialize database using GORM
-	config.InitDB()
+	api, err := New(Options{
+		Config:  config.ApplicationConfig,
+		DB:      config.DB,
+		Redis:   config.Redis,
+		Secrets: refresher,
+	})
+	if err != nil {
+		utils.Sugar.Fatal(err)
+	}
+	if err := api.Start(); err != nil {
+		utils.Sugar.Fatal(err)
+	}
 
-	// Initialize network clients
-	userManager.InitNetworkClients()
+	// SIGHUP and edits of the config files reload the settings that can change at runtime
+	reloadSignals := make(chan os.Signal, 1)
+	signal.Notify(reloadSignals, syscall.SIGHUP)
+	reloader := config.NewReloader(sources, cfg, config.ReloaderOptions{
+		Interval: time.Duration(cfg.ConfigWatch) * time.Second,
+		Signals:  reloadSignals,
+	})
+	reloader.OnReload(api.Reload)
+	reloader.Start()
 
-	appName = config.ApplicationConfig.AppName
-	version = config.ApplicationConfig.AppVersion
-	utils.Sugar.Infow("Starting application: ", appName, version)
+	server := &http.Server{
+		Addr:              config.ApplicationConfig.AppHost + ":" + config.ApplicationConfig.AppPort,
+		Handler:           api.Handler(),
+		ReadHeaderTimeout: time.Duration(config.ApplicationConfig.HTTPReadHeaderTimeout) * time.Second,
+		IdleTimeout:       time.Duration(config.ApplicationConfig.HTTPIdleTimeout) * time.Second,
+	}
+	server.RegisterOnShutdown(api.CloseStreams)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
 
-	// Initialize repositories, services, and controllers
-	taskRepo := repo.NewTaskRepository(config.DB)
-	userService := userManagerServices.NewUserService()
-	validationSvc := validationService.NewValidationService(userService, taskRepo)
-	taskService := taskManagerService.NewTaskService(taskRepo, validationSvc)
-	taskController := controller.NewTaskController(taskService)
-	healthController := controller.NewHealthController(config.DB)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	exitCode := 0
+	select {
+	case sig := <-signals:
+		utils.Sugar.Infow("Shutting down", "signal", sig.String())
+	case err := <-serveErr:
+		utils.Sugar.Errorw("Error starting application", "error", err)
+		exitCode = 1
+	}
+	signal.Stop(signals)
+	signal.Stop(reloadSignals)
 
-	// Register routes
-	RegisterTaskRoutes(router, taskController)
-	RegisterHealthRoutes(router, healthController)
+	// Stop taking work first, then finish it, then release what it used
+	steps := []shutdownStep{
+		{"readiness", func(ctx context.Context) error {
+			api.MarkNotReady(constants.NotReadyShuttingDown)
+			return sleep(ctx, time.Duration(config.ApplicationConfig.ShutdownDelay)*time.Second)
+		}},
+		{"http server", server.Shutdown},
+		{"config reload", reloader.Stop},
+	}
+	steps = append(steps, api.stopSteps()...)
+	steps = append(steps,
+		shutdownStep{"tracing", shutdownTracing},
+		shutdownStep{"redis", closeWith(config.CloseRedis)},
+		shutdownStep{"database", closeWith(config.CloseDB)},
+	)
+	clean := shutdown(time.Duration(config.ApplicationConfig.ShutdownTimeout)*time.Second, steps...)
+	// Stdout cannot always be synced, so errors are not worth reporting
+	_ = utils.Sugar.Sync()
+	if !clean && exitCode == 0 {
+		exitCode = 1
+	}
+	os.Exit(exitCode)
+}
+
+// loadConfig reads and validates the configuration given by args, then starts the logger and
+// resolves secret references with the returned refresher. It exits on any problem.
+func loadConfig(args []string) (config.Sources, *config.Config, *secrets.Refresher) {
+	// The flag set has already printed the usage or the error
+	sources, err := config.ParseFlags(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+	cfg, err := sources.Load()
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	config.ApplicationConfig = cfg
+
+	if _, err := utils.InitLogger(utils.LogOptions{
+		Level:      cfg.LogLevel,
+		Format:     cfg.LogFormat,
+		File:       cfg.LogFile,
+		MaxSizeMB:  cfg.LogMaxSize,
+		MaxBackups: cfg.LogMaxBackups,
+		MaxAgeDays: cfg.LogMaxAge,
+	}); err != nil {
+		log.Fatalf("%s: %v", constants.ErrFailedToInitLogger, err)
+	}
 
-	runErr := router.Run(config.ApplicationConfig.AppHost + ":" + config.ApplicationConfig.AppPort)
-	if runErr != nil {
-		utils.Sugar.Fatal("Error starting application: ", runErr.Error())
+	// References such as POSTGRES_PASSWORD=vault://secret/data/task-manager#password are
+	// replaced by their values before anything connects
+	var refresher *secrets.Refresher
+	resolver, err := config.NewSecretResolver(context.Background(), cfg)
+	if err == nil {
+		refresher = secrets.NewRefresher(resolver, time.Duration(cfg.SecretRefresh)*time.Second, time.Duration(cfg.SecretTimeout)*time.Millisecond, utils.Sugar)
+		err = cfg.ResolveSecrets(context.Background(), refresher)
+	}
+	if err != nil {
+		utils.Sugar.Fatal(constants.ErrFailedToResolveSecrets+": ", err)
 	}
+	return sources, cfg, refresher
 }
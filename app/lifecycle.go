@@ -0,0 +1,58 @@
+package app
+
+import (
+	"context"
+	"task-manager-app/utils"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// shutdownStep stops one part of the application
+type shutdownStep struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// shutdown runs steps in order, all within timeout. A step that fails or runs out of time is
+// logged and the next one still runs, so that the database is closed whatever happened
+// before. It reports whether every step succeeded.
+func shutdown(timeout time.Duration, steps ...shutdownStep) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return shutdownWith(ctx, utils.Sugar, steps...)
+}
+
+// shutdownWith runs steps like shutdown does, until ctx ends, logging to logger
+func shutdownWith(ctx context.Context, logger *zap.SugaredLogger, steps ...shutdownStep) bool {
+	clean := true
+	for _, step := range steps {
+		start := time.Now()
+		if err := step.stop(ctx); err != nil {
+			logger.Errorw("Shutdown step failed", "step", step.name, "error", err)
+			clean = false
+			continue
+		}
+		logger.Infow("Shutdown step done", "step", step.name, "duration_ms", time.Since(start).Milliseconds())
+	}
+	return clean
+}
+
+// sleep waits for d, or less if ctx ends first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// closeWith adapts a Close method to a shutdown step
+func closeWith(close func() error) func(ctx context.Context) error {
+	return func(context.Context) error {
+		return close()
+	}
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"task-manager-app/utils"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	utils.Sugar = zap.NewNop().Sugar()
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+func TestShutdownRunsEveryStepInOrder(t *testing.T) {
+	var ran []string
+	step := func(name string, err error) shutdownStep {
+		return shutdownStep{name, func(context.Context) error {
+			ran = append(ran, name)
+			return err
+		}}
+	}
+
+	clean := shutdown(time.Second,
+		step("http server", nil),
+		step("outbox relay", errors.New("relay is stuck")),
+		step("database", nil),
+	)
+
+	if clean {
+		t.Fatal("shutdown reported success although a step failed")
+	}
+	if want := []string{"http server", "outbox relay", "database"}; !reflect.DeepEqual(ran, want) {
+		t.Fatalf("ran %v, want %v", ran, want)
+	}
+}
+
+func TestShutdownSharesOneTimeout(t *testing.T) {
+	var dbErr error
+	clean := shutdown(20*time.Millisecond,
+		shutdownStep{"readiness", func(ctx context.Context) error { return sleep(ctx, time.Minute) }},
+		shutdownStep{"database", func(ctx context.Context) error {
+			dbErr = ctx.Err()
+			return nil
+		}},
+	)
+
+	if clean {
+		t.Fatal("shutdown reported success although a step timed out")
+	}
+	if !errors.Is(dbErr, context.DeadlineExceeded) {
+		t.Fatalf("later step saw %v, want the expired deadline", dbErr)
+	}
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"task-manager-app/config"
+	"task-manager-app/constants"
+	"task-manager-app/migrations"
+	"task-manager-app/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// prepareSchema applies the pending migrations when DB_MIGRATE_ON_START is set, then makes
+// sure none are left. A replica started with an outdated database fails here instead of
+// serving requests that would fail.
+func prepareSchema(cfg *config.Config, db *gorm.DB) error {
+	migrator, err := newMigrator(cfg, db)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.MigrateTimeout)*time.Second)
+	defer cancel()
+	if cfg.MigrateOnStart {
+		if _, err := migrator.Up(ctx); err != nil {
+			return err
+		}
+	}
+	return migrator.Check(ctx)
+}
+
+func newMigrator(cfg *config.Config, db *gorm.DB) (*migrations.Migrator, error) {
+	all, err := migrations.All()
+	if err != nil {
+		return nil, err
+	}
+	return migrations.NewMigrator(migrations.NewPostgresStore(db), all, migrations.Options{DryRun: cfg.MigrateDryRun}), nil
+}
+
+// migrateCommand is a parsed `migrate` command line
+type migrateCommand struct {
+	action string
+	steps  int
+	// flags are the config flags that follow the action
+	flags []string
+}
+
+// parseMigrateArgs reads `up`, `down [STEPS]` or `status`, followed by config flags. Down
+// rolls back one migration unless told otherwise.
+func parseMigrateArgs(args []string) (migrateCommand, error) {
+	if len(args) == 0 {
+		return migrateCommand{}, errors.New(constants.ErrInvalidMigrateCommand)
+	}
+	command := migrateCommand{action: args[0], flags: args[1:]}
+	switch command.action {
+	case "up", "status":
+	case "down":
+		command.steps = 1
+		if len(command.flags) > 0 {
+			if steps, err := strconv.Atoi(command.flags[0]); err == nil {
+				if steps < 1 {
+					return migrateCommand{}, errors.New(constants.ErrInvalidMigrateCommand)
+				}
+				command.steps = steps
+				command.flags = command.flags[1:]
+			}
+		}
+	default:
+		return migrateCommand{}, errors.New(constants.ErrInvalidMigrateCommand)
+	}
+	return command, nil
+}
+
+// runMigrate runs `task-manager migrate ...` against the configured database and returns the
+// exit code. DB_MIGRATE_DRY_RUN=true logs the SQL that would run instead.
+func runMigrate(args []string) int {
+	command, err := parseMigrateArgs(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	_, cfg, refresher := loadConfig(command.flags)
+	config.InitDB(refresher)
+	defer config.CloseDB()
+
+	migrator, err := newMigrator(cfg, config.DB)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.MigrateTimeout)*time.Second)
+		defer cancel()
+		err = command.run(ctx, migrator, os.Stdout)
+	}
+	if err != nil {
+		utils.Sugar.Errorw(constants.ErrFailedToMigrateDB, "error", err)
+		return 1
+	}
+	return 0
+}
+
+func (c migrateCommand) run(ctx context.Context, migrator *migrations.Migrator, out io.Writer) error {
+	switch c.action {
+	case "up":
+		_, err := migrator.Up(ctx)
+		return err
+	case "down":
+		_, err := migrator.Down(ctx, c.steps)
+		return err
+	}
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, version := range status.Applied {
+		fmt.Fprintf(out, "%04d applied\n", version)
+	}
+	for _, migration := range status.Pending {
+		fmt.Fprintf(out, "%s pending\n", migration)
+	}
+	for _, version := range status.Unknown {
+		fmt.Fprintf(out, "%04d unknown to this version\n", version)
+	}
+	return nil
+}
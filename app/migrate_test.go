@@ -0,0 +1,33 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMigrateArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    migrateCommand
+		wantErr bool
+	}{
+		{args: []string{"up"}, want: migrateCommand{action: "up", flags: []string{}}},
+		{args: []string{"status", "-config", "app.yaml"}, want: migrateCommand{action: "status", flags: []string{"-config", "app.yaml"}}},
+		{args: []string{"down"}, want: migrateCommand{action: "down", steps: 1, flags: []string{}}},
+		{args: []string{"down", "3", "-set", "DB_MIGRATE_DRY_RUN=true"}, want: migrateCommand{action: "down", steps: 3, flags: []string{"-set", "DB_MIGRATE_DRY_RUN=true"}}},
+		{args: []string{"down", "-env-file", ".env"}, want: migrateCommand{action: "down", steps: 1, flags: []string{"-env-file", ".env"}}},
+		{args: []string{"down", "0"}, wantErr: true},
+		{args: []string{"redo"}, wantErr: true},
+		{args: nil, wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := parseMigrateArgs(test.args)
+		if (err != nil) != test.wantErr {
+			t.Errorf("parseMigrateArgs(%q) error = %v, want error %v", test.args, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseMigrateArgs(%q) = %+v, want %+v", test.args, got, test.want)
+		}
+	}
+}
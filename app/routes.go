@@ -1,12 +1,16 @@
 package app
 
 import (
+	"task-manager-app/constants"
 	"task-manager-app/controller"
+	"task-manager-app/metrics"
+	"task-manager-app/middleware"
+
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterTaskRoutes(router *gin.Engine, taskController *controller.TaskController) {
-	tasks := router.Group("/tasks")
+func RegisterTaskRoutes(router *gin.Engine, taskController *controller.TaskController, rateLimiter *middleware.RateLimiter) {
+	tasks := router.Group("/tasks", rateLimiter.Handler())
 	{
 		tasks.POST("", taskController.CreateTask)
 		tasks.GET("", taskController.ListTasks)
@@ -17,6 +21,43 @@ func RegisterTaskRoutes(router *gin.Engine, taskController *controller.TaskContr
 }
 
 func RegisterHealthRoutes(router *gin.Engine, healthController *controller.HealthController) {
-	// Health check endpoint
-	router.GET("/health", healthController.HealthCheck)
+	router.GET("/livez", healthController.Live)
+	router.GET("/readyz", healthController.Ready)
+	// Kept for probes configured before /livez existed
+	router.GET("/health", healthController.Live)
+}
+
+func RegisterMetricsRoutes(router *gin.Engine) {
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+}
+
+func RegisterAdminRoutes(router *gin.Engine, adminController *controller.AdminController, healthController *controller.HealthController, adminToken string) {
+	admin := router.Group("/admin", middleware.AdminAuth(adminToken))
+	{
+		admin.DELETE("/users/:id/cache", adminController.InvalidateUser)
+		admin.PUT("/readiness", healthController.SetReadiness)
+		admin.GET("/config", adminController.GetConfig)
+	}
+}
+
+func RegisterWebhookRoutes(router *gin.Engine, webhookController *controller.WebhookController, adminToken string) {
+	webhooks := router.Group("/webhooks", middleware.AdminAuth(adminToken))
+	{
+		webhooks.POST("", webhookController.CreateWebhook)
+		webhooks.GET("", webhookController.ListWebhooks)
+		webhooks.GET("/:uuid", webhookController.GetWebhook)
+		webhooks.PUT("/:uuid", webhookController.UpdateWebhook)
+		webhooks.DELETE("/:uuid", webhookController.DeleteWebhook)
+		webhooks.GET("/:uuid/deliveries", webhookController.ListDeliveries)
+		webhooks.POST("/:uuid/deliveries/:delivery_id/redeliver", webhookController.Redeliver)
+	}
+}
+
+func RegisterStreamRoutes(router *gin.Engine, streamController *controller.StreamController, changeFeedController *controller.ChangeFeedController, rateLimiter *middleware.RateLimiter, features *middleware.Features) {
+	router.GET("/tasks/stream", rateLimiter.Handler(), features.Require(constants.FeatureTaskStream), streamController.StreamTasks)
+	router.GET("/tasks/changes", rateLimiter.Handler(), features.Require(constants.FeatureChangeFeed), changeFeedController.ListChanges)
+}
+
+func RegisterBoardRoutes(router *gin.Engine, boardController *controller.BoardController, features *middleware.Features) {
+	router.GET("/boards/ws", features.Require(constants.FeatureBoards), boardController.Connect)
 }
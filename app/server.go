@@ -0,0 +1,561 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"task-manager-app/config"
+	"task-manager-app/constants"
+	"task-manager-app/controller"
+	"task-manager-app/events"
+	"task-manager-app/metrics"
+	"task-manager-app/middleware"
+	"task-manager-app/network/userManager"
+	"task-manager-app/repo"
+	"task-manager-app/secrets"
+	"task-manager-app/services/boardService"
+	"task-manager-app/services/changeFeedService"
+	"task-manager-app/services/healthService"
+	"task-manager-app/services/idempotencyService"
+	"task-manager-app/services/outboxService"
+	"task-manager-app/services/streamService"
+	"task-manager-app/services/taskManagerService"
+	"task-manager-app/services/userEventService"
+	"task-manager-app/services/userManagerServices"
+	"task-manager-app/services/validationService"
+	"task-manager-app/services/webhookService"
+	"task-manager-app/tracing"
+	"task-manager-app/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Options configures a Server. Config and DB are required; the other fields are optional.
+type Options struct {
+	Config *config.Config
+	DB     *gorm.DB
+	// Redis backs the user cache and the redis stream backend; both fall back to memory when nil
+	Redis *redis.Client
+	// Logger receives the server logs, utils.Sugar when nil
+	Logger *zap.SugaredLogger
+	// UserProvider looks users up; when nil it is built from Config.UserProvider
+	UserProvider userManagerServices.UserProvider
+	// Clock stamps stored tasks, events and deliveries and expires idempotency keys, cached
+	// users and board tokens, time.Now when nil
+	Clock func() time.Time
+	// LogLevel is set to LOG_LEVEL on every Reload. When nil it is the level of the
+	// utils.InitLogger loggers if Logger is nil too, and the level is not reloaded otherwise.
+	LogLevel *zap.AtomicLevel
+	// Secrets holds the secret settings of Config. The server refreshes them from Start to
+	// Stop and reconnects to DB when its credentials rotate; nil when secrets are not tracked.
+	Secrets *secrets.Refresher
+	// SchemaPrepared skips migrating and checking the schema of DB and recomputing task title
+	// keys in New, for a database the caller prepared
+	SchemaPrepared bool
+}
+
+// Server is the task manager API with its background workers. Several servers can run in
+// one process, each with its own database, users and settings; only Prometheus metrics and
+// tracing are shared by the process.
+type Server struct {
+	config   *config.Config
+	db       *gorm.DB
+	logger   *zap.SugaredLogger
+	logLevel *zap.AtomicLevel
+	secrets  *secrets.Refresher
+	clock    func() time.Time
+	handler  http.Handler
+	health   *healthService.HealthService
+	started  bool
+	// current is the configuration in use, with the settings reloaded since New applied
+	current atomic.Pointer[config.Config]
+
+	taskStream        *streamService.TaskStream
+	changeFeed        *changeFeedService.ChangeFeed
+	outboxRelay       *outboxService.OutboxRelay
+	webhookDispatcher *webhookService.WebhookDispatcher
+	userEvents        *userEventService.UserEventConsumer
+	userEventWriter   events.MessageWriter
+	publisher         events.Publisher
+	boardHub          *boardService.BoardHub
+	boardController   *controller.BoardController
+	rateLimiter       *middleware.RateLimiter
+	cors              *middleware.CORS
+	features          *middleware.Features
+}
+
+// New prepares the database of opts and wires a server without starting anything: Handler
+// serves requests straight away, and Start runs the background workers.
+func New(opts Options) (*Server, error) {
+	if opts.Config == nil || opts.DB == nil {
+		return nil, fmt.Errorf(constants.ErrServerNeedsConfigAndDB)
+	}
+	cfg := opts.Config
+	logger := opts.Logger
+	logLevel := opts.LogLevel
+	if logger == nil {
+		logger = utils.Sugar
+		if logLevel == nil {
+			logLevel = utils.LogLevel()
+		}
+	}
+	clock := opts.Clock
+	if clock == nil {
+		clock = time.Now
+	}
+	db := opts.DB.Session(&gorm.Session{NowFunc: clock})
+	s := &Server{config: cfg, db: opts.DB, logger: logger, logLevel: logLevel, secrets: opts.Secrets, clock: clock}
+	s.current.Store(cfg)
+	if err := s.prepareDatabase(db, opts.SchemaPrepared); err != nil {
+		return nil, err
+	}
+	if s.secrets != nil {
+		s.secrets.OnChange(s.applyRotatedSecrets)
+	}
+
+	taskRepo := repo.NewTaskRepository(db)
+	var userCache userManagerServices.UserCache
+	if cfg.UserCacheEnabled {
+		userCache = userManagerServices.NewUserCache(userManagerServices.UserCacheOptions{
+			PositiveTTL:  time.Duration(cfg.UserCacheTTL) * time.Second,
+			NegativeTTL:  time.Duration(cfg.UserCacheNegTTL) * time.Second,
+			MaxSize:      cfg.UserCacheMaxSize,
+			Redis:        opts.Redis,
+			RedisTimeout: time.Duration(cfg.RedisTimeout) * time.Millisecond,
+			LoadTimeout:  userLoadTimeout(cfg),
+			Clock:        clock,
+			Logger:       logger,
+		})
+	}
+	userProvider := opts.UserProvider
+	var userClient *userManager.UserServiceClient
+	if userProvider == nil {
+		if cfg.UserProvider == "" || cfg.UserProvider == userManagerServices.ProviderHTTP {
+			options := userManager.UserServiceClientOptions(cfg)
+			options.Logger = logger
+			options.Clock = clock
+			userClient = userManager.NewUserServiceClient(cfg.UserServiceURL, options)
+		}
+		provider, err := userManagerServices.NewUserProvider(userManagerServices.UserProviderOptions{
+			Type:     cfg.UserProvider,
+			Client:   userClient,
+			FilePath: cfg.UserProviderFile,
+			LDAP: userManagerServices.LDAPOptions{
+				URL:            cfg.LdapURL,
+				BindDN:         cfg.LdapBindDN,
+				BindPassword:   cfg.LdapBindPassword,
+				BaseDN:         cfg.LdapBaseDN,
+				IDAttribute:    cfg.LdapIDAttribute,
+				NameAttribute:  cfg.LdapNameAttribute,
+				EmailAttribute: cfg.LdapMailAttribute,
+				Timeout:        time.Duration(cfg.LdapTimeout) * time.Millisecond,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", constants.ErrFailedToInitProvider, err)
+		}
+		userProvider = provider
+	}
+	userService := userManagerServices.NewUserService(userProvider, cfg.UserServicePolicy, userCache)
+	validationSvc := validationService.NewValidationService(userService, taskRepo, cfg.TitleNormalizer)
+	taskService := taskManagerService.NewTaskService(taskRepo, validationSvc, userService, clock)
+
+	// Publish events written to the outbox by the task service to webhooks, Kafka and the task
+	// stream. Each gets every event even when another fails, so a Kafka outage does not hold
+	// up the stream; the relay publishes a failed event again to all of them.
+	webhookRepo := repo.NewWebhookRepository(db)
+	changeBus, err := s.newChangeBus(opts.Redis)
+	if err != nil {
+		return nil, err
+	}
+	eventPublisher, err := s.newEventPublisher()
+	if err != nil {
+		return nil, err
+	}
+	s.publisher = events.NewFanOutPublisher(webhookService.NewWebhookPublisher(webhookRepo, clock), eventPublisher, streamService.NewStreamPublisher(changeBus, logger))
+	s.outboxRelay = outboxService.NewOutboxRelay(repo.NewOutboxRepository(db), s.publisher, outboxService.RelayOptions{
+		PollInterval:   time.Duration(cfg.OutboxPollInterval) * time.Millisecond,
+		BatchSize:      cfg.OutboxBatchSize,
+		BaseBackoff:    time.Second,
+		MaxBackoff:     time.Duration(cfg.OutboxMaxBackoff) * time.Second,
+		MaxAttempts:    cfg.OutboxMaxAttempts,
+		Lease:          time.Duration(cfg.OutboxLease) * time.Second,
+		PublishTimeout: time.Duration(cfg.OutboxPublishTimeout) * time.Second,
+		Retention:      time.Duration(cfg.OutboxRetention) * time.Hour,
+		Logger:         logger,
+		Clock:          clock,
+	})
+	s.webhookDispatcher = webhookService.NewWebhookDispatcher(webhookRepo, webhookService.DispatcherOptions{
+		PollInterval: time.Duration(cfg.WebhookPollInterval) * time.Millisecond,
+		Concurrency:  cfg.WebhookConcurrency,
+		Timeout:      time.Duration(cfg.WebhookTimeout) * time.Second,
+		BaseBackoff:  time.Second,
+		MaxBackoff:   time.Duration(cfg.WebhookMaxBackoff) * time.Second,
+		MaxAttempts:  cfg.WebhookMaxAttempts,
+		DisableAfter: cfg.WebhookDisableAfter,
+		Retention:    time.Duration(cfg.WebhookRetention) * time.Hour,
+		Logger:       logger,
+		Clock:        clock,
+	})
+	if err := s.newUserEventConsumer(db, userCache, userService); err != nil {
+		return nil, err
+	}
+	s.taskStream = streamService.NewTaskStream(changeBus, streamService.StreamOptions{
+		ReplaySize:   cfg.StreamReplaySize,
+		ClientBuffer: cfg.StreamClientBuffer,
+	})
+	s.changeFeed = changeFeedService.NewChangeFeed(repo.NewTaskChangeRepository(db), s.taskStream, changeFeedService.FeedOptions{
+		MaxLimit:     cfg.ChangeFeedMaxLimit,
+		MaxWait:      time.Duration(cfg.ChangeFeedMaxWait) * time.Second,
+		PollInterval: time.Duration(cfg.ChangeFeedPoll) * time.Second,
+		Clock:        clock,
+	})
+	idempotencySvc := idempotencyService.NewIdempotencyService(repo.NewIdempotencyRepository(db),
+		time.Duration(cfg.IdempotencyTTL)*time.Minute,
+		time.Duration(cfg.IdempotencyLease)*time.Second, clock, logger)
+	s.health = s.newHealthService(db, opts.Redis, userClient)
+
+	taskController := controller.NewTaskController(taskService, idempotencySvc)
+	healthController := controller.NewHealthController(s.health)
+	streamController := controller.NewStreamController(s.taskStream, validationSvc, time.Duration(cfg.StreamHeartbeat)*time.Second)
+	changeFeedController := controller.NewChangeFeedController(s.changeFeed)
+	adminController := controller.NewAdminController(userCache, func() map[string]string {
+		return s.current.Load().Settings()
+	})
+	webhookController := controller.NewWebhookController(webhookService.NewWebhookService(webhookRepo, clock))
+	s.boardHub = boardService.NewBoardHub(s.taskStream, boardOptions(cfg))
+	s.boardController = controller.NewBoardController(s.boardHub, cfg.BoardTokenSecret, cfg.BoardAllowedOrigins, clock)
+
+	s.rateLimiter = middleware.NewRateLimiter(cfg.RateLimitPerSec, cfg.RateLimitBurst, clock)
+	s.cors = middleware.NewCORS(cfg.CORSAllowedOrigins)
+	s.features = middleware.NewFeatures(cfg.DisabledFeatures)
+
+	// Register routes
+	router := gin.Default()
+	router.Use(middleware.Metrics())
+	router.Use(otelgin.Middleware(cfg.AppName))
+	router.Use(middleware.RequestID(logger))
+	router.Use(s.cors.Handler())
+	metrics.RegisterTaskCollector(taskRepo)
+	RegisterMetricsRoutes(router)
+	RegisterTaskRoutes(router, taskController, s.rateLimiter)
+	RegisterStreamRoutes(router, streamController, changeFeedController, s.rateLimiter, s.features)
+	RegisterHealthRoutes(router, healthController)
+	if cfg.AdminToken != "" {
+		RegisterAdminRoutes(router, adminController, healthController, cfg.AdminToken)
+		RegisterWebhookRoutes(router, webhookController, cfg.AdminToken)
+	} else {
+		logger.Warnf("%s is not set, the /admin and /webhooks routes are disabled", constants.AdminToken)
+	}
+	if cfg.BoardTokenSecret != "" {
+		RegisterBoardRoutes(router, s.boardController, s.features)
+	} else {
+		logger.Warnf("%s is not set, the /boards/ws route is disabled", constants.BoardTokenSecret)
+	}
+	s.handler = router
+	return s, nil
+}
+
+// Handler serves the API
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// Start runs the outbox relay, the webhook dispatcher, the user event consumer, the task
+// stream and the change feed in the background until Stop is called
+func (s *Server) Start() error {
+	if err := s.taskStream.Start(); err != nil {
+		return fmt.Errorf("%s: %w", constants.ErrFailedToStartStream, err)
+	}
+	s.changeFeed.Start()
+	s.outboxRelay.Start()
+	s.webhookDispatcher.Start()
+	if s.userEvents != nil {
+		s.userEvents.Start()
+	}
+	if s.secrets != nil {
+		s.secrets.Start()
+	}
+	s.started = true
+	return nil
+}
+
+// Reload applies the log level, rate limits, CORS origins, disabled features and board
+// settings of cfg while the server runs and reports cfg on /admin/config. The other settings
+// keep the values New was given.
+func (s *Server) Reload(cfg *config.Config) {
+	s.current.Store(cfg)
+	if s.logLevel != nil {
+		if err := s.logLevel.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+			s.logger.Errorw("Failed to change the log level", "error", err)
+		}
+	}
+	s.rateLimiter.SetLimits(cfg.RateLimitPerSec, cfg.RateLimitBurst)
+	s.cors.SetAllowedOrigins(cfg.CORSAllowedOrigins)
+	s.features.SetDisabled(cfg.DisabledFeatures)
+	s.boardHub.SetOptions(boardOptions(cfg))
+	s.boardController.SetAllowedOrigins(cfg.BoardAllowedOrigins)
+}
+
+// MarkNotReady fails readiness probes from now on, so load balancers stop sending traffic
+func (s *Server) MarkNotReady(reason string) {
+	s.health.MarkNotReady(reason)
+}
+
+// CloseStreams ends SSE streams, board sessions and change feed long polls. Register it with
+// http.Server.RegisterOnShutdown, since those requests would otherwise hold the shutdown open.
+func (s *Server) CloseStreams() {
+	if !s.started {
+		return
+	}
+	s.changeFeed.Stop()
+	s.taskStream.Stop()
+}
+
+// Stop stops the background workers after the work in hand and flushes the event publishers,
+// giving up when ctx ends. Call it once requests are drained; the database and Redis clients
+// belong to the caller and are left open.
+func (s *Server) Stop(ctx context.Context) error {
+	if !shutdownWith(ctx, s.logger, s.stopSteps()...) {
+		return fmt.Errorf(constants.ErrServerStopFailed)
+	}
+	return nil
+}
+
+func (s *Server) stopSteps() []shutdownStep {
+	if !s.started {
+		return []shutdownStep{{"event publishers", closeWith(s.publisher.Close)}}
+	}
+	steps := []shutdownStep{
+		{"user event consumer", s.stopUserEvents},
+		{"webhook dispatcher", s.webhookDispatcher.Stop},
+		{"outbox relay", s.outboxRelay.Stop},
+		{"event publishers", closeWith(s.publisher.Close)},
+	}
+	if s.secrets != nil {
+		steps = append(steps, shutdownStep{"secret refresh", s.secrets.Stop})
+	}
+	return steps
+}
+
+// prepareDatabase instruments db with metrics and tracing and, unless the caller prepared
+// the schema, migrates and checks it and brings the stored title keys in line with the
+// TASK_TITLE_UNIQUE_* rules before any task is written
+func (s *Server) prepareDatabase(db *gorm.DB, schemaPrepared bool) error {
+	// Servers sharing a database handle share its plugins too
+	if err := db.Use(metrics.GormPlugin{}); err != nil && !errors.Is(err, gorm.ErrRegistered) {
+		return fmt.Errorf("%s: %w", constants.ErrFailedToInitMetrics, err)
+	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil && !errors.Is(err, gorm.ErrRegistered) {
+		return fmt.Errorf("%s: %w", constants.ErrFailedToInitTracing, err)
+	}
+	if schemaPrepared {
+		return nil
+	}
+
+	// Nothing is served from a schema older than the code
+	if err := prepareSchema(s.config, db); err != nil {
+		return fmt.Errorf("%s: %w", constants.ErrFailedToMigrateDB, err)
+	}
+	rekeyed, taskErr := repo.NewTitleKeyRepository(db).Rekey(s.config.TitleNormalizer)
+	if taskErr != nil {
+		return fmt.Errorf("%s: %s", constants.ErrFailedToRekeyTitles, taskErr.Message)
+	}
+	if rekeyed > 0 {
+		s.logger.Infow("Recomputed task title keys", "tasks", rekeyed)
+	}
+	return nil
+}
+
+// applyRotatedSecrets reconnects to the database when its credentials changed. Redis reads
+// its credentials for every new connection, and the other settings need a restart.
+func (s *Server) applyRotatedSecrets(names []string) {
+	reconnect := false
+	for _, name := range names {
+		switch {
+		case name == constants.PostgresUsername || name == constants.PostgresPassword:
+			reconnect = true
+		case !config.Rotatable(name):
+			s.logger.Warnw("Secret rotated, restart to use it", "setting", name)
+		}
+	}
+	if !reconnect {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.config.DbConnectTimeout)*time.Second)
+	defer cancel()
+	if err := config.ResetDBConnections(ctx, s.db, s.config.DbMaxIdleConns); err != nil {
+		s.logger.Errorw(constants.ErrFailedToResetDBConns, "error", err)
+		return
+	}
+	s.logger.Info("Reconnected to PostgreSQL with rotated credentials")
+}
+
+func (s *Server) stopUserEvents(ctx context.Context) error {
+	if s.userEvents == nil {
+		return nil
+	}
+	if err := s.userEvents.Stop(ctx); err != nil {
+		return err
+	}
+	// The retry and dead letter writer is only used by the consumer
+	return s.userEventWriter.Close()
+}
+
+// newEventPublisher publishes task events to Kafka when KAFKA_HOSTS is set and drops them otherwise
+func (s *Server) newEventPublisher() (events.Publisher, error) {
+	options := kafkaOptions(s.config)
+	if !options.Enabled() {
+		return events.NoopPublisher{}, nil
+	}
+	writer, err := events.NewKafkaWriter(options)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", constants.ErrFailedToInitKafka, err)
+	}
+	return events.NewPublisher(writer, s.config.KafkaTaskTopic), nil
+}
+
+// newHealthService checks the database on every readiness probe, and the user service, Redis
+// and Kafka when they are used. The user service is critical only under the fail-closed policy,
+// since tasks are accepted without it otherwise. Redis and Kafka are not: the user cache falls
+// back to the user service, and events wait in the outbox until Kafka is back.
+func (s *Server) newHealthService(db *gorm.DB, redisClient *redis.Client, userClient *userManager.UserServiceClient) *healthService.HealthService {
+	checks := []healthService.Check{{
+		Name:     constants.DependencyDatabase,
+		Critical: true,
+		Probe: func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		},
+	}}
+	if userClient != nil {
+		checks = append(checks, healthService.Check{
+			Name:     constants.DependencyUserService,
+			Critical: s.config.UserServicePolicy != constants.FailurePolicyOpen,
+			Probe:    userClient.Ping,
+		})
+	}
+	if redisClient != nil {
+		checks = append(checks, healthService.Check{
+			Name: constants.DependencyRedis,
+			Probe: func(ctx context.Context) error {
+				return redisClient.Ping(ctx).Err()
+			},
+		})
+	}
+	if options := kafkaOptions(s.config); options.Enabled() {
+		checks = append(checks, healthService.Check{Name: constants.DependencyKafka, Probe: options.Ping})
+	}
+	return healthService.NewHealthService(time.Duration(s.config.HealthTimeout)*time.Millisecond, checks...)
+}
+
+// newChangeBus carries task changes to the streams of every replica through Redis, or within
+// this process for a single replica
+func (s *Server) newChangeBus(redisClient *redis.Client) (events.ChangeBus, error) {
+	switch s.config.StreamBackend {
+	case constants.StreamBackendMemory:
+		return events.NewMemoryChangeBus(), nil
+	case constants.StreamBackendRedis:
+		if redisClient == nil {
+			return nil, fmt.Errorf(constants.ErrStreamNeedsRedis)
+		}
+		return events.NewRedisChangeBus(redisClient, s.config.StreamChannel, s.logger), nil
+	default:
+		return nil, fmt.Errorf(constants.ErrInvalidStreamBackend)
+	}
+}
+
+// newUserEventConsumer applies user lifecycle events to tasks when KAFKA_HOSTS and
+// KAFKA_GROUP_ID are set
+func (s *Server) newUserEventConsumer(db *gorm.DB, userCache userManagerServices.UserCache, userService userManagerServices.UserService) error {
+	options := kafkaOptions(s.config)
+	if !options.Enabled() {
+		return nil
+	}
+	if s.config.KafkaGroupId == "" {
+		s.logger.Warnf("%s is not set, user events are not consumed", constants.KafkaGroupId)
+		return nil
+	}
+
+	handler, err := userEventService.NewUserEventHandler(repo.NewInboxRepository(db), userCache, userEventService.HandlerOptions{
+		Policy:     s.config.UserTaskPolicy,
+		ReassignTo: s.config.UserReassignTo,
+		Users:      userService,
+		Retention:  time.Duration(s.config.UserEventRetention) * time.Hour,
+		Logger:     s.logger,
+		Clock:      s.clock,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", constants.ErrFailedToInitConsumer, err)
+	}
+	reader, err := events.NewKafkaReader(options, s.config.KafkaGroupId, s.config.KafkaUserTopic)
+	if err != nil {
+		return fmt.Errorf("%s: %w", constants.ErrFailedToInitConsumer, err)
+	}
+	retryReader, err := events.NewKafkaReader(options, s.config.KafkaGroupId, s.config.KafkaRetryTopic)
+	if err != nil {
+		return fmt.Errorf("%s: %w", constants.ErrFailedToInitConsumer, err)
+	}
+	writer, err := events.NewKafkaWriter(options)
+	if err != nil {
+		return fmt.Errorf("%s: %w", constants.ErrFailedToInitConsumer, err)
+	}
+
+	s.userEventWriter = writer
+	s.userEvents = userEventService.NewUserEventConsumer(reader, retryReader, writer, handler, userEventService.ConsumerOptions{
+		RetryTopic:      s.config.KafkaRetryTopic,
+		DeadLetterTopic: s.config.KafkaDLQTopic,
+		MaxAttempts:     s.config.UserEventAttempts,
+		BaseBackoff:     time.Second,
+		MaxBackoff:      time.Duration(s.config.UserEventMaxBackoff) * time.Second,
+		Logger:          s.logger,
+		Clock:           s.clock,
+	})
+	return nil
+}
+
+func boardOptions(cfg *config.Config) boardService.BoardOptions {
+	return boardService.BoardOptions{
+		SendBuffer:              cfg.BoardSendBuffer,
+		MaxSubscriptions:        cfg.BoardMaxSubs,
+		MaxTasksPerSubscription: cfg.BoardMaxTasks,
+		MaxConnectionsPerUser:   cfg.BoardMaxConns,
+		MaxMessageBytes:         int64(cfg.BoardMaxMsgBytes),
+		MessagesPerSecond:       cfg.BoardMsgsPerSec,
+		PingInterval:            time.Duration(cfg.BoardPing) * time.Second,
+		WriteTimeout:            constants.DefaultBoardWriteSecs * time.Second,
+	}
+}
+
+// userLoadTimeout bounds a user lookup shared by concurrent requests by the longest the user
+// directory may take to answer, retries included
+func userLoadTimeout(cfg *config.Config) time.Duration {
+	switch cfg.UserProvider {
+	case userManagerServices.ProviderLDAP:
+		return time.Duration(cfg.LdapTimeout) * time.Millisecond
+	case userManagerServices.ProviderFile:
+		return 0
+	}
+	options := userManager.UserServiceClientOptions(cfg)
+	return time.Duration(options.MaxRetries+1)*options.Timeout + time.Duration(options.MaxRetries)*options.MaxBackoff
+}
+
+func kafkaOptions(cfg *config.Config) events.KafkaOptions {
+	return events.KafkaOptions{
+		Brokers:    cfg.KafkaHosts,
+		Username:   cfg.KafkaUsername,
+		Password:   cfg.KafkaPassword,
+		AuthAlgo:   cfg.KafkaAuthAlgo,
+		TLSEnabled: cfg.KafkaTLS,
+	}
+}
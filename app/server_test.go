@@ -0,0 +1,172 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"task-manager-app/config"
+	"task-manager-app/constants"
+	"task-manager-app/response"
+	"task-manager-app/services/userManagerServices"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// noUsers knows no users
+type noUsers struct{}
+
+func (noUsers) GetUser(ctx context.Context, userID string) (*userManagerServices.UserProfile, error) {
+	return nil, nil
+}
+
+func (noUsers) GetUsers(ctx context.Context, userIDs []string) (map[string]*userManagerServices.UserProfile, error) {
+	return map[string]*userManagerServices.UserProfile{}, nil
+}
+
+// unreachableDB returns a handle on a database nothing listens for, so that servers can be
+// built without one
+func unreachableDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.Open("host=127.0.0.1 port=1 user=test dbname=test sslmode=disable connect_timeout=1"), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("failed to open database handle: %v", err)
+	}
+	return db
+}
+
+func newTestServer(t *testing.T, adminToken string) *Server {
+	t.Helper()
+	cfg := config.FromEnv()
+	cfg.KafkaHosts = nil
+	cfg.StreamBackend = constants.StreamBackendMemory
+	cfg.AdminToken = adminToken
+	server, err := New(Options{
+		Config:         cfg,
+		DB:             unreachableDB(t),
+		Logger:         zap.NewNop().Sugar(),
+		UserProvider:   noUsers{},
+		SchemaPrepared: true,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return server
+}
+
+func serve(server *Server, method, path, token, body string) int {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(constants.HeaderAdminToken, token)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestServersInOneProcessAreIndependent(t *testing.T) {
+	first := newTestServer(t, "first-token")
+	second := newTestServer(t, "second-token")
+	for _, server := range []*Server{first, second} {
+		if err := server.Start(); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+	}
+
+	for name, server := range map[string]*Server{"first": first, "second": second} {
+		if code := serve(server, http.MethodGet, "/livez", "", ""); code != http.StatusOK {
+			t.Errorf("%s server answered /livez with %d, want 200", name, code)
+		}
+	}
+	if code := serve(first, http.MethodPut, "/admin/readiness", "first-token", `{"ready": true}`); code != http.StatusNoContent {
+		t.Errorf("first server rejected its own admin token with %d", code)
+	}
+	if code := serve(second, http.MethodPut, "/admin/readiness", "first-token", `{"ready": true}`); code != http.StatusUnauthorized {
+		t.Errorf("second server answered the first server's admin token with %d, want 401", code)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for name, server := range map[string]*Server{"first": first, "second": second} {
+		server.CloseStreams()
+		if err := server.Stop(ctx); err != nil {
+			t.Errorf("%s server did not stop: %v", name, err)
+		}
+	}
+}
+
+func TestNewNeedsConfigAndDB(t *testing.T) {
+	if _, err := New(Options{Config: config.FromEnv()}); err == nil {
+		t.Fatal("New accepted options without a database")
+	}
+	if _, err := New(Options{DB: unreachableDB(t)}); err == nil {
+		t.Fatal("New accepted options without a config")
+	}
+}
+
+func TestNewChecksTheSchema(t *testing.T) {
+	cfg := config.FromEnv()
+	cfg.KafkaHosts = nil
+	_, err := New(Options{Config: cfg, DB: unreachableDB(t), Logger: zap.NewNop().Sugar(), UserProvider: noUsers{}})
+	if err == nil || !strings.Contains(err.Error(), constants.ErrFailedToMigrateDB) {
+		t.Fatalf("New = %v, want the schema check to fail without a database", err)
+	}
+}
+
+func TestReloadChangesTheLogLevel(t *testing.T) {
+	cfg := config.FromEnv()
+	cfg.KafkaHosts = nil
+	level := zap.NewAtomicLevelAt(zap.InfoLevel)
+	server, err := New(Options{Config: cfg, DB: unreachableDB(t), Logger: zap.NewNop().Sugar(), UserProvider: noUsers{}, LogLevel: &level, SchemaPrepared: true})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	reloaded, _ := config.Sources{Overrides: map[string]string{constants.LogLevel: "debug"}}.Load()
+	server.Reload(reloaded)
+	if level.Level() != zap.DebugLevel {
+		t.Fatalf("level = %s after the reload, want debug", level.Level())
+	}
+}
+
+func TestReloadedSettingsAreReported(t *testing.T) {
+	server := newTestServer(t, "token")
+	reloaded, _ := config.Sources{Overrides: map[string]string{constants.BoardMsgsPerSec: "3"}}.Load()
+	server.Reload(reloaded)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/config", nil)
+	req.Header.Set(constants.HeaderAdminToken, "token")
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+	var body response.ConfigResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("/admin/config answered %d with %q", rec.Code, rec.Body.String())
+	}
+	if got := body.Settings[constants.BoardMsgsPerSec]; got != "3" {
+		t.Fatalf("/admin/config reports %s=%q, want the reloaded 3", constants.BoardMsgsPerSec, got)
+	}
+}
+
+func TestReloadedRateLimitAndFeaturesApplyToRequests(t *testing.T) {
+	server := newTestServer(t, "token")
+	if code := serve(server, http.MethodGet, "/tasks/changes", "", ""); code == http.StatusNotFound || code == http.StatusTooManyRequests {
+		t.Fatalf("/tasks/changes answered %d before the reload, want it served", code)
+	}
+
+	reloaded, _ := config.Sources{Overrides: map[string]string{
+		constants.RateLimitPerSec:  "1",
+		constants.RateLimitBurst:   "1",
+		constants.DisabledFeatures: constants.FeatureChangeFeed,
+	}}.Load()
+	server.Reload(reloaded)
+
+	if code := serve(server, http.MethodGet, "/tasks/changes", "", ""); code != http.StatusNotFound {
+		t.Errorf("/tasks/changes answered %d with the change feed disabled, want 404", code)
+	}
+	if code := serve(server, http.MethodGet, "/tasks/changes", "", ""); code != http.StatusTooManyRequests {
+		t.Errorf("second request answered %d, want 429 over the reloaded limit", code)
+	}
+}
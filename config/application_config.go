@@ -3,62 +3,295 @@ package config
 import (
 	"task-manager-app/constants"
 	"task-manager-app/utils"
-	"os"
-	"strings"
 )
 
-type applicationConfig struct {
-	AppName          string
-	AppVersion       string
-	AppHost          string
-	AppPort          string
-	KafkaHosts       []string
-	KafkaGroupId     string
-	UserAppBaseUri   string
-	ErrorCodes       string
-	KafkaRetryTopic  string
-	RedisPort        string
-	RedisPassword    string
-	RedisUsername    string
-	RedisDb          int
-	RedisTimeout     int
-	RedisPoolTimeout int
-	RedisEndpoint    string
-	KafkaUsername    string
-	KafkaPassword    string
-	PostgresAddress  string
-	Username         string
-	Password         string
-	DbName           string
-	PostgresHost     string
-	PostgresPort     string
-	MaxDbConnections int
+// Config holds the settings of the application, read by Sources.Load
+type Config struct {
+	AppName           string
+	AppVersion        string
+	AppHost           string
+	AppPort           string
+	KafkaHosts        []string
+	KafkaGroupId      string
+	UserAppBaseUri    string
+	ErrorCodes        string
+	KafkaRetryTopic   string
+	RedisPort         string
+	RedisPassword     string
+	RedisUsername     string
+	RedisDb           int
+	RedisTimeout      int
+	RedisPoolTimeout  int
+	RedisEndpoint     string
+	KafkaUsername     string
+	KafkaPassword     string
+	PostgresAddress   string
+	Username          string
+	Password          string
+	DbName            string
+	PostgresHost      string
+	PostgresPort      string
+	MaxDbConnections  int
+	DbMaxIdleConns    int
+	DbConnMaxLifetime int
+	DbConnectTimeout  int
+	DbLogLevel        string
+	MigrateOnStart    bool
+	MigrateDryRun     bool
+	MigrateTimeout    int
+	IdempotencyTTL    int
+	IdempotencyLease  int
+	TitleNormalizer   utils.TitleNormalizer
+	UserServicePolicy string
+	UserCacheEnabled  bool
+	UserCacheTTL      int
+	UserCacheNegTTL   int
+	UserCacheMaxSize  int
+	AdminToken        string
+
+	UserServiceURL              string
+	UserServiceTimeout          int
+	UserServiceMaxRetries       int
+	UserServiceBreakerThreshold int
+	UserServiceBreakerOpenSecs  int
+	UserServiceMaxConcurrent    int
+
+	UserProvider      string
+	UserProviderFile  string
+	LdapURL           string
+	LdapBindDN        string
+	LdapBindPassword  string
+	LdapBaseDN        string
+	LdapIDAttribute   string
+	LdapNameAttribute string
+	LdapMailAttribute string
+	LdapTimeout       int
+
+	KafkaAuthAlgo  string
+	KafkaTLS       bool
+	KafkaTaskTopic string
+	KafkaUserTopic string
+	KafkaDLQTopic  string
+
+	UserTaskPolicy      string
+	UserReassignTo      string
+	UserEventAttempts   int
+	UserEventMaxBackoff int
+	UserEventRetention  int
+
+	WebhookPollInterval int
+	WebhookConcurrency  int
+	WebhookTimeout      int
+	WebhookMaxAttempts  int
+	WebhookMaxBackoff   int
+	WebhookDisableAfter int
+	WebhookRetention    int
+
+	StreamBackend      string
+	StreamChannel      string
+	StreamReplaySize   int
+	StreamClientBuffer int
+	StreamHeartbeat    int
+
+	BoardTokenSecret    string
+	BoardAllowedOrigins []string
+	BoardSendBuffer     int
+	BoardMaxSubs        int
+	BoardMaxTasks       int
+	BoardMaxConns       int
+	BoardMaxMsgBytes    int
+	BoardMsgsPerSec     int
+	BoardPing           int
+
+	ChangeFeedMaxLimit int
+	ChangeFeedMaxWait  int
+	ChangeFeedPoll     int
+
+	TracingExporter string
+
+	LogLevel      string
+	LogFormat     string
+	LogFile       string
+	LogMaxSize    int
+	LogMaxBackups int
+	LogMaxAge     int
+
+	HealthTimeout int
+
+	ShutdownTimeout int
+	ShutdownDelay   int
+
+	HTTPReadHeaderTimeout int
+	HTTPIdleTimeout       int
+
+	RateLimitPerSec    int
+	RateLimitBurst     int
+	CORSAllowedOrigins []string
+	DisabledFeatures   []string
+
+	VaultAddr     string
+	VaultToken    string
+	SecretRefresh int
+	SecretTimeout int
+
+	ConfigWatch int
+
+	OutboxPollInterval   int
+	OutboxBatchSize      int
+	OutboxMaxBackoff     int
+	OutboxMaxAttempts    int
+	OutboxLease          int
+	OutboxPublishTimeout int
+	OutboxRetention      int
+
+	// settings holds the value of every setting as it was read, keyed by its name
+	settings map[string]string
 }
 
 var (
-	ApplicationConfig = &applicationConfig{}
+	ApplicationConfig = &Config{}
 )
 
-func (a applicationConfig) SetAwsSecretValues() {
-
-	ApplicationConfig = &applicationConfig{
-		AppName:          os.Getenv(constants.AppName),
-		AppVersion:       os.Getenv(constants.AppVersion),
-		AppHost:          os.Getenv(constants.HOST),
-		AppPort:          os.Getenv(constants.PORT),
-		KafkaHosts:       strings.Split(os.Getenv(constants.KafkaHosts), ","),
-		KafkaGroupId:     os.Getenv(constants.KafkaGroupId),
-		UserAppBaseUri:   os.Getenv(constants.UserAppBaseUri),
-		KafkaRetryTopic:  os.Getenv(constants.KafkaRetryTopic),
-		KafkaUsername:    os.Getenv(constants.KafkaUsername),
-		KafkaPassword:    os.Getenv(constants.KafkaPassword),
-		PostgresAddress:  os.Getenv(constants.PostgresAddress),
-		Username:         os.Getenv(constants.PostgresUsername),
-		Password:         os.Getenv(constants.PostgresPassword),
-		DbName:           os.Getenv(constants.PostgresDbName),
-		PostgresHost:     os.Getenv(constants.PostgresHost),
-		PostgresPort:     os.Getenv(constants.PostgresPort),
-		MaxDbConnections: utils.TaskManagerUtils.ParseStringToInt(os.Getenv(constants.MaxDbConnections)),
-	}
+// build reads every setting from v, using defaults for unset ones
+func build(v *values) *Config {
+	return &Config{
+		AppName:           v.string(constants.AppName, ""),
+		AppVersion:        v.string(constants.AppVersion, ""),
+		AppHost:           v.string(constants.HOST, ""),
+		AppPort:           v.string(constants.PORT, ""),
+		KafkaHosts:        v.list(constants.KafkaHosts),
+		KafkaGroupId:      v.string(constants.KafkaGroupId, ""),
+		UserAppBaseUri:    v.string(constants.UserAppBaseUri, ""),
+		KafkaRetryTopic:   v.string(constants.KafkaRetryTopic, constants.DefaultKafkaRetryTopic),
+		KafkaUsername:     v.string(constants.KafkaUsername, ""),
+		KafkaPassword:     v.string(constants.KafkaPassword, ""),
+		PostgresAddress:   v.string(constants.PostgresAddress, ""),
+		Username:          v.string(constants.PostgresUsername, ""),
+		Password:          v.string(constants.PostgresPassword, ""),
+		DbName:            v.string(constants.PostgresDbName, ""),
+		PostgresHost:      v.string(constants.PostgresHost, ""),
+		PostgresPort:      v.string(constants.PostgresPort, ""),
+		MaxDbConnections:  v.int(constants.MaxDbConnections, constants.DefaultMaxDbConns),
+		DbMaxIdleConns:    v.int(constants.DbMaxIdleConns, constants.DefaultDbMaxIdleConns),
+		DbConnMaxLifetime: v.int(constants.DbConnMaxLifetime, constants.DefaultDbConnLifetime),
+		DbConnectTimeout:  v.int(constants.DbConnectTimeout, constants.DefaultDbConnectTimeout),
+		DbLogLevel:        v.string(constants.DbLogLevel, constants.DefaultDbLogLevel),
+		MigrateOnStart:    v.bool(constants.MigrateOnStart, true),
+		MigrateDryRun:     v.bool(constants.MigrateDryRun, false),
+		MigrateTimeout:    v.int(constants.MigrateTimeout, constants.DefaultMigrateTimeout),
+		IdempotencyTTL:    v.int(constants.IdempotencyTTL, constants.DefaultIdempotencyTTLMins),
+		IdempotencyLease:  v.int(constants.IdempotencyLease, constants.DefaultIdempotencyLease),
+		UserServicePolicy: v.string(constants.UserServicePolicy, ""),
+		UserCacheEnabled:  v.bool(constants.UserCacheEnabled, true),
+		UserCacheTTL:      v.int(constants.UserCacheTTL, constants.DefaultUserCacheTTLSecs),
+		UserCacheNegTTL:   v.int(constants.UserCacheNegTTL, constants.DefaultUserCacheNegTTL),
+		UserCacheMaxSize:  v.int(constants.UserCacheMaxSize, constants.DefaultUserCacheMaxSize),
+		RedisEndpoint:     v.string(constants.RedisEndpoint, ""),
+		RedisPort:         v.string(constants.RedisPort, ""),
+		RedisUsername:     v.string(constants.RedisUsername, ""),
+		RedisPassword:     v.string(constants.RedisPassword, ""),
+		RedisDb:           v.int(constants.RedisDb, 0),
+		RedisTimeout:      v.int(constants.RedisTimeout, constants.DefaultRedisTimeoutMs),
+		RedisPoolTimeout:  v.int(constants.RedisPoolTimeout, constants.DefaultRedisTimeoutMs),
+		AdminToken:        v.string(constants.AdminToken, ""),
+
+		UserServiceURL:              v.string(constants.UserServiceURL, constants.DefaultUserServiceURL),
+		UserServiceTimeout:          v.int(constants.UserSvcTimeout, constants.DefaultUserSvcTimeoutMs),
+		UserServiceMaxRetries:       v.int(constants.UserSvcRetries, constants.DefaultUserSvcRetries),
+		UserServiceBreakerThreshold: v.int(constants.UserSvcBreakerMax, constants.DefaultUserSvcBreakerMax),
+		UserServiceBreakerOpenSecs:  v.int(constants.UserSvcBreakerTTL, constants.DefaultUserSvcBreakerTTL),
+		UserServiceMaxConcurrent:    v.int(constants.UserSvcBulkhead, constants.DefaultUserSvcBulkhead),
+
+		UserProvider:      v.string(constants.UserProvider, ""),
+		UserProviderFile:  v.string(constants.UserProviderFile, ""),
+		LdapURL:           v.string(constants.LdapURL, ""),
+		LdapBindDN:        v.string(constants.LdapBindDN, ""),
+		LdapBindPassword:  v.string(constants.LdapBindPassword, ""),
+		LdapBaseDN:        v.string(constants.LdapBaseDN, ""),
+		LdapIDAttribute:   v.string(constants.LdapIDAttribute, ""),
+		LdapNameAttribute: v.string(constants.LdapNameAttribute, ""),
+		LdapMailAttribute: v.string(constants.LdapMailAttribute, ""),
+		LdapTimeout:       v.int(constants.LdapTimeout, constants.DefaultLdapTimeoutMs),
+
+		KafkaAuthAlgo:  v.string(constants.KafkaAuthAlgo, ""),
+		KafkaTLS:       v.bool(constants.KafkaTLS, false),
+		KafkaTaskTopic: v.string(constants.KafkaTaskTopic, constants.DefaultKafkaTaskTopic),
+		KafkaUserTopic: v.string(constants.KafkaUserTopic, constants.DefaultKafkaUserTopic),
+		KafkaDLQTopic:  v.string(constants.KafkaDLQTopic, constants.DefaultKafkaDeadLetter),
+
+		UserTaskPolicy:      v.string(constants.UserTaskPolicy, constants.UserTaskPolicyUnassign),
+		UserReassignTo:      v.string(constants.UserReassignTo, ""),
+		UserEventAttempts:   v.int(constants.UserEventAttempts, constants.DefaultUserEventAttempts),
+		UserEventMaxBackoff: v.int(constants.UserEventMaxBackoff, constants.DefaultUserEventBackoff),
+		UserEventRetention:  v.int(constants.UserEventRetention, constants.DefaultUserEventRetention),
+
+		WebhookPollInterval: v.int(constants.WebhookPollInterval, constants.DefaultWebhookPollMs),
+		WebhookConcurrency:  v.int(constants.WebhookConcurrency, constants.DefaultWebhookConcurrency),
+		WebhookTimeout:      v.int(constants.WebhookTimeout, constants.DefaultWebhookTimeoutSecs),
+		WebhookMaxAttempts:  v.int(constants.WebhookMaxAttempts, constants.DefaultWebhookMaxAttempts),
+		WebhookMaxBackoff:   v.int(constants.WebhookMaxBackoff, constants.DefaultWebhookMaxBackoff),
+		WebhookDisableAfter: v.int(constants.WebhookDisableAfter, constants.DefaultWebhookDisableLim),
+		WebhookRetention:    v.int(constants.WebhookRetention, constants.DefaultWebhookRetention),
+
+		StreamBackend:      v.string(constants.StreamBackend, constants.DefaultStreamBackend),
+		StreamChannel:      v.string(constants.StreamChannel, constants.DefaultStreamChannel),
+		StreamReplaySize:   v.int(constants.StreamReplaySize, constants.DefaultStreamReplaySize),
+		StreamClientBuffer: v.int(constants.StreamClientBuffer, constants.DefaultStreamClientBuffer),
+		StreamHeartbeat:    v.int(constants.StreamHeartbeat, constants.DefaultStreamHeartbeat),
+
+		BoardTokenSecret:    v.string(constants.BoardTokenSecret, ""),
+		BoardAllowedOrigins: v.list(constants.BoardAllowedOrigins),
+		BoardSendBuffer:     v.int(constants.BoardSendBuffer, constants.DefaultBoardSendBuffer),
+		BoardMaxSubs:        v.int(constants.BoardMaxSubs, constants.DefaultBoardMaxSubs),
+		BoardMaxTasks:       v.int(constants.BoardMaxTasks, constants.DefaultBoardMaxTasks),
+		BoardMaxConns:       v.int(constants.BoardMaxConns, constants.DefaultBoardMaxConns),
+		BoardMaxMsgBytes:    v.int(constants.BoardMaxMsgBytes, constants.DefaultBoardMaxMsgBytes),
+		BoardMsgsPerSec:     v.int(constants.BoardMsgsPerSec, constants.DefaultBoardMsgsPerSec),
+		BoardPing:           v.int(constants.BoardPing, constants.DefaultBoardPingSecs),
+
+		ChangeFeedMaxLimit: v.int(constants.ChangeFeedMaxLimit, constants.DefaultChangeFeedMaxLimit),
+		ChangeFeedMaxWait:  v.int(constants.ChangeFeedMaxWait, constants.DefaultChangeFeedMaxWait),
+		ChangeFeedPoll:     v.int(constants.ChangeFeedPoll, constants.DefaultChangeFeedPoll),
 
+		TracingExporter: v.string(constants.TracingExporter, constants.DefaultTracingExporter),
+
+		LogLevel:      v.string(constants.LogLevel, constants.DefaultLogLevel),
+		LogFormat:     v.string(constants.LogFormat, constants.DefaultLogFormat),
+		LogFile:       v.string(constants.LogFile, constants.DefaultLogFile),
+		LogMaxSize:    v.int(constants.LogMaxSize, constants.DefaultLogMaxSizeMB),
+		LogMaxBackups: v.int(constants.LogMaxBackups, constants.DefaultLogMaxBackups),
+		LogMaxAge:     v.int(constants.LogMaxAge, constants.DefaultLogMaxAgeDays),
+
+		HealthTimeout: v.int(constants.HealthTimeout, constants.DefaultHealthTimeoutMs),
+
+		ShutdownTimeout: v.int(constants.ShutdownTimeout, constants.DefaultShutdownTimeout),
+		ShutdownDelay:   v.int(constants.ShutdownDelay, constants.DefaultShutdownDelay),
+
+		HTTPReadHeaderTimeout: v.int(constants.HTTPReadHeaderTimeout, constants.DefaultHTTPReadHeader),
+		HTTPIdleTimeout:       v.int(constants.HTTPIdleTimeout, constants.DefaultHTTPIdleTimeout),
+
+		RateLimitPerSec:    v.int(constants.RateLimitPerSec, 0),
+		RateLimitBurst:     v.int(constants.RateLimitBurst, constants.DefaultRateLimitBurst),
+		CORSAllowedOrigins: v.list(constants.CORSAllowedOrigins),
+		DisabledFeatures:   v.list(constants.DisabledFeatures),
+
+		VaultAddr:     v.string(constants.VaultAddr, ""),
+		VaultToken:    v.string(constants.VaultToken, ""),
+		SecretRefresh: v.int(constants.SecretRefresh, constants.DefaultSecretRefresh),
+		SecretTimeout: v.int(constants.SecretTimeout, constants.DefaultSecretTimeoutMs),
+
+		ConfigWatch: v.int(constants.ConfigWatch, constants.DefaultConfigWatch),
+
+		OutboxPollInterval:   v.int(constants.OutboxPollInterval, constants.DefaultOutboxPollMs),
+		OutboxBatchSize:      v.int(constants.OutboxBatchSize, constants.DefaultOutboxBatchSize),
+		OutboxMaxBackoff:     v.int(constants.OutboxMaxBackoff, constants.DefaultOutboxMaxBackoff),
+		OutboxMaxAttempts:    v.int(constants.OutboxMaxAttempts, constants.DefaultOutboxMaxAttempts),
+		OutboxLease:          v.int(constants.OutboxLease, constants.DefaultOutboxLeaseSecs),
+		OutboxPublishTimeout: v.int(constants.OutboxPublishTimeout, constants.DefaultOutboxPublishSecs),
+		OutboxRetention:      v.int(constants.OutboxRetention, constants.DefaultOutboxRetentionHrs),
+		TitleNormalizer: utils.TitleNormalizer{
+			CaseInsensitive:    v.bool(constants.TitleIgnoreCase, false),
+			CollapseWhitespace: v.bool(constants.TitleTrimSpaces, false),
+		},
+	}
 }
@@ -1,11 +1,16 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"task-manager-app/constants"
+	"task-manager-app/metrics"
+	"task-manager-app/secrets"
 	"task-manager-app/utils"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -15,31 +20,85 @@ var (
 	DB *gorm.DB
 )
 
-func InitDB() {
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
+// InitDB connects to PostgreSQL with the credentials held by refresher, read again for every
+// new connection; refresher may be nil when secrets are not tracked
+func InitDB(refresher *secrets.Refresher) {
+	// Credentials are left out of the DSN and set as each connection is opened, so that
+	// rotated ones are used without a restart
+	dsn := fmt.Sprintf("host=%s dbname=%s port=%s sslmode=disable TimeZone=UTC connect_timeout=%d",
 		ApplicationConfig.PostgresHost,
-		ApplicationConfig.Username,
-		ApplicationConfig.Password,
 		ApplicationConfig.DbName,
 		ApplicationConfig.PostgresPort,
+		ApplicationConfig.DbConnectTimeout,
 	)
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		utils.Sugar.Fatal(constants.ErrFailedToConnectDB+":", err)
+	}
+	conn := stdlib.OpenDB(*connConfig, stdlib.OptionBeforeConnect(func(ctx context.Context, cc *pgx.ConnConfig) error {
+		cc.User = currentSecret(refresher, constants.PostgresUsername, ApplicationConfig.Username)
+		cc.Password = currentSecret(refresher, constants.PostgresPassword, ApplicationConfig.Password)
+		return nil
+	}))
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+		Logger: logger.Default.LogMode(gormLogLevel(ApplicationConfig.DbLogLevel)),
 	})
 	if err != nil {
 		utils.Sugar.Fatal(constants.ErrFailedToConnectDB+":", err)
 	}
-
 	// Set connection pool
 	sqlDB, err := db.DB()
 	if err != nil {
 		utils.Sugar.Fatal(constants.ErrFailedToGetSqlDB+":", err)
 	}
-	sqlDB.SetMaxOpenConns(50)
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetConnMaxLifetime(30 * time.Minute)
+	sqlDB.SetMaxOpenConns(ApplicationConfig.MaxDbConnections)
+	sqlDB.SetMaxIdleConns(ApplicationConfig.DbMaxIdleConns)
+	sqlDB.SetConnMaxLifetime(time.Duration(ApplicationConfig.DbConnMaxLifetime) * time.Minute)
+	metrics.RegisterDB(sqlDB, ApplicationConfig.DbName)
 
 	DB = db
 	utils.Sugar.Info("Connected to PostgreSQL successfully")
 }
+
+// gormLogLevel maps DB_LOG_LEVEL to the GORM logger level; Validate rejects other values
+func gormLogLevel(level string) logger.LogLevel {
+	switch level {
+	case constants.DbLogLevelSilent:
+		return logger.Silent
+	case constants.DbLogLevelError:
+		return logger.Error
+	case constants.DbLogLevelWarn:
+		return logger.Warn
+	default:
+		return logger.Info
+	}
+}
+
+// ResetDBConnections closes the idle connections of the pool of db, so that the next queries
+// connect with the current credentials, and checks that those are accepted. Connections in
+// use are closed once they reach DB_CONN_MAX_LIFETIME_MINUTES.
+func ResetDBConnections(ctx context.Context, db *gorm.DB, maxIdleConns int) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	sqlDB.SetMaxIdleConns(0)
+	sqlDB.SetMaxIdleConns(maxIdleConns)
+	return sqlDB.PingContext(ctx)
+}
+
+// CloseDB closes the connection pool, waiting for queries in flight to finish
+func CloseDB() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return fmt.Errorf("%s: %w", constants.ErrorClosingDb, err)
+	}
+	if err := sqlDB.Close(); err != nil {
+		return fmt.Errorf("%s: %w", constants.ErrorClosingDb, err)
+	}
+	return nil
+}
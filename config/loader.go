@@ -0,0 +1,250 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"task-manager-app/constants"
+	"task-manager-app/utils"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Sources are where settings are read from. Each one overrides the ones before it:
+// defaults, File, EnvFile, Env and Overrides. Settings are named after their environment
+// variable everywhere, e.g. POSTGRES_HOST.
+type Sources struct {
+	// File is an optional YAML or JSON file, chosen by its extension, mapping settings to values
+	File string
+	// EnvFile is an optional .env file; it is skipped when it does not exist
+	EnvFile string
+	// Env holds the environment variables
+	Env map[string]string
+	// Overrides come from -set flags
+	Overrides map[string]string
+}
+
+// ParseFlags reads the -config, -env-file and -set flags from args, and the environment of
+// the process. CONFIG_FILE names the config file when -config is not given.
+func ParseFlags(args []string) (Sources, error) {
+	sources := Sources{Env: environ(), Overrides: map[string]string{}}
+	flags := flag.NewFlagSet("task-manager", flag.ContinueOnError)
+	flags.StringVar(&sources.File, "config", sources.Env[constants.ConfigFile], "YAML or JSON config file")
+	flags.StringVar(&sources.EnvFile, "env-file", constants.DefaultEnvFile, ".env file, skipped when missing")
+	flags.Func("set", "KEY=VALUE setting overriding every other source, may be repeated", func(value string) error {
+		key, setting, found := strings.Cut(value, "=")
+		if !found || key == "" {
+			return fmt.Errorf("%q is not KEY=VALUE", value)
+		}
+		sources.Overrides[key] = setting
+		return nil
+	})
+	if err := flags.Parse(args); err != nil {
+		return Sources{}, err
+	}
+	return sources, nil
+}
+
+// Load reads the configuration from sources. Unreadable files, values of the wrong type and
+// unknown settings in the config file or flags are all reported together as Problems.
+func (s Sources) Load() (*Config, error) {
+	v := &values{read: map[string]bool{}, effective: map[string]string{}}
+	var problems Problems
+	if s.File != "" {
+		layer, err := readConfigFile(s.File)
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+		v.push(s.File, layer)
+	}
+	if s.EnvFile != "" {
+		layer, err := godotenv.Read(s.EnvFile)
+		if err != nil && !os.IsNotExist(err) {
+			problems = append(problems, fmt.Sprintf("%s: %v", s.EnvFile, err))
+		}
+		v.push(s.EnvFile, layer)
+	}
+	v.push("environment", s.Env)
+	v.push("flags", s.Overrides)
+
+	cfg := build(v)
+	cfg.settings = v.effective
+
+	problems = append(problems, v.problems...)
+	problems = append(problems, unknownSettings(s.File, v.layerNamed(s.File), v.read)...)
+	problems = append(problems, unknownSettings("-set", s.Overrides, v.read)...)
+	if len(problems) > 0 {
+		return cfg, problems
+	}
+	return cfg, nil
+}
+
+// FromEnv reads the configuration from the environment alone. Values of the wrong type are
+// replaced by their defaults; use Sources.Load to have them reported.
+func FromEnv() *Config {
+	cfg, _ := Sources{Env: environ()}.Load()
+	return cfg
+}
+
+// Problems lists everything wrong with a configuration
+type Problems []string
+
+func (p Problems) Error() string {
+	return constants.ErrInvalidConfig + ":\n  - " + strings.Join(p, "\n  - ")
+}
+
+// layer is the settings of one source
+type layer struct {
+	name     string
+	settings map[string]string
+}
+
+// values resolves settings across layers, remembering which settings were read, the value
+// each one ended up with and which values could not be parsed
+type values struct {
+	layers    []layer
+	read      map[string]bool
+	effective map[string]string
+	problems  Problems
+}
+
+func (v *values) push(name string, settings map[string]string) {
+	v.layers = append(v.layers, layer{name: name, settings: settings})
+}
+
+func (v *values) layerNamed(name string) map[string]string {
+	for _, l := range v.layers {
+		if l.name == name {
+			return l.settings
+		}
+	}
+	return nil
+}
+
+// lookup returns the value of key from the last layer setting it to a non-empty value, and
+// the name of that layer. An empty value counts as unset, as it always has for variables.
+func (v *values) lookup(key string) (string, string, bool) {
+	v.read[key] = true
+	for i := len(v.layers) - 1; i >= 0; i-- {
+		if value := v.layers[i].settings[key]; value != "" {
+			return value, v.layers[i].name, true
+		}
+	}
+	return "", "", false
+}
+
+func (v *values) string(key, def string) string {
+	value, _, ok := v.lookup(key)
+	if !ok {
+		value = def
+	}
+	v.effective[key] = value
+	return value
+}
+
+func (v *values) int(key string, def int) int {
+	n := def
+	if value, source, ok := v.lookup(key); ok {
+		parsed, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			v.problems = append(v.problems, fmt.Sprintf("%s=%q from %s is not a whole number", key, value, source))
+		} else {
+			n = parsed
+		}
+	}
+	v.effective[key] = strconv.Itoa(n)
+	return n
+}
+
+func (v *values) bool(key string, def bool) bool {
+	b := def
+	if value, source, ok := v.lookup(key); ok {
+		parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			v.problems = append(v.problems, fmt.Sprintf("%s=%q from %s is not true or false", key, value, source))
+		} else {
+			b = parsed
+		}
+	}
+	v.effective[key] = strconv.FormatBool(b)
+	return b
+}
+
+// list splits a comma separated value
+func (v *values) list(key string) []string {
+	value, _, _ := v.lookup(key)
+	items := utils.TaskManagerUtils.SplitAndTrim(value)
+	v.effective[key] = strings.Join(items, ",")
+	return items
+}
+
+// readConfigFile flattens a YAML or JSON file into settings. Lists become comma separated
+// values, as they are written in variables.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	raw := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("%s: %s", path, constants.ErrConfigFileFormat)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	settings := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch typed := value.(type) {
+		case nil:
+			settings[key] = ""
+		case []interface{}:
+			items := make([]string, len(typed))
+			for i, item := range typed {
+				items[i] = fmt.Sprint(item)
+			}
+			settings[key] = strings.Join(items, ",")
+		case map[string]interface{}:
+			return nil, fmt.Errorf("%s: %s is a map, settings are flat", path, key)
+		case float64:
+			// JSON numbers; whole numbers must not turn into 1e+06
+			settings[key] = strconv.FormatFloat(typed, 'f', -1, 64)
+		default:
+			settings[key] = fmt.Sprint(typed)
+		}
+	}
+	return settings, nil
+}
+
+// unknownSettings reports the settings of source that nothing reads, which are most likely typos
+func unknownSettings(source string, settings map[string]string, read map[string]bool) Problems {
+	var problems Problems
+	for key := range settings {
+		if !read[key] {
+			problems = append(problems, fmt.Sprintf("%s: unknown setting %s", source, key))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+func environ() map[string]string {
+	env := map[string]string{}
+	for _, entry := range os.Environ() {
+		if key, value, found := strings.Cut(entry, "="); found {
+			env[key] = value
+		}
+	}
+	return env
+}
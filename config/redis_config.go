@@ -0,0 +1,62 @@
+package config
+
+import (
+	"context"
+	"task-manager-app/constants"
+	"task-manager-app/secrets"
+	"task-manager-app/utils"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	Redis *redis.Client
+)
+
+// InitRedis connects to Redis when REDIS_ENDPOINT is configured, with the credentials held by
+// refresher; Redis stays nil otherwise. refresher may be nil when secrets are not tracked.
+func InitRedis(refresher *secrets.Refresher) {
+	if ApplicationConfig.RedisEndpoint == "" {
+		return
+	}
+
+	addr := ApplicationConfig.RedisEndpoint
+	if ApplicationConfig.RedisPort != "" {
+		addr = addr + ":" + ApplicationConfig.RedisPort
+	}
+	timeout := time.Duration(ApplicationConfig.RedisTimeout) * time.Millisecond
+
+	// Read for every new connection, so that rotated credentials are used
+	credentials := func() (string, string) {
+		return currentSecret(refresher, constants.RedisUsername, ApplicationConfig.RedisUsername),
+			currentSecret(refresher, constants.RedisPassword, ApplicationConfig.RedisPassword)
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:                addr,
+		CredentialsProvider: credentials,
+		DB:                  ApplicationConfig.RedisDb,
+		DialTimeout:         timeout,
+		ReadTimeout:         timeout,
+		WriteTimeout:        timeout,
+		PoolTimeout:         time.Duration(ApplicationConfig.RedisPoolTimeout) * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		utils.Sugar.Fatal(constants.ErrFailedToConnectRedis+":", err)
+	}
+
+	Redis = client
+	utils.Sugar.Info("Connected to Redis successfully")
+}
+
+// CloseRedis closes the Redis client when one was created
+func CloseRedis() error {
+	if Redis == nil {
+		return nil
+	}
+	return Redis.Close()
+}
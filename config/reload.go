@@ -0,0 +1,244 @@
+package config
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"task-manager-app/constants"
+	"task-manager-app/secrets"
+	"task-manager-app/utils"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// reloadable are the settings applied while the service runs, with how each one is copied
+// into the running configuration. Every other setting needs a restart.
+var reloadable = map[string]func(dst, src *Config){
+	constants.LogLevel:            func(dst, src *Config) { dst.LogLevel = src.LogLevel },
+	constants.BoardAllowedOrigins: func(dst, src *Config) { dst.BoardAllowedOrigins = src.BoardAllowedOrigins },
+	constants.BoardMsgsPerSec:     func(dst, src *Config) { dst.BoardMsgsPerSec = src.BoardMsgsPerSec },
+	constants.BoardMaxConns:       func(dst, src *Config) { dst.BoardMaxConns = src.BoardMaxConns },
+	constants.BoardMaxSubs:        func(dst, src *Config) { dst.BoardMaxSubs = src.BoardMaxSubs },
+	constants.BoardMaxTasks:       func(dst, src *Config) { dst.BoardMaxTasks = src.BoardMaxTasks },
+	constants.BoardMaxMsgBytes:    func(dst, src *Config) { dst.BoardMaxMsgBytes = src.BoardMaxMsgBytes },
+	constants.RateLimitPerSec:     func(dst, src *Config) { dst.RateLimitPerSec = src.RateLimitPerSec },
+	constants.RateLimitBurst:      func(dst, src *Config) { dst.RateLimitBurst = src.RateLimitBurst },
+	constants.CORSAllowedOrigins:  func(dst, src *Config) { dst.CORSAllowedOrigins = src.CORSAllowedOrigins },
+	constants.DisabledFeatures:    func(dst, src *Config) { dst.DisabledFeatures = src.DisabledFeatures },
+}
+
+// Change is a setting whose value differs between two configurations
+type Change struct {
+	Setting string
+	Old     string
+	New     string
+}
+
+// Settings returns every setting as it was read, keyed by its name. Secret settings show
+// where a secret reference points, or are redacted when given directly.
+func (c *Config) Settings() map[string]string {
+	secretNames := c.secretSettings()
+	settings := make(map[string]string, len(c.settings))
+	for name, value := range c.settings {
+		if _, secret := secretNames[name]; (secret || name == constants.VaultToken) && value != "" {
+			if strings.Contains(value, "://") {
+				value = secrets.Redact(value)
+			} else {
+				value = constants.RedactedValue
+			}
+		}
+		settings[name] = value
+	}
+	return settings
+}
+
+// Diff lists the settings whose values differ in next, by name, with secrets redacted
+func (c *Config) Diff(next *Config) []Change {
+	before, after := c.Settings(), next.Settings()
+	var changes []Change
+	for name, value := range after {
+		if before[name] != value {
+			changes = append(changes, Change{Setting: name, Old: before[name], New: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Setting < changes[j].Setting })
+	return changes
+}
+
+// ReloaderOptions configures a Reloader
+type ReloaderOptions struct {
+	// Interval between checks of the config file and .env file for changes; 0 only reloads
+	// on Signals
+	Interval time.Duration
+	// Signals trigger a reload, usually SIGHUP
+	Signals <-chan os.Signal
+	// Logger receives the changes and problems, utils.Sugar when nil
+	Logger *zap.SugaredLogger
+}
+
+// Reloader reads the configuration again when its files change or a signal arrives, and
+// applies the reloadable settings of a valid one. The rest keep their values until a restart.
+type Reloader struct {
+	sources Sources
+	options ReloaderOptions
+
+	current   atomic.Pointer[Config]
+	mutex     sync.Mutex
+	stamps    map[string]fileStamp
+	listeners []func(cfg *Config)
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// fileStamp tells whether a file changed since it was last read
+type fileStamp struct {
+	modified int64
+	size     int64
+}
+
+// NewReloader reloads from sources, starting from current, the configuration in use
+func NewReloader(sources Sources, current *Config, options ReloaderOptions) *Reloader {
+	if options.Logger == nil {
+		options.Logger = utils.Sugar
+	}
+	r := &Reloader{
+		sources: sources,
+		options: options,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	r.current.Store(current)
+	r.stamps = r.stampFiles()
+	return r
+}
+
+// Current returns the configuration in use, with the reloaded settings applied
+func (r *Reloader) Current() *Config {
+	return r.current.Load()
+}
+
+// OnReload calls fn with the configuration in use after every reload that changed a
+// reloadable setting
+func (r *Reloader) OnReload(fn func(cfg *Config)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.listeners = append(r.listeners, fn)
+}
+
+// Start watches the files and signals in the background until Stop is called
+func (r *Reloader) Start() {
+	go r.run()
+}
+
+// Stop ends the watch and waits for the reload in progress or for ctx to expire
+func (r *Reloader) Stop(ctx context.Context) error {
+	close(r.stop)
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Reloader) run() {
+	defer close(r.done)
+	var tick <-chan time.Time
+	if r.options.Interval > 0 {
+		ticker := time.NewTicker(r.options.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-r.stop:
+			return
+		case sig := <-r.options.Signals:
+			r.options.Logger.Infow("Reloading configuration", "signal", sig.String())
+			r.Reload()
+		case <-tick:
+			if r.filesChanged() {
+				r.options.Logger.Info("Configuration files changed, reloading")
+				r.Reload()
+			}
+		}
+	}
+}
+
+// Reload reads and validates the configuration, then applies the reloadable settings that
+// changed and returns them. An invalid configuration is reported and nothing is applied.
+func (r *Reloader) Reload() ([]Change, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.stamps = r.stampFiles()
+
+	next, err := r.sources.Load()
+	if err == nil {
+		err = next.Validate()
+	}
+	if err != nil {
+		r.options.Logger.Errorw(constants.ErrConfigNotReloaded, "error", err)
+		return nil, err
+	}
+
+	current := r.current.Load()
+	applied := *current
+	applied.settings = make(map[string]string, len(current.settings))
+	for name, value := range current.settings {
+		applied.settings[name] = value
+	}
+	var changes []Change
+	for _, change := range current.Diff(next) {
+		apply, ok := reloadable[change.Setting]
+		if !ok {
+			r.options.Logger.Warnw("Setting changed, restart to apply it", "setting", change.Setting, "old", change.Old, "new", change.New)
+			continue
+		}
+		apply(&applied, next)
+		applied.settings[change.Setting] = next.settings[change.Setting]
+		changes = append(changes, change)
+		r.options.Logger.Infow("Setting changed", "setting", change.Setting, "old", change.Old, "new", change.New)
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	r.current.Store(&applied)
+	for _, listener := range r.listeners {
+		listener(&applied)
+	}
+	return changes, nil
+}
+
+// filesChanged reports whether the config file or .env file changed since they were read
+func (r *Reloader) filesChanged() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for path, stamp := range r.stampFiles() {
+		if r.stamps[path] != stamp {
+			return true
+		}
+	}
+	return false
+}
+
+// stampFiles stamps the config file and .env file; missing files get the zero stamp
+func (r *Reloader) stampFiles() map[string]fileStamp {
+	stamps := map[string]fileStamp{}
+	for _, path := range []string{r.sources.File, r.sources.EnvFile} {
+		if path == "" {
+			continue
+		}
+		var stamp fileStamp
+		if info, err := os.Stat(path); err == nil {
+			stamp = fileStamp{modified: info.ModTime().UnixNano(), size: info.Size()}
+		}
+		stamps[path] = stamp
+	}
+	return stamps
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"task-manager-app/constants"
+	"task-manager-app/secrets"
+	"time"
+)
+
+// secretSettings are the settings that may be given as secret references, with the field
+// each one is read into
+func (c *Config) secretSettings() map[string]*string {
+	return map[string]*string{
+		constants.PostgresUsername: &c.Username,
+		constants.PostgresPassword: &c.Password,
+		constants.RedisUsername:    &c.RedisUsername,
+		constants.RedisPassword:    &c.RedisPassword,
+		constants.KafkaUsername:    &c.KafkaUsername,
+		constants.KafkaPassword:    &c.KafkaPassword,
+		constants.LdapBindPassword: &c.LdapBindPassword,
+		constants.AdminToken:       &c.AdminToken,
+		constants.BoardTokenSecret: &c.BoardTokenSecret,
+	}
+}
+
+// rotatable are the secret settings read again whenever a connection is opened; the others
+// are only read at startup
+var rotatable = map[string]bool{
+	constants.PostgresUsername: true,
+	constants.PostgresPassword: true,
+	constants.RedisUsername:    true,
+	constants.RedisPassword:    true,
+}
+
+// NewSecretResolver resolves file://, env:// and vault:// references. VAULT_TOKEN may itself
+// be a file:// or env:// reference.
+func NewSecretResolver(ctx context.Context, c *Config) (*secrets.Resolver, error) {
+	providers := map[string]secrets.Provider{
+		constants.SecretSchemeFile: secrets.FileProvider{},
+		constants.SecretSchemeEnv:  secrets.EnvProvider{},
+	}
+	token, err := secrets.NewResolver(providers).Resolve(ctx, c.VaultToken)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", constants.VaultToken, err)
+	}
+	providers[constants.SecretSchemeVault] = secrets.NewVaultProvider(secrets.VaultOptions{
+		Address: c.VaultAddr,
+		Token:   token,
+		Timeout: time.Duration(c.SecretTimeout) * time.Millisecond,
+	})
+	return secrets.NewResolver(providers), nil
+}
+
+// ResolveSecrets replaces the secret references of c with their values and tracks them with
+// refresher, so that rotated values are picked up. Every reference that cannot be resolved
+// is reported.
+func (c *Config) ResolveSecrets(ctx context.Context, refresher *secrets.Refresher) error {
+	settings := c.secretSettings()
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems Problems
+	for _, name := range names {
+		value, err := refresher.Track(ctx, name, *settings[name])
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		*settings[name] = value
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// Rotatable reports whether the secret setting name is read again for every new connection,
+// so that a rotated value is used without a restart
+func Rotatable(name string) bool {
+	return rotatable[name]
+}
+
+// currentSecret returns the latest value of the secret setting name held by refresher, or
+// value when secrets are not tracked
+func currentSecret(refresher *secrets.Refresher, name, value string) string {
+	if refresher == nil {
+		return value
+	}
+	return refresher.Get(name)
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"task-manager-app/constants"
+	"task-manager-app/utils"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Validate reports every setting the application cannot start with, rather than stopping
+// at the first one
+func (c *Config) Validate() error {
+	var problems Problems
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	required := func(key, value string) {
+		if strings.TrimSpace(value) == "" {
+			problem("%s is required", key)
+		}
+	}
+	port := func(key, value string) {
+		if value == "" {
+			problem("%s is required", key)
+		} else if n, err := strconv.Atoi(value); err != nil || n < 1 || n > 65535 {
+			problem("%s=%q is not a port number", key, value)
+		}
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		problem("%s=%q must be one of %s", key, value, strings.Join(allowed, ", "))
+	}
+	atLeast := func(min int, settings map[string]int) {
+		for _, key := range sortedKeys(settings) {
+			if settings[key] < min {
+				problem("%s=%d must be at least %d", key, settings[key], min)
+			}
+		}
+	}
+
+	port(constants.PORT, c.AppPort)
+	required(constants.PostgresHost, c.PostgresHost)
+	port(constants.PostgresPort, c.PostgresPort)
+	required(constants.PostgresUsername, c.Username)
+	required(constants.PostgresDbName, c.DbName)
+	if c.DbMaxIdleConns > c.MaxDbConnections {
+		problem("%s=%d is more than %s=%d", constants.DbMaxIdleConns, c.DbMaxIdleConns, constants.MaxDbConnections, c.MaxDbConnections)
+	}
+	oneOf(constants.DbLogLevel, c.DbLogLevel, constants.DbLogLevelSilent, constants.DbLogLevelError, constants.DbLogLevelWarn, constants.DbLogLevelInfo)
+
+	if _, err := zapcore.ParseLevel(c.LogLevel); err != nil {
+		problem("%s=%q is not a log level", constants.LogLevel, c.LogLevel)
+	}
+	oneOf(constants.LogFormat, c.LogFormat, utils.LogFormatConsole, utils.LogFormatJSON)
+	oneOf(constants.TracingExporter, c.TracingExporter, constants.TracingExporterNone, constants.TracingExporterStdout, constants.TracingExporterOTLP)
+
+	oneOf(constants.StreamBackend, c.StreamBackend, constants.StreamBackendMemory, constants.StreamBackendRedis)
+	if c.StreamBackend == constants.StreamBackendRedis && c.RedisEndpoint == "" {
+		problem("%s=%s needs %s", constants.StreamBackend, constants.StreamBackendRedis, constants.RedisEndpoint)
+	}
+
+	if c.UserServicePolicy != "" {
+		oneOf(constants.UserServicePolicy, c.UserServicePolicy, constants.FailurePolicyOpen, constants.FailurePolicyClosed)
+	}
+	switch c.UserProvider {
+	case "", constants.UserDirectoryHTTP:
+		required(constants.UserServiceURL, c.UserServiceURL)
+	case constants.UserDirectoryFile:
+		required(constants.UserProviderFile, c.UserProviderFile)
+	case constants.UserDirectoryLDAP:
+		required(constants.LdapURL, c.LdapURL)
+		required(constants.LdapBaseDN, c.LdapBaseDN)
+	default:
+		oneOf(constants.UserProvider, c.UserProvider, constants.UserDirectoryHTTP, constants.UserDirectoryFile, constants.UserDirectoryLDAP)
+	}
+	oneOf(constants.UserTaskPolicy, c.UserTaskPolicy, constants.UserTaskPolicyUnassign, constants.UserTaskPolicyReassign, constants.UserTaskPolicyArchive)
+	if c.UserTaskPolicy == constants.UserTaskPolicyReassign {
+		required(constants.UserReassignTo, c.UserReassignTo)
+	}
+
+	// Intervals drive tickers and sizes bound queues, so neither can be zero
+	atLeast(1, map[string]int{
+		constants.MaxDbConnections:      c.MaxDbConnections,
+		constants.DbConnMaxLifetime:     c.DbConnMaxLifetime,
+		constants.DbConnectTimeout:      c.DbConnectTimeout,
+		constants.MigrateTimeout:        c.MigrateTimeout,
+		constants.LogMaxSize:            c.LogMaxSize,
+		constants.HTTPReadHeaderTimeout: c.HTTPReadHeaderTimeout,
+		constants.HealthTimeout:         c.HealthTimeout,
+		constants.ShutdownTimeout:       c.ShutdownTimeout,
+		constants.IdempotencyTTL:        c.IdempotencyTTL,
+		constants.IdempotencyLease:      c.IdempotencyLease,
+		constants.UserSvcTimeout:        c.UserServiceTimeout,
+		constants.UserSvcBreakerMax:     c.UserServiceBreakerThreshold,
+		constants.UserSvcBreakerTTL:     c.UserServiceBreakerOpenSecs,
+		constants.UserSvcBulkhead:       c.UserServiceMaxConcurrent,
+		constants.RedisTimeout:          c.RedisTimeout,
+		constants.OutboxPollInterval:    c.OutboxPollInterval,
+		constants.OutboxBatchSize:       c.OutboxBatchSize,
+		constants.OutboxMaxAttempts:     c.OutboxMaxAttempts,
+		constants.OutboxLease:           c.OutboxLease,
+		constants.OutboxPublishTimeout:  c.OutboxPublishTimeout,
+		constants.UserEventAttempts:     c.UserEventAttempts,
+		constants.WebhookPollInterval:   c.WebhookPollInterval,
+		constants.WebhookConcurrency:    c.WebhookConcurrency,
+		constants.WebhookTimeout:        c.WebhookTimeout,
+		constants.WebhookMaxAttempts:    c.WebhookMaxAttempts,
+		constants.StreamClientBuffer:    c.StreamClientBuffer,
+		constants.StreamHeartbeat:       c.StreamHeartbeat,
+		constants.BoardSendBuffer:       c.BoardSendBuffer,
+		constants.BoardPing:             c.BoardPing,
+		constants.ChangeFeedMaxLimit:    c.ChangeFeedMaxLimit,
+		constants.ChangeFeedPoll:        c.ChangeFeedPoll,
+		constants.SecretTimeout:         c.SecretTimeout,
+		constants.UserCacheTTL:          c.UserCacheTTL,
+		constants.UserCacheNegTTL:       c.UserCacheNegTTL,
+		constants.UserCacheMaxSize:      c.UserCacheMaxSize,
+		constants.RateLimitBurst:        c.RateLimitBurst,
+	})
+	atLeast(0, map[string]int{
+		constants.DbMaxIdleConns:   c.DbMaxIdleConns,
+		constants.LogMaxBackups:    c.LogMaxBackups,
+		constants.LogMaxAge:        c.LogMaxAge,
+		constants.HTTPIdleTimeout:  c.HTTPIdleTimeout,
+		constants.ShutdownDelay:    c.ShutdownDelay,
+		constants.UserSvcRetries:   c.UserServiceMaxRetries,
+		constants.StreamReplaySize: c.StreamReplaySize,
+		constants.SecretRefresh:    c.SecretRefresh,
+		constants.ConfigWatch:      c.ConfigWatch,
+		constants.RateLimitPerSec:  c.RateLimitPerSec,
+	})
+	for _, feature := range c.DisabledFeatures {
+		oneOf(constants.DisabledFeatures, feature, constants.FeatureTaskStream, constants.FeatureChangeFeed, constants.FeatureBoards)
+	}
+	// The relay stops before its lease runs out, so a lease shorter than the slowest batch
+	// would leave events unpublished on every poll
+	if c.OutboxLease < c.OutboxBatchSize*c.OutboxPublishTimeout {
+		problem("%s=%d is less than %s=%d times %s=%d", constants.OutboxLease, c.OutboxLease, constants.OutboxBatchSize, c.OutboxBatchSize, constants.OutboxPublishTimeout, c.OutboxPublishTimeout)
+	}
+	if c.ShutdownDelay >= c.ShutdownTimeout && c.ShutdownTimeout > 0 {
+		problem("%s=%d leaves no time within %s=%d to drain requests", constants.ShutdownDelay, c.ShutdownDelay, constants.ShutdownTimeout, c.ShutdownTimeout)
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+func sortedKeys(settings map[string]int) []string {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
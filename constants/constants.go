@@ -8,6 +8,7 @@ const (
 	ErrInvalidTaskStatus      = "invalid task status given in req"
 	ErrInvalidTaskPriority    = "invalid task priority given in req"
 	ErrTaskAlreadyExists      = "task with this title already exists for this user"
+	ErrTaskConflict           = "task conflicts with an existing task"
 	ErrTitleAlreadySame       = "task already has the same title"
 	ErrDescriptionAlreadySame = "task already has the same description"
 	ErrStatusAlreadySame      = "task already has the same status"
@@ -26,6 +27,97 @@ const (
 	ErrorStartingApplication  = "Error starting application"
 	ErrorClosingDb            = "Error closing postgres db"
 	ErrNothingToChange        = "No changes detected for update task"
+	ErrUserServiceUnavailable = "user service is unavailable, please retry later"
+	ErrInvalidExpand          = "invalid expand parameter, supported values: user"
+	ErrConflictingUserFilters = "user_id and user_ids cannot be combined"
+	ErrUserCacheDisabled      = "user cache is not enabled"
+	ErrFailedToInvalidateUser = "Failed to invalidate cached user"
+	ErrInvalidAdminToken      = "invalid or missing admin token"
+	ErrFailedToConnectRedis   = "Failed to connect to redis"
+	ErrFailedToInitProvider   = "Failed to initialise user provider"
+	ErrFailedToInitKafka      = "Failed to initialise kafka publisher"
+	ErrFailedToSaveOutbox     = "Failed to save outbox event"
+	ErrFailedToGetOutbox      = "Failed to get outbox events"
+	ErrFailedToSaveInbox      = "Failed to save consumed event"
+	ErrFailedToGetInbox       = "Failed to get consumed event"
+	ErrFailedToInitConsumer   = "Failed to initialise user event consumer"
+	ErrInvalidUserTaskPolicy  = "USER_DELETED_TASK_POLICY must be unassign, reassign or archive"
+	ErrMissingReassignTarget  = "USER_DELETED_REASSIGN_TO must be set for the reassign policy"
+	ErrUnknownReassignTarget  = "USER_DELETED_REASSIGN_TO is not a known user"
+	ErrFailedToCommitTx       = "Failed to commit transaction"
+	ErrWebhookNotFound        = "webhook not found"
+	ErrDeliveryNotFound       = "webhook delivery not found"
+	ErrInvalidWebhookURL      = "webhook url must be an absolute http or https URL"
+	ErrInvalidWebhookEvent    = "invalid webhook event type, supported values: TaskCreated, TaskUpdated, TaskDeleted, TaskStatusChanged"
+	ErrInvalidWebhookFilter   = "invalid webhook filter, supported keys: user_id, status, priority"
+	ErrWebhookSecretTooShort  = "webhook secret must be at least 16 characters"
+	ErrInvalidDeliveryStatus  = "invalid delivery status, supported values: pending, delivered, failed"
+	ErrWebhookDisabled        = "disabled after repeated delivery failures"
+	ErrFailedToSaveWebhook    = "Failed to save webhook"
+	ErrFailedToGetWebhook     = "Failed to get webhook"
+	ErrFailedToSaveDelivery   = "Failed to save webhook delivery"
+	ErrFailedToGetDelivery    = "Failed to get webhook deliveries"
+	ErrInvalidLastEventID     = "Last-Event-ID must be the id of a streamed task change"
+	ErrInvalidStreamBackend   = "TASK_STREAM_BACKEND must be memory or redis"
+	ErrStreamNeedsRedis       = "TASK_STREAM_BACKEND=redis needs REDIS_ENDPOINT"
+	ErrFailedToStartStream    = "Failed to start task stream"
+	ErrServerNeedsConfigAndDB = "A server needs a config and a database"
+	ErrServerStopFailed       = "Some background workers did not stop cleanly"
+	ErrInvalidBoardToken      = "invalid or expired board access token"
+	ErrTooManyBoardConns      = "too many board connections for this user"
+	ErrInvalidBoardMessage    = "invalid board message"
+	ErrUnknownBoardMessage    = "unknown board message type, supported values: subscribe, unsubscribe, view, leave"
+	ErrInvalidSubscriptionID  = "subscription id must be set"
+	ErrSubscriptionExists     = "subscription id is already in use"
+	ErrSubscriptionNotFound   = "subscription not found"
+	ErrInvalidSubscription    = "subscribe needs either task_uuids or a filter"
+	ErrInvalidBoardFilter     = "invalid filter, supported keys: user_id, status, priority"
+	ErrTooManySubscriptions   = "too many subscriptions on this connection"
+	ErrTooManyBoardTasks      = "too many task_uuids in one subscription"
+	ErrTooManyViewedTasks     = "too many viewed tasks on this connection"
+	ErrInvalidTaskUUID        = "invalid task uuid"
+	ErrBoardRateLimited       = "too many messages, slow down"
+	ErrBoardClientTooSlow     = "client is not reading its messages"
+	ErrRateLimited            = "too many requests, please retry later"
+	ErrFeatureDisabled        = "this feature is disabled"
+	ErrInvalidIdempotencyKey  = "Idempotency-Key header must be between 1 and 255 characters"
+	ErrIdempotencyKeyReused   = "Idempotency-Key was already used with a different request body"
+	ErrIdempotencyKeyInFlight = "A request with this Idempotency-Key is still being processed"
+	ErrFailedToSaveIdempotent = "Failed to save idempotency key"
+	ErrFailedToGetIdempotent  = "Failed to get idempotency key"
+	ErrFailedToRekeyTitles    = "Failed to recompute task title keys"
+	ErrTitleKeysCollide       = "task titles collide under the TASK_TITLE_UNIQUE_* rules, rename them or keep the previous rules"
+	ErrInvalidChangeSince     = "since must be a change sequence number"
+	ErrInvalidChangeWait      = "wait must be a non-negative duration such as 30s"
+	ErrInvalidChangeLimit     = "limit must be a positive number"
+	ErrFailedToSaveChange     = "Failed to record task change"
+	ErrFailedToGetChanges     = "Failed to get task changes"
+	ErrFailedToCountTasks     = "Failed to count tasks"
+	ErrFailedToInitMetrics    = "Failed to initialise metrics"
+	ErrFailedToInitTracing    = "Failed to initialise tracing"
+	ErrInvalidTracingExporter = "TRACING_EXPORTER must be none, stdout or otlp"
+	ErrFailedToInitLogger     = "Failed to initialise logger"
+	ErrInvalidConfig          = "Invalid configuration"
+	ErrConfigFileFormat       = "config files must end in .yaml, .yml or .json"
+	ErrReadyRequired          = "ready is required"
+	ErrInvalidSecretRef       = "invalid secret reference"
+	ErrFailedToResolveSecrets = "Failed to resolve secrets"
+	ErrSecretFileHost         = "file secret references must be local, e.g. file:///run/secrets/name"
+	ErrSecretEnvUnset         = "environment variable is not set:"
+	ErrVaultAddrUnset         = "VAULT_ADDR must be set to resolve vault:// references"
+	ErrVaultStatus            = "vault returned an error"
+	ErrVaultResponse          = "vault returned an unreadable response"
+	ErrVaultKeyNeeded         = "vault secret has several keys, name one after #"
+	ErrVaultKeyMissing        = "vault secret has no key"
+	ErrFailedToResetDBConns   = "Failed to reconnect to database with rotated credentials"
+	ErrConfigNotReloaded      = "Configuration not reloaded, keeping the current one"
+	ErrInvalidMigrationName   = "migration files must be named VERSION_name.up.sql or VERSION_name.down.sql"
+	ErrDuplicateMigration     = "two migrations have the version"
+	ErrMigrationMissingUp     = "migration has no .up.sql file:"
+	ErrMigrationMissingDown   = "migration has no .down.sql file:"
+	ErrUnknownMigration       = "the database has a migration this version does not know:"
+	ErrSchemaBehind           = "database schema is behind, apply the pending migrations"
+	ErrInvalidMigrateCommand  = "usage: migrate up | down [STEPS] | status [config flags]"
 )
 
 // Default values
@@ -38,14 +130,214 @@ const (
 const (
 	QueryParamStatus   = "status"
 	QueryParamUserID   = "user_id"
+	QueryParamUserIDs  = "user_ids"
 	QueryParamPriority = "priority"
 	QueryParamPage     = "page"
 	QueryParamPageSize = "pageSize"
+	QueryParamExpand   = "expand"
+	// QueryParamLastEventID resumes a stream for clients that cannot send Last-Event-ID
+	QueryParamLastEventID = "last_event_id"
+	// QueryParamAccessToken authenticates WebSocket clients, which cannot set headers
+	QueryParamAccessToken = "access_token"
+	// QueryParamSince, QueryParamWait and QueryParamLimit page through the change feed
+	QueryParamSince = "since"
+	QueryParamWait  = "wait"
+	QueryParamLimit = "limit"
+)
+
+// Values accepted by the expand query parameter
+const (
+	ExpandUser = "user"
+)
+
+// User service failure policies
+const (
+	FailurePolicyOpen   = "fail-open"
+	FailurePolicyClosed = "fail-closed"
+)
+
+// What happens to the tasks of a deleted user
+const (
+	UserTaskPolicyUnassign = "unassign"
+	UserTaskPolicyReassign = "reassign"
+	UserTaskPolicyArchive  = "archive"
+)
+
+// Header names
+const (
+	HeaderAdminToken          = "X-Admin-Token"
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotentReplayed  = "Idempotent-Replayed"
+	HeaderWebhookID           = "X-Webhook-Id"
+	HeaderWebhookEvent        = "X-Webhook-Event"
+	HeaderWebhookTimestamp    = "X-Webhook-Timestamp"
+	HeaderWebhookSignature    = "X-Webhook-Signature"
+	HeaderLastEventID         = "Last-Event-ID"
+	HeaderRequestID           = "X-Request-ID"
+	HeaderVaultToken          = "X-Vault-Token"
+	HeaderRetryAfter          = "Retry-After"
+	HeaderOrigin              = "Origin"
+	HeaderVary                = "Vary"
+	HeaderCORSAllowOrigin     = "Access-Control-Allow-Origin"
+	HeaderCORSAllowMethods    = "Access-Control-Allow-Methods"
+	HeaderCORSAllowHeaders    = "Access-Control-Allow-Headers"
+	HeaderCORSExposeHeaders   = "Access-Control-Expose-Headers"
+	HeaderCORSMaxAge          = "Access-Control-Max-Age"
+	HeaderCORSRequestMethod   = "Access-Control-Request-Method"
+	MaxRequestIDLength        = 128
+	MinWebhookSecretLength    = 16
+	MaxIdempotencyKeyLength   = 255
+	DefaultIdempotencyTTLMins = 24 * 60
+	DefaultIdempotencyLease   = 60
+	DefaultUserCacheTTLSecs   = 300
+	DefaultUserCacheNegTTL    = 30
+	DefaultUserCacheMaxSize   = 10000
+	DefaultRedisTimeoutMs     = 200
+	DefaultUserSvcTimeoutMs   = 10000
+	DefaultUserSvcRetries     = 2
+	DefaultUserSvcBreakerMax  = 5
+	DefaultUserSvcBreakerTTL  = 30
+	DefaultUserSvcBulkhead    = 50
+	DefaultLdapTimeoutMs      = 5000
+	DefaultKafkaTaskTopic     = "task-events"
+	DefaultOutboxPollMs       = 500
+	DefaultOutboxBatchSize    = 50
+	DefaultOutboxMaxBackoff   = 300
+	DefaultOutboxMaxAttempts  = 20
+	DefaultOutboxLeaseSecs    = 300
+	DefaultOutboxPublishSecs  = 5
+	DefaultOutboxRetentionHrs = 168
+	DefaultUserTaskBatchSize  = 500
+	DefaultKafkaUserTopic     = "user-events"
+	DefaultKafkaRetryTopic    = "user-events-retry"
+	DefaultKafkaDeadLetter    = "user-events-dlq"
+	DefaultUserEventAttempts  = 5
+	DefaultUserEventBackoff   = 300
+	DefaultUserEventRetention = 168
+	DefaultWebhookPollMs      = 1000
+	DefaultWebhookConcurrency = 10
+	DefaultWebhookTimeoutSecs = 10
+	DefaultWebhookMaxAttempts = 8
+	DefaultWebhookMaxBackoff  = 3600
+	DefaultWebhookDisableLim  = 20
+	DefaultWebhookRetention   = 168
+	DefaultStreamBackend      = StreamBackendMemory
+	DefaultStreamChannel      = "task-changes"
+	DefaultStreamReplaySize   = 1000
+	DefaultStreamClientBuffer = 64
+	DefaultStreamHeartbeat    = 15
+	DefaultBoardSendBuffer    = 64
+	DefaultBoardMaxSubs       = 20
+	DefaultBoardMaxTasks      = 100
+	DefaultBoardMaxConns      = 5
+	DefaultBoardMaxMsgBytes   = 8192
+	DefaultBoardMsgsPerSec    = 10
+	DefaultBoardPingSecs      = 30
+	DefaultBoardWriteSecs     = 10
+	DefaultChangeFeedLimit    = 100
+	DefaultChangeFeedMaxLimit = 1000
+	DefaultChangeFeedMaxWait  = 60
+	DefaultChangeFeedPoll     = 5
+	DefaultTracingExporter    = TracingExporterNone
+	DefaultLogLevel           = "info"
+	DefaultLogFormat          = "console"
+	DefaultHealthTimeoutMs    = 2000
+	DefaultShutdownTimeout    = 30
+	DefaultShutdownDelay      = 0
+	DefaultUserServiceURL     = "http://localhost:8081"
+	DefaultMaxDbConns         = 50
+	DefaultDbMaxIdleConns     = 10
+	DefaultDbConnLifetime     = 30
+	DefaultDbConnectTimeout   = 10
+	DefaultDbLogLevel         = DbLogLevelInfo
+	DefaultLogFile            = "./logs/task_manager.log"
+	DefaultLogMaxSizeMB       = 100
+	DefaultLogMaxBackups      = 5
+	DefaultLogMaxAgeDays      = 30
+	DefaultHTTPReadHeader     = 10
+	DefaultHTTPIdleTimeout    = 120
+	DefaultEnvFile            = "resources/.env"
+	DefaultSecretRefresh      = 300
+	DefaultSecretTimeoutMs    = 5000
+	DefaultConfigWatch        = 5
+	DefaultMigrateTimeout     = 60
+	DefaultRateLimitBurst     = 20
+	CORSMaxAgeSeconds         = 600
+	RedactedValue             = "[redacted]"
+)
+
+// Health statuses
+const (
+	HealthStatusUp       = "up"
+	HealthStatusDown     = "down"
+	HealthStatusReady    = "ready"
+	HealthStatusNotReady = "not_ready"
+	NotReadyMaintenance  = "maintenance"
+	NotReadyShuttingDown = "shutting_down"
+)
+
+// Dependencies checked by readiness probes
+const (
+	DependencyDatabase    = "database"
+	DependencyUserService = "user_service"
+	DependencyRedis       = "redis"
+	DependencyKafka       = "kafka"
+)
+
+// Tracing exporters
+const (
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+)
+
+// GORM log levels
+const (
+	DbLogLevelSilent = "silent"
+	DbLogLevelError  = "error"
+	DbLogLevelWarn   = "warn"
+	DbLogLevelInfo   = "info"
+)
+
+// User directories
+const (
+	UserDirectoryHTTP = "http"
+	UserDirectoryFile = "file"
+	UserDirectoryLDAP = "ldap"
+)
+
+// Schemes of secret references
+const (
+	SecretSchemeFile  = "file"
+	SecretSchemeEnv   = "env"
+	SecretSchemeVault = "vault"
+)
+
+// Features that DISABLED_FEATURES can turn off
+const (
+	FeatureTaskStream = "task_stream"
+	FeatureChangeFeed = "change_feed"
+	FeatureBoards     = "boards"
+)
+
+// Task stream backends
+const (
+	StreamBackendMemory = "memory"
+	StreamBackendRedis  = "redis"
 )
 
 // URL parameter names
 const (
-	URLParamUUID = "uuid"
+	URLParamUUID   = "uuid"
+	URLParamUserID = "id"
+	// URLParamDelivery names the delivery in the webhook delivery log routes
+	URLParamDelivery = "delivery_id"
+)
+
+// Gin context keys
+const (
+	// ContextPrincipal holds the authenticated caller of a request
+	ContextPrincipal = "principal"
 )
 
 // Default string values
@@ -65,21 +357,137 @@ const (
 	KafkaPassword  = "KAFKA_JAAS_CONFIG_PASSWORD"
 	KafkaGroupId   = "KAFKA_GROUP_ID"
 	KafkaAuthAlgo  = "KAFKA_AUTH_ALGO"
-	Err            = "err"
-	AppName        = "APP_NAME"
-	AppVersion     = "APP_VERSION"
-	UserAppBaseUri = "TESSERACT_BASE_URI"
-
-	KafkaRetryTopic  = "KAFKA_RETRY_TOPIC"
-	PostgresAddress  = "POSTGRES_ADDRESS"
-	PostgresUsername = "POSTGRES_USERNAME"
-	PostgresPassword = "POSTGRES_PASSWORD"
-	PostgresDbName   = "POSTGRES_DB_NAME"
-	PostgresHost     = "POSTGRES_HOST"
-	PostgresPort     = "POSTGRES_PORT"
-	MaxDbConnections = "MAX_DB_CONNECTIONS"
-	HOST             = "HOST"
-	PORT             = "PORT"
-	TCP              = "tcp"
-	DriverName       = "postgres"
+	KafkaTLS       = "KAFKA_TLS_ENABLED"
+	KafkaTaskTopic = "KAFKA_TASK_EVENTS_TOPIC"
+	KafkaUserTopic = "KAFKA_USER_EVENTS_TOPIC"
+	KafkaDLQTopic  = "KAFKA_DEAD_LETTER_TOPIC"
+
+	UserTaskPolicy      = "USER_DELETED_TASK_POLICY"
+	UserReassignTo      = "USER_DELETED_REASSIGN_TO"
+	UserEventAttempts   = "USER_EVENTS_MAX_ATTEMPTS"
+	UserEventMaxBackoff = "USER_EVENTS_MAX_BACKOFF_SECONDS"
+	UserEventRetention  = "USER_EVENTS_RETENTION_HOURS"
+
+	WebhookPollInterval = "WEBHOOK_POLL_INTERVAL_MS"
+	WebhookConcurrency  = "WEBHOOK_MAX_CONCURRENT"
+	WebhookTimeout      = "WEBHOOK_TIMEOUT_SECONDS"
+	WebhookMaxAttempts  = "WEBHOOK_MAX_ATTEMPTS"
+	WebhookMaxBackoff   = "WEBHOOK_MAX_BACKOFF_SECONDS"
+	WebhookDisableAfter = "WEBHOOK_DISABLE_AFTER_FAILURES"
+	WebhookRetention    = "WEBHOOK_RETENTION_HOURS"
+
+	StreamBackend      = "TASK_STREAM_BACKEND"
+	StreamChannel      = "TASK_STREAM_REDIS_CHANNEL"
+	StreamReplaySize   = "TASK_STREAM_REPLAY_SIZE"
+	StreamClientBuffer = "TASK_STREAM_CLIENT_BUFFER"
+	StreamHeartbeat    = "TASK_STREAM_HEARTBEAT_SECONDS"
+
+	BoardTokenSecret    = "BOARD_TOKEN_SECRET"
+	BoardAllowedOrigins = "BOARD_ALLOWED_ORIGINS"
+	BoardSendBuffer     = "BOARD_SEND_BUFFER"
+	BoardMaxSubs        = "BOARD_MAX_SUBSCRIPTIONS"
+	BoardMaxTasks       = "BOARD_MAX_TASKS_PER_SUBSCRIPTION"
+	BoardMaxConns       = "BOARD_MAX_CONNECTIONS_PER_USER"
+	BoardMaxMsgBytes    = "BOARD_MAX_MESSAGE_BYTES"
+	BoardMsgsPerSec     = "BOARD_MESSAGES_PER_SECOND"
+	BoardPing           = "BOARD_PING_SECONDS"
+
+	ChangeFeedMaxLimit = "CHANGE_FEED_MAX_LIMIT"
+	ChangeFeedMaxWait  = "CHANGE_FEED_MAX_WAIT_SECONDS"
+	ChangeFeedPoll     = "CHANGE_FEED_POLL_SECONDS"
+
+	TracingExporter = "TRACING_EXPORTER"
+
+	LogLevel      = "LOG_LEVEL"
+	LogFormat     = "LOG_FORMAT"
+	LogFile       = "LOG_FILE"
+	LogMaxSize    = "LOG_MAX_SIZE_MB"
+	LogMaxBackups = "LOG_MAX_BACKUPS"
+	LogMaxAge     = "LOG_MAX_AGE_DAYS"
+
+	HealthTimeout = "HEALTH_CHECK_TIMEOUT_MS"
+
+	ShutdownTimeout = "SHUTDOWN_TIMEOUT_SECONDS"
+	ShutdownDelay   = "SHUTDOWN_DELAY_SECONDS"
+
+	HTTPReadHeaderTimeout = "HTTP_READ_HEADER_TIMEOUT_SECONDS"
+	HTTPIdleTimeout       = "HTTP_IDLE_TIMEOUT_SECONDS"
+
+	RateLimitPerSec    = "RATE_LIMIT_PER_SECOND"
+	RateLimitBurst     = "RATE_LIMIT_BURST"
+	CORSAllowedOrigins = "CORS_ALLOWED_ORIGINS"
+	DisabledFeatures   = "DISABLED_FEATURES"
+
+	VaultAddr     = "VAULT_ADDR"
+	VaultToken    = "VAULT_TOKEN"
+	SecretRefresh = "SECRET_REFRESH_SECONDS"
+	SecretTimeout = "SECRET_TIMEOUT_MS"
+
+	ConfigFile        = "CONFIG_FILE"
+	ConfigWatch       = "CONFIG_WATCH_SECONDS"
+	DbMaxIdleConns    = "DB_MAX_IDLE_CONNECTIONS"
+	DbConnMaxLifetime = "DB_CONN_MAX_LIFETIME_MINUTES"
+	DbConnectTimeout  = "DB_CONNECT_TIMEOUT_SECONDS"
+	DbLogLevel        = "DB_LOG_LEVEL"
+	MigrateOnStart    = "DB_MIGRATE_ON_START"
+	MigrateDryRun     = "DB_MIGRATE_DRY_RUN"
+	MigrateTimeout    = "DB_MIGRATE_TIMEOUT_SECONDS"
+
+	OutboxPollInterval   = "OUTBOX_POLL_INTERVAL_MS"
+	OutboxBatchSize      = "OUTBOX_BATCH_SIZE"
+	OutboxMaxBackoff     = "OUTBOX_MAX_BACKOFF_SECONDS"
+	OutboxMaxAttempts    = "OUTBOX_MAX_ATTEMPTS"
+	OutboxLease          = "OUTBOX_LEASE_SECONDS"
+	OutboxPublishTimeout = "OUTBOX_PUBLISH_TIMEOUT_SECONDS"
+	OutboxRetention      = "OUTBOX_RETENTION_HOURS"
+	Err                  = "err"
+	AppName              = "APP_NAME"
+	AppVersion           = "APP_VERSION"
+	UserAppBaseUri       = "TESSERACT_BASE_URI"
+
+	KafkaRetryTopic   = "KAFKA_RETRY_TOPIC"
+	PostgresAddress   = "POSTGRES_ADDRESS"
+	PostgresUsername  = "POSTGRES_USERNAME"
+	PostgresPassword  = "POSTGRES_PASSWORD"
+	PostgresDbName    = "POSTGRES_DB_NAME"
+	PostgresHost      = "POSTGRES_HOST"
+	PostgresPort      = "POSTGRES_PORT"
+	MaxDbConnections  = "MAX_DB_CONNECTIONS"
+	IdempotencyTTL    = "IDEMPOTENCY_KEY_TTL_MINUTES"
+	IdempotencyLease  = "IDEMPOTENCY_LEASE_SECONDS"
+	TitleIgnoreCase   = "TASK_TITLE_UNIQUE_IGNORE_CASE"
+	TitleTrimSpaces   = "TASK_TITLE_UNIQUE_COLLAPSE_WHITESPACE"
+	UserServiceURL    = "USER_SERVICE_URL"
+	UserServicePolicy = "USER_SERVICE_FAILURE_POLICY"
+	UserProvider      = "USER_PROVIDER"
+	UserProviderFile  = "USER_PROVIDER_FILE"
+	LdapURL           = "LDAP_URL"
+	LdapBindDN        = "LDAP_BIND_DN"
+	LdapBindPassword  = "LDAP_BIND_PASSWORD"
+	LdapBaseDN        = "LDAP_BASE_DN"
+	LdapIDAttribute   = "LDAP_USER_ID_ATTRIBUTE"
+	LdapNameAttribute = "LDAP_NAME_ATTRIBUTE"
+	LdapMailAttribute = "LDAP_EMAIL_ATTRIBUTE"
+	LdapTimeout       = "LDAP_TIMEOUT_MS"
+	UserCacheEnabled  = "USER_CACHE_ENABLED"
+	UserSvcTimeout    = "USER_SERVICE_TIMEOUT_MS"
+	UserSvcRetries    = "USER_SERVICE_MAX_RETRIES"
+	UserSvcBreakerMax = "USER_SERVICE_BREAKER_THRESHOLD"
+	UserSvcBreakerTTL = "USER_SERVICE_BREAKER_OPEN_SECONDS"
+	UserSvcBulkhead   = "USER_SERVICE_MAX_CONCURRENT"
+	UserCacheTTL      = "USER_CACHE_TTL_SECONDS"
+	UserCacheNegTTL   = "USER_CACHE_NEGATIVE_TTL_SECONDS"
+	UserCacheMaxSize  = "USER_CACHE_MAX_SIZE"
+	RedisEndpoint     = "REDIS_ENDPOINT"
+	RedisPort         = "REDIS_PORT"
+	RedisUsername     = "REDIS_USERNAME"
+	RedisPassword     = "REDIS_PASSWORD"
+	RedisDb           = "REDIS_DB"
+	RedisTimeout      = "REDIS_TIMEOUT_MS"
+	RedisPoolTimeout  = "REDIS_POOL_TIMEOUT_MS"
+	AdminToken        = "ADMIN_TOKEN"
+	HOST              = "HOST"
+	PORT              = "PORT"
+	TCP               = "tcp"
+	DriverName        = "postgres"
 )
@@ -6,11 +6,13 @@ const (
 	StatusPending    TaskStatus = "Pending"
 	StatusInProgress TaskStatus = "InProgress"
 	StatusCompleted  TaskStatus = "Completed"
+	// StatusArchived is set on the tasks of a deleted user under the archive policy
+	StatusArchived TaskStatus = "Archived"
 )
 
 func (s TaskStatus) IsValid() bool {
 	switch s {
-	case StatusPending, StatusInProgress, StatusCompleted:
+	case StatusPending, StatusInProgress, StatusCompleted, StatusArchived:
 		return true
 	}
 	return false
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"net/http"
+	"task-manager-app/constants"
+	"task-manager-app/exceptions"
+	"task-manager-app/response"
+	"task-manager-app/services/userManagerServices"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminController struct {
+	userCache userManagerServices.UserCache
+	settings  func() map[string]string
+}
+
+// NewAdminController reports the settings in effect returned by settings, with secrets redacted
+func NewAdminController(userCache userManagerServices.UserCache, settings func() map[string]string) *AdminController {
+	return &AdminController{
+		userCache: userCache,
+		settings:  settings,
+	}
+}
+
+// InvalidateUser drops the cached validation result for a user
+func (a *AdminController) InvalidateUser(ctx *gin.Context) {
+	if a.userCache == nil {
+		taskErr := exceptions.NotFoundException(constants.ErrUserCacheDisabled)
+		respondWithError(ctx, taskErr)
+		return
+	}
+
+	userID := ctx.Param(constants.URLParamUserID)
+	if err := a.userCache.Invalidate(userID); err != nil {
+		taskErr := exceptions.InternalServerException(constants.ErrFailedToInvalidateUser + ": " + err.Error())
+		respondWithError(ctx, taskErr)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetConfig returns the settings in effect, including the ones reloaded since startup
+func (a *AdminController) GetConfig(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, response.ConfigResponse{Settings: a.settings()})
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"task-manager-app/response"
+	"task-manager-app/services/userManagerServices"
+	"task-manager-app/utils"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	utils.Sugar = zap.NewNop().Sugar()
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+func newAdminRouter(userCache userManagerServices.UserCache) *gin.Engine {
+	router := gin.New()
+	router.DELETE("/admin/users/:id/cache", NewAdminController(userCache, nil).InvalidateUser)
+	return router
+}
+
+func TestInvalidateUserDropsCachedUser(t *testing.T) {
+	cache := userManagerServices.NewUserCache(userManagerServices.UserCacheOptions{PositiveTTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 10})
+	loads := 0
+	load := func(_ context.Context, userID string) (*userManagerServices.UserProfile, error) {
+		loads++
+		return &userManagerServices.UserProfile{UserID: userID}, nil
+	}
+	cache.Lookup(context.Background(), "u1", load)
+
+	recorder := httptest.NewRecorder()
+	newAdminRouter(cache).ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/admin/users/u1/cache", nil))
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusNoContent)
+	}
+
+	cache.Lookup(context.Background(), "u1", load)
+	if loads != 2 {
+		t.Fatalf("u1 loaded %d times, want a reload after the invalidation", loads)
+	}
+}
+
+func TestInvalidateUserWithoutCache(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	newAdminRouter(nil).ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/admin/users/u1/cache", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d when the cache is disabled", recorder.Code, http.StatusNotFound)
+	}
+}
+
+func TestGetConfigReturnsTheSettings(t *testing.T) {
+	router := gin.New()
+	router.GET("/admin/config", NewAdminController(nil, func() map[string]string {
+		return map[string]string{"LOG_LEVEL": "debug", "ADMIN_TOKEN": "[redacted]"}
+	}).GetConfig)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/config", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	var body response.ConfigResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response is not JSON: %v", err)
+	}
+	if body.Settings["LOG_LEVEL"] != "debug" || body.Settings["ADMIN_TOKEN"] != "[redacted]" {
+		t.Fatalf("got settings %v", body.Settings)
+	}
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"task-manager-app/constants"
+	"task-manager-app/exceptions"
+	"task-manager-app/middleware"
+	"task-manager-app/services/boardService"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+type BoardController struct {
+	hub            *boardService.BoardHub
+	secret         string
+	upgrader       websocket.Upgrader
+	allowedOrigins atomic.Pointer[[]string]
+	now            func() time.Time
+}
+
+// NewBoardController accepts board connections from pages served by allowedOrigins, where "*"
+// allows any origin, or only from the same origin when none are given. Token expiry is checked
+// against now.
+func NewBoardController(hub *boardService.BoardHub, secret string, allowedOrigins []string, now func() time.Time) *BoardController {
+	b := &BoardController{
+		hub:    hub,
+		secret: secret,
+		now:    now,
+	}
+	b.upgrader.CheckOrigin = b.checkOrigin
+	b.SetAllowedOrigins(allowedOrigins)
+	return b
+}
+
+// SetAllowedOrigins changes the origins accepted for new connections
+func (b *BoardController) SetAllowedOrigins(allowedOrigins []string) {
+	b.allowedOrigins.Store(&allowedOrigins)
+}
+
+func (b *BoardController) checkOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	allowedOrigins := *b.allowedOrigins.Load()
+	if len(allowedOrigins) == 0 {
+		// The default of the upgrader: clients that are not browsers send no origin
+		if origin == "" {
+			return true
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, req.Host)
+	}
+	for _, allowed := range allowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Connect upgrades an authenticated request to a board WebSocket. Browsers cannot set headers
+// on WebSocket requests, so the token is also accepted in the access_token query parameter.
+func (b *BoardController) Connect(ctx *gin.Context) {
+	token := strings.TrimPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+	if token == "" {
+		token = ctx.Query(constants.QueryParamAccessToken)
+	}
+	userID, err := boardService.VerifyToken(b.secret, token, b.now())
+	if err != nil {
+		taskErr := exceptions.UnauthorizedException(constants.ErrInvalidBoardToken)
+		respondWithError(ctx, taskErr)
+		return
+	}
+	middleware.SetPrincipal(ctx, userID)
+	if !b.hub.Acquire(userID) {
+		taskErr := exceptions.TooManyRequestsException(constants.ErrTooManyBoardConns)
+		respondWithError(ctx, taskErr)
+		return
+	}
+
+	// Upgrade has already answered the client when it fails
+	conn, err := b.upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		b.hub.Release(userID)
+		return
+	}
+	b.hub.Serve(conn, userID)
+}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"task-manager-app/events"
+	"task-manager-app/services/boardService"
+	"task-manager-app/services/streamService"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+func boardToken(secret, userID string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	unsigned := encode([]byte(`{"alg":"HS256"}`)) + "." + encode([]byte(`{"sub":"`+userID+`","exp":4102444800}`))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + encode(mac.Sum(nil))
+}
+
+func newBoardServer(t *testing.T, allowedOrigins []string) (string, *BoardController) {
+	stream := streamService.NewTaskStream(events.NewMemoryChangeBus(), streamService.StreamOptions{ReplaySize: 10, ClientBuffer: 10})
+	if err := stream.Start(); err != nil {
+		t.Fatalf("Start returned error %v", err)
+	}
+	hub := boardService.NewBoardHub(stream, boardService.BoardOptions{
+		SendBuffer:              10,
+		MaxSubscriptions:        10,
+		MaxTasksPerSubscription: 10,
+		MaxConnectionsPerUser:   1,
+		MaxMessageBytes:         1024,
+		MessagesPerSecond:       10,
+		PingInterval:            time.Minute,
+		WriteTimeout:            time.Second,
+	})
+
+	boardController := NewBoardController(hub, "secret", allowedOrigins, time.Now)
+	router := gin.New()
+	router.GET("/boards/ws", boardController.Connect)
+	server := httptest.NewServer(router)
+	t.Cleanup(func() {
+		server.Close()
+		stream.Stop()
+	})
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/boards/ws", boardController
+}
+
+func TestBoardConnectAuthenticatesClients(t *testing.T) {
+	url, _ := newBoardServer(t, nil)
+
+	for name, header := range map[string]http.Header{
+		"no token":    nil,
+		"wrong token": {"Authorization": {"Bearer " + boardToken("other", "alice")}},
+	} {
+		if _, resp, err := websocket.DefaultDialer.Dial(url, header); err == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s: connection was not rejected as unauthorized", name)
+		}
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": {"Bearer " + boardToken("secret", "alice")}})
+	if err != nil {
+		t.Fatalf("Dial with a valid token returned error %v", err)
+	}
+	defer conn.Close()
+
+	// alice is limited to one connection; the query parameter authenticates her as well
+	if _, resp, err := websocket.DefaultDialer.Dial(url+"?access_token="+boardToken("secret", "alice"), nil); err == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Error("second connection of alice was not rejected as too many")
+	}
+}
+
+func TestBoardConnectChecksTheOrigin(t *testing.T) {
+	header := func(origin string) http.Header {
+		return http.Header{"Authorization": {"Bearer " + boardToken("secret", "alice")}, "Origin": {origin}}
+	}
+
+	url, _ := newBoardServer(t, []string{"https://board.example.com"})
+	if _, resp, err := websocket.DefaultDialer.Dial(url, header("https://evil.example.com")); err == nil || resp.StatusCode != http.StatusForbidden {
+		t.Error("connection from an unlisted origin was not rejected")
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(url, header("https://board.example.com"))
+	if err != nil {
+		t.Fatalf("Dial from an allowed origin returned error %v", err)
+	}
+	conn.Close()
+}
+
+func TestBoardConnectUsesChangedOrigins(t *testing.T) {
+	header := http.Header{"Authorization": {"Bearer " + boardToken("secret", "alice")}, "Origin": {"https://board.example.com"}}
+
+	// Without allowed origins only pages of the same origin may connect
+	url, boardController := newBoardServer(t, nil)
+	if _, resp, err := websocket.DefaultDialer.Dial(url, header); err == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatal("connection from another origin was not rejected")
+	}
+
+	boardController.SetAllowedOrigins([]string{"https://board.example.com"})
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatalf("Dial from a newly allowed origin returned error %v", err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"task-manager-app/constants"
+	"task-manager-app/exceptions"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/services/changeFeedService"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ChangeFeedController struct {
+	feed *changeFeedService.ChangeFeed
+}
+
+func NewChangeFeedController(feed *changeFeedService.ChangeFeed) *ChangeFeedController {
+	return &ChangeFeedController{
+		feed: feed,
+	}
+}
+
+// ListChanges returns the task changes after the since query parameter in sequence order.
+// With wait, a request without changes is held until one arrives or the wait is over.
+func (c *ChangeFeedController) ListChanges(ctx *gin.Context) {
+	since, err := strconv.ParseUint(ctx.DefaultQuery(constants.QueryParamSince, "0"), 10, 64)
+	if err != nil {
+		taskErr := exceptions.NewBadRequestException(constants.ErrInvalidChangeSince)
+		respondWithError(ctx, taskErr)
+		return
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery(constants.QueryParamLimit, strconv.Itoa(constants.DefaultChangeFeedLimit)))
+	if err != nil || limit < 1 {
+		taskErr := exceptions.NewBadRequestException(constants.ErrInvalidChangeLimit)
+		respondWithError(ctx, taskErr)
+		return
+	}
+	wait, taskErr := parseWait(ctx.Query(constants.QueryParamWait))
+	if taskErr != nil {
+		respondWithError(ctx, taskErr)
+		return
+	}
+
+	resp, taskErr := c.feed.Changes(ctx.Request.Context(), since, limit, wait)
+	if taskErr != nil {
+		respondWithError(ctx, taskErr)
+		return
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// parseWait reads a duration such as 30s, or a number of seconds
+func parseWait(value string) (time.Duration, *errors.TaskManagerError) {
+	if value == "" {
+		return 0, nil
+	}
+	wait, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, atoiErr := strconv.Atoi(value)
+		wait, err = time.Duration(seconds)*time.Second, atoiErr
+	}
+	if err != nil || wait < 0 {
+		return 0, exceptions.NewBadRequestException(constants.ErrInvalidChangeWait)
+	}
+	return wait, nil
+}
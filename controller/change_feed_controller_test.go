@@ -0,0 +1,24 @@
+package controller
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWait(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"":      0,
+		"30s":   30 * time.Second,
+		"1m30s": 90 * time.Second,
+		"45":    45 * time.Second,
+	} {
+		if got, taskErr := parseWait(value); taskErr != nil || got != want {
+			t.Errorf("parseWait(%q) = %v, %v, want %v", value, got, taskErr, want)
+		}
+	}
+	for _, value := range []string{"-5s", "-1", "soon"} {
+		if _, taskErr := parseWait(value); taskErr == nil {
+			t.Errorf("parseWait(%q) returned no error", value)
+		}
+	}
+}
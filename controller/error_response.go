@@ -0,0 +1,21 @@
+package controller
+
+import (
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondWithError sends taskErr with the ID of the request, which the caller can quote to
+// find the log lines of the failure
+func respondWithError(ctx *gin.Context, taskErr *errors.TaskManagerError) {
+	withRequestID(ctx, taskErr)
+	ctx.JSON(taskErr.ResponseCode, taskErr)
+}
+
+// withRequestID fills in the request ID of taskErr, e.g. before it is stored for replays
+func withRequestID(ctx *gin.Context, taskErr *errors.TaskManagerError) *errors.TaskManagerError {
+	taskErr.RequestID = utils.RequestID(ctx.Request.Context())
+	return taskErr
+}
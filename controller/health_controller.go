@@ -1,25 +1,63 @@
 package controller
 
 import (
-	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 	"net/http"
 	"task-manager-app/constants"
+	"task-manager-app/exceptions"
+	"task-manager-app/request"
+	"task-manager-app/services/healthService"
+
+	"github.com/gin-gonic/gin"
 )
 
 type HealthController struct {
-	db *gorm.DB
+	health *healthService.HealthService
 }
 
-func NewHealthController(db *gorm.DB) *HealthController {
+func NewHealthController(health *healthService.HealthService) *HealthController {
 	return &HealthController{
-		db: db,
+		health: health,
 	}
 }
 
-func (h *HealthController) HealthCheck(c *gin.Context) {
+// Live reports that the process is up and serving requests. It checks no dependencies, so
+// that an outage elsewhere does not get every instance restarted.
+func (h *HealthController) Live(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": constants.HealthCheckOKMessage,
 	})
-	return
+}
+
+// Ready reports each dependency and answers 503 when the instance should not get traffic
+func (h *HealthController) Ready(c *gin.Context) {
+	resp, ready := h.health.Ready(c.Request.Context())
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// SetReadiness marks the instance not ready, for maintenance, or ready again
+func (h *HealthController) SetReadiness(c *gin.Context) {
+	var req request.ReqSetReadiness
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, exceptions.NewBadRequestException(constants.ErrInvalidRequestBody+": "+err.Error()))
+		return
+	}
+	if req.Ready == nil {
+		respondWithError(c, exceptions.NewBadRequestException(constants.ErrReadyRequired))
+		return
+	}
+
+	if *req.Ready {
+		h.health.MarkReady()
+	} else {
+		reason := req.Reason
+		if reason == "" {
+			reason = constants.NotReadyMaintenance
+		}
+		h.health.MarkNotReady(reason)
+	}
+	c.Status(http.StatusNoContent)
 }
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"task-manager-app/constants"
+	"task-manager-app/response"
+	"task-manager-app/services/healthService"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newHealthRouter(health *healthService.HealthService) *gin.Engine {
+	healthController := NewHealthController(health)
+	router := gin.New()
+	router.GET("/livez", healthController.Live)
+	router.GET("/readyz", healthController.Ready)
+	router.PUT("/admin/readiness", healthController.SetReadiness)
+	return router
+}
+
+func readiness(t *testing.T, router *gin.Engine) (int, response.ReadinessResponse) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var resp response.ReadinessResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("readiness body %q is not JSON: %v", recorder.Body.String(), err)
+	}
+	return recorder.Code, resp
+}
+
+func TestLivenessIgnoresDependencies(t *testing.T) {
+	router := newHealthRouter(healthService.NewHealthService(time.Second, healthService.Check{
+		Name: constants.DependencyDatabase, Critical: true,
+		Probe: func(context.Context) error { return errors.New("down") },
+	}))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("/livez = %d, want 200", recorder.Code)
+	}
+	if code, resp := readiness(t, router); code != http.StatusServiceUnavailable || resp.Dependencies[constants.DependencyDatabase].Status != constants.HealthStatusDown {
+		t.Fatalf("/readyz = %d %+v, want 503 with the database down", code, resp)
+	}
+}
+
+func TestSetReadinessForMaintenance(t *testing.T) {
+	router := newHealthRouter(healthService.NewHealthService(time.Second))
+
+	set := func(body string) int {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/admin/readiness", strings.NewReader(body)))
+		return recorder.Code
+	}
+
+	if code := set(`{"reason": "no ready flag"}`); code != http.StatusBadRequest {
+		t.Fatalf("missing ready = %d, want 400", code)
+	}
+	if code := set(`{"ready": false}`); code != http.StatusNoContent {
+		t.Fatalf("marking not ready = %d, want 204", code)
+	}
+	if code, resp := readiness(t, router); code != http.StatusServiceUnavailable || resp.Reason != constants.NotReadyMaintenance {
+		t.Fatalf("/readyz = %d %+v, want 503 for maintenance", code, resp)
+	}
+	if code := set(`{"ready": true}`); code != http.StatusNoContent {
+		t.Fatalf("marking ready = %d, want 204", code)
+	}
+	if code, resp := readiness(t, router); code != http.StatusOK || resp.Status != constants.HealthStatusReady {
+		t.Fatalf("/readyz = %d %+v, want 200 ready", code, resp)
+	}
+}
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"task-manager-app/constants"
+	"task-manager-app/events"
+	"task-manager-app/exceptions"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/services/streamService"
+	"task-manager-app/services/validationService"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamResetEvent tells a resuming client that changes were missed and it should reload
+const streamResetEvent = "reset"
+
+type StreamController struct {
+	stream     *streamService.TaskStream
+	validation validationService.ValidationService
+	heartbeat  time.Duration
+}
+
+func NewStreamController(stream *streamService.TaskStream, validation validationService.ValidationService, heartbeat time.Duration) *StreamController {
+	return &StreamController{
+		stream:     stream,
+		validation: validation,
+		heartbeat:  heartbeat,
+	}
+}
+
+// StreamTasks pushes the task changes matching the status, user_id and priority query
+// parameters as Server-Sent Events. Each event is named after its event type and carries the
+// change ID, so clients resume with Last-Event-ID after a reconnect.
+func (s *StreamController) StreamTasks(ctx *gin.Context) {
+	filter := streamService.Filter{
+		Status:   ctx.Query(constants.QueryParamStatus),
+		UserID:   ctx.Query(constants.QueryParamUserID),
+		Priority: ctx.Query(constants.QueryParamPriority),
+	}
+	if taskErr := s.validateFilter(ctx.Request.Context(), filter); taskErr != nil {
+		respondWithError(ctx, taskErr)
+		return
+	}
+	lastEventID, taskErr := parseLastEventID(ctx)
+	if taskErr != nil {
+		respondWithError(ctx, taskErr)
+		return
+	}
+
+	client, replay, complete := s.stream.Subscribe(filter, lastEventID)
+	defer s.stream.Unsubscribe(client)
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	// Keep nginx from buffering the stream
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	if !complete {
+		fmt.Fprintf(ctx.Writer, "event: %s\ndata: {}\n\n", streamResetEvent)
+	}
+	for _, change := range replay {
+		if writeChange(ctx, change) != nil {
+			return
+		}
+	}
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(s.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case change, ok := <-client.C:
+			if !ok {
+				return
+			}
+			if writeChange(ctx, change) != nil {
+				return
+			}
+		case <-heartbeat.C:
+			// Moving an idle client's Last-Event-ID past changes it filtered out keeps its
+			// resume point inside the replay buffer
+			if id, ok := s.stream.Position(client); ok {
+				fmt.Fprintf(ctx.Writer, "id: %d\n", id)
+			}
+			if _, err := fmt.Fprint(ctx.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		ctx.Writer.Flush()
+	}
+}
+
+func (s *StreamController) validateFilter(ctx context.Context, filter streamService.Filter) *errors.TaskManagerError {
+	if filter.Status != "" {
+		if taskErr := s.validation.ValidateTaskStatus(filter.Status); taskErr != nil {
+			return taskErr
+		}
+	}
+	if filter.Priority != "" {
+		if taskErr := s.validation.ValidateTaskPriority(filter.Priority); taskErr != nil {
+			return taskErr
+		}
+	}
+	if filter.UserID != "" {
+		return s.validation.ValidateUserID(ctx, filter.UserID)
+	}
+	return nil
+}
+
+// parseLastEventID reads the Last-Event-ID header sent by reconnecting EventSources, or the
+// last_event_id query parameter for the first connection of a resuming client
+func parseLastEventID(ctx *gin.Context) (*uint64, *errors.TaskManagerError) {
+	value := ctx.GetHeader(constants.HeaderLastEventID)
+	if value == "" {
+		value = ctx.Query(constants.QueryParamLastEventID)
+	}
+	if value == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, exceptions.NewBadRequestException(constants.ErrInvalidLastEventID)
+	}
+	return &id, nil
+}
+
+func writeChange(ctx *gin.Context, change events.Change) error {
+	data, err := json.Marshal(change.Event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(ctx.Writer, "id: %d\nevent: %s\ndata: %s\n\n", change.ID, change.Event.EventType, data)
+	return err
+}
@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"task-manager-app/constants"
+	"task-manager-app/events"
+	"task-manager-app/exceptions"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/services/streamService"
+	"task-manager-app/services/validationService"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// knownUsersValidator accepts the Pending and Completed statuses and only the users it lists
+type knownUsersValidator struct {
+	validationService.ValidationService
+	users map[string]bool
+}
+
+func (v *knownUsersValidator) ValidateTaskStatus(status string) *errors.TaskManagerError {
+	if status != "Pending" && status != "Completed" {
+		return exceptions.NewBadRequestException(constants.ErrInvalidTaskStatus)
+	}
+	return nil
+}
+
+func (v *knownUsersValidator) ValidateUserID(ctx context.Context, userID string) *errors.TaskManagerError {
+	if !v.users[userID] {
+		return exceptions.NotFoundException(constants.ErrUserNotFound)
+	}
+	return nil
+}
+
+func newStreamServer(t *testing.T, heartbeat time.Duration) (*events.MemoryChangeBus, *streamService.TaskStream, *httptest.Server) {
+	bus := events.NewMemoryChangeBus()
+	stream := streamService.NewTaskStream(bus, streamService.StreamOptions{ReplaySize: 10, ClientBuffer: 10})
+	if err := stream.Start(); err != nil {
+		t.Fatalf("Start returned error %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/tasks/stream", NewStreamController(stream, &knownUsersValidator{users: map[string]bool{"alice": true}}, heartbeat).StreamTasks)
+	router.GET("/tasks/:uuid", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	server := httptest.NewServer(router)
+	t.Cleanup(func() {
+		stream.Stop()
+		server.Close()
+	})
+	return bus, stream, server
+}
+
+// openStream connects to the stream and returns a function reading the next event as its
+// lines, without the blank line ending it
+func openStream(t *testing.T, url string, header http.Header) func() []string {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s returned error %v", url, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("got status %d and content type %q, want an event stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return func() []string {
+		var event []string
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatal("stream ended")
+				}
+				if line == "" {
+					return event
+				}
+				event = append(event, line)
+			case <-time.After(2 * time.Second):
+				t.Fatal("timed out waiting for an event")
+			}
+		}
+	}
+}
+
+// waitForClients waits until n clients are subscribed, so that changes published next reach them
+func waitForClients(t *testing.T, stream *streamService.TaskStream, n int) {
+	deadline := time.Now().Add(2 * time.Second)
+	for stream.Clients() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d stream clients", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func updated(eventID, status string) *events.TaskEvent {
+	return &events.TaskEvent{EventID: eventID, EventType: events.TaskUpdated, Task: &events.TaskSnapshot{Status: status}}
+}
+
+func TestStreamTasksPushesFilteredChanges(t *testing.T) {
+	bus, stream, server := newStreamServer(t, time.Hour)
+	next := openStream(t, server.URL+"/tasks/stream?status=Pending", nil)
+	waitForClients(t, stream, 1)
+
+	bus.Publish(context.Background(), updated("e1", "Completed"), updated("e2", "Pending"))
+
+	event := next()
+	if len(event) != 3 || event[0] != "id: 2" || event[1] != "event: TaskUpdated" || !strings.Contains(event[2], `"event_id":"e2"`) {
+		t.Errorf("got event %q, want change 2 for e2", event)
+	}
+}
+
+func TestStreamTasksResumesFromLastEventID(t *testing.T) {
+	bus, stream, server := newStreamServer(t, time.Hour)
+	next := openStream(t, server.URL+"/tasks/stream", nil)
+	waitForClients(t, stream, 1)
+	bus.Publish(context.Background(), updated("e1", "Pending"), updated("e2", "Pending"))
+	next()
+	next()
+
+	resumed := openStream(t, server.URL+"/tasks/stream", http.Header{constants.HeaderLastEventID: {"1"}})
+	if event := resumed(); event[0] != "id: 2" {
+		t.Errorf("resumed stream started with %q, want change 2", event)
+	}
+
+	fromQuery := openStream(t, server.URL+"/tasks/stream?last_event_id=2", nil)
+	bus.Publish(context.Background(), updated("e3", "Pending"))
+	if event := fromQuery(); event[0] != "id: 3" {
+		t.Errorf("stream resumed from the query started with %q, want change 3", event)
+	}
+}
+
+func TestStreamTasksTellsClientsToResetAfterAGap(t *testing.T) {
+	_, _, server := newStreamServer(t, time.Hour)
+	next := openStream(t, server.URL+"/tasks/stream", http.Header{constants.HeaderLastEventID: {"7"}})
+	if event := next(); len(event) != 2 || event[0] != "event: reset" {
+		t.Errorf("got %q, want a reset event", event)
+	}
+}
+
+func TestStreamTasksSendsHeartbeats(t *testing.T) {
+	bus, stream, server := newStreamServer(t, 10*time.Millisecond)
+	next := openStream(t, server.URL+"/tasks/stream?status=Completed", nil)
+	waitForClients(t, stream, 1)
+	bus.Publish(context.Background(), updated("e1", "Pending"))
+
+	// The filtered out change still moves the client's resume point
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		event := next()
+		if len(event) == 2 && event[0] == "id: 1" && event[1] == ": heartbeat" {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("last heartbeat %q, want one with id 1", event)
+		}
+	}
+}
+
+func TestStreamTasksValidatesFilters(t *testing.T) {
+	_, _, server := newStreamServer(t, time.Hour)
+	for query, want := range map[string]int{
+		"status=Done":      http.StatusBadRequest,
+		"user_id=mallory":  http.StatusNotFound,
+		"last_event_id=x1": http.StatusBadRequest,
+	} {
+		resp, err := http.Get(server.URL + "/tasks/stream?" + query)
+		if err != nil {
+			t.Fatalf("GET returned error %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s: status = %d, want %d", query, resp.StatusCode, want)
+		}
+	}
+}
@@ -1,46 +1,134 @@
 package controller
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 	"task-manager-app/constants"
 	"task-manager-app/exceptions"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/middleware"
+	"task-manager-app/models"
 	"task-manager-app/request"
+	"task-manager-app/services/idempotencyService"
 	"task-manager-app/services/taskManagerService"
 
 	"github.com/gin-gonic/gin"
 )
 
 type TaskController struct {
-	service taskManagerService.TaskService
+	service     taskManagerService.TaskService
+	idempotency idempotencyService.IdempotencyService
 }
 
-func NewTaskController(service taskManagerService.TaskService) *TaskController {
-	return &TaskController{service: service}
+func NewTaskController(service taskManagerService.TaskService, idempotency idempotencyService.IdempotencyService) *TaskController {
+	return &TaskController{
+		service:     service,
+		idempotency: idempotency,
+	}
 }
 
 func (c *TaskController) CreateTask(ctx *gin.Context) {
+	if key := ctx.GetHeader(constants.HeaderIdempotencyKey); key != "" {
+		c.createTaskIdempotent(ctx, key)
+		return
+	}
+
 	var req request.ReqCreateOrUpdateTasks
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		taskErr := exceptions.NewBadRequestException(constants.ErrInvalidRequestBody + ": " + err.Error())
-		ctx.JSON(taskErr.ResponseCode, taskErr)
+		respondWithError(ctx, taskErr)
+		return
+	}
+
+	resp, taskErr := c.service.CreateTask(ctx.Request.Context(), &req)
+	if taskErr != nil {
+		respondWithError(ctx, taskErr)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+// createTaskIdempotent creates a task at most once per Idempotency-Key, replaying the
+// stored response when a client retries with the same key and body
+func (c *TaskController) createTaskIdempotent(ctx *gin.Context, key string) {
+	var req request.ReqCreateOrUpdateTasks
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		taskErr := exceptions.NewBadRequestException(constants.ErrInvalidRequestBody + ": " + err.Error())
+		respondWithError(ctx, taskErr)
+		return
+	}
+
+	// Fingerprint the decoded request so formatting differences do not count as a new body
+	canonical, err := json.Marshal(req)
+	if err != nil {
+		taskErr := exceptions.InternalServerException(constants.ErrFailedToCreateTask + ": " + err.Error())
+		respondWithError(ctx, taskErr)
+		return
+	}
+	fingerprint := idempotencyService.Fingerprint(ctx.Request.Method, ctx.FullPath(), canonical)
+
+	record, taskErr := c.idempotency.Begin(idempotencyScope(ctx), key, fingerprint)
+	if taskErr != nil {
+		respondWithError(ctx, taskErr)
+		return
+	}
+	if record.IsCompleted() {
+		ctx.Header(constants.HeaderIdempotentReplayed, "true")
+		ctx.Data(record.ResponseCode, gin.MIMEJSON+"; charset=utf-8", record.ResponseBody)
 		return
 	}
 
-	resp, taskErr := c.service.CreateTask(&req)
+	resp, taskErr := c.service.CreateTask(ctx.Request.Context(), &req)
 	if taskErr != nil {
-		ctx.JSON(taskErr.ResponseCode, taskErr)
+		if taskErr.ResponseCode >= http.StatusInternalServerError {
+			c.idempotency.Release(record)
+		} else {
+			c.completeIdempotent(record, taskErr.ResponseCode, withRequestID(ctx, taskErr))
+		}
+		respondWithError(ctx, taskErr)
 		return
 	}
 
+	c.completeIdempotent(record, http.StatusCreated, resp)
 	ctx.JSON(http.StatusCreated, resp)
 }
 
+func (c *TaskController) completeIdempotent(reservation *models.IdempotencyKey, responseCode int, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		c.idempotency.Release(reservation)
+		return
+	}
+	if taskErr := c.idempotency.Complete(reservation, responseCode, body); taskErr != nil {
+		c.idempotency.Release(reservation)
+	}
+}
+
+// idempotencyScope scopes Idempotency-Key headers to the authenticated caller and the route.
+// Requests without a principal share one scope: their address may change between retries,
+// and credentials are only known once checked.
+func idempotencyScope(ctx *gin.Context) string {
+	caller := "anonymous"
+	if principal := middleware.Principal(ctx); principal != "" {
+		caller = "principal:" + principal
+	}
+	return idempotencyService.Scope(caller, ctx.Request.Method, ctx.FullPath())
+}
+
 func (c *TaskController) GetTask(ctx *gin.Context) {
 	uuid := ctx.Param(constants.URLParamUUID)
-	resp, taskErr := c.service.GetTaskByUUID(uuid)
+	expandUser, taskErr := parseExpand(ctx)
+	if taskErr != nil {
+		respondWithError(ctx, taskErr)
+		return
+	}
+
+	resp, taskErr := c.service.GetTaskByUUID(ctx.Request.Context(), uuid, expandUser)
 	if taskErr != nil {
-		ctx.JSON(taskErr.ResponseCode, taskErr)
+		respondWithError(ctx, taskErr)
 		return
 	}
 
@@ -52,14 +140,14 @@ func (c *TaskController) UpdateTask(ctx *gin.Context) {
 	var req request.ReqCreateOrUpdateTasks
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		taskErr := exceptions.NewBadRequestException(constants.ErrInvalidRequestBody + ": " + err.Error())
-		ctx.JSON(taskErr.ResponseCode, taskErr)
+		respondWithError(ctx, taskErr)
 		return
 	}
 
 	// Update task with validation in service
-	resp, taskErr := c.service.UpdateTask(uuid, &req)
+	resp, taskErr := c.service.UpdateTask(ctx.Request.Context(), uuid, &req)
 	if taskErr != nil {
-		ctx.JSON(taskErr.ResponseCode, taskErr)
+		respondWithError(ctx, taskErr)
 		return
 	}
 
@@ -68,8 +156,8 @@ func (c *TaskController) UpdateTask(ctx *gin.Context) {
 
 func (c *TaskController) DeleteTask(ctx *gin.Context) {
 	uuid := ctx.Param(constants.URLParamUUID)
-	if taskErr := c.service.DeleteTask(uuid); taskErr != nil {
-		ctx.JSON(taskErr.ResponseCode, taskErr)
+	if taskErr := c.service.DeleteTask(ctx.Request.Context(), uuid); taskErr != nil {
+		respondWithError(ctx, taskErr)
 		return
 	}
 	ctx.Status(http.StatusNoContent)
@@ -77,7 +165,7 @@ func (c *TaskController) DeleteTask(ctx *gin.Context) {
 
 func (c *TaskController) ListTasks(ctx *gin.Context) {
 	status := ctx.Query(constants.QueryParamStatus)
-	userID := ctx.Query(constants.QueryParamUserID)
+	userIDs := parseList(ctx.Query(constants.QueryParamUserIDs))
 	priority := ctx.Query(constants.QueryParamPriority)
 	pageStr := ctx.DefaultQuery(constants.QueryParamPage, constants.DefaultPageStr)
 	sizeStr := ctx.DefaultQuery(constants.QueryParamPageSize, constants.DefaultPageSizeStr)
@@ -85,11 +173,56 @@ func (c *TaskController) ListTasks(ctx *gin.Context) {
 	page, _ := strconv.Atoi(pageStr)
 	pageSize, _ := strconv.Atoi(sizeStr)
 
-	resp, taskErr := c.service.ListTasks(status, userID, priority, page, pageSize)
+	if userID := ctx.Query(constants.QueryParamUserID); userID != "" {
+		if len(userIDs) > 0 {
+			taskErr := exceptions.NewBadRequestException(constants.ErrConflictingUserFilters)
+			respondWithError(ctx, taskErr)
+			return
+		}
+		userIDs = []string{userID}
+	}
+
+	expandUser, taskErr := parseExpand(ctx)
+	if taskErr != nil {
+		respondWithError(ctx, taskErr)
+		return
+	}
+
+	resp, taskErr := c.service.ListTasks(ctx.Request.Context(), status, userIDs, priority, page, pageSize, expandUser)
 	if taskErr != nil {
-		ctx.JSON(taskErr.ResponseCode, taskErr)
+		respondWithError(ctx, taskErr)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, resp)
 }
+
+// parseExpand reads the comma separated expand query parameter and reports whether the
+// user expansion was requested
+func parseExpand(ctx *gin.Context) (bool, *errors.TaskManagerError) {
+	expandUser := false
+	for _, value := range strings.Split(ctx.Query(constants.QueryParamExpand), ",") {
+		switch strings.TrimSpace(value) {
+		case "":
+		case constants.ExpandUser:
+			expandUser = true
+		default:
+			return false, exceptions.NewBadRequestException(constants.ErrInvalidExpand)
+		}
+	}
+	return expandUser, nil
+}
+
+// parseList splits a comma separated query parameter, dropping empty values and repeats
+func parseList(value string) []string {
+	var values []string
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" && !seen[item] {
+			seen[item] = true
+			values = append(values, item)
+		}
+	}
+	return values
+}
@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"task-manager-app/constants"
+	"task-manager-app/exceptions"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/middleware"
+	"task-manager-app/models"
+	"task-manager-app/request"
+	"task-manager-app/response"
+	"task-manager-app/services/idempotencyService"
+	"task-manager-app/services/taskManagerService"
+	"task-manager-app/utils"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listingTaskService records the user filter ListTasks was called with
+type listingTaskService struct {
+	taskManagerService.TaskService
+	userIDs []string
+	called  bool
+}
+
+func (s *listingTaskService) ListTasks(ctx context.Context, status string, userIDs []string, priority string, page, pageSize int, expandUser bool) (*response.TaskListResponse, *errors.TaskManagerError) {
+	s.called = true
+	s.userIDs = userIDs
+	return &response.TaskListResponse{}, nil
+}
+
+func TestListTasksUserFilters(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		wantIDs  []string
+	}{
+		{name: "no filter", query: "", wantCode: http.StatusOK},
+		{name: "user_id is one user", query: "?user_id=u1", wantCode: http.StatusOK, wantIDs: []string{"u1"}},
+		{name: "user_id is not split", query: "?user_id=u1,u2", wantCode: http.StatusOK, wantIDs: []string{"u1,u2"}},
+		{name: "user_ids is a list", query: "?user_ids=u1,%20u2,,u1", wantCode: http.StatusOK, wantIDs: []string{"u1", "u2"}},
+		{name: "both are rejected", query: "?user_id=u1&user_ids=u2", wantCode: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service := &listingTaskService{}
+			router := gin.New()
+			router.GET("/tasks", NewTaskController(service, nil).ListTasks)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/tasks"+test.query, nil))
+			if recorder.Code != test.wantCode {
+				t.Fatalf("status = %d, want %d", recorder.Code, test.wantCode)
+			}
+			if test.wantCode != http.StatusOK {
+				if service.called {
+					t.Fatalf("ListTasks was called for a rejected request")
+				}
+				return
+			}
+			if !reflect.DeepEqual(service.userIDs, test.wantIDs) {
+				t.Fatalf("ListTasks got users %q, want %q", service.userIDs, test.wantIDs)
+			}
+		})
+	}
+}
+
+func TestErrorResponsesCarryTheRequestID(t *testing.T) {
+	router := gin.New()
+	router.Use(middleware.RequestID(utils.Sugar))
+	router.GET("/tasks", NewTaskController(&listingTaskService{}, nil).ListTasks)
+
+	request := httptest.NewRequest(http.MethodGet, "/tasks?user_id=u1&user_ids=u2", nil)
+	request.Header.Set(constants.HeaderRequestID, "req-42")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	var body errors.TaskManagerError
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("error body %q is not JSON: %v", recorder.Body.String(), err)
+	}
+	if recorder.Code != http.StatusBadRequest || body.RequestID != "req-42" {
+		t.Fatalf("got %d with request_id %q, want 400 with req-42", recorder.Code, body.RequestID)
+	}
+}
+
+// rejectingTaskService fails every CreateTask with a client error
+type rejectingTaskService struct {
+	taskManagerService.TaskService
+}
+
+func (s *rejectingTaskService) CreateTask(ctx context.Context, req *request.ReqCreateOrUpdateTasks) (*response.TaskResponse, *errors.TaskManagerError) {
+	return nil, exceptions.NewBadRequestException(constants.ErrInvalidTaskStatus)
+}
+
+// storingIdempotency reserves every key and keeps the body stored for replays
+type storingIdempotency struct {
+	idempotencyService.IdempotencyService
+	body  []byte
+	scope string
+}
+
+func (s *storingIdempotency) Begin(scope, key, fingerprint string) (*models.IdempotencyKey, *errors.TaskManagerError) {
+	s.scope = scope
+	return &models.IdempotencyKey{Key: key}, nil
+}
+
+func (s *storingIdempotency) Complete(reservation *models.IdempotencyKey, responseCode int, responseBody []byte) *errors.TaskManagerError {
+	s.body = responseBody
+	return nil
+}
+
+func TestStoredIdempotentErrorsCarryTheRequestID(t *testing.T) {
+	idempotency := &storingIdempotency{}
+	router := gin.New()
+	router.Use(middleware.RequestID(utils.Sugar))
+	router.POST("/tasks", NewTaskController(&rejectingTaskService{}, idempotency).CreateTask)
+
+	request := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{"title": "Write docs"}`))
+	request.Header.Set(constants.HeaderRequestID, "req-42")
+	request.Header.Set(constants.HeaderIdempotencyKey, "key-1")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	var stored errors.TaskManagerError
+	if err := json.Unmarshal(idempotency.body, &stored); err != nil {
+		t.Fatalf("stored body %q is not JSON: %v", idempotency.body, err)
+	}
+	if recorder.Code != http.StatusBadRequest || stored.RequestID != "req-42" {
+		t.Fatalf("got %d with stored request_id %q, want 400 with req-42", recorder.Code, stored.RequestID)
+	}
+}
+
+func TestIdempotencyKeysAreScopedToThePrincipal(t *testing.T) {
+	idempotency := &storingIdempotency{}
+	router := gin.New()
+	router.Use(middleware.RequestID(utils.Sugar), func(ctx *gin.Context) {
+		if user := ctx.GetHeader("X-Test-User"); user != "" {
+			middleware.SetPrincipal(ctx, user)
+		}
+	})
+	router.POST("/tasks", NewTaskController(&rejectingTaskService{}, idempotency).CreateTask)
+
+	tests := []struct {
+		user          string
+		authorization string
+		want          string
+	}{
+		{user: "alice", authorization: "Bearer one", want: idempotencyService.Scope("principal:alice", http.MethodPost, "/tasks")},
+		{user: "alice", authorization: "Bearer two", want: idempotencyService.Scope("principal:alice", http.MethodPost, "/tasks")},
+		{authorization: "Bearer unchecked", want: idempotencyService.Scope("anonymous", http.MethodPost, "/tasks")},
+	}
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{"title": "Write docs"}`))
+		request.Header.Set(constants.HeaderIdempotencyKey, "key-1")
+		request.Header.Set("Authorization", test.authorization)
+		if test.user != "" {
+			request.Header.Set("X-Test-User", test.user)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), request)
+		if idempotency.scope != test.want {
+			t.Errorf("user %q with %q got scope %q, want %q", test.user, test.authorization, idempotency.scope, test.want)
+		}
+	}
+}
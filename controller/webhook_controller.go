@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"task-manager-app/constants"
+	"task-manager-app/exceptions"
+	"task-manager-app/request"
+	"task-manager-app/services/webhookService"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookController struct {
+	service webhookService.WebhookService
+}
+
+func NewWebhookController(service webhookService.WebhookService) *WebhookController {
+	return &WebhookController{
+		service: service,
+	}
+}
+
+func (w *WebhookController) CreateWebhook(ctx *gin.Context) {
+	var req request.ReqCreateOrUpdateWebhook
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		taskErr := exceptions.NewBadRequestException(constants.ErrInvalidRequestBody + ": " + err.Error())
+		respondWithError(ctx, taskErr)
+		return
+	}
+
+	resp, taskErr := w.service.CreateWebhook(&req)
+	if taskErr != nil {
+		respondWithError(ctx, taskErr)
+		return
+	}
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+func (w *WebhookController) ListWebhooks(ctx *gin.Context) {
+	resp, taskErr := w.service.ListWebhooks()
+	if taskErr != nil {
+		respondWithError(ctx, taskErr)
+		return
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (w *WebhookController) GetWebhook(ctx *gin.Context) {
+	resp, taskErr := w.service.GetWebhook(ctx.Param(constants.URLParamUUID))
+	if taskErr != nil {
+		respondWithError(ctx, taskErr)
+		return
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (w *WebhookController) UpdateWebhook(ctx *gin.Context) {
+	var req request.ReqCreateOrUpdateWebhook
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		taskErr := exceptions.NewBadRequestException(constants.ErrInvalidRequestBody + ": " + err.Error())
+		respondWithError(ctx, taskErr)
+		return
+	}
+
+	resp, taskErr := w.service.UpdateWebhook(ctx.Param(constants.URLParamUUID), &req)
+	if taskErr != nil {
+		respondWithError(ctx, taskErr)
+		return
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (w *WebhookController) DeleteWebhook(ctx *gin.Context) {
+	if taskErr := w.service.DeleteWebhook(ctx.Param(constants.URLParamUUID)); taskErr != nil {
+		respondWithError(ctx, taskErr)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// ListDeliveries returns the delivery log of a webhook, optionally filtered by status
+func (w *WebhookController) ListDeliveries(ctx *gin.Context) {
+	status := ctx.Query(constants.QueryParamStatus)
+	page, _ := strconv.Atoi(ctx.DefaultQuery(constants.QueryParamPage, constants.DefaultPageStr))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery(constants.QueryParamPageSize, constants.DefaultPageSizeStr))
+
+	resp, taskErr := w.service.ListDeliveries(ctx.Param(constants.URLParamUUID), status, page, pageSize)
+	if taskErr != nil {
+		respondWithError(ctx, taskErr)
+		return
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// Redeliver queues a logged delivery to be sent again; it is dispatched asynchronously
+func (w *WebhookController) Redeliver(ctx *gin.Context) {
+	deliveryID, err := strconv.ParseUint(ctx.Param(constants.URLParamDelivery), 10, 64)
+	if err != nil {
+		taskErr := exceptions.NotFoundException(constants.ErrDeliveryNotFound)
+		respondWithError(ctx, taskErr)
+		return
+	}
+
+	resp, taskErr := w.service.Redeliver(ctx.Param(constants.URLParamUUID), deliveryID)
+	if taskErr != nil {
+		respondWithError(ctx, taskErr)
+		return
+	}
+	ctx.JSON(http.StatusAccepted, resp)
+}
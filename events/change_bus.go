@@ -0,0 +1,75 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// Change is a task event with the ID a ChangeBus assigned to it. IDs grow with every
+// published event and are the same on every replica.
+type Change struct {
+	ID    uint64
+	Event *TaskEvent
+}
+
+// ChangeBus broadcasts task events to the subscribers of every replica. It is a Publisher so
+// that the outbox relay can feed it.
+type ChangeBus interface {
+	Publisher
+	// Subscribe delivers the changes published from now on, in ID order, until ctx is done
+	// and then closes the channel
+	Subscribe(ctx context.Context) (<-chan Change, error)
+}
+
+// subscriberBuffer is how many changes a subscriber may fall behind before Publish waits
+const subscriberBuffer = 256
+
+// MemoryChangeBus is a ChangeBus within one process; it is used when there is a single replica
+// and in tests
+type MemoryChangeBus struct {
+	mutex       sync.Mutex
+	lastID      uint64
+	subscribers map[chan Change]context.Context
+}
+
+func NewMemoryChangeBus() *MemoryChangeBus {
+	return &MemoryChangeBus{subscribers: make(map[chan Change]context.Context)}
+}
+
+func (b *MemoryChangeBus) Publish(ctx context.Context, events ...*TaskEvent) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, event := range events {
+		b.lastID++
+		change := Change{ID: b.lastID, Event: event}
+		for subscriber, subscriberCtx := range b.subscribers {
+			select {
+			case subscriber <- change:
+			case <-subscriberCtx.Done():
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
+
+func (b *MemoryChangeBus) Subscribe(ctx context.Context) (<-chan Change, error) {
+	subscriber := make(chan Change, subscriberBuffer)
+	b.mutex.Lock()
+	b.subscribers[subscriber] = ctx
+	b.mutex.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mutex.Lock()
+		delete(b.subscribers, subscriber)
+		b.mutex.Unlock()
+		close(subscriber)
+	}()
+	return subscriber, nil
+}
+
+func (b *MemoryChangeBus) Close() error {
+	return nil
+}
@@ -0,0 +1,63 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type kafkaReader struct {
+	reader *kafka.Reader
+}
+
+// NewKafkaReader creates a MessageReader that consumes topic as member groupID of a consumer
+// group. A group that has not committed anything yet starts at the oldest message.
+func NewKafkaReader(options KafkaOptions, groupID, topic string) (MessageReader, error) {
+	mechanism, err := options.SASLMechanism()
+	if err != nil {
+		return nil, err
+	}
+
+	return &kafkaReader{
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:     options.Brokers,
+			GroupID:     groupID,
+			Topic:       topic,
+			StartOffset: kafka.FirstOffset,
+			Dialer: &kafka.Dialer{
+				Timeout:       10 * time.Second,
+				DualStack:     true,
+				SASLMechanism: mechanism,
+				TLS:           options.TLSConfig(),
+			},
+		}),
+	}, nil
+}
+
+func (r *kafkaReader) FetchMessage(ctx context.Context) (Message, error) {
+	m, err := r.reader.FetchMessage(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+	headers := make(map[string]string, len(m.Headers))
+	for _, h := range m.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	return Message{
+		Topic:     m.Topic,
+		Partition: m.Partition,
+		Offset:    m.Offset,
+		Key:       m.Key,
+		Value:     m.Value,
+		Headers:   headers,
+	}, nil
+}
+
+func (r *kafkaReader) CommitMessage(ctx context.Context, message Message) error {
+	return r.reader.CommitMessages(ctx, kafka.Message{Topic: message.Topic, Partition: message.Partition, Offset: message.Offset})
+}
+
+func (r *kafkaReader) Close() error {
+	return r.reader.Close()
+}
@@ -0,0 +1,122 @@
+package events
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// KafkaOptions configures the connection to the Kafka cluster
+type KafkaOptions struct {
+	Brokers    []string
+	Username   string
+	Password   string
+	AuthAlgo   string
+	TLSEnabled bool
+}
+
+// Enabled reports whether any broker is configured
+func (o KafkaOptions) Enabled() bool {
+	for _, broker := range o.Brokers {
+		if strings.TrimSpace(broker) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// SASLMechanism returns the SASL mechanism for AuthAlgo (PLAIN, SCRAM-SHA-256 or
+// SCRAM-SHA-512), or nil when no username is configured
+func (o KafkaOptions) SASLMechanism() (sasl.Mechanism, error) {
+	if o.Username == "" {
+		return nil, nil
+	}
+	switch strings.ToUpper(o.AuthAlgo) {
+	case "", "PLAIN":
+		return plain.Mechanism{Username: o.Username, Password: o.Password}, nil
+	case "SCRAM-SHA-256":
+		return scram.Mechanism(scram.SHA256, o.Username, o.Password)
+	case "SCRAM-SHA-512":
+		return scram.Mechanism(scram.SHA512, o.Username, o.Password)
+	default:
+		return nil, fmt.Errorf("unsupported kafka auth algorithm %q", o.AuthAlgo)
+	}
+}
+
+// TLSConfig returns the TLS settings or nil when TLS is disabled
+func (o KafkaOptions) TLSConfig() *tls.Config {
+	if !o.TLSEnabled {
+		return nil
+	}
+	return &tls.Config{MinVersion: tls.VersionTLS12}
+}
+
+// Ping connects to the first broker that accepts a connection, authenticating as the
+// writers and readers do
+func (o KafkaOptions) Ping(ctx context.Context) error {
+	mechanism, err := o.SASLMechanism()
+	if err != nil {
+		return err
+	}
+	dialer := &kafka.Dialer{SASLMechanism: mechanism, TLS: o.TLSConfig()}
+
+	for _, broker := range o.Brokers {
+		if strings.TrimSpace(broker) == "" {
+			continue
+		}
+		var conn *kafka.Conn
+		if conn, err = dialer.DialContext(ctx, "tcp", strings.TrimSpace(broker)); err == nil {
+			return conn.Close()
+		}
+	}
+	return fmt.Errorf("no kafka broker reachable: %w", err)
+}
+
+type kafkaWriter struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaWriter creates a MessageWriter that produces to the configured brokers. Messages
+// are partitioned by key hash and acknowledged by all in-sync replicas.
+func NewKafkaWriter(options KafkaOptions) (MessageWriter, error) {
+	mechanism, err := options.SASLMechanism()
+	if err != nil {
+		return nil, err
+	}
+
+	return &kafkaWriter{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(options.Brokers...),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			BatchTimeout: 10 * time.Millisecond,
+			Transport: &kafka.Transport{
+				SASL: mechanism,
+				TLS:  options.TLSConfig(),
+			},
+		},
+	}, nil
+}
+
+func (w *kafkaWriter) WriteMessages(ctx context.Context, messages ...Message) error {
+	kafkaMessages := make([]kafka.Message, len(messages))
+	for i, m := range messages {
+		headers := make([]kafka.Header, 0, len(m.Headers))
+		for k, v := range m.Headers {
+			headers = append(headers, kafka.Header{Key: k, Value: []byte(v)})
+		}
+		kafkaMessages[i] = kafka.Message{Topic: m.Topic, Key: m.Key, Value: m.Value, Headers: headers}
+	}
+	return w.writer.WriteMessages(ctx, kafkaMessages...)
+}
+
+func (w *kafkaWriter) Close() error {
+	return w.writer.Close()
+}
@@ -0,0 +1,110 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryBroker is an in-memory stand-in for Kafka. Messages are appended to per-topic logs
+// in write order and can be inspected with Messages or consumed with Reader.
+type MemoryBroker struct {
+	mutex     sync.Mutex
+	topics    map[string][]Message
+	committed map[string]int64
+	written   chan struct{}
+	err       error
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		topics:    make(map[string][]Message),
+		committed: make(map[string]int64),
+		written:   make(chan struct{}),
+	}
+}
+
+func (b *MemoryBroker) WriteMessages(ctx context.Context, messages ...Message) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.err != nil {
+		return b.err
+	}
+	for _, m := range messages {
+		m.Offset = int64(len(b.topics[m.Topic]))
+		b.topics[m.Topic] = append(b.topics[m.Topic], m)
+	}
+	// Wake up readers waiting for new messages
+	close(b.written)
+	b.written = make(chan struct{})
+	return nil
+}
+
+func (b *MemoryBroker) Close() error {
+	return nil
+}
+
+// Messages returns a copy of everything written to topic
+func (b *MemoryBroker) Messages(topic string) []Message {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return append([]Message(nil), b.topics[topic]...)
+}
+
+// SetError makes every write fail with err; pass nil to recover
+func (b *MemoryBroker) SetError(err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.err = err
+}
+
+// Reader consumes topic from its first message. Topics have a single partition.
+func (b *MemoryBroker) Reader(topic string) MessageReader {
+	return &memoryReader{broker: b, topic: topic}
+}
+
+// Committed returns the offset after the last committed message of topic
+func (b *MemoryBroker) Committed(topic string) int64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.committed[topic]
+}
+
+type memoryReader struct {
+	broker *MemoryBroker
+	topic  string
+	next   int64
+}
+
+func (r *memoryReader) FetchMessage(ctx context.Context) (Message, error) {
+	for {
+		r.broker.mutex.Lock()
+		if log := r.broker.topics[r.topic]; r.next < int64(len(log)) {
+			message := log[r.next]
+			r.next++
+			r.broker.mutex.Unlock()
+			return message, nil
+		}
+		written := r.broker.written
+		r.broker.mutex.Unlock()
+
+		select {
+		case <-written:
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		}
+	}
+}
+
+func (r *memoryReader) CommitMessage(ctx context.Context, message Message) error {
+	r.broker.mutex.Lock()
+	defer r.broker.mutex.Unlock()
+	if message.Offset+1 > r.broker.committed[message.Topic] {
+		r.broker.committed[message.Topic] = message.Offset + 1
+	}
+	return nil
+}
+
+func (r *memoryReader) Close() error {
+	return nil
+}
@@ -0,0 +1,134 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+// Message is a keyed record written to a topic. Partition and Offset are set on messages
+// read from a topic and identify them when they are committed.
+type Message struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+}
+
+// MessageWriter delivers messages to a broker. It is implemented by the Kafka writer and by
+// MemoryBroker.
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, messages ...Message) error
+	Close() error
+}
+
+// MessageReader consumes a topic as part of a consumer group. It is implemented by the Kafka
+// reader and by MemoryBroker.
+type MessageReader interface {
+	// FetchMessage blocks until the next message is available or ctx is done
+	FetchMessage(ctx context.Context) (Message, error)
+	// CommitMessage marks message and everything before it in its partition as consumed
+	CommitMessage(ctx context.Context, message Message) error
+	Close() error
+}
+
+// Publisher publishes task events
+type Publisher interface {
+	Publish(ctx context.Context, events ...*TaskEvent) error
+	Close() error
+}
+
+// Headers set on every published event
+const (
+	HeaderEventType     = "event_type"
+	HeaderSchemaVersion = "schema_version"
+)
+
+type publisher struct {
+	writer MessageWriter
+	topic  string
+}
+
+// NewPublisher publishes events as JSON to topic, keyed by task UUID so that all events of
+// a task land on the same partition and keep their order
+func NewPublisher(writer MessageWriter, topic string) Publisher {
+	return &publisher{writer: writer, topic: topic}
+}
+
+func (p *publisher) Publish(ctx context.Context, events ...*TaskEvent) error {
+	messages := make([]Message, 0, len(events))
+	for _, event := range events {
+		message, err := EncodeMessage(p.topic, event)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, message)
+	}
+	return p.writer.WriteMessages(ctx, messages...)
+}
+
+func (p *publisher) Close() error {
+	return p.writer.Close()
+}
+
+// EncodeMessage serialises event into a message for topic
+func EncodeMessage(topic string, event *TaskEvent) (Message, error) {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Topic: topic,
+		Key:   []byte(event.TaskUUID),
+		Value: value,
+		Headers: map[string]string{
+			HeaderEventType:     event.EventType,
+			HeaderSchemaVersion: strconv.Itoa(event.SchemaVersion),
+		},
+	}, nil
+}
+
+// NoopPublisher drops all events; it is used when no broker is configured
+type NoopPublisher struct{}
+
+func (NoopPublisher) Publish(ctx context.Context, events ...*TaskEvent) error {
+	return nil
+}
+
+func (NoopPublisher) Close() error {
+	return nil
+}
+
+type fanOutPublisher struct {
+	publishers []Publisher
+}
+
+// NewFanOutPublisher publishes events to every one of publishers, even after one fails, and
+// returns their joined errors. The outbox relay then publishes them again to all of them, so
+// every publisher must tolerate events it has already seen.
+func NewFanOutPublisher(publishers ...Publisher) Publisher {
+	return &fanOutPublisher{publishers: publishers}
+}
+
+func (p *fanOutPublisher) Publish(ctx context.Context, events ...*TaskEvent) error {
+	var errs []error
+	for _, publisher := range p.publishers {
+		if err := publisher.Publish(ctx, events...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *fanOutPublisher) Close() error {
+	var closeErr error
+	for _, publisher := range p.publishers {
+		if err := publisher.Close(); err != nil && closeErr == nil {
+			closeErr = err
+		}
+	}
+	return closeErr
+}
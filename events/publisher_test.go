@@ -0,0 +1,71 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"task-manager-app/models"
+	"testing"
+	"time"
+)
+
+func TestPublisherWritesKeyedEventsInOrder(t *testing.T) {
+	broker := NewMemoryBroker()
+	publisher := NewPublisher(broker, "task-events")
+
+	before := &models.Task{UUID: "t1", Title: "Write docs", Status: "Pending", Priority: "Medium"}
+	after := *before
+	after.Status = "Completed"
+	published := append([]*TaskEvent{NewTaskCreated(before, time.Now())}, NewTaskUpdated(before, &after, time.Now())...)
+	if err := publisher.Publish(context.Background(), published...); err != nil {
+		t.Fatalf("Publish returned error %v", err)
+	}
+
+	messages := broker.Messages("task-events")
+	wantTypes := []string{TaskCreated, TaskUpdated, TaskStatusChanged}
+	if len(messages) != len(wantTypes) {
+		t.Fatalf("got %d messages, want %d", len(messages), len(wantTypes))
+	}
+	for i, message := range messages {
+		if string(message.Key) != "t1" {
+			t.Errorf("message %d key = %q, want the task UUID", i, message.Key)
+		}
+		if message.Headers[HeaderEventType] != wantTypes[i] || message.Headers[HeaderSchemaVersion] != strconv.Itoa(SchemaVersion) {
+			t.Errorf("message %d headers = %v, want %s version %d", i, message.Headers, wantTypes[i], SchemaVersion)
+		}
+		var event TaskEvent
+		if err := json.Unmarshal(message.Value, &event); err != nil {
+			t.Fatalf("message %d is not a task event: %v", i, err)
+		}
+		if event.EventID != published[i].EventID {
+			t.Errorf("message %d event_id = %s, want %s", i, event.EventID, published[i].EventID)
+		}
+	}
+}
+
+func TestPublisherReturnsBrokerErrors(t *testing.T) {
+	broker := NewMemoryBroker()
+	broker.SetError(errors.New("broker down"))
+
+	err := NewPublisher(broker, "task-events").Publish(context.Background(), NewTaskCreated(&models.Task{UUID: "t1"}, time.Now()))
+	if err == nil {
+		t.Fatal("Publish succeeded, want the broker error")
+	}
+	if messages := broker.Messages("task-events"); len(messages) != 0 {
+		t.Fatalf("got %d messages after a failed write, want none", len(messages))
+	}
+}
+
+func TestFanOutPublisherPublishesPastAFailure(t *testing.T) {
+	first, second, third := NewMemoryBroker(), NewMemoryBroker(), NewMemoryBroker()
+	second.SetError(errors.New("broker down"))
+	publisher := NewFanOutPublisher(NewPublisher(first, "a"), NewPublisher(second, "b"), NewPublisher(third, "c"))
+
+	if err := publisher.Publish(context.Background(), NewTaskCreated(&models.Task{UUID: "t1"}, time.Now())); err == nil {
+		t.Fatal("Publish succeeded, want the error of the second publisher")
+	}
+	if len(first.Messages("a")) != 1 || len(third.Messages("c")) != 1 {
+		t.Errorf("got %d and %d messages, want the event on both healthy brokers", len(first.Messages("a")), len(third.Messages("c")))
+	}
+}
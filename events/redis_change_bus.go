@@ -0,0 +1,97 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// publishChange numbers an event and publishes it as "<id> <event>" in one step, so that
+// subscribers receive the changes in ID order whichever replica published them
+var publishChange = redis.NewScript(`
+local id = redis.call('INCR', KEYS[1])
+redis.call('PUBLISH', KEYS[2], id .. ' ' .. ARGV[1])
+return id`)
+
+type redisChangeBus struct {
+	client  *redis.Client
+	channel string
+	logger  *zap.SugaredLogger
+}
+
+// NewRedisChangeBus broadcasts changes through the Redis pub/sub channel, numbering them with
+// the channel + ":id" counter. Unreadable changes are logged to logger and skipped.
+func NewRedisChangeBus(client *redis.Client, channel string, logger *zap.SugaredLogger) ChangeBus {
+	return &redisChangeBus{client: client, channel: channel, logger: logger}
+}
+
+func (b *redisChangeBus) Publish(ctx context.Context, events ...*TaskEvent) error {
+	for _, event := range events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if err := publishChange.Run(ctx, b.client, []string{b.channel + ":id", b.channel}, value).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *redisChangeBus) Subscribe(ctx context.Context) (<-chan Change, error) {
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	// Wait for the subscription so that no change published after Subscribe returns is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	changes := make(chan Change, subscriberBuffer)
+	go func() {
+		defer close(changes)
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				change, err := decodeChange(message.Payload)
+				if err != nil {
+					b.logger.Errorf("Dropping unreadable task change from %s: %v", b.channel, err)
+					continue
+				}
+				select {
+				case changes <- change:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
+
+func (b *redisChangeBus) Close() error {
+	return nil
+}
+
+func decodeChange(payload string) (Change, error) {
+	idText, value, _ := strings.Cut(payload, " ")
+	id, err := strconv.ParseUint(idText, 10, 64)
+	if err != nil {
+		return Change{}, err
+	}
+	var event TaskEvent
+	if err := json.Unmarshal([]byte(value), &event); err != nil {
+		return Change{}, err
+	}
+	return Change{ID: id, Event: &event}, nil
+}
@@ -0,0 +1,132 @@
+package events
+
+import (
+	"task-manager-app/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SchemaVersion is bumped whenever TaskEvent changes incompatibly. The JSON schema for each
+// version lives in resources/schemas.
+const SchemaVersion = 1
+
+// Task event types
+const (
+	TaskCreated       = "TaskCreated"
+	TaskUpdated       = "TaskUpdated"
+	TaskDeleted       = "TaskDeleted"
+	TaskStatusChanged = "TaskStatusChanged"
+)
+
+// TaskEvent is the envelope published for every task change
+type TaskEvent struct {
+	SchemaVersion int                    `json:"schema_version"`
+	EventID       string                 `json:"event_id"`
+	EventType     string                 `json:"event_type"`
+	OccurredAt    time.Time              `json:"occurred_at"`
+	TaskUUID      string                 `json:"task_uuid"`
+	Task          *TaskSnapshot          `json:"task,omitempty"`
+	Changes       map[string]FieldChange `json:"changes,omitempty"`
+}
+
+// TaskSnapshot is the state of the task after the change
+type TaskSnapshot struct {
+	UUID        string    `json:"uuid"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Status      string    `json:"status"`
+	Priority    string    `json:"priority"`
+	UserID      *string   `json:"user_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// FieldChange holds the previous and new value of a changed field
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+func newTaskEvent(eventType string, task *models.Task, occurredAt time.Time) *TaskEvent {
+	return &TaskEvent{
+		SchemaVersion: SchemaVersion,
+		EventID:       uuid.New().String(),
+		EventType:     eventType,
+		OccurredAt:    occurredAt.UTC(),
+		TaskUUID:      task.UUID,
+		Task:          Snapshot(task),
+	}
+}
+
+// NewTaskCreated builds the event for a task created at occurredAt
+func NewTaskCreated(task *models.Task, occurredAt time.Time) *TaskEvent {
+	return newTaskEvent(TaskCreated, task, occurredAt)
+}
+
+// NewTaskDeleted builds the event for a deleted task, carrying its last known state
+func NewTaskDeleted(task *models.Task, occurredAt time.Time) *TaskEvent {
+	return newTaskEvent(TaskDeleted, task, occurredAt)
+}
+
+// NewTaskUpdated builds the events for an update from before to after: a TaskUpdated
+// with the changed fields and, when the status moved, a TaskStatusChanged
+func NewTaskUpdated(before, after *models.Task, occurredAt time.Time) []*TaskEvent {
+	changes := Diff(before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	updated := newTaskEvent(TaskUpdated, after, occurredAt)
+	updated.Changes = changes
+	result := []*TaskEvent{updated}
+
+	if status, ok := changes["status"]; ok {
+		statusChanged := newTaskEvent(TaskStatusChanged, after, occurredAt)
+		statusChanged.Changes = map[string]FieldChange{"status": status}
+		result = append(result, statusChanged)
+	}
+	return result
+}
+
+// Diff returns the user visible fields that differ between before and after
+func Diff(before, after *models.Task) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+	if before.Title != after.Title {
+		changes["title"] = FieldChange{Old: before.Title, New: after.Title}
+	}
+	if before.Description != after.Description {
+		changes["description"] = FieldChange{Old: before.Description, New: after.Description}
+	}
+	if before.Status != after.Status {
+		changes["status"] = FieldChange{Old: before.Status, New: after.Status}
+	}
+	if before.Priority != after.Priority {
+		changes["priority"] = FieldChange{Old: before.Priority, New: after.Priority}
+	}
+	if !sameUser(before.UserID, after.UserID) {
+		changes["user_id"] = FieldChange{Old: before.UserID, New: after.UserID}
+	}
+	return changes
+}
+
+// Snapshot copies the public fields of task
+func Snapshot(task *models.Task) *TaskSnapshot {
+	return &TaskSnapshot{
+		UUID:        task.UUID,
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      task.Status,
+		Priority:    task.Priority,
+		UserID:      task.UserID,
+		CreatedAt:   task.CreatedAt,
+		UpdatedAt:   task.UpdatedAt,
+	}
+}
+
+func sameUser(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,41 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// maxUserEventIDLength is the size of inbox_events.event_id
+const maxUserEventIDLength = 64
+
+// User event types published by the user manager
+const (
+	UserCreated = "UserCreated"
+	UserUpdated = "UserUpdated"
+	UserDeleted = "UserDeleted"
+)
+
+// UserEvent is the envelope of a user lifecycle event consumed from the user manager. The
+// JSON schema lives in resources/schemas/user_event.v1.json.
+type UserEvent struct {
+	EventID    string    `json:"event_id"`
+	EventType  string    `json:"event_type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	UserID     string    `json:"user_id"`
+}
+
+// DecodeUserEvent parses a user event and checks the fields needed to handle it
+func DecodeUserEvent(value []byte) (*UserEvent, error) {
+	var event UserEvent
+	if err := json.Unmarshal(value, &event); err != nil {
+		return nil, err
+	}
+	if event.EventID == "" || event.EventType == "" || event.UserID == "" {
+		return nil, fmt.Errorf("user event needs event_id, event_type and user_id")
+	}
+	if len(event.EventID) > maxUserEventIDLength {
+		return nil, fmt.Errorf("user event id is longer than %d characters", maxUserEventIDLength)
+	}
+	return &event, nil
+}
@@ -4,4 +4,6 @@ type TaskManagerError struct {
 	ErrorTimestamp int64  `json:"timestamp"`
 	Message        string `json:"message"`
 	ResponseCode   int    `json:"response_code"`
+	// RequestID is the X-Request-ID of the request that failed, set when the error is sent
+	RequestID string `json:"request_id,omitempty"`
 }
@@ -0,0 +1,17 @@
+package exceptions
+
+import (
+	"net/http"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/utils"
+	"time"
+)
+
+func ServiceUnavailableException(message string) *errors.TaskManagerError {
+	utils.Sugar.Error(message)
+	return &errors.TaskManagerError{
+		ErrorTimestamp: time.Now().UnixMilli(),
+		Message:        message,
+		ResponseCode:   http.StatusServiceUnavailable,
+	}
+}
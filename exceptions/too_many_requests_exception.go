@@ -0,0 +1,15 @@
+package exceptions
+
+import (
+	"net/http"
+	"task-manager-app/exceptions/errors"
+	"time"
+)
+
+func TooManyRequestsException(message string) *errors.TaskManagerError {
+	return &errors.TaskManagerError{
+		ErrorTimestamp: time.Now().UnixMilli(),
+		Message:        message,
+		ResponseCode:   http.StatusTooManyRequests,
+	}
+}
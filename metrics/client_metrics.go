@@ -0,0 +1,36 @@
+package metrics
+
+import "task-manager-app/network/resilientClient"
+
+var circuitStates = []resilientClient.State{resilientClient.StateClosed, resilientClient.StateOpen, resilientClient.StateHalfOpen}
+
+// ClientMetrics reports the outcomes, retries and breaker state of resilient clients to Prometheus
+type ClientMetrics struct{}
+
+// NewClientMetrics starts the breaker of client as closed, which is how every client begins
+func NewClientMetrics(client string) ClientMetrics {
+	setCircuitState(client, resilientClient.StateClosed)
+	return ClientMetrics{}
+}
+
+func (m ClientMetrics) StateChanged(client string, from, to resilientClient.State) {
+	setCircuitState(client, to)
+}
+
+func (m ClientMetrics) Retried(client string, attempt int) {
+	upstreamRetries.WithLabelValues(client).Inc()
+}
+
+func (m ClientMetrics) Outcome(client string, outcome string) {
+	upstreamOutcomes.WithLabelValues(client, outcome).Inc()
+}
+
+func setCircuitState(client string, current resilientClient.State) {
+	for _, state := range circuitStates {
+		value := 0.0
+		if state == current {
+			value = 1
+		}
+		circuitState.WithLabelValues(client, string(state)).Set(value)
+	}
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const startedAtKey = "metrics:started_at"
+
+// GormPlugin times every GORM statement by operation: create, query, update, delete, row or raw
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "metrics"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	// The processors are not exported, so each one is registered on its own
+	for _, err := range []error{
+		callbacks.Create().Before("*").Register("metrics:before_create", start),
+		callbacks.Create().After("*").Register("metrics:after_create", observe("create")),
+		callbacks.Query().Before("*").Register("metrics:before_query", start),
+		callbacks.Query().After("*").Register("metrics:after_query", observe("query")),
+		callbacks.Update().Before("*").Register("metrics:before_update", start),
+		callbacks.Update().After("*").Register("metrics:after_update", observe("update")),
+		callbacks.Delete().Before("*").Register("metrics:before_delete", start),
+		callbacks.Delete().After("*").Register("metrics:after_delete", observe("delete")),
+		callbacks.Row().Before("*").Register("metrics:before_row", start),
+		callbacks.Row().After("*").Register("metrics:after_row", observe("row")),
+		callbacks.Raw().Before("*").Register("metrics:before_raw", start),
+		callbacks.Raw().After("*").Register("metrics:after_raw", observe("raw")),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func start(db *gorm.DB) {
+	db.InstanceSet(startedAtKey, time.Now())
+}
+
+func observe(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		startedAt, ok := db.InstanceGet(startedAtKey)
+		if !ok {
+			return
+		}
+		dbQueryDuration.WithLabelValues(operation).Observe(time.Since(startedAt.(time.Time)).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			dbQueryErrors.WithLabelValues(operation).Inc()
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"task-manager-app/exceptions"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/network/resilientClient"
+	"task-manager-app/repo"
+	"task-manager-app/utils"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	utils.Sugar = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
+
+func TestObserveRequestBoundsItsLabels(t *testing.T) {
+	ObserveRequest(http.MethodGet, "/tasks/:uuid", http.StatusOK, time.Millisecond)
+	ObserveRequest("PROPFIND", "", http.StatusNotFound, time.Millisecond)
+
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "/tasks/:uuid", "200")); got != 1 {
+		t.Errorf("requests to the route template = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("OTHER", "unmatched", "404")); got != 1 {
+		t.Errorf("requests with an unknown method and no route = %v, want 1", got)
+	}
+}
+
+func TestClientMetricsTrackTheBreakerState(t *testing.T) {
+	clientMetrics := NewClientMetrics("users")
+	if got := testutil.ToFloat64(circuitState.WithLabelValues("users", "closed")); got != 1 {
+		t.Errorf("closed = %v, want 1 for a new client", got)
+	}
+
+	clientMetrics.StateChanged("users", resilientClient.StateClosed, resilientClient.StateOpen)
+	clientMetrics.Outcome("users", resilientClient.OutcomeCircuitOpen)
+	if closed, open := testutil.ToFloat64(circuitState.WithLabelValues("users", "closed")), testutil.ToFloat64(circuitState.WithLabelValues("users", "open")); closed != 0 || open != 1 {
+		t.Errorf("closed = %v, open = %v, want only open", closed, open)
+	}
+	if got := testutil.ToFloat64(upstreamOutcomes.WithLabelValues("users", resilientClient.OutcomeCircuitOpen)); got != 1 {
+		t.Errorf("circuit_open outcomes = %v, want 1", got)
+	}
+}
+
+type countingTasks struct {
+	repo.TaskRepository
+	counts []repo.TaskCount
+	err    *errors.TaskManagerError
+}
+
+func (c *countingTasks) CountByStatusAndPriority() ([]repo.TaskCount, *errors.TaskManagerError) {
+	return c.counts, c.err
+}
+
+func TestTaskCollectorReportsEveryStatusAndPriority(t *testing.T) {
+	collector := &taskCollector{tasks: &countingTasks{counts: []repo.TaskCount{
+		{Status: "Pending", Priority: "High", Count: 3},
+		{Status: "Lost", Priority: "High", Count: 9},
+	}}}
+
+	want := `
+# HELP task_manager_tasks Tasks by status and priority, counted when scraped.
+# TYPE task_manager_tasks gauge
+`
+	for _, status := range taskStatuses {
+		for _, priority := range taskPriorities {
+			value := "0"
+			if status == "Pending" && priority == "High" {
+				value = "3"
+			}
+			want += `task_manager_tasks{priority="` + string(priority) + `",status="` + string(status) + `"} ` + value + "\n"
+		}
+	}
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTaskCollectorFailsTheScrapeWhenCountingFails(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(&taskCollector{tasks: &countingTasks{err: exceptions.InternalServerException("down")}})
+	if _, err := registry.Gather(); err == nil {
+		t.Error("Gather returned no error")
+	}
+}
+
+func TestWorkerMetricsAreServed(t *testing.T) {
+	CountOutboxEvent(ResultDeadLettered)
+	SetOutboxBacklog(7, 42.5)
+	CountBoardEvent(BoardRateLimited)
+
+	want := `
+# HELP task_manager_outbox_lag_seconds Age of the oldest outbox event waiting to be delivered, as of the last relay poll.
+# TYPE task_manager_outbox_lag_seconds gauge
+task_manager_outbox_lag_seconds 42.5
+# HELP task_manager_outbox_pending_events Outbox events waiting to be delivered, as of the last relay poll.
+# TYPE task_manager_outbox_pending_events gauge
+task_manager_outbox_pending_events 7
+`
+	if err := testutil.GatherAndCompare(Registry, strings.NewReader(want), "task_manager_outbox_lag_seconds", "task_manager_outbox_pending_events"); err != nil {
+		t.Error(err)
+	}
+	if got := testutil.ToFloat64(outboxEvents.WithLabelValues(ResultDeadLettered)); got != 1 {
+		t.Errorf("dead-lettered outbox events = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(boardEvents.WithLabelValues(BoardRateLimited)); got != 1 {
+		t.Errorf("rate limited board messages = %v, want 1", got)
+	}
+}
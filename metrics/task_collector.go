@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	stdErrors "errors"
+	"task-manager-app/constants/enums"
+	"task-manager-app/repo"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	taskStatuses   = []enums.TaskStatus{enums.StatusPending, enums.StatusInProgress, enums.StatusCompleted, enums.StatusArchived}
+	taskPriorities = []enums.TaskPriority{enums.PriorityLow, enums.PriorityMedium, enums.PriorityHigh, enums.PriorityUrgent}
+)
+
+var tasksDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "tasks"),
+	"Tasks by status and priority, counted when scraped.", []string{"status", "priority"}, nil)
+
+// taskCollector counts the tasks of every status and priority on each scrape. Combinations
+// without tasks are reported as 0 and values outside the enums are left out.
+type taskCollector struct {
+	tasks repo.TaskRepository
+}
+
+// RegisterTaskCollector reports the task counts of tasks on Registry. Registry serves the whole
+// process, so when several servers run in one process the first one registered is reported.
+func RegisterTaskCollector(tasks repo.TaskRepository) {
+	var registered prometheus.AlreadyRegisteredError
+	if err := Registry.Register(&taskCollector{tasks: tasks}); err != nil && !stdErrors.As(err, &registered) {
+		panic(err)
+	}
+}
+
+func (c *taskCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- tasksDesc
+}
+
+func (c *taskCollector) Collect(ch chan<- prometheus.Metric) {
+	counts, taskErr := c.tasks.CountByStatusAndPriority()
+	if taskErr != nil {
+		ch <- prometheus.NewInvalidMetric(tasksDesc, stdErrors.New(taskErr.Message))
+		return
+	}
+
+	byLabels := make(map[repo.TaskCount]int64, len(counts))
+	for _, count := range counts {
+		byLabels[repo.TaskCount{Status: count.Status, Priority: count.Priority}] = count.Count
+	}
+	for _, status := range taskStatuses {
+		for _, priority := range taskPriorities {
+			count := byLabels[repo.TaskCount{Status: string(status), Priority: string(priority)}]
+			ch <- prometheus.MustNewConstMetric(tasksDesc, prometheus.GaugeValue, float64(count), string(status), string(priority))
+		}
+	}
+}
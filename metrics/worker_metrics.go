@@ -0,0 +1,160 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Results counted by the background workers
+const (
+	ResultDelivered    = "delivered"
+	ResultFailed       = "failed"
+	ResultDeadLettered = "dead_lettered"
+	ResultProcessed    = "processed"
+	ResultRetried      = "retried"
+	ResultGivenUp      = "given_up"
+)
+
+// Board events that end or refuse work
+const (
+	BoardRejectedConnection = "rejected_connection"
+	BoardSlowClient         = "slow_client"
+	BoardRateLimited        = "rate_limited"
+)
+
+var (
+	outboxEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_events_total",
+		Help:      "Outbox events handled by the relay by result: delivered, failed or dead_lettered.",
+	}, []string{"result"})
+
+	outboxPending = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "outbox_pending_events",
+		Help:      "Outbox events waiting to be delivered, as of the last relay poll.",
+	})
+
+	outboxLag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "outbox_lag_seconds",
+		Help:      "Age of the oldest outbox event waiting to be delivered, as of the last relay poll.",
+	})
+
+	userEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "user_events_total",
+		Help:      "Consumed user events by result: processed, failed, retried or dead_lettered.",
+	}, []string{"result"})
+
+	webhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts by result: delivered, failed or given_up.",
+	}, []string{"result"})
+
+	webhooksDisabled = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_subscriptions_disabled_total",
+		Help:      "Webhook subscriptions disabled after consecutive failures.",
+	})
+
+	streamClients = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "stream_clients",
+		Help:      "Clients subscribed to the task change stream.",
+	})
+
+	streamChanges = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stream_changes_total",
+		Help:      "Task changes broadcast to stream clients.",
+	})
+
+	streamDroppedClients = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stream_dropped_clients_total",
+		Help:      "Stream clients dropped for not keeping up.",
+	})
+
+	streamPublishFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stream_publish_failures_total",
+		Help:      "Batches of task events that could not be published to the stream.",
+	})
+
+	boardConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "board_connections",
+		Help:      "Open board WebSocket connections.",
+	})
+
+	boardEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "board_events_total",
+		Help:      "Board connections refused or limited, by event: rejected_connection, slow_client or rate_limited.",
+	}, []string{"event"})
+)
+
+func init() {
+	Registry.MustRegister(
+		outboxEvents, outboxPending, outboxLag,
+		userEvents,
+		webhookDeliveries, webhooksDisabled,
+		streamClients, streamChanges, streamDroppedClients, streamPublishFailures,
+		boardConnections, boardEvents,
+	)
+}
+
+// CountOutboxEvent counts an outbox event delivered, failed or dead-lettered by the relay
+func CountOutboxEvent(result string) {
+	outboxEvents.WithLabelValues(result).Inc()
+}
+
+// SetOutboxBacklog records the events waiting to be delivered and the age of the oldest one
+func SetOutboxBacklog(pending int64, lagSeconds float64) {
+	outboxPending.Set(float64(pending))
+	outboxLag.Set(lagSeconds)
+}
+
+// CountUserEvent counts a user event processed, failed, retried or dead-lettered
+func CountUserEvent(result string) {
+	userEvents.WithLabelValues(result).Inc()
+}
+
+// CountWebhookDelivery counts a webhook delivery attempt delivered, failed or given up
+func CountWebhookDelivery(result string) {
+	webhookDeliveries.WithLabelValues(result).Inc()
+}
+
+// CountWebhookDisabled counts a subscription disabled after consecutive failures
+func CountWebhookDisabled() {
+	webhooksDisabled.Inc()
+}
+
+// SetStreamClients records the clients subscribed to the task change stream
+func SetStreamClients(clients int) {
+	streamClients.Set(float64(clients))
+}
+
+// CountStreamChange counts a change broadcast to the stream clients
+func CountStreamChange() {
+	streamChanges.Inc()
+}
+
+// CountStreamDroppedClient counts a stream client dropped for not keeping up
+func CountStreamDroppedClient() {
+	streamDroppedClients.Inc()
+}
+
+// CountStreamPublishFailure counts events that could not be published to the stream
+func CountStreamPublishFailure() {
+	streamPublishFailures.Inc()
+}
+
+// SetBoardConnections records the open board connections
+func SetBoardConnections(connections int) {
+	boardConnections.Set(float64(connections))
+}
+
+// CountBoardEvent counts a board connection rejected, dropped as too slow or rate limited
+func CountBoardEvent(event string) {
+	boardEvents.WithLabelValues(event).Inc()
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"task-manager-app/constants"
+	"task-manager-app/exceptions"
+	"task-manager-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth guards admin routes with a shared token sent in the X-Admin-Token header.
+// An empty token rejects every request rather than leaving the routes open.
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		given := ctx.GetHeader(constants.HeaderAdminToken)
+		if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			taskErr := exceptions.UnauthorizedException(constants.ErrInvalidAdminToken)
+			taskErr.RequestID = utils.RequestID(ctx.Request.Context())
+			ctx.AbortWithStatusJSON(taskErr.ResponseCode, taskErr)
+			return
+		}
+		SetPrincipal(ctx, "admin")
+		ctx.Next()
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"task-manager-app/constants"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	corsAllowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
+	corsAllowedHeaders = []string{"Authorization", "Content-Type", constants.HeaderIdempotencyKey, constants.HeaderLastEventID, constants.HeaderRequestID}
+	corsExposedHeaders = []string{constants.HeaderRequestID, constants.HeaderIdempotentReplayed, constants.HeaderRetryAfter}
+)
+
+// CORS lets pages served by the allowed origins call the API from browsers, where "*" allows
+// any origin. With no allowed origins it sends no CORS headers, so browsers only let pages of
+// the API's own origin read its responses. The origins can change while it runs.
+type CORS struct {
+	allowedOrigins atomic.Pointer[[]string]
+}
+
+func NewCORS(allowedOrigins []string) *CORS {
+	c := &CORS{}
+	c.SetAllowedOrigins(allowedOrigins)
+	return c
+}
+
+// SetAllowedOrigins changes the origins allowed from the next request on
+func (c *CORS) SetAllowedOrigins(allowedOrigins []string) {
+	c.allowedOrigins.Store(&allowedOrigins)
+}
+
+// Handler adds the CORS headers to requests from allowed origins and answers their preflight
+// requests itself. Register it before the routes, so that preflight requests for routes that
+// only exist for other methods are answered too.
+func (c *CORS) Handler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		origin := ctx.GetHeader(constants.HeaderOrigin)
+		allowedOrigins := *c.allowedOrigins.Load()
+		if origin == "" || len(allowedOrigins) == 0 {
+			ctx.Next()
+			return
+		}
+		header := ctx.Writer.Header()
+		header.Add(constants.HeaderVary, constants.HeaderOrigin)
+		if !allowed(allowedOrigins, origin) {
+			ctx.Next()
+			return
+		}
+		header.Set(constants.HeaderCORSAllowOrigin, origin)
+		header.Set(constants.HeaderCORSExposeHeaders, strings.Join(corsExposedHeaders, ", "))
+		if ctx.Request.Method == http.MethodOptions && ctx.GetHeader(constants.HeaderCORSRequestMethod) != "" {
+			header.Set(constants.HeaderCORSAllowMethods, strings.Join(corsAllowedMethods, ", "))
+			header.Set(constants.HeaderCORSAllowHeaders, strings.Join(corsAllowedHeaders, ", "))
+			header.Set(constants.HeaderCORSMaxAge, strconv.Itoa(constants.CORSMaxAgeSeconds))
+			ctx.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func allowed(allowedOrigins []string, origin string) bool {
+	for _, allowed := range allowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
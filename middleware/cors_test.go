@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"task-manager-app/constants"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORSAnswersAllowedOriginsOnly(t *testing.T) {
+	cors := NewCORS([]string{"https://app.example.com"})
+	router := gin.New()
+	router.Use(cors.Handler())
+	router.GET("/tasks", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	send := func(method, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/tasks", nil)
+		req.Header.Set(constants.HeaderOrigin, origin)
+		if method == http.MethodOptions {
+			req.Header.Set(constants.HeaderCORSRequestMethod, http.MethodPost)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send(http.MethodOptions, "https://app.example.com")
+	if rec.Code != http.StatusNoContent || rec.Header().Get(constants.HeaderCORSAllowOrigin) != "https://app.example.com" || rec.Header().Get(constants.HeaderCORSAllowMethods) == "" {
+		t.Fatalf("preflight answered %d with headers %v, want 204 allowing the origin", rec.Code, rec.Header())
+	}
+	if rec := send(http.MethodGet, "https://app.example.com"); rec.Header().Get(constants.HeaderCORSAllowOrigin) != "https://app.example.com" {
+		t.Fatalf("request of an allowed origin got headers %v, want it allowed", rec.Header())
+	}
+	if rec := send(http.MethodGet, "https://evil.example.com"); rec.Header().Get(constants.HeaderCORSAllowOrigin) != "" {
+		t.Fatalf("request of another origin got headers %v, want it not allowed", rec.Header())
+	}
+
+	cors.SetAllowedOrigins(nil)
+	if rec := send(http.MethodGet, "https://app.example.com"); rec.Header().Get(constants.HeaderCORSAllowOrigin) != "" {
+		t.Fatalf("request after the origins were cleared got headers %v, want no CORS headers", rec.Header())
+	}
+}
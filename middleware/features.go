@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"sync/atomic"
+	"task-manager-app/constants"
+	"task-manager-app/exceptions"
+	"task-manager-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Features knows which features are turned off. They can be turned on and off while the
+// service runs; requests already being served are not affected.
+type Features struct {
+	disabled atomic.Pointer[map[string]bool]
+}
+
+func NewFeatures(disabled []string) *Features {
+	f := &Features{}
+	f.SetDisabled(disabled)
+	return f
+}
+
+// SetDisabled turns off the given features and turns on all others
+func (f *Features) SetDisabled(disabled []string) {
+	set := make(map[string]bool, len(disabled))
+	for _, feature := range disabled {
+		set[feature] = true
+	}
+	f.disabled.Store(&set)
+}
+
+func (f *Features) Enabled(feature string) bool {
+	return !(*f.disabled.Load())[feature]
+}
+
+// Require answers the routes of feature with 404 while it is turned off
+func (f *Features) Require(feature string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !f.Enabled(feature) {
+			taskErr := exceptions.NotFoundException(constants.ErrFeatureDisabled)
+			taskErr.RequestID = utils.RequestID(ctx.Request.Context())
+			ctx.AbortWithStatusJSON(taskErr.ResponseCode, taskErr)
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"task-manager-app/metrics"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics counts and times every request by method, route template and status
+func Metrics() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+		metrics.ObserveRequest(ctx.Request.Method, ctx.FullPath(), ctx.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"task-manager-app/constants"
+	"task-manager-app/exceptions"
+	"task-manager-app/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimiter limits the requests of each client, told apart by IP address, with a token
+// bucket that refills at a rate per second up to a burst. Its limits can change while it runs.
+type RateLimiter struct {
+	mutex     sync.Mutex
+	perSecond float64
+	burst     float64
+	buckets   map[string]*bucket
+	swept     time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens   float64
+	refilled time.Time
+}
+
+// NewRateLimiter allows each client perSecond requests with bursts of up to burst; a perSecond
+// of 0 turns the limit off. now refills the buckets, time.Now when nil.
+func NewRateLimiter(perSecond, burst int, now func() time.Time) *RateLimiter {
+	if now == nil {
+		now = time.Now
+	}
+	l := &RateLimiter{buckets: make(map[string]*bucket), now: now}
+	l.SetLimits(perSecond, burst)
+	return l
+}
+
+// SetLimits changes the limits; clients keep the tokens they have, up to the new burst
+func (l *RateLimiter) SetLimits(perSecond, burst int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.perSecond, l.burst = float64(perSecond), float64(burst)
+}
+
+// Handler rejects a request over the limit with 429 and a Retry-After of the seconds until
+// the client has a token again
+func (l *RateLimiter) Handler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if wait, ok := l.take(ctx.ClientIP()); !ok {
+			ctx.Header(constants.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			taskErr := exceptions.TooManyRequestsException(constants.ErrRateLimited)
+			taskErr.RequestID = utils.RequestID(ctx.Request.Context())
+			ctx.AbortWithStatusJSON(taskErr.ResponseCode, taskErr)
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// take takes a token of client, or returns how long it has to wait for one
+func (l *RateLimiter) take(client string) (time.Duration, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.perSecond <= 0 {
+		return 0, true
+	}
+
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, refilled: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.refilled).Seconds()*l.perSecond)
+	b.refilled = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.perSecond * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// sweep drops, at most once a minute, the buckets that have refilled completely since they
+// were last used; they would behave like new ones
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+	l.swept = now
+	full := time.Duration(l.burst / l.perSecond * float64(time.Second))
+	for client, b := range l.buckets {
+		if now.Sub(b.refilled) >= full {
+			delete(l.buckets, client)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"task-manager-app/constants"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newLimitedRouter(limiter *RateLimiter) *gin.Engine {
+	router := gin.New()
+	router.GET("/", limiter.Handler(), func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	return router
+}
+
+func requestFrom(router *gin.Engine, addr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = addr
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimiterAllowsBurstThenRefills(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	limiter := NewRateLimiter(2, 3, func() time.Time { return now })
+	router := newLimitedRouter(limiter)
+
+	for i := 0; i < 3; i++ {
+		if rec := requestFrom(router, "10.0.0.1:1234"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d answered %d, want the burst served", i+1, rec.Code)
+		}
+	}
+	rec := requestFrom(router, "10.0.0.1:1234")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get(constants.HeaderRetryAfter) != "1" {
+		t.Fatalf("request over the burst answered %d with Retry-After %q, want 429 and 1", rec.Code, rec.Header().Get(constants.HeaderRetryAfter))
+	}
+	if rec := requestFrom(router, "10.0.0.2:1234"); rec.Code != http.StatusOK {
+		t.Fatalf("another client answered %d, want its own bucket", rec.Code)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if rec := requestFrom(router, "10.0.0.1:1234"); rec.Code != http.StatusOK {
+		t.Fatalf("request after a refill answered %d, want it served", rec.Code)
+	}
+}
+
+func TestRateLimiterLimitsChangeWhileRunning(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	limiter := NewRateLimiter(0, 1, func() time.Time { return now })
+	router := newLimitedRouter(limiter)
+	for i := 0; i < 5; i++ {
+		if rec := requestFrom(router, "10.0.0.1:1234"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d answered %d, want no limit", i+1, rec.Code)
+		}
+	}
+
+	limiter.SetLimits(1, 1)
+	requestFrom(router, "10.0.0.1:1234")
+	if rec := requestFrom(router, "10.0.0.1:1234"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("request answered %d, want 429 under the new limit", rec.Code)
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"task-manager-app/constants"
+	"task-manager-app/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// RequestID gives every request an ID, taken from its X-Request-ID header when that is a
+// reasonable one and generated otherwise, and echoes it in the response. The request context
+// carries the ID and a logger with the request ID, route, task UUID and trace ID, so that
+// utils.Logger(ctx) lines can be correlated with the request; that logger is derived from
+// logger. Register it after the tracing middleware so the trace ID is known.
+func RequestID(logger *zap.SugaredLogger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(constants.HeaderRequestID)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		ctx.Header(constants.HeaderRequestID, requestID)
+
+		fields := []interface{}{"request_id", requestID, "method", ctx.Request.Method, "route", ctx.FullPath()}
+		if taskUUID := ctx.Param(constants.URLParamUUID); taskUUID != "" {
+			fields = append(fields, "task_uuid", taskUUID)
+		}
+		if span := trace.SpanContextFromContext(ctx.Request.Context()); span.IsValid() {
+			fields = append(fields, "trace_id", span.TraceID().String())
+		}
+		requestCtx := utils.WithRequestID(ctx.Request.Context(), requestID)
+		ctx.Request = ctx.Request.WithContext(utils.WithLogger(requestCtx, logger.With(fields...)))
+
+		start := time.Now()
+		ctx.Next()
+		utils.Logger(ctx.Request.Context()).Infow("Request completed",
+			"status", ctx.Writer.Status(), "duration_ms", time.Since(start).Milliseconds())
+	}
+}
+
+// SetPrincipal records the authenticated caller on the request and adds it to the request
+// logger, for handlers and middleware that authenticate the request
+func SetPrincipal(ctx *gin.Context, principal string) {
+	ctx.Set(constants.ContextPrincipal, principal)
+	logger := utils.Logger(ctx.Request.Context()).With("principal", principal)
+	ctx.Request = ctx.Request.WithContext(utils.WithLogger(ctx.Request.Context(), logger))
+}
+
+// Principal returns the caller recorded by SetPrincipal, or "" for requests that were not
+// authenticated
+func Principal(ctx *gin.Context) string {
+	return ctx.GetString(constants.ContextPrincipal)
+}
+
+// validRequestID accepts up to MaxRequestIDLength printable ASCII characters, so a caller
+// cannot break log lines or response headers with the ID it sends
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > constants.MaxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < '!' || requestID[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"task-manager-app/constants"
+	"task-manager-app/utils"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestMain(m *testing.M) {
+	utils.Sugar = zap.NewNop().Sugar()
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+func TestRequestIDIsAcceptedOrGenerated(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		wantKept  bool
+		wantAnyID bool
+	}{
+		{name: "missing", header: "", wantAnyID: true},
+		{name: "accepted", header: "req-42", wantKept: true},
+		{name: "too long", header: strings.Repeat("a", constants.MaxRequestIDLength+1), wantAnyID: true},
+		{name: "not printable", header: "req 42", wantAnyID: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var seen string
+			router := gin.New()
+			router.Use(RequestID(utils.Sugar))
+			router.GET("/tasks", func(ctx *gin.Context) {
+				seen = utils.RequestID(ctx.Request.Context())
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+			if test.header != "" {
+				request.Header.Set(constants.HeaderRequestID, test.header)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			echoed := recorder.Header().Get(constants.HeaderRequestID)
+			if echoed == "" || echoed != seen {
+				t.Fatalf("handler saw request ID %q, response has %q", seen, echoed)
+			}
+			if test.wantKept && echoed != test.header {
+				t.Fatalf("request ID = %q, want %q", echoed, test.header)
+			}
+			if test.wantAnyID && echoed == test.header {
+				t.Fatalf("request ID %q was accepted, want a generated one", echoed)
+			}
+		})
+	}
+}
+
+func TestRequestLoggerCarriesRequestFields(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	previous := utils.Sugar
+	utils.Sugar = zap.New(core).Sugar()
+	defer func() { utils.Sugar = previous }()
+
+	router := gin.New()
+	router.Use(RequestID(utils.Sugar))
+	router.GET("/tasks/:uuid", AdminAuth("secret"), func(ctx *gin.Context) {
+		utils.Logger(ctx.Request.Context()).Info("Getting task")
+	})
+	request := httptest.NewRequest(http.MethodGet, "/tasks/t1", nil)
+	request.Header.Set(constants.HeaderRequestID, "req-42")
+	request.Header.Set(constants.HeaderAdminToken, "secret")
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	want := map[string]string{"request_id": "req-42", "route": "/tasks/:uuid", "task_uuid": "t1", "principal": "admin"}
+	for _, message := range []string{"Getting task", "Request completed"} {
+		entries := logs.FilterMessage(message).All()
+		if len(entries) != 1 {
+			t.Fatalf("logged %q %d times, want once", message, len(entries))
+		}
+		fields := entries[0].ContextMap()
+		for key, value := range want {
+			if fields[key] != value {
+				t.Errorf("%q has %s = %v, want %s", message, key, fields[key], value)
+			}
+		}
+	}
+}
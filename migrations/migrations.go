@@ -0,0 +1,79 @@
+// Package migrations holds the versioned schema of the database and applies it. Each change
+// is a pair of SQL files in sql/, VERSION_name.up.sql and VERSION_name.down.sql, embedded in
+// the binary and applied in version order.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"task-manager-app/constants"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// Migration is one versioned change of the schema
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// All returns the migrations embedded in the binary, in version order
+func All() ([]Migration, error) {
+	return Load(files, "sql")
+}
+
+// Load reads the migrations in dir of fsys. Every version needs both an up and a down file,
+// and no two migrations may share a version.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%s: %s", entry.Name(), constants.ErrInvalidMigrationName)
+		}
+		version, _ := strconv.Atoi(match[1])
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("%s %d: %s and %s", constants.ErrDuplicateMigration, version, migration.Name, match[2])
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("%s %d_%s", constants.ErrMigrationMissingUp, migration.Version, migration.Name)
+		}
+		if migration.Down == "" {
+			return nil, fmt.Errorf("%s %d_%s", constants.ErrMigrationMissingDown, migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
@@ -0,0 +1,259 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"task-manager-app/constants"
+	"task-manager-app/models"
+	"task-manager-app/utils"
+	"testing"
+	"testing/fstest"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm/schema"
+)
+
+func TestMain(m *testing.M) {
+	utils.Sugar = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
+
+func TestAllLoadsEmbeddedMigrationsInOrder(t *testing.T) {
+	all, err := All()
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	if len(all) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, migration := range all {
+		if migration.Version != i+1 {
+			t.Errorf("migration %d has version %d, versions must follow each other", i, migration.Version)
+		}
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			t.Errorf("%s has an empty up or down file", migration)
+		}
+	}
+}
+
+// Every table and column of the models must be created by a migration, or queries fail on
+// a migrated database
+func TestMigrationsCoverModels(t *testing.T) {
+	all, err := All()
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	var up strings.Builder
+	for _, migration := range all {
+		up.WriteString(strings.ToLower(migration.Up))
+	}
+	sql := up.String()
+
+	// schema_migrations is created by the store
+	entities := []interface{}{
+		&models.Task{}, &models.TitleKeyRules{}, &models.IdempotencyKey{}, &models.OutboxEvent{},
+		&models.TaskChange{}, &models.InboxEvent{}, &models.WebhookSubscription{}, &models.WebhookDelivery{},
+	}
+	for _, entity := range entities {
+		parsed, err := schema.Parse(entity, &sync.Map{}, schema.NamingStrategy{})
+		if err != nil {
+			t.Fatalf("failed to parse %T: %v", entity, err)
+		}
+		if !regexp.MustCompile(`create table if not exists ` + parsed.Table + `\b`).MatchString(sql) {
+			t.Errorf("no migration creates table %s of %T", parsed.Table, entity)
+		}
+		for _, column := range parsed.DBNames {
+			if !regexp.MustCompile(`\b` + column + `\b`).MatchString(sql) {
+				t.Errorf("no migration creates column %s.%s of %T", parsed.Table, column, entity)
+			}
+		}
+	}
+}
+
+func TestLoadRejectsInvalidMigrations(t *testing.T) {
+	file := func(content string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(content)} }
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{"bad name", fstest.MapFS{"sql/create.sql": file("SELECT 1")}, constants.ErrInvalidMigrationName},
+		{"duplicate version", fstest.MapFS{
+			"sql/0001_a.up.sql": file("SELECT 1"), "sql/0001_a.down.sql": file("SELECT 1"),
+			"sql/0001_b.up.sql": file("SELECT 1"), "sql/0001_b.down.sql": file("SELECT 1"),
+		}, constants.ErrDuplicateMigration},
+		{"missing up", fstest.MapFS{"sql/0001_a.down.sql": file("SELECT 1")}, constants.ErrMigrationMissingUp},
+		{"missing down", fstest.MapFS{"sql/0001_a.up.sql": file("SELECT 1")}, constants.ErrMigrationMissingDown},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Load(test.files, "sql")
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Load() error = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+// fakeStore keeps the applied versions in memory
+type fakeStore struct {
+	applied []int
+	ran     []string
+	locks   int
+	fail    int
+}
+
+func (s *fakeStore) WithLock(ctx context.Context, fn func(locked Store) error) error {
+	s.locks++
+	return fn(lockedFakeStore{s})
+}
+
+func (s *fakeStore) Applied(ctx context.Context) ([]int, error) {
+	return append([]int(nil), s.applied...), nil
+}
+
+// Apply only works through the store handed out by WithLock, as the lock of the Postgres
+// store is held by one connection
+func (s *fakeStore) Apply(ctx context.Context, migration Migration, down bool) error {
+	return errors.New("applied outside the lock")
+}
+
+// lockedFakeStore is the fakeStore as seen while holding its lock
+type lockedFakeStore struct {
+	*fakeStore
+}
+
+func (s lockedFakeStore) Apply(ctx context.Context, migration Migration, down bool) error {
+	if migration.Version == s.fail {
+		return errors.New("syntax error")
+	}
+	if down {
+		s.ran = append(s.ran, migration.Down)
+		for i, version := range s.applied {
+			if version == migration.Version {
+				s.applied = append(s.applied[:i], s.applied[i+1:]...)
+			}
+		}
+		return nil
+	}
+	s.ran = append(s.ran, migration.Up)
+	s.applied = append(s.applied, migration.Version)
+	return nil
+}
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "one", Up: "up 1", Down: "down 1"},
+	{Version: 2, Name: "two", Up: "up 2", Down: "down 2"},
+	{Version: 3, Name: "three", Up: "up 3", Down: "down 3"},
+}
+
+func TestUpAppliesPendingMigrationsInOrder(t *testing.T) {
+	store := &fakeStore{applied: []int{1}}
+	migrator := NewMigrator(store, testMigrations, Options{})
+
+	done, err := migrator.Up(context.Background())
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if len(done) != 2 || done[0].Version != 2 || done[1].Version != 3 {
+		t.Errorf("Up applied %v, want versions 2 and 3", done)
+	}
+	if want := []string{"up 2", "up 3"}; !reflect.DeepEqual(store.ran, want) {
+		t.Errorf("ran %v, want %v", store.ran, want)
+	}
+	if store.locks != 1 {
+		t.Errorf("took the lock %d times, want 1", store.locks)
+	}
+	if err := migrator.Check(context.Background()); err != nil {
+		t.Errorf("Check after Up failed: %v", err)
+	}
+}
+
+func TestUpStopsAtFailedMigration(t *testing.T) {
+	store := &fakeStore{fail: 2}
+	migrator := NewMigrator(store, testMigrations, Options{})
+
+	done, err := migrator.Up(context.Background())
+	if err == nil {
+		t.Fatal("Up succeeded although a migration failed")
+	}
+	if len(done) != 1 || !reflect.DeepEqual(store.applied, []int{1}) {
+		t.Errorf("applied %v, want only version 1", store.applied)
+	}
+}
+
+func TestDownRollsBackLatestFirst(t *testing.T) {
+	store := &fakeStore{applied: []int{1, 2, 3}}
+	migrator := NewMigrator(store, testMigrations, Options{})
+
+	if _, err := migrator.Down(context.Background(), 2); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if want := []string{"down 3", "down 2"}; !reflect.DeepEqual(store.ran, want) {
+		t.Errorf("ran %v, want %v", store.ran, want)
+	}
+	if !reflect.DeepEqual(store.applied, []int{1}) {
+		t.Errorf("applied %v, want [1]", store.applied)
+	}
+}
+
+func TestDownRefusesUnknownMigration(t *testing.T) {
+	store := &fakeStore{applied: []int{1, 4}}
+	migrator := NewMigrator(store, testMigrations, Options{})
+
+	_, err := migrator.Down(context.Background(), 1)
+	if err == nil || !strings.Contains(err.Error(), constants.ErrUnknownMigration) {
+		t.Errorf("Down() error = %v, want %q", err, constants.ErrUnknownMigration)
+	}
+	if len(store.ran) != 0 {
+		t.Errorf("ran %v, want nothing", store.ran)
+	}
+}
+
+func TestDryRunChangesNothing(t *testing.T) {
+	store := &fakeStore{applied: []int{1}}
+	migrator := NewMigrator(store, testMigrations, Options{DryRun: true})
+
+	done, err := migrator.Up(context.Background())
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if len(done) != 2 {
+		t.Errorf("Up reported %d migrations, want 2", len(done))
+	}
+	if _, err := migrator.Down(context.Background(), 1); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if len(store.ran) != 0 || store.locks != 0 || !reflect.DeepEqual(store.applied, []int{1}) {
+		t.Errorf("dry run changed the store: ran %v, locks %d, applied %v", store.ran, store.locks, store.applied)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		applied []int
+		wantErr bool
+	}{
+		{"up to date", []int{1, 2, 3}, false},
+		{"behind", []int{1}, true},
+		{"ahead", []int{1, 2, 3, 4}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			migrator := NewMigrator(&fakeStore{applied: test.applied}, testMigrations, Options{})
+			err := migrator.Check(context.Background())
+			if (err != nil) != test.wantErr {
+				t.Errorf("Check() error = %v, want error %v", err, test.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "0002_two, 0003_three") {
+				t.Errorf("Check() error = %v, want the pending migrations listed", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"task-manager-app/constants"
+	"task-manager-app/utils"
+
+	"go.uber.org/zap"
+)
+
+// Store records which migrations were applied and applies them
+type Store interface {
+	// WithLock runs fn while holding a lock shared by every migrator of the database, so
+	// that replicas starting together do not apply the same migrations. fn works through
+	// locked, which runs on the connection holding the lock.
+	WithLock(ctx context.Context, fn func(locked Store) error) error
+	// Applied returns the versions of the applied migrations in ascending order
+	Applied(ctx context.Context) ([]int, error)
+	// Apply runs the up or down SQL of migration and records the outcome, all or nothing
+	Apply(ctx context.Context, migration Migration, down bool) error
+}
+
+// Options configures a Migrator
+type Options struct {
+	// DryRun logs the migrations that would run instead of running them
+	DryRun bool
+	// Logger receives the progress, utils.Sugar when nil
+	Logger *zap.SugaredLogger
+}
+
+// Migrator brings the schema of a database to the version of the migrations it knows
+type Migrator struct {
+	store      Store
+	migrations []Migration
+	options    Options
+}
+
+func NewMigrator(store Store, migrations []Migration, options Options) *Migrator {
+	if options.Logger == nil {
+		options.Logger = utils.Sugar
+	}
+	return &Migrator{store: store, migrations: migrations, options: options}
+}
+
+// Status compares the database with the known migrations
+type Status struct {
+	// Applied are the versions recorded in the database
+	Applied []int
+	// Pending are the known migrations that were not applied, in version order
+	Pending []Migration
+	// Unknown are applied versions missing from the known migrations, usually applied by a
+	// newer release
+	Unknown []int
+}
+
+func (m *Migrator) Status(ctx context.Context) (Status, error) {
+	return m.status(ctx, m.store)
+}
+
+func (m *Migrator) status(ctx context.Context, store Store) (Status, error) {
+	applied, err := store.Applied(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+	status := Status{Applied: applied}
+	isApplied := make(map[int]bool, len(applied))
+	for _, version := range applied {
+		isApplied[version] = true
+	}
+	known := make(map[int]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+		if !isApplied[migration.Version] {
+			status.Pending = append(status.Pending, migration)
+		}
+	}
+	for _, version := range applied {
+		if !known[version] {
+			status.Unknown = append(status.Unknown, version)
+		}
+	}
+	return status, nil
+}
+
+// Up applies the pending migrations in version order and returns them. A migration that
+// fails is rolled back and stops the ones after it.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(store Store) error {
+		status, err := m.status(ctx, store)
+		if err != nil {
+			return err
+		}
+		for _, migration := range status.Pending {
+			if err := m.apply(ctx, store, migration, false); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the last steps applied migrations, latest first, and returns them
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(store Store) error {
+		applied, err := store.Applied(ctx)
+		if err != nil {
+			return err
+		}
+		byVersion := make(map[int]Migration, len(m.migrations))
+		for _, migration := range m.migrations {
+			byVersion[migration.Version] = migration
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(applied)))
+		for i := 0; i < steps && i < len(applied); i++ {
+			migration, ok := byVersion[applied[i]]
+			if !ok {
+				return fmt.Errorf("%s %d", constants.ErrUnknownMigration, applied[i])
+			}
+			if err := m.apply(ctx, store, migration, true); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Check fails when migrations are pending, since the code would then query tables or
+// columns that do not exist yet. A database ahead of the known migrations only gets a
+// warning: releases keep working on the schema of the next one during a rolling deploy.
+func (m *Migrator) Check(ctx context.Context) error {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if len(status.Unknown) > 0 {
+		m.options.Logger.Warnw("Database schema is ahead of this version", "unknown_versions", status.Unknown)
+	}
+	if len(status.Pending) > 0 {
+		pending := make([]string, len(status.Pending))
+		for i, migration := range status.Pending {
+			pending[i] = migration.String()
+		}
+		return fmt.Errorf("%s: %s", constants.ErrSchemaBehind, strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// locked runs fn under the lock of the store, with the store to use while it is held. Dry
+// runs change nothing and need no lock.
+func (m *Migrator) locked(ctx context.Context, fn func(store Store) error) error {
+	if m.options.DryRun {
+		return fn(m.store)
+	}
+	return m.store.WithLock(ctx, fn)
+}
+
+func (m *Migrator) apply(ctx context.Context, store Store, migration Migration, down bool) error {
+	direction, sql := "up", migration.Up
+	if down {
+		direction, sql = "down", migration.Down
+	}
+	if m.options.DryRun {
+		m.options.Logger.Infow("Would run migration", "migration", migration.String(), "direction", direction, "sql", sql)
+		return nil
+	}
+	if err := store.Apply(ctx, migration, down); err != nil {
+		return fmt.Errorf("%s %s: %w", migration, direction, err)
+	}
+	m.options.Logger.Infow("Ran migration", "migration", migration.String(), "direction", direction)
+	return nil
+}
+
+// String names the migration as its files are named, e.g. 0001_create_tasks
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
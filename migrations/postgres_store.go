@@ -0,0 +1,73 @@
+package migrations
+
+import (
+	"context"
+	"task-manager-app/models"
+
+	"gorm.io/gorm"
+)
+
+// advisoryLockKey identifies the migration lock among the advisory locks of the database
+const advisoryLockKey = 7203861
+
+const createSchemaMigrations = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+)`
+
+type postgresStore struct {
+	db *gorm.DB
+}
+
+// NewPostgresStore records applied migrations in the schema_migrations table of db, which is
+// created by the first migration applied. Migrators take turns through a session advisory
+// lock, and every migration runs in a transaction with its record.
+func NewPostgresStore(db *gorm.DB) Store {
+	return &postgresStore{db: db}
+}
+
+func (s *postgresStore) WithLock(ctx context.Context, fn func(locked Store) error) error {
+	// The lock belongs to the session, so it is taken, used and released on one connection.
+	// Working through other connections of the pool would also hang with a pool of one.
+	return s.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", advisoryLockKey).Error; err != nil {
+			return err
+		}
+		// Released even when ctx has ended, since the connection goes back to the pool
+		defer conn.WithContext(context.Background()).Exec("SELECT pg_advisory_unlock(?)", advisoryLockKey)
+		return fn(&postgresStore{db: conn})
+	})
+}
+
+func (s *postgresStore) Applied(ctx context.Context) ([]int, error) {
+	db := s.db.WithContext(ctx)
+	var exists bool
+	if err := db.Raw("SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists).Error; err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+	var versions []int
+	err := db.Model(&models.SchemaMigration{}).Order("version").Pluck("version", &versions).Error
+	return versions, err
+}
+
+func (s *postgresStore) Apply(ctx context.Context, migration Migration, down bool) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(createSchemaMigrations).Error; err != nil {
+			return err
+		}
+		if down {
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&models.SchemaMigration{}, migration.Version).Error
+		}
+		if err := tx.Exec(migration.Up).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.SchemaMigration{Version: migration.Version, Name: migration.Name}).Error
+	})
+}
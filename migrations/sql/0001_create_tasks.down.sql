@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS title_key_rules;
+DROP TABLE IF EXISTS tasks;
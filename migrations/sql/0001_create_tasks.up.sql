@@ -0,0 +1,44 @@
+-- Tasks, as in models.Task. Every statement is idempotent so that databases set up by hand
+-- from the old resources/create_table.sql are adopted.
+CREATE TABLE IF NOT EXISTS tasks (
+    id SERIAL PRIMARY KEY,
+    uuid CHAR(36) UNIQUE NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    title_key VARCHAR(255),
+    description TEXT,
+    status VARCHAR(20) NOT NULL,
+    priority VARCHAR(20) NOT NULL DEFAULT 'Medium',
+    user_id TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- resources/create_table.sql created the table without these columns
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS priority VARCHAR(20) NOT NULL DEFAULT 'Medium';
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS title_key VARCHAR(255);
+
+-- UUID lookup
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_uuid ON tasks(uuid);
+
+-- List(): every filter combination of status, user_id and priority, ordered by created_at
+CREATE INDEX IF NOT EXISTS idx_tasks_composite_list ON tasks(status, user_id, priority, created_at DESC);
+
+-- Duplicate check of ExistsByTitleAndUser
+CREATE INDEX IF NOT EXISTS idx_tasks_title_user_id ON tasks(title, user_id);
+
+-- Fallbacks for partial filters
+CREATE INDEX IF NOT EXISTS idx_tasks_user_created ON tasks(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_tasks_status_created ON tasks(status, created_at DESC);
+
+-- Atomic uniqueness of titles per user. title_key holds the title normalised according to
+-- TASK_TITLE_UNIQUE_IGNORE_CASE / TASK_TITLE_UNIQUE_COLLAPSE_WHITESPACE; rows without a user are
+-- exempt. The service computes the keys of existing rows at startup, with the same code as for
+-- new writes, and records the rules it used in title_key_rules.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_user_title_key ON tasks(user_id, title_key);
+
+CREATE TABLE IF NOT EXISTS title_key_rules (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    ignore_case BOOLEAN NOT NULL,
+    collapse_whitespace BOOLEAN NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS task_changes;
+DROP TABLE IF EXISTS task_change_sequence;
@@ -0,0 +1,30 @@
+-- Change feed: the latest change of every task under a global sequence number. Deleted tasks
+-- stay as tombstones. Sequence numbers come from the single task_change_sequence row, which
+-- serialises the writers so that changes commit in sequence order.
+CREATE TABLE IF NOT EXISTS task_change_sequence (
+    id SMALLINT PRIMARY KEY CHECK (id = 1),
+    value BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS task_changes (
+    task_uuid CHAR(36) PRIMARY KEY,
+    seq BIGINT UNIQUE NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    deleted BOOLEAN NOT NULL,
+    -- The task as in the TaskSnapshot of domain events; NULL for tombstones
+    task JSONB,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Tasks created before the feed existed enter it once, in id order
+INSERT INTO task_changes (task_uuid, seq, event_type, deleted, task, changed_at)
+SELECT uuid, ROW_NUMBER() OVER (ORDER BY id), 'TaskCreated', FALSE,
+       json_strip_nulls(json_build_object('uuid', uuid, 'title', title, 'description', NULLIF(description, ''),
+           'status', status, 'priority', priority, 'user_id', user_id, 'created_at', created_at, 'updated_at', updated_at)),
+       COALESCE(updated_at, CURRENT_TIMESTAMP)
+FROM tasks
+WHERE NOT EXISTS (SELECT 1 FROM task_change_sequence);
+
+INSERT INTO task_change_sequence (id, value)
+SELECT 1, COALESCE(MAX(seq), 0) FROM task_changes
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,9 @@
+-- Consumed user events that were handled, written in the same transaction as their task changes
+-- so that redelivered events are skipped. Rows are purged after USER_EVENTS_RETENTION_HOURS.
+CREATE TABLE IF NOT EXISTS inbox_events (
+    event_id VARCHAR(64) PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL,
+    processed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_inbox_events_processed_at ON inbox_events(processed_at);
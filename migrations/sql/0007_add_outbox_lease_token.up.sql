@@ -0,0 +1,3 @@
+-- Identifies the claim holding an outbox event, so a relay whose lease ran out cannot settle
+-- an event another relay claimed since
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS lease_token VARCHAR(36);
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// IdempotencyKey stores the fingerprint and outcome of a request sent with an Idempotency-Key header.
+// Keys are unique per Scope, which identifies the caller and the route.
+type IdempotencyKey struct {
+	ID           uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	Scope        string `gorm:"type:char(64);uniqueIndex:idx_idempotency_keys_scope_key;not null" json:"scope"`
+	Key          string `gorm:"type:varchar(255);uniqueIndex:idx_idempotency_keys_scope_key;not null" json:"key"`
+	RequestHash  string `gorm:"type:char(64);not null" json:"request_hash"`
+	ResponseCode int    `gorm:"not null;default:0" json:"response_code"`
+	ResponseBody []byte `gorm:"type:bytea" json:"-"`
+	// LeaseToken identifies the request holding an in-flight key, so that a request whose
+	// lease was taken over cannot complete or release the key of the one that took it
+	LeaseToken string `gorm:"type:char(36);not null" json:"-"`
+	// ExpiresAt ends the lease of an in-flight key and the replay window of a completed one
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// IsCompleted reports whether a response has been recorded for the key
+func (k *IdempotencyKey) IsCompleted() bool {
+	return k.ResponseCode != 0
+}
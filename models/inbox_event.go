@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// InboxEvent records a consumed event that was handled, so that a redelivered copy of it is
+// skipped. It is written in the same transaction as the changes the event caused.
+type InboxEvent struct {
+	EventID     string    `gorm:"type:varchar(64);primaryKey" json:"event_id"`
+	EventType   string    `gorm:"type:varchar(50);not null" json:"event_type"`
+	ProcessedAt time.Time `gorm:"index;not null" json:"processed_at"`
+}
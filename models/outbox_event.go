@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// OutboxEvent is a domain event written in the same transaction as the task change that
+// produced it, waiting to be published by the outbox relay. FailedAt is set when the event
+// was dead-lettered and is no longer retried. LeaseToken identifies the claim of the relay
+// that holds the event while it is leased.
+type OutboxEvent struct {
+	ID            uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	AggregateID   string     `gorm:"type:char(36);index;not null" json:"aggregate_id"`
+	EventType     string     `gorm:"type:varchar(50);not null" json:"event_type"`
+	Payload       []byte     `gorm:"type:jsonb;not null" json:"-"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `gorm:"not null" json:"next_attempt_at"`
+	LeaseToken    string     `gorm:"type:varchar(36)" json:"-"`
+	DeliveredAt   *time.Time `gorm:"index" json:"delivered_at,omitempty"`
+	FailedAt      *time.Time `json:"failed_at,omitempty"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
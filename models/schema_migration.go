@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// SchemaMigration records a migration applied to the database; rolling it back deletes the row
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false" json:"version"`
+	Name      string    `gorm:"type:varchar(255);not null" json:"name"`
+	AppliedAt time.Time `gorm:"autoCreateTime" json:"applied_at"`
+}
@@ -11,10 +11,11 @@ type Task struct {
 	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
 	UUID        string    `gorm:"type:char(36);uniqueIndex;not null" json:"uuid"`
 	Title       string    `gorm:"type:varchar(255);not null" json:"title"`
+	TitleKey    string    `gorm:"type:varchar(255);uniqueIndex:idx_tasks_user_title_key,priority:2" json:"-"`
 	Description string    `gorm:"type:text" json:"description,omitempty"`
 	Status      string    `gorm:"type:varchar(20);not null" json:"status"`
 	Priority    string    `gorm:"type:varchar(20);not null;default:'Medium'" json:"priority"`
-	UserID      *string   `gorm:"index" json:"user_id,omitempty"`
+	UserID      *string   `gorm:"index;uniqueIndex:idx_tasks_user_title_key,priority:1" json:"user_id,omitempty"`
 	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updated_at"`
 }
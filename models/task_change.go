@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// TaskChange is the latest change of a task in the change feed. Every change moves the task to
+// the next Seq, so the feed holds each task once; deleted tasks stay as tombstones without a
+// Task.
+type TaskChange struct {
+	TaskUUID  string    `gorm:"type:char(36);primaryKey" json:"task_uuid"`
+	Seq       uint64    `gorm:"uniqueIndex;not null" json:"seq"`
+	EventType string    `gorm:"type:varchar(50);not null" json:"event_type"`
+	Deleted   bool      `gorm:"not null" json:"deleted"`
+	Task      []byte    `gorm:"type:jsonb" json:"-"`
+	ChangedAt time.Time `gorm:"not null" json:"changed_at"`
+}
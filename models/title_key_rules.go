@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// TitleKeyRules records the TASK_TITLE_UNIQUE_* rules the stored task title keys were
+// computed with. The table holds a single row.
+type TitleKeyRules struct {
+	ID                 int       `gorm:"primaryKey" json:"-"`
+	IgnoreCase         bool      `gorm:"not null" json:"ignore_case"`
+	CollapseWhitespace bool      `gorm:"not null" json:"collapse_whitespace"`
+	UpdatedAt          time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Webhook delivery states
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one task event to be POSTed to a subscription, and the log of its
+// attempts. A manual redelivery is a new row with RedeliveryOf pointing at the original.
+type WebhookDelivery struct {
+	ID             uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	SubscriptionID uint       `gorm:"index;not null" json:"-"`
+	EventID        string     `gorm:"type:char(36);not null" json:"event_id"`
+	EventType      string     `gorm:"type:varchar(50);not null" json:"event_type"`
+	Payload        []byte     `gorm:"type:jsonb;not null" json:"-"`
+	Status         string     `gorm:"type:varchar(20);not null" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"not null" json:"next_attempt_at"`
+	LastStatusCode int        `gorm:"not null;default:0" json:"last_status_code,omitempty"`
+	LastError      string     `gorm:"type:text" json:"last_error,omitempty"`
+	RedeliveryOf   *uint64    `json:"redelivery_of,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
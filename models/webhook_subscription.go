@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebhookSubscription is an HTTP endpoint that receives task events as signed POSTs. An empty
+// EventTypes receives every type; Filters match fields of the task in the event.
+type WebhookSubscription struct {
+	ID         uint              `gorm:"primaryKey;autoIncrement" json:"-"`
+	UUID       string            `gorm:"type:char(36);uniqueIndex;not null" json:"uuid"`
+	URL        string            `gorm:"type:text;not null" json:"url"`
+	EventTypes []string          `gorm:"type:jsonb;serializer:json;not null" json:"event_types"`
+	Filters    map[string]string `gorm:"type:jsonb;serializer:json;not null" json:"filters"`
+	Secret     string            `gorm:"type:varchar(255);not null" json:"-"`
+	Active     bool              `gorm:"not null" json:"active"`
+	// ConsecutiveFailures counts failed attempts since the last successful delivery; the
+	// subscription is disabled when it reaches the configured limit
+	ConsecutiveFailures int       `gorm:"not null;default:0" json:"consecutive_failures"`
+	DisabledReason      string    `gorm:"type:text" json:"disabled_reason,omitempty"`
+	CreatedAt           time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt           time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (s *WebhookSubscription) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.UUID == "" {
+		s.UUID = uuid.New().String()
+	}
+	return
+}
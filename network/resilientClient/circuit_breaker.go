@@ -0,0 +1,160 @@
+package resilientClient
+
+import (
+	"sync"
+	"time"
+)
+
+// State is the state of a circuit breaker
+type State string
+
+const (
+	StateClosed   State = "closed"
+	StateOpen     State = "open"
+	StateHalfOpen State = "half_open"
+)
+
+// circuitBreaker opens after a run of consecutive failures, rejects calls while open and
+// lets a limited number of probes through once the open timeout has passed
+type circuitBreaker struct {
+	mutex sync.Mutex
+
+	name             string
+	failureThreshold int
+	openTimeout      time.Duration
+	halfOpenProbes   int
+	metrics          Metrics
+	now              func() time.Time
+
+	state          State
+	failures       int
+	openedAt       time.Time
+	probesInFlight int
+	// opened counts how often the breaker opened, so that probes of an earlier half-open
+	// period are not taken for probes of the current one
+	opened int
+}
+
+// admission is a call let through by allow, to be settled with done or abandon
+type admission struct {
+	probe  bool
+	opened int
+}
+
+func newCircuitBreaker(name string, failureThreshold int, openTimeout time.Duration, halfOpenProbes int, metrics Metrics) *circuitBreaker {
+	return &circuitBreaker{
+		name:             name,
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		halfOpenProbes:   halfOpenProbes,
+		metrics:          metrics,
+		now:              time.Now,
+		state:            StateClosed,
+	}
+}
+
+// allow reports whether a call may proceed. Every allowed call must be settled with done or
+// abandon.
+func (b *circuitBreaker) allow() (admission, bool) {
+	if b.failureThreshold <= 0 {
+		return admission{}, true
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return admission{}, false
+		}
+		b.transition(StateHalfOpen)
+		fallthrough
+	case StateHalfOpen:
+		if b.probesInFlight >= b.halfOpenProbes {
+			return admission{}, false
+		}
+		b.probesInFlight++
+		return admission{probe: true, opened: b.opened}, true
+	}
+	return admission{}, true
+}
+
+// done records the outcome of a call that allow let through. Only the probes of the current
+// half-open period decide whether it closes; calls let through before the breaker opened
+// no longer count.
+func (b *circuitBreaker) done(call admission, success bool) {
+	if b.failureThreshold <= 0 {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if call.probe {
+		if !b.currentProbe(call) {
+			return
+		}
+		b.probesInFlight--
+		if success {
+			b.failures = 0
+			b.transition(StateClosed)
+		} else {
+			b.open()
+		}
+		return
+	}
+
+	if b.state != StateClosed {
+		return
+	}
+	if success {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.failureThreshold {
+		b.open()
+	}
+}
+
+// abandon settles a call whose caller gave up on it, which says nothing about the server. A
+// probe frees its place for the next one.
+func (b *circuitBreaker) abandon(call admission) {
+	if b.failureThreshold <= 0 || !call.probe {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.currentProbe(call) {
+		b.probesInFlight--
+	}
+}
+
+func (b *circuitBreaker) currentProbe(call admission) bool {
+	return b.state == StateHalfOpen && call.opened == b.opened
+}
+
+// State returns the current breaker state
+func (b *circuitBreaker) State() State {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.state
+}
+
+func (b *circuitBreaker) open() {
+	b.opened++
+	b.openedAt = b.now()
+	b.probesInFlight = 0
+	b.transition(StateOpen)
+}
+
+func (b *circuitBreaker) transition(to State) {
+	if b.state == to {
+		return
+	}
+	from := b.state
+	b.state = to
+	b.metrics.StateChanged(b.name, from, to)
+}
@@ -0,0 +1,254 @@
+package resilientClient
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingMetrics keeps every event a Client or breaker reports
+type recordingMetrics struct {
+	mutex       sync.Mutex
+	transitions []State
+	retries     int
+	outcomes    []string
+}
+
+func (m *recordingMetrics) StateChanged(client string, from, to State) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.transitions = append(m.transitions, to)
+}
+
+func (m *recordingMetrics) Retried(client string, attempt int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.retries++
+}
+
+func (m *recordingMetrics) Outcome(client string, outcome string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.outcomes = append(m.outcomes, outcome)
+}
+
+// fakeClock only moves when advance is called
+type fakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// breakerStep is one call through the breaker, or a move of the clock when advance is set
+type breakerStep struct {
+	advance time.Duration
+	allowed bool
+	success bool
+	state   State
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	tests := []struct {
+		name           string
+		halfOpenProbes int
+		steps          []breakerStep
+	}{
+		{
+			name:           "opens after consecutive failures",
+			halfOpenProbes: 1,
+			steps: []breakerStep{
+				{allowed: true, success: false, state: StateClosed},
+				{allowed: true, success: false, state: StateClosed},
+				{allowed: true, success: false, state: StateOpen},
+				{allowed: false, state: StateOpen},
+			},
+		},
+		{
+			name:           "a success resets the failure count",
+			halfOpenProbes: 1,
+			steps: []breakerStep{
+				{allowed: true, success: false, state: StateClosed},
+				{allowed: true, success: false, state: StateClosed},
+				{allowed: true, success: true, state: StateClosed},
+				{allowed: true, success: false, state: StateClosed},
+				{allowed: true, success: false, state: StateClosed},
+			},
+		},
+		{
+			name:           "stays open until the open timeout passed",
+			halfOpenProbes: 1,
+			steps: []breakerStep{
+				{allowed: true, success: false},
+				{allowed: true, success: false},
+				{allowed: true, success: false, state: StateOpen},
+				{advance: 29 * time.Second},
+				{allowed: false, state: StateOpen},
+			},
+		},
+		{
+			name:           "a successful probe closes the breaker",
+			halfOpenProbes: 1,
+			steps: []breakerStep{
+				{allowed: true, success: false},
+				{allowed: true, success: false},
+				{allowed: true, success: false, state: StateOpen},
+				{advance: 30 * time.Second},
+				{allowed: true, success: true, state: StateClosed},
+				{allowed: true, success: true, state: StateClosed},
+			},
+		},
+		{
+			name:           "a failed probe opens the breaker again for another timeout",
+			halfOpenProbes: 1,
+			steps: []breakerStep{
+				{allowed: true, success: false},
+				{allowed: true, success: false},
+				{allowed: true, success: false, state: StateOpen},
+				{advance: 30 * time.Second},
+				{allowed: true, success: false, state: StateOpen},
+				{advance: 29 * time.Second},
+				{allowed: false, state: StateOpen},
+				{advance: time.Second},
+				{allowed: true, success: true, state: StateClosed},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := newFakeClock()
+			breaker := newCircuitBreaker("test", 3, 30*time.Second, test.halfOpenProbes, &recordingMetrics{})
+			breaker.now = clock.Now
+
+			for i, step := range test.steps {
+				if step.advance > 0 {
+					clock.advance(step.advance)
+					continue
+				}
+				call, allowed := breaker.allow()
+				if allowed != step.allowed {
+					t.Fatalf("step %d: allow() = %t, want %t", i, allowed, step.allowed)
+				}
+				if step.allowed {
+					breaker.done(call, step.success)
+				}
+				if step.state != "" && breaker.State() != step.state {
+					t.Fatalf("step %d: state = %s, want %s", i, breaker.State(), step.state)
+				}
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerLimitsHalfOpenProbes(t *testing.T) {
+	clock := newFakeClock()
+	metrics := &recordingMetrics{}
+	breaker := newCircuitBreaker("test", 1, time.Second, 2, metrics)
+	breaker.now = clock.Now
+
+	call, _ := breaker.allow()
+	breaker.done(call, false)
+	clock.advance(time.Second)
+
+	probe, first := breaker.allow()
+	_, second := breaker.allow()
+	if !first || !second {
+		t.Fatalf("the first two probes were rejected, want them let through")
+	}
+	if breaker.State() != StateHalfOpen {
+		t.Fatalf("state = %s, want %s while probes are in flight", breaker.State(), StateHalfOpen)
+	}
+	if _, third := breaker.allow(); third {
+		t.Fatalf("a third probe was let through, want at most 2 in flight")
+	}
+
+	breaker.done(probe, true)
+	if breaker.State() != StateClosed {
+		t.Fatalf("state = %s, want %s after a successful probe", breaker.State(), StateClosed)
+	}
+	want := []State{StateOpen, StateHalfOpen, StateClosed}
+	if len(metrics.transitions) != len(want) {
+		t.Fatalf("transitions = %v, want %v", metrics.transitions, want)
+	}
+	for i := range want {
+		if metrics.transitions[i] != want[i] {
+			t.Fatalf("transitions = %v, want %v", metrics.transitions, want)
+		}
+	}
+}
+
+func TestCircuitBreakerDisabledWithoutThreshold(t *testing.T) {
+	breaker := newCircuitBreaker("test", 0, time.Second, 1, &recordingMetrics{})
+	for i := 0; i < 10; i++ {
+		call, allowed := breaker.allow()
+		if !allowed {
+			t.Fatalf("call %d rejected by a disabled breaker", i)
+		}
+		breaker.done(call, false)
+	}
+	if breaker.State() != StateClosed {
+		t.Fatalf("state = %s, want %s", breaker.State(), StateClosed)
+	}
+}
+
+func TestCircuitBreakerOnlyProbesDecideHalfOpen(t *testing.T) {
+	clock := newFakeClock()
+	breaker := newCircuitBreaker("test", 1, time.Second, 1, &recordingMetrics{})
+	breaker.now = clock.Now
+
+	// A slow call admitted while closed finishes only after the breaker went half-open
+	slow, _ := breaker.allow()
+	failing, _ := breaker.allow()
+	breaker.done(failing, false)
+	clock.advance(time.Second)
+	probe, allowed := breaker.allow()
+	if !allowed || !probe.probe {
+		t.Fatalf("allow() = %+v, %t, want a probe", probe, allowed)
+	}
+
+	breaker.done(slow, true)
+	if breaker.State() != StateHalfOpen {
+		t.Fatalf("state = %s, want %s after a call that was not a probe", breaker.State(), StateHalfOpen)
+	}
+	if _, allowed := breaker.allow(); allowed {
+		t.Fatalf("a second probe was let through, want the slow call not to free the probe's place")
+	}
+	breaker.done(probe, true)
+	if breaker.State() != StateClosed {
+		t.Fatalf("state = %s, want %s after the probe succeeded", breaker.State(), StateClosed)
+	}
+}
+
+func TestCircuitBreakerAbandonedProbeFreesItsPlace(t *testing.T) {
+	clock := newFakeClock()
+	breaker := newCircuitBreaker("test", 1, time.Second, 1, &recordingMetrics{})
+	breaker.now = clock.Now
+
+	call, _ := breaker.allow()
+	breaker.done(call, false)
+	clock.advance(time.Second)
+	probe, _ := breaker.allow()
+	breaker.abandon(probe)
+
+	if breaker.State() != StateHalfOpen {
+		t.Fatalf("state = %s, want %s after an abandoned probe", breaker.State(), StateHalfOpen)
+	}
+	if _, allowed := breaker.allow(); !allowed {
+		t.Fatalf("the next probe was rejected, want the abandoned probe's place free")
+	}
+}
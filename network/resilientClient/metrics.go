@@ -0,0 +1,38 @@
+package resilientClient
+
+import "go.uber.org/zap"
+
+// Outcomes reported for each logical request
+const (
+	OutcomeSuccess          = "success"
+	OutcomeFailure          = "failure"
+	OutcomeCircuitOpen      = "circuit_open"
+	OutcomeBulkheadRejected = "bulkhead_rejected"
+)
+
+// Metrics receives resilience events from a Client
+type Metrics interface {
+	StateChanged(client string, from, to State)
+	Retried(client string, attempt int)
+	Outcome(client string, outcome string)
+}
+
+// NopMetrics counts nothing; metrics.ClientMetrics reports the events to Prometheus
+type NopMetrics struct{}
+
+func (NopMetrics) StateChanged(client string, from, to State) {}
+
+func (NopMetrics) Retried(client string, attempt int) {}
+
+func (NopMetrics) Outcome(client string, outcome string) {}
+
+// loggedMetrics logs breaker transitions before passing every event on to Metrics
+type loggedMetrics struct {
+	Metrics
+	logger *zap.SugaredLogger
+}
+
+func (m loggedMetrics) StateChanged(client string, from, to State) {
+	m.logger.Warnf("Circuit breaker %s changed state from %s to %s", client, from, to)
+	m.Metrics.StateChanged(client, from, to)
+}
@@ -0,0 +1,292 @@
+// Package resilientClient wraps http.Client with retries, a circuit breaker and a
+// bulkhead so that a slow or failing dependency cannot take the service down with it.
+package resilientClient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"task-manager-app/constants"
+	"task-manager-app/utils"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrCircuitOpen is returned without calling the remote while the breaker is open
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+	// ErrBulkheadFull is returned when the concurrency limit was reached for too long
+	ErrBulkheadFull = errors.New("too many concurrent requests")
+)
+
+// Options configures a Client. Zero values disable the corresponding feature.
+type Options struct {
+	Name    string
+	Timeout time.Duration
+
+	MaxRetries  int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	FailureThreshold int
+	OpenTimeout      time.Duration
+	HalfOpenProbes   int
+
+	MaxConcurrent int
+	BulkheadWait  time.Duration
+
+	Metrics Metrics
+	// Logger receives breaker transitions, utils.Sugar when nil
+	Logger *zap.SugaredLogger
+	// Clock times the open circuit breaker and Retry-After dates, time.Now when nil
+	Clock func() time.Time
+}
+
+// Client is a resilient replacement for *http.Client
+type Client struct {
+	name       string
+	httpClient *http.Client
+	options    Options
+	breaker    *circuitBreaker
+	bulkhead   chan struct{}
+	metrics    Metrics
+	now        func() time.Time
+}
+
+func NewClient(options Options) *Client {
+	if options.Metrics == nil {
+		options.Metrics = NopMetrics{}
+	}
+	if options.Logger == nil {
+		options.Logger = utils.Sugar
+	}
+	if options.Clock == nil {
+		options.Clock = time.Now
+	}
+	metrics := loggedMetrics{Metrics: options.Metrics, logger: options.Logger}
+	if options.HalfOpenProbes <= 0 {
+		options.HalfOpenProbes = 1
+	}
+
+	// Every attempt is traced as a client span that passes the trace context on
+	client := &Client{
+		name:       options.Name,
+		httpClient: &http.Client{Timeout: options.Timeout, Transport: otelhttp.NewTransport(http.DefaultTransport)},
+		options:    options,
+		metrics:    metrics,
+		breaker:    newCircuitBreaker(options.Name, options.FailureThreshold, options.OpenTimeout, options.HalfOpenProbes, metrics),
+		now:        options.Clock,
+	}
+	client.breaker.now = options.Clock
+	if options.MaxConcurrent > 0 {
+		client.bulkhead = make(chan struct{}, options.MaxConcurrent)
+	}
+	return client
+}
+
+// State returns the current circuit breaker state
+func (c *Client) State() State {
+	return c.breaker.State()
+}
+
+// Probe sends req once, bypassing retries, the circuit breaker and the bulkhead, so that
+// health checks neither wait for nor count as user traffic
+func (c *Client) Probe(req *http.Request) (*http.Response, error) {
+	return c.httpClient.Do(req)
+}
+
+// Do sends req, retrying idempotent requests on transport errors and retryable status
+// codes. A 429 or 503 with Retry-After is retried after the time it asks for, at most
+// MaxBackoff. The returned response is the last one received; its body must be closed.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if err := c.acquire(req.Context()); err != nil {
+		c.metrics.Outcome(c.name, OutcomeBulkheadRejected)
+		return nil, err
+	}
+	defer c.release()
+
+	attempts := 1
+	if isRetryable(req) {
+		attempts += c.options.MaxRetries
+	}
+
+	var resp *http.Response
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if waitErr := sleep(req.Context(), c.retryDelay(resp, attempt)); waitErr != nil {
+				break
+			}
+			if req, err = rewind(req); err != nil {
+				break
+			}
+			c.metrics.Retried(c.name, attempt)
+		}
+
+		call, allowed := c.breaker.allow()
+		if !allowed {
+			c.metrics.Outcome(c.name, OutcomeCircuitOpen)
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, ErrCircuitOpen
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+		resp, err = c.httpClient.Do(req)
+		if err != nil && req.Context().Err() != nil {
+			// The caller cancelled or ran out of time, which says nothing about the server
+			c.breaker.abandon(call)
+			break
+		}
+		failed := err != nil || isServerFailure(resp.StatusCode)
+		c.breaker.done(call, !failed)
+		if err == nil && !isRetryableStatus(resp.StatusCode) {
+			break
+		}
+	}
+
+	if err != nil || isServerFailure(resp.StatusCode) {
+		c.metrics.Outcome(c.name, OutcomeFailure)
+	} else {
+		c.metrics.Outcome(c.name, OutcomeSuccess)
+	}
+	if err != nil && resp != nil {
+		resp.Body.Close()
+		resp = nil
+	}
+	return resp, err
+}
+
+func (c *Client) acquire(ctx context.Context) error {
+	if c.bulkhead == nil {
+		return nil
+	}
+	select {
+	case c.bulkhead <- struct{}{}:
+		return nil
+	default:
+	}
+	if c.options.BulkheadWait <= 0 {
+		return ErrBulkheadFull
+	}
+
+	timer := time.NewTimer(c.options.BulkheadWait)
+	defer timer.Stop()
+	select {
+	case c.bulkhead <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return ErrBulkheadFull
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) release() {
+	if c.bulkhead != nil {
+		<-c.bulkhead
+	}
+}
+
+// retryDelay is how long to wait before attempt after resp: the Retry-After of resp capped
+// at MaxBackoff, or else an exponentially growing, fully jittered delay
+func (c *Client) retryDelay(resp *http.Response, attempt int) time.Duration {
+	if delay, ok := c.retryAfter(resp); ok {
+		if c.options.MaxBackoff > 0 && delay > c.options.MaxBackoff {
+			return c.options.MaxBackoff
+		}
+		return delay
+	}
+
+	ceiling := c.options.BaseBackoff << (attempt - 1)
+	if ceiling <= 0 || (c.options.MaxBackoff > 0 && ceiling > c.options.MaxBackoff) {
+		ceiling = c.options.MaxBackoff
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)) + 1)
+}
+
+// retryAfter reads the Retry-After header of a 429 or 503 response, given in seconds or as
+// an HTTP date
+func (c *Client) retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return 0, false
+	}
+	value := strings.TrimSpace(resp.Header.Get(constants.HeaderRetryAfter))
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds >= 0 {
+		if seconds > int64(math.MaxInt64/time.Second) {
+			return math.MaxInt64, true
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(c.now()), 0), true
+	}
+	return 0, false
+}
+
+// sleep waits for delay or until ctx ends
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rewind returns a copy of req whose body can be sent again
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("request body cannot be replayed")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = io.NopCloser(body)
+	return clone, nil
+}
+
+// isRetryable reports whether req can be sent more than once without side effects
+func isRetryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return req.Header.Get(constants.HeaderIdempotencyKey) != ""
+}
+
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func isServerFailure(status int) bool {
+	return status >= http.StatusInternalServerError
+}
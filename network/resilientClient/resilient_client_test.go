@@ -0,0 +1,322 @@
+package resilientClient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"task-manager-app/constants"
+	"task-manager-app/utils"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	utils.Sugar = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
+
+// newStatusServer answers every request with status and counts the requests it got
+func newStatusServer(status int) (*httptest.Server, *int32) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(status)
+	}))
+	return server, &hits
+}
+
+func send(t *testing.T, client *Client, method, url string, header http.Header) (*http.Response, error) {
+	t.Helper()
+	var body *strings.Reader
+	if method == http.MethodPost || method == http.MethodPut {
+		body = strings.NewReader(`{}`)
+	}
+	var req *http.Request
+	var err error
+	if body != nil {
+		req, err = http.NewRequest(method, url, body)
+	} else {
+		req, err = http.NewRequest(method, url, nil)
+	}
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	resp, err := client.Do(req)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+func TestDoRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		header   http.Header
+		status   int
+		wantHits int32
+	}{
+		{name: "success is not retried", method: http.MethodGet, status: http.StatusOK, wantHits: 1},
+		{name: "404 is not retried", method: http.MethodGet, status: http.StatusNotFound, wantHits: 1},
+		{name: "500 is not retried", method: http.MethodGet, status: http.StatusInternalServerError, wantHits: 1},
+		{name: "429 is retried", method: http.MethodGet, status: http.StatusTooManyRequests, wantHits: 3},
+		{name: "502 is retried", method: http.MethodGet, status: http.StatusBadGateway, wantHits: 3},
+		{name: "503 is retried", method: http.MethodGet, status: http.StatusServiceUnavailable, wantHits: 3},
+		{name: "504 is retried", method: http.MethodGet, status: http.StatusGatewayTimeout, wantHits: 3},
+		{name: "PUT is retried", method: http.MethodPut, status: http.StatusServiceUnavailable, wantHits: 3},
+		{name: "POST is not retried", method: http.MethodPost, status: http.StatusServiceUnavailable, wantHits: 1},
+		{
+			name:     "POST with an idempotency key is retried",
+			method:   http.MethodPost,
+			header:   http.Header{constants.HeaderIdempotencyKey: {"key-1"}},
+			status:   http.StatusServiceUnavailable,
+			wantHits: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, hits := newStatusServer(test.status)
+			defer server.Close()
+			metrics := &recordingMetrics{}
+			client := NewClient(Options{Name: "test", Timeout: time.Second, MaxRetries: 2, Metrics: metrics})
+
+			resp, err := send(t, client, test.method, server.URL, test.header)
+			if err != nil {
+				t.Fatalf("Do returned %v", err)
+			}
+			if resp.StatusCode != test.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, test.status)
+			}
+			if *hits != test.wantHits {
+				t.Fatalf("server got %d requests, want %d", *hits, test.wantHits)
+			}
+			if metrics.retries != int(test.wantHits-1) {
+				t.Fatalf("%d retries reported, want %d", metrics.retries, test.wantHits-1)
+			}
+		})
+	}
+}
+
+func TestDoStopsRetryingOnSuccess(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	metrics := &recordingMetrics{}
+	client := NewClient(Options{Name: "test", Timeout: time.Second, MaxRetries: 5, Metrics: metrics})
+
+	resp, err := send(t, client, http.MethodGet, server.URL, nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Do = %v, %v, want 200", resp, err)
+	}
+	if hits != 3 {
+		t.Fatalf("server got %d requests, want 3", hits)
+	}
+	if len(metrics.outcomes) != 1 || metrics.outcomes[0] != OutcomeSuccess {
+		t.Fatalf("outcomes = %v, want one %s", metrics.outcomes, OutcomeSuccess)
+	}
+}
+
+func TestRetryDelayHonoursRetryAfter(t *testing.T) {
+	clock := newFakeClock()
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		want       time.Duration
+	}{
+		{name: "seconds on 429", status: http.StatusTooManyRequests, retryAfter: "3", want: 3 * time.Second},
+		{name: "seconds on 503", status: http.StatusServiceUnavailable, retryAfter: "0", want: 0},
+		{name: "HTTP date", status: http.StatusServiceUnavailable, retryAfter: clock.Now().Add(5 * time.Second).Format(http.TimeFormat), want: 5 * time.Second},
+		{name: "HTTP date in the past", status: http.StatusTooManyRequests, retryAfter: clock.Now().Add(-time.Minute).Format(http.TimeFormat), want: 0},
+		{name: "capped at MaxBackoff", status: http.StatusTooManyRequests, retryAfter: "3600", want: 10 * time.Second},
+		{name: "huge value capped at MaxBackoff", status: http.StatusTooManyRequests, retryAfter: "99999999999999999", want: 10 * time.Second},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := NewClient(Options{Name: "test", BaseBackoff: time.Second, MaxBackoff: 10 * time.Second, Clock: clock.Now})
+			resp := &http.Response{StatusCode: test.status, Header: http.Header{constants.HeaderRetryAfter: {test.retryAfter}}}
+			if got := client.retryDelay(resp, 1); got != test.want {
+				t.Errorf("retryDelay = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRetryDelayIgnoresUnusableRetryAfter(t *testing.T) {
+	client := NewClient(Options{Name: "test", BaseBackoff: time.Second, MaxBackoff: 10 * time.Second, Clock: newFakeClock().Now})
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+	}{
+		{name: "502 is not a Retry-After status", status: http.StatusBadGateway, retryAfter: "60"},
+		{name: "negative seconds", status: http.StatusServiceUnavailable, retryAfter: "-5"},
+		{name: "garbage", status: http.StatusServiceUnavailable, retryAfter: "soon"},
+		{name: "missing", status: http.StatusTooManyRequests},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: test.status, Header: http.Header{}}
+			if test.retryAfter != "" {
+				resp.Header.Set(constants.HeaderRetryAfter, test.retryAfter)
+			}
+			// The jittered backoff of the first retry never exceeds BaseBackoff
+			if got := client.retryDelay(resp, 1); got <= 0 || got > time.Second {
+				t.Errorf("retryDelay = %v, want a jittered backoff up to 1s", got)
+			}
+		})
+	}
+}
+
+func TestDoWaitsForRetryAfterInsteadOfBackoff(t *testing.T) {
+	clock := newFakeClock()
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			// The fake clock does not move, so this date asks for no wait at all
+			w.Header().Set(constants.HeaderRetryAfter, clock.Now().Format(http.TimeFormat))
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	// Without Retry-After the retry would wait up to an hour
+	client := NewClient(Options{Name: "test", Timeout: time.Second, MaxRetries: 1, BaseBackoff: time.Hour, MaxBackoff: time.Hour, Clock: clock.Now})
+
+	start := time.Now()
+	resp, err := send(t, client, http.MethodGet, server.URL, nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Do = %v, %v, want 200", resp, err)
+	}
+	if hits != 2 {
+		t.Fatalf("server got %d requests, want 2", hits)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Do took %v, want the retry as soon as Retry-After allows", elapsed)
+	}
+}
+
+func TestDoRetriesTransportErrors(t *testing.T) {
+	server, _ := newStatusServer(http.StatusOK)
+	url := server.URL
+	server.Close()
+	metrics := &recordingMetrics{}
+	client := NewClient(Options{Name: "test", Timeout: time.Second, MaxRetries: 2, Metrics: metrics})
+
+	if _, err := send(t, client, http.MethodGet, url, nil); err == nil {
+		t.Fatalf("Do returned no error for a closed server")
+	}
+	if metrics.retries != 2 {
+		t.Fatalf("%d retries reported, want 2", metrics.retries)
+	}
+	if len(metrics.outcomes) != 1 || metrics.outcomes[0] != OutcomeFailure {
+		t.Fatalf("outcomes = %v, want one %s", metrics.outcomes, OutcomeFailure)
+	}
+}
+
+func TestDoOpensCircuitAndProbesAfterTimeout(t *testing.T) {
+	status := int32(http.StatusInternalServerError)
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+	clock := newFakeClock()
+	client := NewClient(Options{Name: "test", Timeout: time.Second, FailureThreshold: 2, OpenTimeout: 30 * time.Second, Metrics: &recordingMetrics{}})
+	client.breaker.now = clock.Now
+
+	send(t, client, http.MethodGet, server.URL, nil)
+	send(t, client, http.MethodGet, server.URL, nil)
+	if client.State() != StateOpen {
+		t.Fatalf("state = %s, want %s after 2 failures", client.State(), StateOpen)
+	}
+	if _, err := send(t, client, http.MethodGet, server.URL, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Do while open = %v, want %v", err, ErrCircuitOpen)
+	}
+	if hits != 2 {
+		t.Fatalf("server got %d requests, want none while the circuit is open", hits)
+	}
+
+	atomic.StoreInt32(&status, http.StatusOK)
+	clock.advance(30 * time.Second)
+	if resp, err := send(t, client, http.MethodGet, server.URL, nil); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("probe = %v, %v, want 200", resp, err)
+	}
+	if client.State() != StateClosed {
+		t.Fatalf("state = %s, want %s after a successful probe", client.State(), StateClosed)
+	}
+}
+
+func TestDoDoesNotCountCallerCancellationsAsFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	client := NewClient(Options{Name: "test", Timeout: 5 * time.Second, MaxRetries: 2, FailureThreshold: 1, OpenTimeout: time.Minute, Metrics: &recordingMetrics{}})
+
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		_, err := client.Do(req)
+		cancel()
+		if errors.Is(err, ErrCircuitOpen) || err == nil {
+			t.Fatalf("call %d returned %v, want the caller's deadline", i+1, err)
+		}
+	}
+	if client.State() != StateClosed {
+		t.Fatalf("state = %s, want %s after calls that only ran out of the caller's time", client.State(), StateClosed)
+	}
+}
+
+func TestDoRejectsWhenBulkheadIsFull(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+	defer server.Close()
+	metrics := &recordingMetrics{}
+	client := NewClient(Options{Name: "test", Timeout: 5 * time.Second, MaxConcurrent: 1, BulkheadWait: 10 * time.Millisecond, Metrics: metrics})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := send(t, client, http.MethodGet, server.URL, nil)
+		done <- err
+	}()
+	<-started
+
+	if _, err := send(t, client, http.MethodGet, server.URL, nil); !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("Do with a full bulkhead = %v, want %v", err, ErrBulkheadFull)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("first request returned %v", err)
+	}
+	if _, err := send(t, client, http.MethodGet, server.URL, nil); err != nil {
+		t.Fatalf("Do after the bulkhead freed up = %v", err)
+	}
+	if metrics.outcomes[0] != OutcomeBulkheadRejected {
+		t.Fatalf("outcomes = %v, want %s first", metrics.outcomes, OutcomeBulkheadRejected)
+	}
+}
@@ -1,21 +1,30 @@
 package userManager
 
 import (
-	"os"
-	"task-manager-app/utils"
+	"task-manager-app/config"
+	"task-manager-app/metrics"
+	"task-manager-app/network/resilientClient"
+	"time"
 )
 
-var (
-	UserClient *UserServiceClient
-)
+// NewUserServiceClientFromConfig builds the user service client described by cfg
+func NewUserServiceClientFromConfig(cfg *config.Config) *UserServiceClient {
+	return NewUserServiceClient(cfg.UserServiceURL, UserServiceClientOptions(cfg))
+}
 
-func InitNetworkClients() {
-	userServiceURL := os.Getenv("USER_SERVICE_URL")
-	if userServiceURL == "" {
-		userServiceURL = "http://localhost:8081"
+// UserServiceClientOptions builds the resilience settings for the user service client
+func UserServiceClientOptions(cfg *config.Config) resilientClient.Options {
+	return resilientClient.Options{
+		Name:             "user_service",
+		Timeout:          time.Duration(cfg.UserServiceTimeout) * time.Millisecond,
+		MaxRetries:       cfg.UserServiceMaxRetries,
+		BaseBackoff:      100 * time.Millisecond,
+		MaxBackoff:       2 * time.Second,
+		FailureThreshold: cfg.UserServiceBreakerThreshold,
+		OpenTimeout:      time.Duration(cfg.UserServiceBreakerOpenSecs) * time.Second,
+		HalfOpenProbes:   1,
+		MaxConcurrent:    cfg.UserServiceMaxConcurrent,
+		BulkheadWait:     100 * time.Millisecond,
+		Metrics:          metrics.NewClientMetrics("user_service"),
 	}
-
-	UserClient = NewUserServiceClient(userServiceURL)
-
-	utils.Sugar.Infof("Network clients initialized successfully")
 }
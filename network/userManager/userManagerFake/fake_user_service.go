@@ -0,0 +1,186 @@
+// Package userManagerFake provides an in-process stand-in for the user manager service.
+// It implements the same HTTP contract as the real service so the user-service client
+// can be exercised locally and in tests without network dependencies.
+package userManagerFake
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// User is a user known to the fake service
+type User struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name,omitempty"`
+	Email  string `json:"email,omitempty"`
+}
+
+type validationResponse struct {
+	Valid  bool   `json:"valid"`
+	UserID string `json:"user_id"`
+	Name   string `json:"name,omitempty"`
+	Email  string `json:"email,omitempty"`
+}
+
+type batchValidationRequest struct {
+	UserIDs []string `json:"user_ids"`
+}
+
+type batchValidationResponse struct {
+	Users []validationResponse `json:"users"`
+}
+
+// FakeUserService serves GET /api/users/{id}/validate and POST /api/users/validate from an
+// in-memory user set
+type FakeUserService struct {
+	server *httptest.Server
+
+	mutex         sync.RWMutex
+	users         map[string]User
+	failureStatus int
+	latency       time.Duration
+	batchDisabled bool
+
+	calls       atomic.Int64
+	traceparent atomic.Value
+}
+
+// NewFakeUserService starts a fake user service seeded with users. Call Close when done.
+func NewFakeUserService(users ...User) *FakeUserService {
+	f := &FakeUserService{users: make(map[string]User)}
+	for _, u := range users {
+		f.users[u.UserID] = u
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/users/{id}/validate", f.handleValidate)
+	mux.HandleFunc("POST /api/users/validate", f.handleValidateBatch)
+	f.server = httptest.NewServer(mux)
+	return f
+}
+
+// URL returns the base URL to configure as USER_SERVICE_URL
+func (f *FakeUserService) URL() string {
+	return f.server.URL
+}
+
+// Close shuts the fake service down
+func (f *FakeUserService) Close() {
+	f.server.Close()
+}
+
+// AddUser registers or replaces a user
+func (f *FakeUserService) AddUser(user User) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.users[user.UserID] = user
+}
+
+// RemoveUser deletes a user so that it validates as not found
+func (f *FakeUserService) RemoveUser(userID string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.users, userID)
+}
+
+// SetFailure makes every request fail with status; pass 0 to recover
+func (f *FakeUserService) SetFailure(status int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.failureStatus = status
+}
+
+// SetLatency delays every response by d
+func (f *FakeUserService) SetLatency(d time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.latency = d
+}
+
+// SetBatchSupported toggles the batch endpoint; when disabled it answers 404 like an
+// older user service would
+func (f *FakeUserService) SetBatchSupported(supported bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.batchDisabled = !supported
+}
+
+// Calls returns the number of requests served so far
+func (f *FakeUserService) Calls() int64 {
+	return f.calls.Load()
+}
+
+// LastTraceparent returns the W3C traceparent header of the last request, if it had one
+func (f *FakeUserService) LastTraceparent() string {
+	traceparent, _ := f.traceparent.Load().(string)
+	return traceparent
+}
+
+func (f *FakeUserService) handleValidate(w http.ResponseWriter, r *http.Request) {
+	f.calls.Add(1)
+	f.traceparent.Store(r.Header.Get("traceparent"))
+
+	f.mutex.RLock()
+	failureStatus, latency := f.failureStatus, f.latency
+	user, ok := f.users[r.PathValue("id")]
+	f.mutex.RUnlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	if failureStatus != 0 {
+		w.WriteHeader(failureStatus)
+		return
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, validationResponse{Valid: false, UserID: r.PathValue("id")})
+		return
+	}
+	writeJSON(w, http.StatusOK, validationResponse{Valid: true, UserID: user.UserID, Name: user.Name, Email: user.Email})
+}
+
+func (f *FakeUserService) handleValidateBatch(w http.ResponseWriter, r *http.Request) {
+	f.calls.Add(1)
+	f.traceparent.Store(r.Header.Get("traceparent"))
+
+	f.mutex.RLock()
+	failureStatus, latency, batchDisabled := f.failureStatus, f.latency, f.batchDisabled
+	f.mutex.RUnlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	if batchDisabled {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if failureStatus != 0 {
+		w.WriteHeader(failureStatus)
+		return
+	}
+
+	var req batchValidationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	resp := batchValidationResponse{Users: make([]validationResponse, 0, len(req.UserIDs))}
+	for _, userID := range req.UserIDs {
+		user, ok := f.users[userID]
+		resp.Users = append(resp.Users, validationResponse{Valid: ok, UserID: userID, Name: user.Name, Email: user.Email})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -1,17 +1,40 @@
 package userManager
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
 	"task-manager-app/exceptions/errors"
+	"task-manager-app/network/resilientClient"
+	"task-manager-app/tracing"
 	"task-manager-app/utils"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxConcurrentSingleCalls bounds the fallback calls made when batch validation is unavailable
+	maxConcurrentSingleCalls = 8
+	// batchReprobeInterval is how long single calls are used after the batch endpoint was
+	// missing, before trying it again in case the user service was upgraded
+	batchReprobeInterval = 10 * time.Minute
 )
 
 type UserServiceClient struct {
 	baseURL    string
-	httpClient *http.Client
+	httpClient *resilientClient.Client
+	// batchUnsupportedUntil is the UnixNano time until which batch validation is skipped
+	batchUnsupportedUntil atomic.Int64
+	batchReprobe          time.Duration
+	logger                *zap.SugaredLogger
+	now                   func() time.Time
 }
 
 type UserValidationResponse struct {
@@ -21,23 +44,39 @@ type UserValidationResponse struct {
 	Email  string `json:"email,omitempty"`
 }
 
-func NewUserServiceClient(baseURL string) *UserServiceClient {
-	return &UserServiceClient{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+// NewUserServiceClient logs to options.Logger outside of requests and times the batch
+// re-probe with options.Clock, like the circuit breaker
+func NewUserServiceClient(baseURL string, options resilientClient.Options) *UserServiceClient {
+	client := &UserServiceClient{
+		baseURL:      baseURL,
+		httpClient:   resilientClient.NewClient(options),
+		batchReprobe: batchReprobeInterval,
+		logger:       options.Logger,
+		now:          options.Clock,
+	}
+	if client.logger == nil {
+		client.logger = utils.Sugar
+	}
+	if client.now == nil {
+		client.now = time.Now
 	}
+	return client
 }
 
-func (c *UserServiceClient) ValidateUserID(userID string) (*UserValidationResponse, *errors.TaskManagerError) {
-	url := fmt.Sprintf("%s/api/users/%s/validate", c.baseURL, userID)
+// ValidateUserID asks the user service whether userID exists. A 404 from the user service
+// is a definitive "not valid" answer; any other failure, a 400 included, is returned as an
+// error with a 5xx ResponseCode so callers can tell an outage apart from an invalid user.
+func (c *UserServiceClient) ValidateUserID(ctx context.Context, userID string) (result *UserValidationResponse, taskErr *errors.TaskManagerError) {
+	ctx, span := tracing.Start(ctx, "UserServiceClient.ValidateUserID")
+	defer func() { tracing.End(span, taskErr) }()
 
-	utils.Sugar.Infof("Validating user ID %s with URL: %s", userID, url)
+	requestURL := fmt.Sprintf("%s/api/users/%s/validate", c.baseURL, url.PathEscape(userID))
 
-	req, err := http.NewRequest("GET", url, nil)
+	c.log(ctx).Infof("Validating user ID %s with URL: %s", userID, requestURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
 	if err != nil {
-		utils.Sugar.Errorf("Failed to create request: %v", err)
+		c.log(ctx).Errorf("Failed to create request: %v", err)
 		return nil, &errors.TaskManagerError{
 			Message:      "Failed to create user validation request",
 			ResponseCode: http.StatusInternalServerError,
@@ -49,7 +88,7 @@ func (c *UserServiceClient) ValidateUserID(userID string) (*UserValidationRespon
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		utils.Sugar.Errorf("Failed to call user service: %v", err)
+		c.log(ctx).Errorf("Failed to call user service: %v", err)
 		return nil, &errors.TaskManagerError{
 			Message:      "User service is unavailable",
 			ResponseCode: http.StatusServiceUnavailable,
@@ -58,15 +97,15 @@ func (c *UserServiceClient) ValidateUserID(userID string) (*UserValidationRespon
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		utils.Sugar.Warnf("User ID %d not found", userID)
+		c.log(ctx).Warnf("User ID %s not found", userID)
 		return &UserValidationResponse{
-			Valid:  true,
+			Valid:  false,
 			UserID: userID,
 		}, nil
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		utils.Sugar.Errorf("User service returned status: %d", resp.StatusCode)
+		c.log(ctx).Errorf("User service returned status: %d", resp.StatusCode)
 		return nil, &errors.TaskManagerError{
 			Message:      fmt.Sprintf("User service error: %d", resp.StatusCode),
 			ResponseCode: http.StatusBadGateway,
@@ -75,21 +114,166 @@ func (c *UserServiceClient) ValidateUserID(userID string) (*UserValidationRespon
 
 	var validationResp UserValidationResponse
 	if err := json.NewDecoder(resp.Body).Decode(&validationResp); err != nil {
-		utils.Sugar.Errorf("Failed to decode user service response: %v", err)
+		c.log(ctx).Errorf("Failed to decode user service response: %v", err)
 		return nil, &errors.TaskManagerError{
 			Message:      "Invalid response from user service",
 			ResponseCode: http.StatusBadGateway,
 		}
 	}
 
-	utils.Sugar.Infof("User validation result for ID %d: valid=%t", userID, validationResp.Valid)
+	c.log(ctx).Infof("User validation result for ID %s: valid=%t", userID, validationResp.Valid)
 	return &validationResp, nil
 }
 
-func (c *UserServiceClient) CheckUserExists(userID string) (bool, *errors.TaskManagerError) {
-	//_, err := c.ValidateUserID(userID)
-	//if err != nil {
-	//	return false, err
-	//}
-	return true, nil
+// Ping checks that the user service answers without a server error. It fails while the
+// circuit breaker is open, since calls are refused then anyway.
+func (c *UserServiceClient) Ping(ctx context.Context) error {
+	if c.httpClient.State() == resilientClient.StateOpen {
+		return resilientClient.ErrCircuitOpen
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Probe(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("user service returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type batchValidationRequest struct {
+	UserIDs []string `json:"user_ids"`
+}
+
+type batchValidationResponse struct {
+	Users []UserValidationResponse `json:"users"`
+}
+
+// ValidateUserIDs validates many users with one POST /api/users/validate call. If the user
+// service does not implement the batch endpoint it falls back to single calls with bounded
+// concurrency, and keeps doing so for a while before trying the batch endpoint again.
+// Every requested ID is present in the result unless its lookup failed, in which case the
+// error describes the first failure.
+func (c *UserServiceClient) ValidateUserIDs(ctx context.Context, userIDs []string) (results map[string]*UserValidationResponse, taskErr *errors.TaskManagerError) {
+	if len(userIDs) == 0 {
+		return map[string]*UserValidationResponse{}, nil
+	}
+	ctx, span := tracing.Start(ctx, "UserServiceClient.ValidateUserIDs", attribute.Int("user_ids", len(userIDs)))
+	defer func() { tracing.End(span, taskErr) }()
+
+	if c.now().UnixNano() >= c.batchUnsupportedUntil.Load() {
+		results, batchErr, supported := c.validateBatch(ctx, userIDs)
+		if supported {
+			return results, batchErr
+		}
+		c.log(ctx).Warnf("User service does not support batch validation, falling back to single calls for %s", c.batchReprobe)
+		c.batchUnsupportedUntil.Store(c.now().Add(c.batchReprobe).UnixNano())
+	}
+	return c.validateEach(ctx, userIDs)
+}
+
+// validateBatch returns supported=false when the remote does not know the batch endpoint
+func (c *UserServiceClient) validateBatch(ctx context.Context, userIDs []string) (map[string]*UserValidationResponse, *errors.TaskManagerError, bool) {
+	payload, err := json.Marshal(batchValidationRequest{UserIDs: userIDs})
+	if err != nil {
+		return nil, &errors.TaskManagerError{
+			Message:      "Failed to create user validation request",
+			ResponseCode: http.StatusInternalServerError,
+		}, true
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/users/validate", bytes.NewReader(payload))
+	if err != nil {
+		c.log(ctx).Errorf("Failed to create request: %v", err)
+		return nil, &errors.TaskManagerError{
+			Message:      "Failed to create user validation request",
+			ResponseCode: http.StatusInternalServerError,
+		}, true
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TaskManager/1.0")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.log(ctx).Errorf("Failed to call user service: %v", err)
+		return nil, &errors.TaskManagerError{
+			Message:      "User service is unavailable",
+			ResponseCode: http.StatusServiceUnavailable,
+		}, true
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return nil, nil, false
+	case http.StatusOK:
+	default:
+		c.log(ctx).Errorf("User service returned status: %d", resp.StatusCode)
+		return nil, &errors.TaskManagerError{
+			Message:      fmt.Sprintf("User service error: %d", resp.StatusCode),
+			ResponseCode: http.StatusBadGateway,
+		}, true
+	}
+
+	var batchResp batchValidationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&batchResp); err != nil {
+		c.log(ctx).Errorf("Failed to decode user service response: %v", err)
+		return nil, &errors.TaskManagerError{
+			Message:      "Invalid response from user service",
+			ResponseCode: http.StatusBadGateway,
+		}, true
+	}
+
+	results := make(map[string]*UserValidationResponse, len(userIDs))
+	for i := range batchResp.Users {
+		results[batchResp.Users[i].UserID] = &batchResp.Users[i]
+	}
+	// IDs the user service left out of its answer do not exist
+	for _, userID := range userIDs {
+		if _, ok := results[userID]; !ok {
+			results[userID] = &UserValidationResponse{Valid: false, UserID: userID}
+		}
+	}
+	return results, nil, true
+}
+
+func (c *UserServiceClient) validateEach(ctx context.Context, userIDs []string) (map[string]*UserValidationResponse, *errors.TaskManagerError) {
+	results := make(map[string]*UserValidationResponse, len(userIDs))
+	var firstErr *errors.TaskManagerError
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, maxConcurrentSingleCalls)
+
+	for _, userID := range userIDs {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(userID string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			result, taskErr := c.ValidateUserID(ctx, userID)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if taskErr != nil {
+				if firstErr == nil {
+					firstErr = taskErr
+				}
+				return
+			}
+			results[userID] = result
+		}(userID)
+	}
+	wg.Wait()
+
+	return results, firstErr
+}
+
+// log returns the logger of the request ctx serves, or the logger of the client
+func (c *UserServiceClient) log(ctx context.Context) *zap.SugaredLogger {
+	return utils.LoggerOr(ctx, c.logger)
 }
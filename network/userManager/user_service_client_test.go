@@ -0,0 +1,170 @@
+package userManager
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"task-manager-app/network/resilientClient"
+	"task-manager-app/network/userManager/userManagerFake"
+	"task-manager-app/tracing"
+	"task-manager-app/utils"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	utils.Sugar = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
+
+func newTestClient(fake *userManagerFake.FakeUserService, timeout time.Duration) *UserServiceClient {
+	return NewUserServiceClient(fake.URL(), resilientClient.Options{Name: "user_service_test", Timeout: timeout})
+}
+
+func TestValidateUserIDFound(t *testing.T) {
+	fake := userManagerFake.NewFakeUserService(userManagerFake.User{UserID: "u1", Name: "Ada", Email: "ada@example.com"})
+	defer fake.Close()
+
+	resp, taskErr := newTestClient(fake, time.Second).ValidateUserID(context.Background(), "u1")
+	if taskErr != nil {
+		t.Fatalf("ValidateUserID returned error %+v", taskErr)
+	}
+	if !resp.Valid || resp.UserID != "u1" || resp.Name != "Ada" || resp.Email != "ada@example.com" {
+		t.Fatalf("ValidateUserID = %+v, want valid u1 with profile", resp)
+	}
+}
+
+func TestValidateUserIDNotFound(t *testing.T) {
+	fake := userManagerFake.NewFakeUserService()
+	defer fake.Close()
+
+	resp, taskErr := newTestClient(fake, time.Second).ValidateUserID(context.Background(), "missing")
+	if taskErr != nil {
+		t.Fatalf("a 404 must be an answer, not an error: %+v", taskErr)
+	}
+	if resp.Valid || resp.UserID != "missing" {
+		t.Fatalf("ValidateUserID = %+v, want invalid", resp)
+	}
+}
+
+func TestValidateUserIDServerError(t *testing.T) {
+	// A 400 means the request was not understood, not that the user is unknown
+	for _, status := range []int{http.StatusInternalServerError, http.StatusBadRequest} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			fake := userManagerFake.NewFakeUserService(userManagerFake.User{UserID: "u1"})
+			defer fake.Close()
+			fake.SetFailure(status)
+
+			resp, taskErr := newTestClient(fake, time.Second).ValidateUserID(context.Background(), "u1")
+			if taskErr == nil {
+				t.Fatalf("ValidateUserID = %+v, want an error", resp)
+			}
+			if taskErr.ResponseCode < http.StatusInternalServerError {
+				t.Fatalf("ResponseCode = %d, want 5xx so callers can tell an outage from an invalid user", taskErr.ResponseCode)
+			}
+		})
+	}
+}
+
+func TestValidateUserIDTimeout(t *testing.T) {
+	fake := userManagerFake.NewFakeUserService(userManagerFake.User{UserID: "u1"})
+	defer fake.Close()
+	fake.SetLatency(200 * time.Millisecond)
+
+	resp, taskErr := newTestClient(fake, 20*time.Millisecond).ValidateUserID(context.Background(), "u1")
+	if taskErr == nil {
+		t.Fatalf("ValidateUserID = %+v, want an error", resp)
+	}
+	if taskErr.ResponseCode != http.StatusServiceUnavailable {
+		t.Fatalf("ResponseCode = %d, want %d", taskErr.ResponseCode, http.StatusServiceUnavailable)
+	}
+}
+
+func TestValidateUserIDsReprobesBatchEndpoint(t *testing.T) {
+	fake := userManagerFake.NewFakeUserService(userManagerFake.User{UserID: "u1"}, userManagerFake.User{UserID: "u2"})
+	defer fake.Close()
+	fake.SetBatchSupported(false)
+	client := newTestClient(fake, time.Second)
+	client.batchReprobe = 50 * time.Millisecond
+
+	results, taskErr := client.ValidateUserIDs(context.Background(), []string{"u1", "u2", "u3"})
+	if taskErr != nil {
+		t.Fatalf("ValidateUserIDs returned error %+v", taskErr)
+	}
+	if !results["u1"].Valid || !results["u2"].Valid || results["u3"].Valid {
+		t.Fatalf("fallback results = %+v, want u1 and u2 valid", results)
+	}
+	// One rejected batch call and three single calls
+	if calls := fake.Calls(); calls != 4 {
+		t.Fatalf("calls = %d, want 4", calls)
+	}
+
+	fake.SetBatchSupported(true)
+	if _, taskErr := client.ValidateUserIDs(context.Background(), []string{"u1", "u2"}); taskErr != nil {
+		t.Fatalf("ValidateUserIDs returned error %+v", taskErr)
+	}
+	if calls := fake.Calls(); calls != 6 {
+		t.Fatalf("calls = %d, want single calls until the batch endpoint is probed again", calls)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, taskErr := client.ValidateUserIDs(context.Background(), []string{"u1", "u2"}); taskErr != nil {
+		t.Fatalf("ValidateUserIDs returned error %+v", taskErr)
+	}
+	if calls := fake.Calls(); calls != 7 {
+		t.Fatalf("calls = %d, want one batch call after the re-probe interval", calls)
+	}
+}
+
+func TestValidateUserIDPropagatesTraceContext(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewTracerProvider(sdktrace.NewSimpleSpanProcessor(exporter), tracing.Options{ServiceName: "task-manager-test"})
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(previous)
+
+	fake := userManagerFake.NewFakeUserService(userManagerFake.User{UserID: "u1"})
+	defer fake.Close()
+
+	ctx, parent := tracing.Start(context.Background(), "TaskService.CreateTask")
+	if _, taskErr := newTestClient(fake, time.Second).ValidateUserID(ctx, "u1"); taskErr != nil {
+		t.Fatalf("ValidateUserID returned error %+v", taskErr)
+	}
+	tracing.End(parent, nil)
+
+	traceID := parent.SpanContext().TraceID().String()
+	if traceparent := fake.LastTraceparent(); !strings.Contains(traceparent, traceID) {
+		t.Fatalf("user service got traceparent %q, want one in trace %s", traceparent, traceID)
+	}
+	names := make(map[string]bool)
+	for _, span := range exporter.GetSpans() {
+		if span.SpanContext.TraceID().String() != traceID {
+			t.Errorf("span %q is in another trace", span.Name)
+		}
+		names[span.Name] = true
+	}
+	if !names["UserServiceClient.ValidateUserID"] {
+		t.Fatalf("spans %v have no UserServiceClient.ValidateUserID span", names)
+	}
+}
+
+func TestPingReportsUnreachableUserService(t *testing.T) {
+	fake := userManagerFake.NewFakeUserService()
+	client := newTestClient(fake, time.Second)
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping of a running user service failed: %v", err)
+	}
+
+	fake.Close()
+	if err := client.Ping(context.Background()); err == nil {
+		t.Fatal("Ping of a stopped user service succeeded")
+	}
+}
@@ -0,0 +1,28 @@
+package repo
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// errRollback aborts a gorm transaction whose failure is reported through a TaskManagerError
+var errRollback = errors.New("rollback")
+
+const (
+	pgUniqueViolation = "23505"
+
+	// UserTitleUniqueIndex enforces one task title per user
+	UserTitleUniqueIndex = "idx_tasks_user_title_key"
+)
+
+// uniqueViolation reports whether err is a unique constraint violation and, when the
+// driver exposes it, the name of the violated constraint
+func uniqueViolation(err error) (bool, string) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgUniqueViolation, pgErr.ConstraintName
+	}
+	return errors.Is(err, gorm.ErrDuplicatedKey), ""
+}
@@ -0,0 +1,103 @@
+package repo
+
+import (
+	"task-manager-app/constants"
+	"task-manager-app/exceptions"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository interface {
+	Reserve(record *models.IdempotencyKey) (bool, *errors.TaskManagerError)
+	TakeOver(record *models.IdempotencyKey, now time.Time) (bool, *errors.TaskManagerError)
+	GetByKey(scope, key string) (*models.IdempotencyKey, *errors.TaskManagerError)
+	Complete(record *models.IdempotencyKey, responseCode int, responseBody []byte, expiresAt time.Time) (bool, *errors.TaskManagerError)
+	Release(record *models.IdempotencyKey) *errors.TaskManagerError
+	DeleteExpired(before time.Time) *errors.TaskManagerError
+}
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// Reserve inserts the key if it is not present yet and reports whether this call owns it
+func (r *idempotencyRepository) Reserve(record *models.IdempotencyKey) (bool, *errors.TaskManagerError) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return false, exceptions.InternalServerException(constants.ErrFailedToSaveIdempotent + ": " + result.Error.Error())
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// TakeOver reserves a stored key again for record once its lease or replay window has ended
+// at now. Only one of several concurrent callers succeeds.
+func (r *idempotencyRepository) TakeOver(record *models.IdempotencyKey, now time.Time) (bool, *errors.TaskManagerError) {
+	result := r.db.Model(&models.IdempotencyKey{}).
+		Where("scope = ? AND key = ? AND expires_at <= ?", record.Scope, record.Key, now).
+		Updates(map[string]interface{}{
+			"request_hash":  record.RequestHash,
+			"lease_token":   record.LeaseToken,
+			"response_code": 0,
+			"response_body": nil,
+			"expires_at":    record.ExpiresAt,
+		})
+	if result.Error != nil {
+		return false, exceptions.InternalServerException(constants.ErrFailedToSaveIdempotent + ": " + result.Error.Error())
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// GetByKey finds a stored idempotency key
+func (r *idempotencyRepository) GetByKey(scope, key string) (*models.IdempotencyKey, *errors.TaskManagerError) {
+	var record models.IdempotencyKey
+	result := r.db.Where("scope = ? AND key = ?", scope, key).First(&record)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, exceptions.InternalServerException(constants.ErrFailedToGetIdempotent + ": " + result.Error.Error())
+	}
+	return &record, nil
+}
+
+// Complete records the response that will be replayed for the key until expiresAt. It
+// reports false when the reservation of record no longer holds the key.
+func (r *idempotencyRepository) Complete(record *models.IdempotencyKey, responseCode int, responseBody []byte, expiresAt time.Time) (bool, *errors.TaskManagerError) {
+	result := r.db.Model(&models.IdempotencyKey{}).
+		Where("scope = ? AND key = ? AND lease_token = ? AND response_code = 0", record.Scope, record.Key, record.LeaseToken).
+		Updates(map[string]interface{}{
+			"response_code": responseCode,
+			"response_body": responseBody,
+			"expires_at":    expiresAt,
+		})
+	if result.Error != nil {
+		return false, exceptions.InternalServerException(constants.ErrFailedToSaveIdempotent + ": " + result.Error.Error())
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Release removes the reservation of record so that the key can be reserved again
+func (r *idempotencyRepository) Release(record *models.IdempotencyKey) *errors.TaskManagerError {
+	err := r.db.Where("scope = ? AND key = ? AND lease_token = ? AND response_code = 0", record.Scope, record.Key, record.LeaseToken).
+		Delete(&models.IdempotencyKey{}).Error
+	if err != nil {
+		return exceptions.InternalServerException(constants.ErrFailedToSaveIdempotent + ": " + err.Error())
+	}
+	return nil
+}
+
+// DeleteExpired purges keys whose replay window or lease has passed
+func (r *idempotencyRepository) DeleteExpired(before time.Time) *errors.TaskManagerError {
+	if err := r.db.Where("expires_at < ?", before).Delete(&models.IdempotencyKey{}).Error; err != nil {
+		return exceptions.InternalServerException(constants.ErrFailedToSaveIdempotent + ": " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package repo
+
+import (
+	"task-manager-app/constants"
+	"task-manager-app/exceptions"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InboxRepository interface {
+	// MarkProcessed records eventID and reports whether this call recorded it; false means the
+	// event was handled before. Call it on the transaction that applies the event.
+	MarkProcessed(eventID, eventType string, processedAt time.Time) (bool, *errors.TaskManagerError)
+	// Processed reports whether eventID was recorded by MarkProcessed
+	Processed(eventID string) (bool, *errors.TaskManagerError)
+	DeleteProcessed(before time.Time) *errors.TaskManagerError
+	// WithinTransaction runs fn with repositories bound to one database transaction, so a
+	// consumed event is recorded together with the task changes and events it caused
+	WithinTransaction(fn func(inbox InboxRepository, tasks TaskRepository, outbox OutboxRepository) *errors.TaskManagerError) *errors.TaskManagerError
+}
+
+type inboxRepository struct {
+	db *gorm.DB
+}
+
+func NewInboxRepository(db *gorm.DB) InboxRepository {
+	return &inboxRepository{db: db}
+}
+
+func (r *inboxRepository) MarkProcessed(eventID, eventType string, processedAt time.Time) (bool, *errors.TaskManagerError) {
+	record := &models.InboxEvent{EventID: eventID, EventType: eventType, ProcessedAt: processedAt}
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return false, exceptions.InternalServerException(constants.ErrFailedToSaveInbox + ": " + result.Error.Error())
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *inboxRepository) Processed(eventID string) (bool, *errors.TaskManagerError) {
+	var count int64
+	if err := r.db.Model(&models.InboxEvent{}).Where("event_id = ?", eventID).Count(&count).Error; err != nil {
+		return false, exceptions.InternalServerException(constants.ErrFailedToGetInbox + ": " + err.Error())
+	}
+	return count > 0, nil
+}
+
+func (r *inboxRepository) DeleteProcessed(before time.Time) *errors.TaskManagerError {
+	if err := r.db.Where("processed_at < ?", before).Delete(&models.InboxEvent{}).Error; err != nil {
+		return exceptions.InternalServerException(constants.ErrFailedToSaveInbox + ": " + err.Error())
+	}
+	return nil
+}
+
+func (r *inboxRepository) WithinTransaction(fn func(inbox InboxRepository, tasks TaskRepository, outbox OutboxRepository) *errors.TaskManagerError) *errors.TaskManagerError {
+	var fnErr *errors.TaskManagerError
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if fnErr = fn(&inboxRepository{db: tx}, &taskRepository{db: tx}, NewOutboxRepository(tx)); fnErr != nil {
+			return errRollback
+		}
+		return nil
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return exceptions.InternalServerException(constants.ErrFailedToCommitTx + ": " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package repo
+
+import (
+	"encoding/json"
+	"sort"
+	"task-manager-app/constants"
+	"task-manager-app/events"
+	"task-manager-app/exceptions"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// outboxRelayLockKey is the advisory lock that makes relays claim events one at a time
+const outboxRelayLockKey = 7_301_001
+
+type OutboxRepository interface {
+	Add(taskEvents ...*events.TaskEvent) *errors.TaskManagerError
+	// ClaimDue leases up to limit events that are due at now until leaseUntil under token and
+	// returns them in id order once the lease is committed. An event is left out while an
+	// earlier event of its task is leased or waiting for a retry, so a task's events are
+	// published in order. Nothing is returned while another relay is claiming.
+	ClaimDue(limit int, now, leaseUntil time.Time, token string) ([]models.OutboxEvent, *errors.TaskManagerError)
+	// Release ends the lease of claimed events that were not attempted
+	Release(ids []uint64, token string, now time.Time) *errors.TaskManagerError
+	// The Mark methods settle an attempt of an event still claimed under token and report
+	// whether it was. False means the lease ran out and another relay claimed the event.
+	MarkDelivered(id uint64, token string) (bool, *errors.TaskManagerError)
+	MarkFailed(id uint64, token, lastError string, nextAttemptAt time.Time) (bool, *errors.TaskManagerError)
+	// MarkDeadLettered stops retrying an event; later events of its task are published
+	// without it
+	MarkDeadLettered(id uint64, token, lastError string, failedAt time.Time) (bool, *errors.TaskManagerError)
+	Stats() (pending int64, oldest *time.Time, taskErr *errors.TaskManagerError)
+	DeleteDelivered(before time.Time) *errors.TaskManagerError
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// Add stores events for later publication and moves their tasks to the head of the change
+// feed. Call it on the transaction of the change, after the task was written.
+func (r *outboxRepository) Add(taskEvents ...*events.TaskEvent) *errors.TaskManagerError {
+	if len(taskEvents) == 0 {
+		return nil
+	}
+	rows := make([]models.OutboxEvent, len(taskEvents))
+	for i, event := range taskEvents {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return exceptions.InternalServerException(constants.ErrFailedToSaveOutbox + ": " + err.Error())
+		}
+		rows[i] = models.OutboxEvent{
+			AggregateID:   event.TaskUUID,
+			EventType:     event.EventType,
+			Payload:       payload,
+			NextAttemptAt: event.OccurredAt,
+		}
+	}
+	if err := r.db.Create(&rows).Error; err != nil {
+		return exceptions.InternalServerException(constants.ErrFailedToSaveOutbox + ": " + err.Error())
+	}
+	return recordTaskChanges(r.db, taskEvents)
+}
+
+func (r *outboxRepository) ClaimDue(limit int, now, leaseUntil time.Time, token string) ([]models.OutboxEvent, *errors.TaskManagerError) {
+	var batch []models.OutboxEvent
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Claims must not interleave, or two relays could each take one event of a task
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", outboxRelayLockKey).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+		return tx.Raw(`
+			UPDATE outbox_events SET next_attempt_at = ?, lease_token = ?
+			WHERE id IN (
+				SELECT e.id FROM outbox_events e
+				WHERE e.delivered_at IS NULL AND e.failed_at IS NULL AND e.next_attempt_at <= ?
+				AND NOT EXISTS (
+					SELECT 1 FROM outbox_events earlier
+					WHERE earlier.aggregate_id = e.aggregate_id AND earlier.id < e.id
+					AND earlier.delivered_at IS NULL AND earlier.failed_at IS NULL
+					AND earlier.next_attempt_at > ?
+				)
+				ORDER BY e.id
+				LIMIT ?
+			)
+			RETURNING *`, leaseUntil, token, now, now, limit).Scan(&batch).Error
+	})
+	if err != nil {
+		return nil, exceptions.InternalServerException(constants.ErrFailedToGetOutbox + ": " + err.Error())
+	}
+	sort.Slice(batch, func(i, j int) bool { return batch[i].ID < batch[j].ID })
+	return batch, nil
+}
+
+func (r *outboxRepository) Release(ids []uint64, token string, now time.Time) *errors.TaskManagerError {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := r.db.Model(&models.OutboxEvent{}).Where("id IN ? AND lease_token = ?", ids, token).Update("next_attempt_at", now).Error; err != nil {
+		return exceptions.InternalServerException(constants.ErrFailedToSaveOutbox + ": " + err.Error())
+	}
+	return nil
+}
+
+func (r *outboxRepository) MarkDelivered(id uint64, token string) (bool, *errors.TaskManagerError) {
+	return r.settle(id, token, map[string]interface{}{
+		"delivered_at": r.db.NowFunc(),
+		"attempts":     gorm.Expr("attempts + 1"),
+		"last_error":   "",
+	})
+}
+
+func (r *outboxRepository) MarkFailed(id uint64, token, lastError string, nextAttemptAt time.Time) (bool, *errors.TaskManagerError) {
+	return r.settle(id, token, map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      lastError,
+		"next_attempt_at": nextAttemptAt,
+	})
+}
+
+func (r *outboxRepository) MarkDeadLettered(id uint64, token, lastError string, failedAt time.Time) (bool, *errors.TaskManagerError) {
+	return r.settle(id, token, map[string]interface{}{
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": lastError,
+		"failed_at":  failedAt,
+	})
+}
+
+// settle applies updates to event id if it is still claimed under token
+func (r *outboxRepository) settle(id uint64, token string, updates map[string]interface{}) (bool, *errors.TaskManagerError) {
+	result := r.db.Model(&models.OutboxEvent{}).Where("id = ? AND lease_token = ?", id, token).Updates(updates)
+	if result.Error != nil {
+		return false, exceptions.InternalServerException(constants.ErrFailedToSaveOutbox + ": " + result.Error.Error())
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Stats returns the number of events still to be delivered, not counting dead-lettered ones,
+// and the creation time of the oldest one
+func (r *outboxRepository) Stats() (int64, *time.Time, *errors.TaskManagerError) {
+	var result struct {
+		Pending int64
+		Oldest  *time.Time
+	}
+	err := r.db.Model(&models.OutboxEvent{}).
+		Select("COUNT(*) AS pending, MIN(created_at) AS oldest").
+		Where("delivered_at IS NULL AND failed_at IS NULL").
+		Scan(&result).Error
+	if err != nil {
+		return 0, nil, exceptions.InternalServerException(constants.ErrFailedToGetOutbox + ": " + err.Error())
+	}
+	return result.Pending, result.Oldest, nil
+}
+
+// DeleteDelivered purges events delivered before the given time
+func (r *outboxRepository) DeleteDelivered(before time.Time) *errors.TaskManagerError {
+	if err := r.db.Where("delivered_at < ?", before).Delete(&models.OutboxEvent{}).Error; err != nil {
+		return exceptions.InternalServerException(constants.ErrFailedToSaveOutbox + ": " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package repo
+
+import (
+	"encoding/json"
+	"task-manager-app/constants"
+	"task-manager-app/events"
+	"task-manager-app/exceptions"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TaskChangeRepository interface {
+	// ListSince returns up to limit changes after since in sequence order
+	ListSince(since uint64, limit int) ([]models.TaskChange, *errors.TaskManagerError)
+}
+
+type taskChangeRepository struct {
+	db *gorm.DB
+}
+
+func NewTaskChangeRepository(db *gorm.DB) TaskChangeRepository {
+	return &taskChangeRepository{db: db}
+}
+
+func (r *taskChangeRepository) ListSince(since uint64, limit int) ([]models.TaskChange, *errors.TaskManagerError) {
+	var changes []models.TaskChange
+	if err := r.db.Where("seq > ?", since).Order("seq").Limit(limit).Find(&changes).Error; err != nil {
+		return nil, exceptions.InternalServerException(constants.ErrFailedToGetChanges + ": " + err.Error())
+	}
+	return changes, nil
+}
+
+// recordTaskChanges moves the tasks of taskEvents to the head of the change feed. It takes the
+// sequence numbers from the single task_change_sequence row, whose lock is held until the
+// transaction commits, so changes become visible in sequence order and a client reading the
+// feed never skips one that commits late. Call it last in the transaction of the change.
+//
+// The row lock makes every transaction that changes tasks wait for the one before it to
+// commit, from this statement on, so task writes are serialised for their last statement and
+// commit. A Postgres SEQUENCE would not block, but hands out numbers that commit out of order,
+// and the reads would then have to hold back numbers of transactions still in flight, which
+// the transaction IDs of the rows cannot tell reliably. The lock was kept as the simpler of
+// the two while task writes stay in the hundreds per second.
+func recordTaskChanges(db *gorm.DB, taskEvents []*events.TaskEvent) *errors.TaskManagerError {
+	latest := latestEventPerTask(taskEvents)
+	if len(latest) == 0 {
+		return nil
+	}
+
+	var last uint64
+	err := db.Raw("UPDATE task_change_sequence SET value = value + ? WHERE id = 1 RETURNING value", len(latest)).Scan(&last).Error
+	if err != nil {
+		return exceptions.InternalServerException(constants.ErrFailedToSaveChange + ": " + err.Error())
+	}
+	rows, taskErr := taskChangeRows(latest, last)
+	if taskErr != nil {
+		return taskErr
+	}
+	err = db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "task_uuid"}},
+		UpdateAll: true,
+	}).Create(&rows).Error
+	if err != nil {
+		return exceptions.InternalServerException(constants.ErrFailedToSaveChange + ": " + err.Error())
+	}
+	return nil
+}
+
+// latestEventPerTask keeps the last event of every task, in the order of those events. An
+// update produces several events for a task, and one statement cannot upsert a row twice.
+func latestEventPerTask(taskEvents []*events.TaskEvent) []*events.TaskEvent {
+	seen := make(map[string]bool)
+	var latest []*events.TaskEvent
+	for i := len(taskEvents) - 1; i >= 0; i-- {
+		if !seen[taskEvents[i].TaskUUID] {
+			seen[taskEvents[i].TaskUUID] = true
+			latest = append(latest, taskEvents[i])
+		}
+	}
+	for i, j := 0, len(latest)-1; i < j; i, j = i+1, j-1 {
+		latest[i], latest[j] = latest[j], latest[i]
+	}
+	return latest
+}
+
+// taskChangeRows numbers the changes of latest so that the last one gets the sequence number last
+func taskChangeRows(latest []*events.TaskEvent, last uint64) ([]models.TaskChange, *errors.TaskManagerError) {
+	rows := make([]models.TaskChange, len(latest))
+	first := last - uint64(len(latest)) + 1
+	for i, event := range latest {
+		rows[i] = models.TaskChange{
+			TaskUUID:  event.TaskUUID,
+			Seq:       first + uint64(i),
+			EventType: event.EventType,
+			Deleted:   event.EventType == events.TaskDeleted,
+			ChangedAt: event.OccurredAt,
+		}
+		if !rows[i].Deleted {
+			task, err := json.Marshal(event.Task)
+			if err != nil {
+				return nil, exceptions.InternalServerException(constants.ErrFailedToSaveChange + ": " + err.Error())
+			}
+			rows[i].Task = task
+		}
+	}
+	return rows, nil
+}
@@ -0,0 +1,31 @@
+package repo
+
+import (
+	"task-manager-app/events"
+	"task-manager-app/models"
+	"testing"
+	"time"
+)
+
+func TestTaskChangeRowsKeepTheLastChangeOfEachTask(t *testing.T) {
+	task := &models.Task{UUID: "a", Title: "Write docs", Status: "Pending", Priority: "High"}
+	done := *task
+	done.Status = "Completed"
+	other := &models.Task{UUID: "b", Title: "Ship", Status: "Pending", Priority: "Low"}
+
+	latest := latestEventPerTask(append(events.NewTaskUpdated(task, &done, time.Now()), events.NewTaskDeleted(other, time.Now())))
+	rows, taskErr := taskChangeRows(latest, 12)
+	if taskErr != nil {
+		t.Fatalf("taskChangeRows returned error %v", taskErr)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want one per task", len(rows))
+	}
+	if rows[0].TaskUUID != "a" || rows[0].Seq != 11 || rows[0].EventType != events.TaskStatusChanged || rows[0].Deleted || len(rows[0].Task) == 0 {
+		t.Errorf("first row = %+v, want the status change of a at 11", rows[0])
+	}
+	if rows[1].TaskUUID != "b" || rows[1].Seq != 12 || !rows[1].Deleted || rows[1].Task != nil {
+		t.Errorf("second row = %+v, want the tombstone of b at 12", rows[1])
+	}
+}
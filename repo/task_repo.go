@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"context"
 	"sync"
 	"task-manager-app/constants"
 	"task-manager-app/exceptions"
@@ -8,6 +9,7 @@ import (
 	"task-manager-app/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TaskRepository interface {
@@ -16,8 +18,21 @@ type TaskRepository interface {
 	GetByUUIDForUpdate(uuid string) (*models.Task, *errors.TaskManagerError)
 	Update(task *models.Task) *errors.TaskManagerError
 	Delete(uuid string) *errors.TaskManagerError
-	List(status string, userID string, priority string, limit, offset int) ([]models.Task, *errors.TaskManagerError)
-	ExistsByTitleAndUser(title string, userID string) (bool, *errors.TaskManagerError)
+	List(status string, userIDs []string, priority string, limit, offset int) ([]models.Task, *errors.TaskManagerError)
+	ListByUserForUpdate(userID string, afterID uint, limit int) ([]models.Task, *errors.TaskManagerError)
+	ExistsByTitleAndUser(titleKey string, userID string, excludeUUID string) (bool, *errors.TaskManagerError)
+	CountByStatusAndPriority() ([]TaskCount, *errors.TaskManagerError)
+	WithinTransaction(fn func(tasks TaskRepository, outbox OutboxRepository) *errors.TaskManagerError) *errors.TaskManagerError
+	// WithContext returns the repository running its queries with ctx, which carries the
+	// request deadline and the trace of the queries
+	WithContext(ctx context.Context) TaskRepository
+}
+
+// TaskCount is the number of tasks with a status and priority
+type TaskCount struct {
+	Status   string
+	Priority string
+	Count    int64
 }
 
 type taskRepository struct {
@@ -34,7 +49,7 @@ func (r *taskRepository) Create(task *models.Task) *errors.TaskManagerError {
 	defer r.mutex.Unlock()
 
 	if err := r.db.Create(task).Error; err != nil {
-		return exceptions.InternalServerException(constants.ErrFailedToCreateTask + ": " + err.Error())
+		return translateWriteError(err, constants.ErrFailedToCreateTask)
 	}
 	return nil
 }
@@ -61,7 +76,7 @@ func (r *taskRepository) GetByUUIDForUpdate(uuid string) (*models.Task, *errors.
 	defer r.mutex.Unlock()
 
 	var task models.Task
-	result := r.db.Set("gorm:query_option", "FOR UPDATE").Where("uuid = ?", uuid).First(&task)
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("uuid = ?", uuid).First(&task)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -77,7 +92,7 @@ func (r *taskRepository) Update(task *models.Task) *errors.TaskManagerError {
 	defer r.mutex.Unlock()
 
 	if err := r.db.Save(task).Error; err != nil {
-		return exceptions.InternalServerException(constants.ErrFailedToUpdateTask + ": " + err.Error())
+		return translateWriteError(err, constants.ErrFailedToUpdateTask)
 	}
 	return nil
 }
@@ -93,8 +108,8 @@ func (r *taskRepository) Delete(uuid string) *errors.TaskManagerError {
 	return nil
 }
 
-// List fetches tasks with optional status, user_id, and priority filters + pagination
-func (r *taskRepository) List(status string, userID string, priority string, limit, offset int) ([]models.Task, *errors.TaskManagerError) {
+// List fetches tasks with optional status, user_id (any of userIDs), and priority filters + pagination
+func (r *taskRepository) List(status string, userIDs []string, priority string, limit, offset int) ([]models.Task, *errors.TaskManagerError) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
@@ -105,8 +120,8 @@ func (r *taskRepository) List(status string, userID string, priority string, lim
 		query = query.Where("status = ?", status)
 	}
 
-	if userID != "" {
-		query = query.Where("user_id = ?", userID)
+	if len(userIDs) > 0 {
+		query = query.Where("user_id IN ?", userIDs)
 	}
 
 	if priority != "" {
@@ -119,15 +134,80 @@ func (r *taskRepository) List(status string, userID string, priority string, lim
 	return tasks, nil
 }
 
-// ExistsByTitleAndUser checks if a task with the same title already exists for a user
-func (r *taskRepository) ExistsByTitleAndUser(title string, userID string) (bool, *errors.TaskManagerError) {
+// ListByUserForUpdate locks and returns up to limit tasks assigned to a user with an id above
+// afterID, oldest first
+func (r *taskRepository) ListByUserForUpdate(userID string, afterID uint, limit int) ([]models.Task, *errors.TaskManagerError) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var tasks []models.Task
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ? AND id > ?", userID, afterID).Order("id").Limit(limit).Find(&tasks).Error
+	if err != nil {
+		return nil, exceptions.InternalServerException(constants.ErrFailedToListTasks + ": " + err.Error())
+	}
+	return tasks, nil
+}
+
+// ExistsByTitleAndUser checks if another task with the same normalised title already exists for a user
+func (r *taskRepository) ExistsByTitleAndUser(titleKey string, userID string, excludeUUID string) (bool, *errors.TaskManagerError) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
 	var count int64
-	err := r.db.Model(&models.Task{}).Where("title = ? AND user_id = ?", title, userID).Count(&count).Error
+	query := r.db.Model(&models.Task{}).Where("title_key = ? AND user_id = ?", titleKey, userID)
+	if excludeUUID != "" {
+		query = query.Where("uuid <> ?", excludeUUID)
+	}
+	err := query.Count(&count).Error
 	if err != nil {
 		return false, exceptions.InternalServerException(constants.ErrFailedToGetTask + ": " + err.Error())
 	}
 	return count > 0, nil
 }
+
+// CountByStatusAndPriority counts the tasks of every status and priority that has any
+func (r *taskRepository) CountByStatusAndPriority() ([]TaskCount, *errors.TaskManagerError) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var counts []TaskCount
+	err := r.db.Model(&models.Task{}).Select("status, priority, COUNT(*) AS count").Group("status, priority").Scan(&counts).Error
+	if err != nil {
+		return nil, exceptions.InternalServerException(constants.ErrFailedToCountTasks + ": " + err.Error())
+	}
+	return counts, nil
+}
+
+func (r *taskRepository) WithContext(ctx context.Context) TaskRepository {
+	return &taskRepository{db: r.db.WithContext(ctx)}
+}
+
+// WithinTransaction runs fn with repositories bound to one database transaction, so task
+// changes and their outbox events are committed or rolled back together
+func (r *taskRepository) WithinTransaction(fn func(tasks TaskRepository, outbox OutboxRepository) *errors.TaskManagerError) *errors.TaskManagerError {
+	var fnErr *errors.TaskManagerError
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if fnErr = fn(&taskRepository{db: tx}, NewOutboxRepository(tx)); fnErr != nil {
+			return errRollback
+		}
+		return nil
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return exceptions.InternalServerException(constants.ErrFailedToCommitTx + ": " + err.Error())
+	}
+	return nil
+}
+
+// translateWriteError maps unique violations to 409 Conflict and everything else to 500
+func translateWriteError(err error, message string) *errors.TaskManagerError {
+	if isUnique, constraint := uniqueViolation(err); isUnique {
+		if constraint == UserTitleUniqueIndex || constraint == "" {
+			return exceptions.ConflictException(constants.ErrTaskAlreadyExists)
+		}
+		return exceptions.ConflictException(constants.ErrTaskConflict)
+	}
+	return exceptions.InternalServerException(message + ": " + err.Error())
+}
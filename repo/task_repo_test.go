@@ -0,0 +1,44 @@
+package repo
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"task-manager-app/constants"
+	"task-manager-app/utils"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	utils.Sugar = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
+
+func TestTranslateWriteErrorMapsUniqueViolations(t *testing.T) {
+	cases := []struct {
+		name    string
+		err     error
+		code    int
+		message string
+	}{
+		{"title index", &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: UserTitleUniqueIndex}, http.StatusConflict, constants.ErrTaskAlreadyExists},
+		{"wrapped title index", errors.Join(errors.New("insert"), &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: UserTitleUniqueIndex}), http.StatusConflict, constants.ErrTaskAlreadyExists},
+		{"other unique index", &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "idx_tasks_uuid"}, http.StatusConflict, constants.ErrTaskConflict},
+		{"translated by gorm", gorm.ErrDuplicatedKey, http.StatusConflict, constants.ErrTaskAlreadyExists},
+		{"other constraint", &pgconn.PgError{Code: "23503", ConstraintName: "fk_tasks_user"}, http.StatusInternalServerError, ""},
+		{"connection", errors.New("connection reset"), http.StatusInternalServerError, ""},
+	}
+	for _, tc := range cases {
+		taskErr := translateWriteError(tc.err, constants.ErrFailedToCreateTask)
+		if taskErr.ResponseCode != tc.code {
+			t.Errorf("%s: response code %d, want %d", tc.name, taskErr.ResponseCode, tc.code)
+		}
+		if tc.message != "" && taskErr.Message != tc.message {
+			t.Errorf("%s: message %q, want %q", tc.name, taskErr.Message, tc.message)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package repo
+
+import (
+	stdErrors "errors"
+	"fmt"
+	"sort"
+	"strings"
+	"task-manager-app/constants"
+	"task-manager-app/exceptions"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/models"
+	"task-manager-app/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// titleKeyRulesID is the id of the only row of title_key_rules
+	titleKeyRulesID = 1
+	// rekeyBatchSize bounds the tasks updated by one statement
+	rekeyBatchSize = 500
+	// maxReportedCollisions bounds the colliding titles listed in the error of Rekey
+	maxReportedCollisions = 20
+)
+
+var errTitleKeysCollide = stdErrors.New("title keys collide")
+
+type TitleKeyRepository interface {
+	Rekey(normalizer utils.TitleNormalizer) (int, *errors.TaskManagerError)
+}
+
+type titleKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewTitleKeyRepository(db *gorm.DB) TitleKeyRepository {
+	return &titleKeyRepository{db: db}
+}
+
+// titledTask is the part of a task its title key is derived from
+type titledTask struct {
+	ID       uint
+	UserID   *string
+	Title    string
+	TitleKey *string
+}
+
+// Rekey computes the title keys of all tasks with normalizer when they were computed with
+// other rules, and fills in missing keys, e.g. after the column was added. Keys are derived
+// by the same code as for new writes, so stored and new keys always agree. When titles of
+// one user would collide under the rules nothing is changed and the titles are reported.
+// It returns the number of tasks whose key changed.
+func (r *titleKeyRepository) Rekey(normalizer utils.TitleNormalizer) (int, *errors.TaskManagerError) {
+	var rekeyed int
+	var collisions []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Replicas starting together take turns; the later ones find the keys up to date
+		if err := tx.Exec("LOCK TABLE title_key_rules IN EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+		var rules []models.TitleKeyRules
+		if err := tx.Where("id = ?", titleKeyRulesID).Find(&rules).Error; err != nil {
+			return err
+		}
+		var unkeyed int64
+		if err := tx.Model(&models.Task{}).Where("title_key IS NULL").Count(&unkeyed).Error; err != nil {
+			return err
+		}
+		if unkeyed == 0 && len(rules) == 1 &&
+			rules[0].IgnoreCase == normalizer.CaseInsensitive && rules[0].CollapseWhitespace == normalizer.CollapseWhitespace {
+			return nil
+		}
+
+		// Keep task writes out until every key follows the rules
+		if err := tx.Exec("LOCK TABLE tasks IN EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+		var tasks []titledTask
+		if err := tx.Model(&models.Task{}).Select("id", "user_id", "title", "title_key").Find(&tasks).Error; err != nil {
+			return err
+		}
+		var keys map[uint]string
+		keys, collisions = planRekey(tasks, normalizer)
+		if len(collisions) > 0 {
+			return errTitleKeysCollide
+		}
+
+		ids := make([]uint, 0, len(keys))
+		for id := range keys {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		// Clear the changing keys first, so that no update meets a key that is about to change
+		for start := 0; start < len(ids); start += rekeyBatchSize {
+			batch := ids[start:min(start+rekeyBatchSize, len(ids))]
+			if err := tx.Model(&models.Task{}).Where("id IN ?", batch).UpdateColumn("title_key", nil).Error; err != nil {
+				return err
+			}
+		}
+		for start := 0; start < len(ids); start += rekeyBatchSize {
+			if err := setTitleKeys(tx, ids[start:min(start+rekeyBatchSize, len(ids))], keys); err != nil {
+				return err
+			}
+		}
+
+		rekeyed = len(ids)
+		return tx.Save(&models.TitleKeyRules{
+			ID:                 titleKeyRulesID,
+			IgnoreCase:         normalizer.CaseInsensitive,
+			CollapseWhitespace: normalizer.CollapseWhitespace,
+		}).Error
+	})
+	if stdErrors.Is(err, errTitleKeysCollide) {
+		if len(collisions) > maxReportedCollisions {
+			collisions = append(collisions[:maxReportedCollisions], fmt.Sprintf("and %d more", len(collisions)-maxReportedCollisions))
+		}
+		return 0, exceptions.ConflictException(constants.ErrTitleKeysCollide + ": " + strings.Join(collisions, "; "))
+	}
+	if err != nil {
+		return 0, exceptions.InternalServerException(constants.ErrFailedToRekeyTitles + ": " + err.Error())
+	}
+	return rekeyed, nil
+}
+
+// setTitleKeys stores the keys of the tasks with ids in one statement
+func setTitleKeys(tx *gorm.DB, ids []uint, keys map[uint]string) error {
+	values := make([]string, 0, len(ids))
+	args := make([]interface{}, 0, 2*len(ids))
+	for _, id := range ids {
+		values = append(values, "(?::integer, ?::varchar)")
+		args = append(args, id, keys[id])
+	}
+	return tx.Exec("UPDATE tasks SET title_key = v.key FROM (VALUES "+strings.Join(values, ", ")+") AS v(id, key) WHERE tasks.id = v.id", args...).Error
+}
+
+// planRekey returns the new key of every task whose key is missing or differs from the one
+// normalizer derives from its title, and the titles of one user that share a key
+func planRekey(tasks []titledTask, normalizer utils.TitleNormalizer) (map[uint]string, []string) {
+	keys := make(map[uint]string)
+	titles := make(map[[2]string][]string)
+	for _, task := range tasks {
+		key := normalizer.Normalize(task.Title)
+		if task.TitleKey == nil || *task.TitleKey != key {
+			keys[task.ID] = key
+		}
+		if task.UserID != nil {
+			owner := [2]string{*task.UserID, key}
+			titles[owner] = append(titles[owner], task.Title)
+		}
+	}
+
+	var collisions []string
+	for owner, same := range titles {
+		if len(same) > 1 {
+			collisions = append(collisions, fmt.Sprintf("user %s: %q", owner[0], same))
+		}
+	}
+	sort.Strings(collisions)
+	return keys, collisions
+}
@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"reflect"
+	"strings"
+	"task-manager-app/utils"
+	"testing"
+)
+
+func TestPlanRekeyUsesTheNormalizerOfNewWrites(t *testing.T) {
+	u1, u2 := "u1", "u2"
+	stale, current := "Plan  Sprint", "review"
+	tasks := []titledTask{
+		{ID: 1, UserID: &u1, Title: "Plan  Sprint", TitleKey: &stale},
+		{ID: 2, UserID: &u1, Title: "review", TitleKey: &current},
+		// Unicode case folding and whitespace that SQL lower() and \s may treat differently
+		{ID: 3, UserID: &u2, Title: "ÉTÉ Plan"},
+		{ID: 4, Title: "Plan sprint"},
+	}
+	normalizer := utils.TitleNormalizer{CaseInsensitive: true, CollapseWhitespace: true}
+
+	keys, collisions := planRekey(tasks, normalizer)
+	if len(collisions) > 0 {
+		t.Fatalf("collisions = %v, want none", collisions)
+	}
+	want := map[uint]string{
+		1: normalizer.Normalize("Plan  Sprint"),
+		3: normalizer.Normalize("ÉTÉ Plan"),
+		4: normalizer.Normalize("Plan sprint"),
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("keys = %v, want %v", keys, want)
+	}
+}
+
+func TestPlanRekeyReportsTitlesThatCollideUnderNewRules(t *testing.T) {
+	u1, u2 := "u1", "u2"
+	tasks := []titledTask{
+		{ID: 1, UserID: &u1, Title: "Buy milk"},
+		{ID: 2, UserID: &u1, Title: "buy  milk"},
+		{ID: 3, UserID: &u2, Title: "buy milk"},
+		{ID: 4, Title: "BUY MILK"},
+		{ID: 5, Title: "buy milk"},
+	}
+
+	_, collisions := planRekey(tasks, utils.TitleNormalizer{})
+	if len(collisions) > 0 {
+		t.Fatalf("exact rules: collisions = %v, want none", collisions)
+	}
+	_, collisions = planRekey(tasks, utils.TitleNormalizer{CaseInsensitive: true, CollapseWhitespace: true})
+	if len(collisions) != 1 || !strings.Contains(collisions[0], "user u1") ||
+		!strings.Contains(collisions[0], `"Buy milk"`) || !strings.Contains(collisions[0], `"buy  milk"`) {
+		t.Fatalf("collisions = %v, want only the two titles of u1", collisions)
+	}
+}
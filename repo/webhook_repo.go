@@ -0,0 +1,251 @@
+package repo
+
+import (
+	"task-manager-app/constants"
+	"task-manager-app/exceptions"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepository interface {
+	CreateSubscription(subscription *models.WebhookSubscription) *errors.TaskManagerError
+	GetSubscription(uuid string) (*models.WebhookSubscription, *errors.TaskManagerError)
+	ListSubscriptions() ([]models.WebhookSubscription, *errors.TaskManagerError)
+	ListActiveSubscriptions() ([]models.WebhookSubscription, *errors.TaskManagerError)
+	ListSubscriptionsByID(ids []uint) ([]models.WebhookSubscription, *errors.TaskManagerError)
+	UpdateSubscription(subscription *models.WebhookSubscription) *errors.TaskManagerError
+	// DeleteSubscription removes a subscription together with its delivery log
+	DeleteSubscription(id uint) *errors.TaskManagerError
+
+	// AddDeliveries stores pending deliveries, skipping events already queued for a subscription
+	AddDeliveries(deliveries []models.WebhookDelivery) *errors.TaskManagerError
+	CreateDelivery(delivery *models.WebhookDelivery) *errors.TaskManagerError
+	GetDelivery(subscriptionID uint, id uint64) (*models.WebhookDelivery, *errors.TaskManagerError)
+	ListDeliveries(subscriptionID uint, status string, limit, offset int) ([]models.WebhookDelivery, *errors.TaskManagerError)
+	// ClaimDue leases up to limit pending deliveries of active subscriptions that are due at
+	// now until leaseUntil. Deliveries claimed by another dispatcher are skipped.
+	ClaimDue(limit int, now, leaseUntil time.Time) ([]models.WebhookDelivery, *errors.TaskManagerError)
+	// RecordSuccess marks a delivery delivered and resets the failure count of its subscription
+	RecordSuccess(delivery *models.WebhookDelivery, statusCode int, deliveredAt time.Time) *errors.TaskManagerError
+	// RecordFailure counts a failed attempt on the delivery and its subscription. The delivery
+	// is retried at nextAttemptAt, or given up when nextAttemptAt is nil. The subscription is
+	// disabled once disableAfter consecutive attempts have failed; disabled reports whether
+	// this call disabled it.
+	RecordFailure(delivery *models.WebhookDelivery, statusCode int, lastError string, nextAttemptAt *time.Time, disableAfter int) (disabled bool, taskErr *errors.TaskManagerError)
+	// DeleteFinished removes delivered and failed deliveries last updated before before
+	DeleteFinished(before time.Time) *errors.TaskManagerError
+}
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+func (r *webhookRepository) CreateSubscription(subscription *models.WebhookSubscription) *errors.TaskManagerError {
+	if err := r.db.Create(subscription).Error; err != nil {
+		return exceptions.InternalServerException(constants.ErrFailedToSaveWebhook + ": " + err.Error())
+	}
+	return nil
+}
+
+// GetSubscription finds a subscription by its UUID
+func (r *webhookRepository) GetSubscription(uuid string) (*models.WebhookSubscription, *errors.TaskManagerError) {
+	var subscription models.WebhookSubscription
+	result := r.db.Where("uuid = ?", uuid).First(&subscription)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, exceptions.InternalServerException(constants.ErrFailedToGetWebhook + ": " + result.Error.Error())
+	}
+	return &subscription, nil
+}
+
+func (r *webhookRepository) ListSubscriptions() ([]models.WebhookSubscription, *errors.TaskManagerError) {
+	return r.findSubscriptions(r.db)
+}
+
+func (r *webhookRepository) ListActiveSubscriptions() ([]models.WebhookSubscription, *errors.TaskManagerError) {
+	return r.findSubscriptions(r.db.Where("active"))
+}
+
+func (r *webhookRepository) ListSubscriptionsByID(ids []uint) ([]models.WebhookSubscription, *errors.TaskManagerError) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return r.findSubscriptions(r.db.Where("id IN ?", ids))
+}
+
+func (r *webhookRepository) findSubscriptions(query *gorm.DB) ([]models.WebhookSubscription, *errors.TaskManagerError) {
+	var subscriptions []models.WebhookSubscription
+	if err := query.Order("id").Find(&subscriptions).Error; err != nil {
+		return nil, exceptions.InternalServerException(constants.ErrFailedToGetWebhook + ": " + err.Error())
+	}
+	return subscriptions, nil
+}
+
+func (r *webhookRepository) UpdateSubscription(subscription *models.WebhookSubscription) *errors.TaskManagerError {
+	if err := r.db.Save(subscription).Error; err != nil {
+		return exceptions.InternalServerException(constants.ErrFailedToSaveWebhook + ": " + err.Error())
+	}
+	return nil
+}
+
+func (r *webhookRepository) DeleteSubscription(id uint) *errors.TaskManagerError {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&models.WebhookSubscription{}).Error
+	})
+	if err != nil {
+		return exceptions.InternalServerException(constants.ErrFailedToSaveWebhook + ": " + err.Error())
+	}
+	return nil
+}
+
+func (r *webhookRepository) AddDeliveries(deliveries []models.WebhookDelivery) *errors.TaskManagerError {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	// The outbox relay may publish an event again after a partial failure
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "subscription_id"}, {Name: "event_id"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "redelivery_of IS NULL"}}},
+		DoNothing:   true,
+	}).Create(&deliveries).Error
+	if err != nil {
+		return exceptions.InternalServerException(constants.ErrFailedToSaveDelivery + ": " + err.Error())
+	}
+	return nil
+}
+
+func (r *webhookRepository) CreateDelivery(delivery *models.WebhookDelivery) *errors.TaskManagerError {
+	if err := r.db.Create(delivery).Error; err != nil {
+		return exceptions.InternalServerException(constants.ErrFailedToSaveDelivery + ": " + err.Error())
+	}
+	return nil
+}
+
+func (r *webhookRepository) GetDelivery(subscriptionID uint, id uint64) (*models.WebhookDelivery, *errors.TaskManagerError) {
+	var delivery models.WebhookDelivery
+	result := r.db.Where("subscription_id = ? AND id = ?", subscriptionID, id).First(&delivery)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, exceptions.InternalServerException(constants.ErrFailedToGetDelivery + ": " + result.Error.Error())
+	}
+	return &delivery, nil
+}
+
+// ListDeliveries returns the delivery log of a subscription, newest first, optionally
+// filtered by status
+func (r *webhookRepository) ListDeliveries(subscriptionID uint, status string, limit, offset int) ([]models.WebhookDelivery, *errors.TaskManagerError) {
+	query := r.db.Where("subscription_id = ?", subscriptionID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&deliveries).Error; err != nil {
+		return nil, exceptions.InternalServerException(constants.ErrFailedToGetDelivery + ": " + err.Error())
+	}
+	return deliveries, nil
+}
+
+func (r *webhookRepository) ClaimDue(limit int, now, leaseUntil time.Time) ([]models.WebhookDelivery, *errors.TaskManagerError) {
+	var batch []models.WebhookDelivery
+	err := r.db.Raw(`
+		UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON s.id = d.subscription_id
+			WHERE d.status = ? AND d.next_attempt_at <= ? AND s.active
+			ORDER BY d.next_attempt_at, d.id
+			LIMIT ?
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		RETURNING *`, leaseUntil, models.WebhookDeliveryPending, now, limit).Scan(&batch).Error
+	if err != nil {
+		return nil, exceptions.InternalServerException(constants.ErrFailedToGetDelivery + ": " + err.Error())
+	}
+	return batch, nil
+}
+
+func (r *webhookRepository) RecordSuccess(delivery *models.WebhookDelivery, statusCode int, deliveredAt time.Time) *errors.TaskManagerError {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+			"status":           models.WebhookDeliveryDelivered,
+			"attempts":         gorm.Expr("attempts + 1"),
+			"last_status_code": statusCode,
+			"last_error":       "",
+			"delivered_at":     deliveredAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.WebhookSubscription{}).Where("id = ?", delivery.SubscriptionID).
+			Update("consecutive_failures", 0).Error
+	})
+	if err != nil {
+		return exceptions.InternalServerException(constants.ErrFailedToSaveDelivery + ": " + err.Error())
+	}
+	return nil
+}
+
+func (r *webhookRepository) RecordFailure(delivery *models.WebhookDelivery, statusCode int, lastError string, nextAttemptAt *time.Time, disableAfter int) (bool, *errors.TaskManagerError) {
+	disabled := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"attempts":         gorm.Expr("attempts + 1"),
+			"last_status_code": statusCode,
+			"last_error":       lastError,
+		}
+		if nextAttemptAt != nil {
+			updates["next_attempt_at"] = *nextAttemptAt
+		} else {
+			updates["status"] = models.WebhookDeliveryFailed
+		}
+		if err := tx.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		var subscription models.WebhookSubscription
+		err := tx.Raw(`
+			UPDATE webhook_subscriptions SET consecutive_failures = consecutive_failures + 1, updated_at = ?
+			WHERE id = ?
+			RETURNING *`, tx.NowFunc(), delivery.SubscriptionID).Scan(&subscription).Error
+		if err != nil {
+			return err
+		}
+		if disableAfter <= 0 || !subscription.Active || subscription.ConsecutiveFailures < disableAfter {
+			return nil
+		}
+		disabled = true
+		return tx.Model(&models.WebhookSubscription{}).Where("id = ?", delivery.SubscriptionID).Updates(map[string]interface{}{
+			"active":          false,
+			"disabled_reason": constants.ErrWebhookDisabled + ": " + lastError,
+		}).Error
+	})
+	if err != nil {
+		return false, exceptions.InternalServerException(constants.ErrFailedToSaveDelivery + ": " + err.Error())
+	}
+	return disabled, nil
+}
+
+func (r *webhookRepository) DeleteFinished(before time.Time) *errors.TaskManagerError {
+	err := r.db.Where("status <> ? AND updated_at < ?", models.WebhookDeliveryPending, before).
+		Delete(&models.WebhookDelivery{}).Error
+	if err != nil {
+		return exceptions.InternalServerException(constants.ErrFailedToSaveDelivery + ": " + err.Error())
+	}
+	return nil
+}
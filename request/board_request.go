@@ -0,0 +1,27 @@
+package request
+
+// Board message types sent by clients
+const (
+	BoardSubscribe   = "subscribe"
+	BoardUnsubscribe = "unsubscribe"
+	BoardView        = "view"
+	BoardLeave       = "leave"
+)
+
+// BoardMessage is a message sent by a client over the board WebSocket. subscribe takes an ID
+// chosen by the client and either TaskUUIDs or a Filter; unsubscribe takes the ID. view and
+// leave mark the client as viewing TaskUUID or no longer viewing it.
+type BoardMessage struct {
+	Type      string       `json:"type"`
+	ID        string       `json:"id,omitempty"`
+	TaskUUIDs []string     `json:"task_uuids,omitempty"`
+	Filter    *BoardFilter `json:"filter,omitempty"`
+	TaskUUID  string       `json:"task_uuid,omitempty"`
+}
+
+// BoardFilter selects tasks like the query parameters of ListTasks
+type BoardFilter struct {
+	Status   string `json:"status,omitempty"`
+	UserID   string `json:"user_id,omitempty"`
+	Priority string `json:"priority,omitempty"`
+}
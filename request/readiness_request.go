@@ -0,0 +1,7 @@
+package request
+
+// ReqSetReadiness takes the instance out of load balancing, or puts it back
+type ReqSetReadiness struct {
+	Ready  *bool  `json:"ready"`
+	Reason string `json:"reason,omitempty"`
+}
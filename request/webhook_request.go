@@ -0,0 +1,11 @@
+package request
+
+// ReqCreateOrUpdateWebhook creates a webhook subscription or changes the given fields of one.
+// Filters match the task in the event on user_id, status and priority.
+type ReqCreateOrUpdateWebhook struct {
+	URL        *string            `json:"url,omitempty"`
+	EventTypes *[]string          `json:"event_types,omitempty"`
+	Filters    *map[string]string `json:"filters,omitempty"`
+	Secret     *string            `json:"secret,omitempty"`
+	Active     *bool              `json:"active,omitempty"`
+}
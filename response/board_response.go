@@ -0,0 +1,41 @@
+package response
+
+import "task-manager-app/events"
+
+// Board message types sent to clients
+const (
+	BoardSubscribed   = "subscribed"
+	BoardUnsubscribed = "unsubscribed"
+	BoardChange       = "change"
+	BoardPresence     = "presence"
+	BoardError        = "error"
+)
+
+// BoardAck confirms a subscribe or unsubscribe message
+type BoardAck struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// BoardChangeMessage carries a task change to a client, naming the subscriptions it matched
+type BoardChangeMessage struct {
+	Type          string            `json:"type"`
+	Subscriptions []string          `json:"subscriptions"`
+	ChangeID      uint64            `json:"change_id"`
+	Event         *events.TaskEvent `json:"event"`
+}
+
+// BoardPresenceMessage lists the users viewing a task; it is sent whenever they change to the
+// clients that subscribed to the task by UUID or view it
+type BoardPresenceMessage struct {
+	Type     string   `json:"type"`
+	TaskUUID string   `json:"task_uuid"`
+	Viewers  []string `json:"viewers"`
+}
+
+// BoardErrorMessage rejects a client message; ID is set when the message had one
+type BoardErrorMessage struct {
+	Type  string `json:"type"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error"`
+}
@@ -0,0 +1,6 @@
+package response
+
+// ConfigResponse lists the settings in effect, keyed by name, with secrets redacted
+type ConfigResponse struct {
+	Settings map[string]string `json:"settings"`
+}
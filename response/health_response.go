@@ -0,0 +1,17 @@
+package response
+
+// DependencyStatus is the outcome of checking one dependency
+type DependencyStatus struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// ReadinessResponse reports whether the instance should receive traffic. Reason is set when
+// it was marked not ready, for shutdown or maintenance.
+type ReadinessResponse struct {
+	Status       string                      `json:"status"`
+	Reason       string                      `json:"reason,omitempty"`
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+}
@@ -0,0 +1,20 @@
+package response
+
+import "time"
+
+// TaskChange is a task as of its latest change. Deleted changes are tombstones without a Task.
+type TaskChange struct {
+	Seq       uint64        `json:"seq"`
+	TaskUUID  string        `json:"task_uuid"`
+	EventType string        `json:"event_type"`
+	Deleted   bool          `json:"deleted"`
+	Task      *TaskResponse `json:"task,omitempty"`
+	ChangedAt time.Time     `json:"changed_at"`
+}
+
+// TaskChangesResponse is a page of the change feed. Clients pass NextSince as since to read on.
+type TaskChangesResponse struct {
+	Changes   []TaskChange `json:"changes"`
+	NextSince uint64       `json:"next_since"`
+	HasMore   bool         `json:"has_more"`
+}
@@ -3,14 +3,22 @@ package response
 import "time"
 
 type TaskResponse struct {
-	UUID        string    `json:"uuid"`
-	Title       string    `json:"title"`
-	Description string    `json:"description,omitempty"`
-	Status      string    `json:"status"`
-	Priority    string    `json:"priority"`
-	UserID      *string   `json:"user_id,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	UUID        string        `json:"uuid"`
+	Title       string        `json:"title"`
+	Description string        `json:"description,omitempty"`
+	Status      string        `json:"status"`
+	Priority    string        `json:"priority"`
+	UserID      *string       `json:"user_id,omitempty"`
+	User        *UserResponse `json:"user,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// UserResponse is the assigned user embedded in a task when requested with expand=user
+type UserResponse struct {
+	ID    string `json:"id"`
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
 }
 
 type TaskListResponse struct {
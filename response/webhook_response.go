@@ -0,0 +1,45 @@
+package response
+
+import "time"
+
+// WebhookResponse describes a webhook subscription. Secret is only returned when it was set
+// or generated by the request.
+type WebhookResponse struct {
+	UUID                string            `json:"uuid"`
+	URL                 string            `json:"url"`
+	EventTypes          []string          `json:"event_types"`
+	Filters             map[string]string `json:"filters"`
+	Secret              string            `json:"secret,omitempty"`
+	Active              bool              `json:"active"`
+	ConsecutiveFailures int               `json:"consecutive_failures"`
+	DisabledReason      string            `json:"disabled_reason,omitempty"`
+	CreatedAt           time.Time         `json:"created_at"`
+	UpdatedAt           time.Time         `json:"updated_at"`
+}
+
+type WebhookListResponse struct {
+	Webhooks []WebhookResponse `json:"webhooks"`
+	Count    int               `json:"count"`
+}
+
+// WebhookDeliveryResponse is one entry of a subscription's delivery log
+type WebhookDeliveryResponse struct {
+	ID             uint64     `json:"id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	RedeliveryOf   *uint64    `json:"redelivery_of,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type WebhookDeliveryListResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+	Page       int                       `json:"page"`
+	PageSize   int                       `json:"pageSize"`
+	Count      int                       `json:"count"`
+}
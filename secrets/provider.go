@@ -0,0 +1,92 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"task-manager-app/constants"
+)
+
+// Provider resolves the secret references of one URL scheme
+type Provider interface {
+	Resolve(ctx context.Context, ref *url.URL) (string, error)
+}
+
+// Resolver resolves secret references with the provider registered for their scheme. Values
+// that are not references are returned unchanged.
+type Resolver struct {
+	providers map[string]Provider
+}
+
+// NewResolver resolves references of each scheme with its provider
+func NewResolver(providers map[string]Provider) *Resolver {
+	return &Resolver{providers: providers}
+}
+
+// IsReference reports whether value is a reference to a secret this resolver can resolve
+func (r *Resolver) IsReference(value string) bool {
+	scheme, _, found := strings.Cut(value, "://")
+	if !found {
+		return false
+	}
+	_, ok := r.providers[scheme]
+	return ok
+}
+
+// Resolve returns the secret value references, or value itself when it is not a reference
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	if !r.IsReference(value) {
+		return value, nil
+	}
+	ref, err := url.Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", constants.ErrInvalidSecretRef, err)
+	}
+	secret, err := r.providers[ref.Scheme].Resolve(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", Redact(value), err)
+	}
+	return secret, nil
+}
+
+// Redact drops the parts of a reference that may be sensitive, such as credentials in the
+// user info or query, keeping what identifies the secret
+func Redact(value string) string {
+	ref, err := url.Parse(value)
+	if err != nil {
+		return constants.RedactedValue
+	}
+	ref.User = nil
+	ref.RawQuery = ""
+	return ref.String()
+}
+
+// FileProvider reads file:///path/to/secret, as mounted by Docker and Kubernetes secrets.
+// Surrounding whitespace, usually a trailing newline, is dropped.
+type FileProvider struct{}
+
+func (FileProvider) Resolve(ctx context.Context, ref *url.URL) (string, error) {
+	if ref.Host != "" && ref.Host != "localhost" {
+		return "", fmt.Errorf(constants.ErrSecretFileHost)
+	}
+	data, err := os.ReadFile(ref.Path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// EnvProvider reads env://NAME from the environment variable NAME, which lets a setting point
+// at a variable injected under another name
+type EnvProvider struct{}
+
+func (EnvProvider) Resolve(ctx context.Context, ref *url.URL) (string, error) {
+	name := ref.Host + ref.Path
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return "", fmt.Errorf("%s %s", constants.ErrSecretEnvUnset, name)
+	}
+	return value, nil
+}
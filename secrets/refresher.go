@@ -0,0 +1,158 @@
+package secrets
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"task-manager-app/utils"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Refresher holds the current values of named settings and resolves the ones that are
+// secret references again every interval, so that rotated credentials are picked up
+// without a restart
+type Refresher struct {
+	resolver *Resolver
+	interval time.Duration
+	timeout  time.Duration
+	logger   *zap.SugaredLogger
+
+	mutex     sync.RWMutex
+	refs      map[string]string
+	values    map[string]string
+	listeners []func(names []string)
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewRefresher resolves references with resolver, again every interval (never when it is 0),
+// giving each resolution at most timeout. Rotations and failed refreshes are logged to logger,
+// utils.Sugar when nil.
+func NewRefresher(resolver *Resolver, interval, timeout time.Duration, logger *zap.SugaredLogger) *Refresher {
+	if logger == nil {
+		logger = utils.Sugar
+	}
+	return &Refresher{
+		resolver: resolver,
+		interval: interval,
+		timeout:  timeout,
+		logger:   logger,
+		refs:     make(map[string]string),
+		values:   make(map[string]string),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Track resolves value, the setting name, and returns the result. References are kept and
+// resolved again on every refresh; other values are kept as they are.
+func (r *Refresher) Track(ctx context.Context, name, value string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	resolved, err := r.resolver.Resolve(ctx, value)
+	if err != nil {
+		return "", err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.resolver.IsReference(value) {
+		r.refs[name] = value
+	}
+	r.values[name] = resolved
+	return resolved, nil
+}
+
+// Get returns the current value of the setting name
+func (r *Refresher) Get(name string) string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.values[name]
+}
+
+// OnChange calls fn with the settings whose value changed, after each refresh that changed any
+func (r *Refresher) OnChange(fn func(names []string)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.listeners = append(r.listeners, fn)
+}
+
+// Start refreshes in the background until Stop is called
+func (r *Refresher) Start() {
+	if r.interval <= 0 {
+		close(r.done)
+		return
+	}
+	go r.run()
+}
+
+// Stop ends the refreshes and waits for the one in progress or for ctx to expire
+func (r *Refresher) Stop(ctx context.Context) error {
+	close(r.stop)
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Refresher) run() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.Refresh()
+		}
+	}
+}
+
+// Refresh resolves every reference again and returns the settings whose value changed. A
+// reference that cannot be resolved keeps its last value.
+func (r *Refresher) Refresh() []string {
+	r.mutex.RLock()
+	refs := make(map[string]string, len(r.refs))
+	for name, ref := range r.refs {
+		refs[name] = ref
+	}
+	r.mutex.RUnlock()
+
+	resolved := make(map[string]string, len(refs))
+	for name, ref := range refs {
+		ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+		value, err := r.resolver.Resolve(ctx, ref)
+		cancel()
+		if err != nil {
+			r.logger.Errorw("Failed to refresh secret, keeping its last value", "setting", name, "error", err)
+			continue
+		}
+		resolved[name] = value
+	}
+
+	r.mutex.Lock()
+	var changed []string
+	for name, value := range resolved {
+		if r.values[name] != value {
+			r.values[name] = value
+			changed = append(changed, name)
+		}
+	}
+	listeners := append([]func([]string){}, r.listeners...)
+	r.mutex.Unlock()
+
+	if len(changed) == 0 {
+		return nil
+	}
+	sort.Strings(changed)
+	r.logger.Infow("Secrets rotated", "settings", changed)
+	for _, listener := range listeners {
+		listener(changed)
+	}
+	return changed
+}
@@ -0,0 +1,96 @@
+// Package secretsFake provides an in-process stand-in for a Vault-style secret store. It
+// serves the KV version 2 read API so the vault provider can be exercised locally and in
+// tests without a running Vault.
+package secretsFake
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"task-manager-app/constants"
+)
+
+// FakeVault serves GET /v1/{path} from in-memory secrets to callers sending its token
+type FakeVault struct {
+	server *httptest.Server
+	token  string
+
+	mutex   sync.RWMutex
+	secrets map[string]map[string]string
+	version map[string]int
+
+	reads atomic.Int64
+}
+
+// NewFakeVault starts a fake store accepting token. Call Close when done.
+func NewFakeVault(token string) *FakeVault {
+	f := &FakeVault{
+		token:   token,
+		secrets: make(map[string]map[string]string),
+		version: make(map[string]int),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/{path...}", f.handleRead)
+	f.server = httptest.NewServer(mux)
+	return f
+}
+
+// URL returns the address to configure as VAULT_ADDR
+func (f *FakeVault) URL() string {
+	return f.server.URL
+}
+
+// Close shuts the fake store down
+func (f *FakeVault) Close() {
+	f.server.Close()
+}
+
+// Put stores a new version of the secret at path, e.g. "secret/data/task-manager"
+func (f *FakeVault) Put(path string, data map[string]string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	copied := make(map[string]string, len(data))
+	for k, v := range data {
+		copied[k] = v
+	}
+	f.secrets[path] = copied
+	f.version[path]++
+}
+
+// Reads returns how many authorised reads were served
+func (f *FakeVault) Reads() int64 {
+	return f.reads.Load()
+}
+
+func (f *FakeVault) handleRead(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(constants.HeaderVaultToken) != f.token {
+		writeJSON(w, http.StatusForbidden, map[string][]string{"errors": {"permission denied"}})
+		return
+	}
+	f.reads.Add(1)
+
+	path := strings.Trim(r.PathValue("path"), "/")
+	f.mutex.RLock()
+	data, ok := f.secrets[path]
+	version := f.version[path]
+	f.mutex.RUnlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string][]string{"errors": {}})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			"data":     data,
+			"metadata": map[string]int{"version": version},
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,103 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"task-manager-app/constants"
+	"time"
+)
+
+// VaultOptions configures a VaultProvider
+type VaultOptions struct {
+	// Address is the base URL of the API, e.g. https://vault.internal:8200
+	Address string
+	Token   string
+	Timeout time.Duration
+}
+
+// VaultProvider reads vault://mount/path#key from a Vault-style HTTP API, with a GET of
+// {Address}/v1/mount/path carrying the token in X-Vault-Token. Responses of both KV engine
+// versions are understood: {"data": {"data": {...}, "metadata": {...}}} and {"data": {...}}.
+// The fragment names the key and may be left out for secrets with a single key.
+type VaultProvider struct {
+	options VaultOptions
+	client  *http.Client
+}
+
+func NewVaultProvider(options VaultOptions) *VaultProvider {
+	return &VaultProvider{
+		options: options,
+		client:  &http.Client{Timeout: options.Timeout},
+	}
+}
+
+type vaultResponse struct {
+	Data map[string]json.RawMessage `json:"data"`
+}
+
+func (p *VaultProvider) Resolve(ctx context.Context, ref *url.URL) (string, error) {
+	if p.options.Address == "" {
+		return "", fmt.Errorf(constants.ErrVaultAddrUnset)
+	}
+	path := strings.Trim(ref.Host+ref.Path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(p.options.Address, "/")+"/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(constants.HeaderVaultToken, p.options.Token)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", constants.ErrVaultStatus, resp.Status)
+	}
+
+	var body vaultResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("%s: %w", constants.ErrVaultResponse, err)
+	}
+	data := body.Data
+	if nested, ok := data["data"]; ok {
+		if _, versioned := data["metadata"]; versioned {
+			data = nil
+			if err := json.Unmarshal(nested, &data); err != nil {
+				return "", fmt.Errorf("%s: %w", constants.ErrVaultResponse, err)
+			}
+		}
+	}
+	return pickKey(data, ref.Fragment)
+}
+
+// pickKey returns the value of key in data, or its only value when key is empty
+func pickKey(data map[string]json.RawMessage, key string) (string, error) {
+	if key == "" {
+		if len(data) != 1 {
+			keys := make([]string, 0, len(data))
+			for k := range data {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			return "", fmt.Errorf("%s, it has %s", constants.ErrVaultKeyNeeded, strings.Join(keys, ", "))
+		}
+		for k := range data {
+			key = k
+		}
+	}
+	raw, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("%s %q", constants.ErrVaultKeyMissing, key)
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		// Numbers and booleans are used as written
+		return string(raw), nil
+	}
+	return value, nil
+}
@@ -0,0 +1,171 @@
+package boardService
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"task-manager-app/metrics"
+	"task-manager-app/response"
+	"task-manager-app/services/streamService"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// BoardOptions sets the limits of every board connection
+type BoardOptions struct {
+	// SendBuffer is how many messages a client may fall behind before it is disconnected
+	SendBuffer int
+	// MaxSubscriptions caps both the subscriptions and the viewed tasks of a connection
+	MaxSubscriptions        int
+	MaxTasksPerSubscription int
+	MaxConnectionsPerUser   int
+	MaxMessageBytes         int64
+	// MessagesPerSecond limits client messages; excess ones are rejected with an error
+	MessagesPerSecond int
+	PingInterval      time.Duration
+	WriteTimeout      time.Duration
+}
+
+// BoardHub serves board WebSockets. It forwards the task changes of a TaskStream to the
+// connections whose subscriptions match them and tracks who is viewing which task. Presence is
+// kept per replica, so the viewers of a task are only seen by each other when their board
+// connections land on the same replica.
+type BoardHub struct {
+	stream  *streamService.TaskStream
+	options atomic.Pointer[BoardOptions]
+
+	mutex       sync.Mutex
+	connections map[string]int
+	sessions    map[*session]struct{}
+	viewers     map[string]map[*session]struct{}
+}
+
+func NewBoardHub(stream *streamService.TaskStream, options BoardOptions) *BoardHub {
+	h := &BoardHub{
+		stream:      stream,
+		connections: make(map[string]int),
+		sessions:    make(map[*session]struct{}),
+		viewers:     make(map[string]map[*session]struct{}),
+	}
+	h.SetOptions(options)
+	return h
+}
+
+// SetOptions changes the limits while connections are served. The buffer size, message size
+// and ping interval of a connection are set when it opens; the other limits apply to the next
+// messages and connections.
+func (h *BoardHub) SetOptions(options BoardOptions) {
+	h.options.Store(&options)
+}
+
+func (h *BoardHub) currentOptions() BoardOptions {
+	return *h.options.Load()
+}
+
+// Acquire reserves one of the connections of userID and reports whether the user was below
+// MaxConnectionsPerUser. Serve releases it; Release does when the connection is not served.
+func (h *BoardHub) Acquire(userID string) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.connections[userID] >= h.currentOptions().MaxConnectionsPerUser {
+		metrics.CountBoardEvent(metrics.BoardRejectedConnection)
+		return false
+	}
+	h.connections[userID]++
+	return true
+}
+
+func (h *BoardHub) Release(userID string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.connections[userID]--
+	if h.connections[userID] <= 0 {
+		delete(h.connections, userID)
+	}
+}
+
+// Serve runs a board connection of userID, acquired before, until it is closed
+func (h *BoardHub) Serve(conn *websocket.Conn, userID string) {
+	defer h.Release(userID)
+	s := newSession(h, conn, userID)
+
+	h.mutex.Lock()
+	h.sessions[s] = struct{}{}
+	h.recordConnections()
+	h.mutex.Unlock()
+
+	s.run()
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	delete(h.sessions, s)
+	for taskUUID := range s.viewedTasks() {
+		h.removeViewer(taskUUID, s)
+	}
+	h.recordConnections()
+}
+
+// view marks s as viewing taskUUID and tells everyone watching the task
+func (h *BoardHub) view(taskUUID string, s *session) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.viewers[taskUUID] == nil {
+		h.viewers[taskUUID] = make(map[*session]struct{})
+	}
+	h.viewers[taskUUID][s] = struct{}{}
+	h.announce(taskUUID)
+}
+
+func (h *BoardHub) leave(taskUUID string, s *session) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.removeViewer(taskUUID, s)
+}
+
+func (h *BoardHub) removeViewer(taskUUID string, s *session) {
+	if _, ok := h.viewers[taskUUID][s]; !ok {
+		return
+	}
+	delete(h.viewers[taskUUID], s)
+	if len(h.viewers[taskUUID]) == 0 {
+		delete(h.viewers, taskUUID)
+	}
+	h.announce(taskUUID)
+}
+
+// presence sends s the viewers of taskUUID, for a client that just subscribed to it
+func (h *BoardHub) presence(taskUUID string, s *session) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	s.enqueue(h.presenceMessage(taskUUID))
+}
+
+// announce sends the viewers of taskUUID to every session that views or subscribed to it
+func (h *BoardHub) announce(taskUUID string) {
+	message := h.presenceMessage(taskUUID)
+	for s := range h.sessions {
+		if s.watches(taskUUID) {
+			s.enqueue(message)
+		}
+	}
+}
+
+func (h *BoardHub) presenceMessage(taskUUID string) []byte {
+	seen := make(map[string]bool)
+	viewers := []string{}
+	for s := range h.viewers[taskUUID] {
+		if !seen[s.userID] {
+			seen[s.userID] = true
+			viewers = append(viewers, s.userID)
+		}
+	}
+	sort.Strings(viewers)
+	message, _ := json.Marshal(response.BoardPresenceMessage{Type: response.BoardPresence, TaskUUID: taskUUID, Viewers: viewers})
+	return message
+}
+
+func (h *BoardHub) recordConnections() {
+	metrics.SetBoardConnections(len(h.sessions))
+}
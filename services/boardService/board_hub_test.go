@@ -0,0 +1,254 @@
+package boardService
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"task-manager-app/constants"
+	"task-manager-app/events"
+	"task-manager-app/response"
+	"task-manager-app/services/streamService"
+	"task-manager-app/utils"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+const (
+	taskA = "0b3e1b1e-7d1c-4a8e-9a55-1a2b3c4d5e6f"
+	taskB = "5f6e7d8c-9b0a-4c1d-8e2f-3a4b5c6d7e8f"
+)
+
+func TestMain(m *testing.M) {
+	utils.Sugar = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
+
+func testOptions() BoardOptions {
+	return BoardOptions{
+		SendBuffer:              16,
+		MaxSubscriptions:        2,
+		MaxTasksPerSubscription: 2,
+		MaxConnectionsPerUser:   1,
+		MaxMessageBytes:         1024,
+		MessagesPerSecond:       100,
+		PingInterval:            time.Minute,
+		WriteTimeout:            time.Second,
+	}
+}
+
+// startHub serves the hub on a test server where the user is given by the user query parameter
+func startHub(t *testing.T, options BoardOptions) (*events.MemoryChangeBus, *streamService.TaskStream, *BoardHub, string) {
+	bus := events.NewMemoryChangeBus()
+	stream := streamService.NewTaskStream(bus, streamService.StreamOptions{ReplaySize: 10, ClientBuffer: 10})
+	if err := stream.Start(); err != nil {
+		t.Fatalf("Start returned error %v", err)
+	}
+	hub := NewBoardHub(stream, options)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		userID := req.URL.Query().Get("user")
+		if !hub.Acquire(userID) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, req, nil)
+		if err != nil {
+			hub.Release(userID)
+			return
+		}
+		hub.Serve(conn, userID)
+	}))
+	t.Cleanup(func() {
+		server.Close()
+		stream.Stop()
+	})
+	return bus, stream, hub, "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+type boardClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+func dial(t *testing.T, url, userID string) *boardClient {
+	conn, resp, err := websocket.DefaultDialer.Dial(url+"?user="+userID, nil)
+	if err != nil {
+		t.Fatalf("Dial returned error %v (response %v)", err, resp)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &boardClient{t: t, conn: conn}
+}
+
+func (c *boardClient) send(message string) {
+	if err := c.conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+		c.t.Fatalf("WriteMessage returned error %v", err)
+	}
+}
+
+// next reads the next message as a map of its fields
+func (c *boardClient) next() map[string]interface{} {
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		c.t.Fatalf("ReadMessage returned error %v", err)
+	}
+	var message map[string]interface{}
+	json.Unmarshal(data, &message)
+	return message
+}
+
+// waitForSubscriber waits until the board's stream client is subscribed, so that changes
+// published next reach it
+func waitForSubscriber(t *testing.T, stream *streamService.TaskStream, n int) {
+	deadline := time.Now().Add(2 * time.Second)
+	for stream.Clients() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d stream clients", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func changed(taskUUID, status string) *events.TaskEvent {
+	return &events.TaskEvent{EventID: taskUUID + status, EventType: events.TaskUpdated, TaskUUID: taskUUID, Task: &events.TaskSnapshot{UUID: taskUUID, Status: status}}
+}
+
+func TestBoardForwardsChangesMatchingSubscriptions(t *testing.T) {
+	bus, stream, _, url := startHub(t, testOptions())
+	client := dial(t, url, "alice")
+	waitForSubscriber(t, stream, 1)
+
+	client.send(`{"type":"subscribe","id":"mine","task_uuids":["` + taskA + `"]}`)
+	if ack := client.next(); ack["type"] != response.BoardSubscribed || ack["id"] != "mine" {
+		t.Fatalf("got %v, want the subscription acknowledged", ack)
+	}
+	if presence := client.next(); presence["type"] != response.BoardPresence || len(presence["viewers"].([]interface{})) != 0 {
+		t.Fatalf("got %v, want the empty presence of the task", presence)
+	}
+	client.send(`{"type":"subscribe","id":"done","filter":{"status":"Completed"}}`)
+	client.next()
+
+	bus.Publish(context.Background(), changed(taskB, "Pending"), changed(taskA, "Pending"), changed(taskB, "Completed"))
+
+	first, second := client.next(), client.next()
+	if first["type"] != response.BoardChange || first["change_id"] != float64(2) || first["subscriptions"].([]interface{})[0] != "mine" {
+		t.Errorf("got %v, want change 2 for the mine subscription", first)
+	}
+	if second["change_id"] != float64(3) || second["subscriptions"].([]interface{})[0] != "done" {
+		t.Errorf("got %v, want change 3 for the done subscription", second)
+	}
+
+	client.send(`{"type":"unsubscribe","id":"mine"}`)
+	if ack := client.next(); ack["type"] != response.BoardUnsubscribed {
+		t.Errorf("got %v, want the unsubscription acknowledged", ack)
+	}
+	client.send(`{"type":"unsubscribe","id":"mine"}`)
+	if reply := client.next(); reply["error"] != constants.ErrSubscriptionNotFound {
+		t.Errorf("got %v, want %q", reply, constants.ErrSubscriptionNotFound)
+	}
+}
+
+func TestBoardAnnouncesWhoIsViewingATask(t *testing.T) {
+	_, _, _, url := startHub(t, testOptions())
+	alice, bob := dial(t, url, "alice"), dial(t, url, "bob")
+
+	bob.send(`{"type":"subscribe","id":"a","task_uuids":["` + taskA + `"]}`)
+	bob.next()
+	bob.next()
+
+	alice.send(`{"type":"view","task_uuid":"` + taskA + `"}`)
+	if presence := alice.next(); presence["task_uuid"] != taskA || presence["viewers"].([]interface{})[0] != "alice" {
+		t.Errorf("alice got %v, want herself viewing the task", presence)
+	}
+	if presence := bob.next(); presence["viewers"].([]interface{})[0] != "alice" {
+		t.Errorf("bob got %v, want alice viewing the task", presence)
+	}
+
+	// Disconnecting stops viewing
+	alice.conn.Close()
+	if presence := bob.next(); len(presence["viewers"].([]interface{})) != 0 {
+		t.Errorf("bob got %v, want nobody viewing the task once alice left", presence)
+	}
+}
+
+func TestBoardRejectsInvalidMessages(t *testing.T) {
+	_, _, _, url := startHub(t, testOptions())
+	client := dial(t, url, "alice")
+
+	for message, want := range map[string]string{
+		`{"type":"subscribe","id":"x","task_uuids":["nope"]}`:                                          constants.ErrInvalidTaskUUID,
+		`{"type":"subscribe","task_uuids":["` + taskA + `"]}`:                                          constants.ErrInvalidSubscriptionID,
+		`{"type":"subscribe","id":"x"}`:                                                                constants.ErrInvalidSubscription,
+		`{"type":"subscribe","id":"x","filter":{"status":"Done"}}`:                                     constants.ErrInvalidBoardFilter,
+		`{"type":"subscribe","id":"x","task_uuids":["` + taskA + `","` + taskB + `","` + taskA + `"]}`: constants.ErrTooManyBoardTasks,
+		`{"type":"shout"}`: constants.ErrUnknownBoardMessage,
+	} {
+		client.send(message)
+		if reply := client.next(); reply["type"] != response.BoardError || reply["error"] != want {
+			t.Errorf("%s: got %v, want %q", message, reply, want)
+		}
+	}
+
+	for _, id := range []string{"1", "2"} {
+		client.send(`{"type":"subscribe","id":"` + id + `","filter":{}}`)
+		client.next()
+	}
+	client.send(`{"type":"subscribe","id":"3","filter":{}}`)
+	if reply := client.next(); reply["error"] != constants.ErrTooManySubscriptions {
+		t.Errorf("got %v, want %q", reply, constants.ErrTooManySubscriptions)
+	}
+}
+
+func TestBoardLimitsConnectionsAndMessages(t *testing.T) {
+	options := testOptions()
+	options.MessagesPerSecond = 1
+	_, _, hub, url := startHub(t, options)
+	client := dial(t, url, "alice")
+
+	if _, resp, err := websocket.DefaultDialer.Dial(url+"?user=alice", nil); err == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("second connection of alice was not rejected")
+	}
+
+	client.send(`{"type":"leave","task_uuid":"` + taskA + `"}`)
+	client.send(`{"type":"leave","task_uuid":"` + taskA + `"}`)
+	if reply := client.next(); reply["error"] != constants.ErrBoardRateLimited {
+		t.Errorf("got %v, want %q", reply, constants.ErrBoardRateLimited)
+	}
+
+	client.conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for !hub.Acquire("alice") {
+		if time.Now().After(deadline) {
+			t.Fatal("connection of alice was not released")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBoardDisconnectsSlowClients(t *testing.T) {
+	options := testOptions()
+	options.SendBuffer = 1
+	s := newSession(NewBoardHub(nil, options), nil, "alice")
+
+	s.enqueue([]byte("{}"))
+	select {
+	case <-s.closed:
+		t.Fatal("session was closed while its buffer had room")
+	default:
+	}
+	s.enqueue([]byte("{}"))
+	select {
+	case <-s.closed:
+		if s.closeCode != websocket.CloseTryAgainLater {
+			t.Errorf("close code = %d, want %d", s.closeCode, websocket.CloseTryAgainLater)
+		}
+	default:
+		t.Error("session was not closed once its buffer was full")
+	}
+}
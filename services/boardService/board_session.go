@@ -0,0 +1,331 @@
+package boardService
+
+import (
+	"encoding/json"
+	"sync"
+	"task-manager-app/constants"
+	"task-manager-app/constants/enums"
+	"task-manager-app/events"
+	"task-manager-app/metrics"
+	"task-manager-app/request"
+	"task-manager-app/response"
+	"task-manager-app/services/streamService"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// subscription matches changes of the tasks in taskUUIDs, or of the tasks matching filter
+type subscription struct {
+	taskUUIDs map[string]bool
+	filter    *streamService.Filter
+}
+
+func (s subscription) matches(event *events.TaskEvent) bool {
+	if s.filter != nil {
+		return s.filter.Matches(event)
+	}
+	return s.taskUUIDs[event.TaskUUID]
+}
+
+// session is one board connection. Its reader handles client messages, its writer sends the
+// queued messages and pings, and its forwarder queues the matching task changes.
+type session struct {
+	hub    *BoardHub
+	conn   *websocket.Conn
+	userID string
+
+	send       chan []byte
+	closed     chan struct{}
+	closeMutex sync.Mutex
+	closeCode  int
+	closeText  string
+
+	mutex         sync.Mutex
+	subscriptions map[string]subscription
+	viewing       map[string]bool
+
+	// tokens and refilled implement the MessagesPerSecond token bucket; only the reader uses them
+	tokens   float64
+	refilled time.Time
+}
+
+func newSession(hub *BoardHub, conn *websocket.Conn, userID string) *session {
+	return &session{
+		hub:           hub,
+		conn:          conn,
+		userID:        userID,
+		send:          make(chan []byte, hub.currentOptions().SendBuffer),
+		closed:        make(chan struct{}),
+		subscriptions: make(map[string]subscription),
+		viewing:       make(map[string]bool),
+		tokens:        float64(hub.currentOptions().MessagesPerSecond),
+		refilled:      time.Now(),
+	}
+}
+
+func (s *session) run() {
+	client, _, _ := s.hub.stream.Subscribe(streamService.Filter{}, nil)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		s.write()
+	}()
+	go func() {
+		defer wg.Done()
+		s.forward(client)
+	}()
+
+	s.read()
+	s.close(websocket.CloseNormalClosure, "")
+	s.hub.stream.Unsubscribe(client)
+	wg.Wait()
+}
+
+// close ends the session, sending code and text to the client
+func (s *session) close(code int, text string) {
+	s.closeMutex.Lock()
+	defer s.closeMutex.Unlock()
+	select {
+	case <-s.closed:
+	default:
+		s.closeCode, s.closeText = code, text
+		close(s.closed)
+	}
+}
+
+// enqueue queues message for the client, or disconnects a client that stopped reading rather
+// than let it hold up the changes and presence of everyone else
+func (s *session) enqueue(message []byte) {
+	select {
+	case s.send <- message:
+	default:
+		metrics.CountBoardEvent(metrics.BoardSlowClient)
+		s.close(websocket.CloseTryAgainLater, constants.ErrBoardClientTooSlow)
+	}
+}
+
+func (s *session) enqueueJSON(v interface{}) {
+	message, _ := json.Marshal(v)
+	s.enqueue(message)
+}
+
+func (s *session) write() {
+	ping := time.NewTicker(s.hub.currentOptions().PingInterval)
+	defer ping.Stop()
+	defer s.conn.Close()
+
+	for {
+		select {
+		case message := <-s.send:
+			s.conn.SetWriteDeadline(time.Now().Add(s.hub.currentOptions().WriteTimeout))
+			if err := s.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				s.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ping.C:
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(s.hub.currentOptions().WriteTimeout)); err != nil {
+				s.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-s.closed:
+			if s.closeCode != websocket.CloseAbnormalClosure {
+				message := websocket.FormatCloseMessage(s.closeCode, s.closeText)
+				s.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(s.hub.currentOptions().WriteTimeout))
+			}
+			return
+		}
+	}
+}
+
+// forward queues the changes matching the subscriptions of the session
+func (s *session) forward(client *streamService.Client) {
+	for {
+		select {
+		case <-s.closed:
+			return
+		case change, ok := <-client.C:
+			if !ok {
+				// The stream dropped us for falling behind
+				s.close(websocket.CloseTryAgainLater, constants.ErrBoardClientTooSlow)
+				return
+			}
+			if matched := s.matching(change.Event); len(matched) > 0 {
+				s.enqueueJSON(response.BoardChangeMessage{Type: response.BoardChange, Subscriptions: matched, ChangeID: change.ID, Event: change.Event})
+			}
+		}
+	}
+}
+
+func (s *session) matching(event *events.TaskEvent) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var matched []string
+	for id, sub := range s.subscriptions {
+		if sub.matches(event) {
+			matched = append(matched, id)
+		}
+	}
+	return matched
+}
+
+func (s *session) read() {
+	s.conn.SetReadLimit(s.hub.currentOptions().MaxMessageBytes)
+	pongWait := 2 * s.hub.currentOptions().PingInterval
+	s.conn.SetReadDeadline(time.Now().Add(pongWait))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if !s.allow() {
+			metrics.CountBoardEvent(metrics.BoardRateLimited)
+			s.enqueueJSON(response.BoardErrorMessage{Type: response.BoardError, Error: constants.ErrBoardRateLimited})
+			continue
+		}
+
+		var message request.BoardMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			s.enqueueJSON(response.BoardErrorMessage{Type: response.BoardError, Error: constants.ErrInvalidBoardMessage + ": " + err.Error()})
+			continue
+		}
+		if errText := s.handle(&message); errText != "" {
+			s.enqueueJSON(response.BoardErrorMessage{Type: response.BoardError, ID: message.ID, Error: errText})
+		}
+	}
+}
+
+// allow takes a token from the bucket, which refills at MessagesPerSecond up to one second's worth
+func (s *session) allow() bool {
+	rate := float64(s.hub.currentOptions().MessagesPerSecond)
+	now := time.Now()
+	s.tokens += now.Sub(s.refilled).Seconds() * rate
+	if s.tokens > rate {
+		s.tokens = rate
+	}
+	s.refilled = now
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+	return true
+}
+
+// handle applies a client message and returns the error to send back, if any
+func (s *session) handle(message *request.BoardMessage) string {
+	switch message.Type {
+	case request.BoardSubscribe:
+		return s.subscribe(message)
+	case request.BoardUnsubscribe:
+		s.mutex.Lock()
+		_, ok := s.subscriptions[message.ID]
+		delete(s.subscriptions, message.ID)
+		s.mutex.Unlock()
+		if !ok {
+			return constants.ErrSubscriptionNotFound
+		}
+		s.enqueueJSON(response.BoardAck{Type: response.BoardUnsubscribed, ID: message.ID})
+		return ""
+	case request.BoardView:
+		if _, err := uuid.Parse(message.TaskUUID); err != nil {
+			return constants.ErrInvalidTaskUUID
+		}
+		s.mutex.Lock()
+		if !s.viewing[message.TaskUUID] && len(s.viewing) >= s.hub.currentOptions().MaxSubscriptions {
+			s.mutex.Unlock()
+			return constants.ErrTooManyViewedTasks
+		}
+		s.viewing[message.TaskUUID] = true
+		s.mutex.Unlock()
+		s.hub.view(message.TaskUUID, s)
+		return ""
+	case request.BoardLeave:
+		s.mutex.Lock()
+		delete(s.viewing, message.TaskUUID)
+		s.mutex.Unlock()
+		s.hub.leave(message.TaskUUID, s)
+		return ""
+	default:
+		return constants.ErrUnknownBoardMessage
+	}
+}
+
+func (s *session) subscribe(message *request.BoardMessage) string {
+	if message.ID == "" {
+		return constants.ErrInvalidSubscriptionID
+	}
+	if (len(message.TaskUUIDs) == 0) == (message.Filter == nil) {
+		return constants.ErrInvalidSubscription
+	}
+
+	var sub subscription
+	if message.Filter != nil {
+		filter := message.Filter
+		if (filter.Status != "" && !enums.TaskStatus(filter.Status).IsValid()) ||
+			(filter.Priority != "" && !enums.TaskPriority(filter.Priority).IsValid()) {
+			return constants.ErrInvalidBoardFilter
+		}
+		sub.filter = &streamService.Filter{Status: filter.Status, UserID: filter.UserID, Priority: filter.Priority}
+	} else {
+		if len(message.TaskUUIDs) > s.hub.currentOptions().MaxTasksPerSubscription {
+			return constants.ErrTooManyBoardTasks
+		}
+		sub.taskUUIDs = make(map[string]bool, len(message.TaskUUIDs))
+		for _, taskUUID := range message.TaskUUIDs {
+			if _, err := uuid.Parse(taskUUID); err != nil {
+				return constants.ErrInvalidTaskUUID
+			}
+			sub.taskUUIDs[taskUUID] = true
+		}
+	}
+
+	s.mutex.Lock()
+	if _, ok := s.subscriptions[message.ID]; ok {
+		s.mutex.Unlock()
+		return constants.ErrSubscriptionExists
+	}
+	if len(s.subscriptions) >= s.hub.currentOptions().MaxSubscriptions {
+		s.mutex.Unlock()
+		return constants.ErrTooManySubscriptions
+	}
+	s.subscriptions[message.ID] = sub
+	s.mutex.Unlock()
+
+	s.enqueueJSON(response.BoardAck{Type: response.BoardSubscribed, ID: message.ID})
+	// Tell the client who is already looking at the tasks it subscribed to
+	for taskUUID := range sub.taskUUIDs {
+		s.hub.presence(taskUUID, s)
+	}
+	return ""
+}
+
+// watches reports whether s views taskUUID or subscribed to it by UUID
+func (s *session) watches(taskUUID string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.viewing[taskUUID] {
+		return true
+	}
+	for _, sub := range s.subscriptions {
+		if sub.taskUUIDs[taskUUID] {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *session) viewedTasks() map[string]bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	viewed := make(map[string]bool, len(s.viewing))
+	for taskUUID := range s.viewing {
+		viewed[taskUUID] = true
+	}
+	return viewed
+}
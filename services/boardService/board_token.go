@@ -0,0 +1,60 @@
+package boardService
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	stdErrors "errors"
+	"strings"
+	"time"
+)
+
+var errInvalidToken = stdErrors.New("invalid token")
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+}
+
+type tokenClaims struct {
+	Subject   string `json:"sub"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// VerifyToken checks a JSON Web Token signed with HS256 and secret, as issued by the auth
+// service in front of the boards, and returns its subject. The token must not be expired at
+// now and must carry both sub and exp.
+func VerifyToken(secret, token string, now time.Time) (string, error) {
+	parts := strings.Split(token, ".")
+	if secret == "" || len(parts) != 3 {
+		return "", errInvalidToken
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+		return "", errInvalidToken
+	}
+
+	var header tokenHeader
+	if decodeSegment(parts[0], &header) != nil || header.Alg != "HS256" {
+		return "", errInvalidToken
+	}
+	var claims tokenClaims
+	if decodeSegment(parts[1], &claims) != nil || claims.Subject == "" || claims.ExpiresAt == 0 {
+		return "", errInvalidToken
+	}
+	if !now.Before(time.Unix(claims.ExpiresAt, 0)) {
+		return "", errInvalidToken
+	}
+	return claims.Subject, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,49 @@
+package boardService
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signToken builds a token from the raw JSON of its header and claims
+func signToken(secret, header, claims string) string {
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString([]byte(claims))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyToken(t *testing.T) {
+	now := time.Unix(1000, 0)
+	valid := signToken("secret", `{"alg":"HS256","typ":"JWT"}`, `{"sub":"alice","exp":2000}`)
+	parts := strings.Split(valid, ".")
+
+	tests := []struct {
+		name   string
+		secret string
+		token  string
+		want   string
+	}{
+		{"valid", "secret", valid, "alice"},
+		{"wrong secret", "other", valid, ""},
+		{"no secret configured", "", valid, ""},
+		{"tampered claims", "secret", parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"bob","exp":2000}`)) + "." + parts[2], ""},
+		{"expired", "secret", signToken("secret", `{"alg":"HS256"}`, `{"sub":"alice","exp":1000}`), ""},
+		{"no expiry", "secret", signToken("secret", `{"alg":"HS256"}`, `{"sub":"alice"}`), ""},
+		{"no subject", "secret", signToken("secret", `{"alg":"HS256"}`, `{"exp":2000}`), ""},
+		{"other algorithm", "secret", signToken("secret", `{"alg":"none"}`, `{"sub":"alice","exp":2000}`), ""},
+		{"malformed", "secret", "not-a-token", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := VerifyToken(test.secret, test.token, now)
+			if got != test.want || (err == nil) != (test.want != "") {
+				t.Errorf("VerifyToken = %q, %v, want %q", got, err, test.want)
+			}
+		})
+	}
+}
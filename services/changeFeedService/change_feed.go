@@ -0,0 +1,185 @@
+package changeFeedService
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"task-manager-app/constants"
+	"task-manager-app/exceptions"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/models"
+	"task-manager-app/repo"
+	"task-manager-app/response"
+	"task-manager-app/services/streamService"
+	"time"
+)
+
+// FeedOptions configures the change feed
+type FeedOptions struct {
+	// MaxLimit caps the changes returned at once
+	MaxLimit int
+	// MaxWait caps how long a request waits for new changes
+	MaxWait time.Duration
+	// PollInterval is how often waiting requests look for changes the task stream did not
+	// announce, such as those published by other replicas with the memory stream backend
+	PollInterval time.Duration
+	// Clock times the waits of requests, time.Now when nil
+	Clock func() time.Time
+}
+
+// ChangeFeed serves the task change feed to clients that can only poll. Requests without new
+// changes wait for the task stream to announce one and then read the feed again.
+type ChangeFeed struct {
+	repo    repo.TaskChangeRepository
+	stream  *streamService.TaskStream
+	options FeedOptions
+
+	mutex sync.Mutex
+	// woken is closed and replaced whenever the stream carries a change
+	woken chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+func NewChangeFeed(repository repo.TaskChangeRepository, stream *streamService.TaskStream, options FeedOptions) *ChangeFeed {
+	if options.Clock == nil {
+		options.Clock = time.Now
+	}
+	return &ChangeFeed{
+		repo:    repository,
+		stream:  stream,
+		options: options,
+		woken:   make(chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start listens to the task stream in the background
+func (f *ChangeFeed) Start() {
+	go f.listen()
+}
+
+// Stop stops listening and answers waiting requests, so that they do not hold up a shutdown
+func (f *ChangeFeed) Stop() {
+	close(f.stop)
+	<-f.done
+}
+
+func (f *ChangeFeed) listen() {
+	defer close(f.done)
+	for {
+		client, _, _ := f.stream.Subscribe(streamService.Filter{}, nil)
+		if !f.forward(client) {
+			f.stream.Unsubscribe(client)
+			return
+		}
+		// The stream dropped the client; changes may have been missed, so wake everyone
+		f.wake()
+		select {
+		case <-f.stop:
+			return
+		case <-time.After(f.options.PollInterval):
+		}
+	}
+}
+
+// forward wakes waiting requests on every change of client until it is closed, and reports
+// false when the feed is stopping instead
+func (f *ChangeFeed) forward(client *streamService.Client) bool {
+	for {
+		select {
+		case <-f.stop:
+			return false
+		case _, ok := <-client.C:
+			if !ok {
+				return true
+			}
+			f.wake()
+		}
+	}
+}
+
+func (f *ChangeFeed) wake() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	close(f.woken)
+	f.woken = make(chan struct{})
+}
+
+func (f *ChangeFeed) wakeup() <-chan struct{} {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.woken
+}
+
+// Changes returns up to limit changes after since. When there are none it waits up to wait
+// for new ones, or until ctx is done, and returns an empty page if nothing changed.
+func (f *ChangeFeed) Changes(ctx context.Context, since uint64, limit int, wait time.Duration) (*response.TaskChangesResponse, *errors.TaskManagerError) {
+	if limit > f.options.MaxLimit {
+		limit = f.options.MaxLimit
+	}
+	if wait > f.options.MaxWait {
+		wait = f.options.MaxWait
+	}
+	deadline := f.options.Clock().Add(wait)
+
+	for {
+		// Take the wakeup before reading, so a change committed in between is not missed
+		woken := f.wakeup()
+		changes, taskErr := f.repo.ListSince(since, limit+1)
+		if taskErr != nil {
+			return nil, taskErr
+		}
+		remaining := deadline.Sub(f.options.Clock())
+		if len(changes) > 0 || remaining <= 0 {
+			return page(since, changes, limit)
+		}
+
+		if remaining > f.options.PollInterval {
+			remaining = f.options.PollInterval
+		}
+		timer := time.NewTimer(remaining)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return page(since, nil, limit)
+		case <-f.stop:
+			timer.Stop()
+			return page(since, nil, limit)
+		case <-woken:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// page converts changes, read with one more than limit to tell whether more follow
+func page(since uint64, changes []models.TaskChange, limit int) (*response.TaskChangesResponse, *errors.TaskManagerError) {
+	resp := &response.TaskChangesResponse{
+		Changes:   []response.TaskChange{},
+		NextSince: since,
+		HasMore:   len(changes) > limit,
+	}
+	if resp.HasMore {
+		changes = changes[:limit]
+	}
+	for _, change := range changes {
+		converted := response.TaskChange{
+			Seq:       change.Seq,
+			TaskUUID:  change.TaskUUID,
+			EventType: change.EventType,
+			Deleted:   change.Deleted,
+			ChangedAt: change.ChangedAt,
+		}
+		if !change.Deleted {
+			converted.Task = &response.TaskResponse{}
+			if err := json.Unmarshal(change.Task, converted.Task); err != nil {
+				return nil, exceptions.InternalServerException(constants.ErrFailedToGetChanges + ": " + err.Error())
+			}
+		}
+		resp.Changes = append(resp.Changes, converted)
+		resp.NextSince = change.Seq
+	}
+	return resp, nil
+}
@@ -0,0 +1,157 @@
+package changeFeedService
+
+import (
+	"context"
+	"os"
+	"sync"
+	"task-manager-app/events"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/models"
+	"task-manager-app/repo"
+	"task-manager-app/services/streamService"
+	"task-manager-app/utils"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	utils.Sugar = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
+
+// memoryChanges is a change feed table that the tests append to
+type memoryChanges struct {
+	repo.TaskChangeRepository
+	mutex   sync.Mutex
+	changes []models.TaskChange
+}
+
+func (m *memoryChanges) ListSince(since uint64, limit int) ([]models.TaskChange, *errors.TaskManagerError) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var changes []models.TaskChange
+	for _, change := range m.changes {
+		if change.Seq > since && len(changes) < limit {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (m *memoryChanges) add(change models.TaskChange) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.changes = append(m.changes, change)
+}
+
+func taskChange(seq uint64, taskUUID string) models.TaskChange {
+	return models.TaskChange{Seq: seq, TaskUUID: taskUUID, EventType: events.TaskUpdated, Task: []byte(`{"uuid":"` + taskUUID + `","status":"Pending"}`)}
+}
+
+func startFeed(t *testing.T, pollInterval time.Duration) (*memoryChanges, *events.MemoryChangeBus, *ChangeFeed) {
+	bus := events.NewMemoryChangeBus()
+	stream := streamService.NewTaskStream(bus, streamService.StreamOptions{ReplaySize: 10, ClientBuffer: 10})
+	if err := stream.Start(); err != nil {
+		t.Fatalf("Start returned error %v", err)
+	}
+	changes := &memoryChanges{}
+	feed := NewChangeFeed(changes, stream, FeedOptions{MaxLimit: 2, MaxWait: time.Minute, PollInterval: pollInterval})
+	feed.Start()
+	t.Cleanup(func() {
+		feed.Stop()
+		stream.Stop()
+	})
+	return changes, bus, feed
+}
+
+func TestChangesReturnsPagesOfChangesAndTombstones(t *testing.T) {
+	changes, _, feed := startFeed(t, time.Hour)
+	changes.add(taskChange(1, "a"))
+	changes.add(models.TaskChange{Seq: 2, TaskUUID: "b", EventType: events.TaskDeleted, Deleted: true})
+	changes.add(taskChange(3, "c"))
+
+	first, taskErr := feed.Changes(context.Background(), 0, 10, 0)
+	if taskErr != nil {
+		t.Fatalf("Changes returned error %v", taskErr)
+	}
+	if len(first.Changes) != 2 || !first.HasMore || first.NextSince != 2 {
+		t.Fatalf("got %+v, want a page of 2 capped by MaxLimit with more to come", first)
+	}
+	if first.Changes[0].Task == nil || first.Changes[0].Task.UUID != "a" || !first.Changes[1].Deleted || first.Changes[1].Task != nil {
+		t.Errorf("got %+v and %+v, want task a and the tombstone of b", first.Changes[0], first.Changes[1])
+	}
+
+	second, _ := feed.Changes(context.Background(), first.NextSince, 10, 0)
+	if len(second.Changes) != 1 || second.HasMore || second.NextSince != 3 {
+		t.Errorf("got %+v, want the last change", second)
+	}
+}
+
+func TestChangesWaitsForTheStreamToAnnounceAChange(t *testing.T) {
+	changes, bus, feed := startFeed(t, time.Hour)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		changes.add(taskChange(1, "a"))
+		bus.Publish(context.Background(), &events.TaskEvent{EventID: "e1", TaskUUID: "a", Task: &events.TaskSnapshot{UUID: "a"}})
+	}()
+
+	start := time.Now()
+	resp, _ := feed.Changes(context.Background(), 0, 10, 10*time.Second)
+	if len(resp.Changes) != 1 || resp.NextSince != 1 {
+		t.Errorf("got %+v, want the announced change", resp)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("waited %v, want to be woken by the stream", elapsed)
+	}
+}
+
+func TestChangesPollsForChangesTheStreamMissed(t *testing.T) {
+	changes, _, feed := startFeed(t, 10*time.Millisecond)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		changes.add(taskChange(5, "a"))
+	}()
+
+	if resp, _ := feed.Changes(context.Background(), 4, 10, 10*time.Second); len(resp.Changes) != 1 {
+		t.Errorf("got %+v, want the change found by polling", resp)
+	}
+}
+
+func TestChangesReturnsAnEmptyPageWhenNothingChanged(t *testing.T) {
+	_, _, feed := startFeed(t, time.Hour)
+
+	resp, _ := feed.Changes(context.Background(), 7, 10, 20*time.Millisecond)
+	if len(resp.Changes) != 0 || resp.NextSince != 7 || resp.HasMore {
+		t.Errorf("got %+v, want an empty page resuming from 7", resp)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if resp, _ := feed.Changes(ctx, 7, 10, time.Minute); len(resp.Changes) != 0 {
+		t.Errorf("got %+v from a cancelled request, want an empty page", resp)
+	}
+}
+
+func TestChangesTimesTheWaitWithTheClock(t *testing.T) {
+	bus := events.NewMemoryChangeBus()
+	stream := streamService.NewTaskStream(bus, streamService.StreamOptions{ReplaySize: 10, ClientBuffer: 10})
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	// Every reading of the clock is a minute later, so the wait is over on the first check
+	clock := func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+	feed := NewChangeFeed(&memoryChanges{}, stream, FeedOptions{MaxLimit: 2, MaxWait: time.Minute, PollInterval: time.Hour, Clock: clock})
+
+	start := time.Now()
+	resp, _ := feed.Changes(context.Background(), 3, 10, 30*time.Second)
+	if len(resp.Changes) != 0 || resp.NextSince != 3 {
+		t.Errorf("got %+v, want an empty page", resp)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("waited %v, want the wait to end by the clock", elapsed)
+	}
+}
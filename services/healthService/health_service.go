@@ -0,0 +1,103 @@
+package healthService
+
+import (
+	"context"
+	"sync"
+	"task-manager-app/constants"
+	"task-manager-app/response"
+	"time"
+)
+
+// Check probes one dependency. Readiness fails when a critical check fails; the others are
+// only reported, for dependencies the service can do without for a while.
+type Check struct {
+	Name     string
+	Critical bool
+	Probe    func(ctx context.Context) error
+}
+
+// HealthService answers liveness and readiness probes
+type HealthService struct {
+	checks  []Check
+	timeout time.Duration
+
+	mutex sync.RWMutex
+	// notReadyReason is set while the instance is marked not ready
+	notReadyReason string
+}
+
+// NewHealthService checks the given dependencies on every readiness probe, each bounded by
+// timeout
+func NewHealthService(timeout time.Duration, checks ...Check) *HealthService {
+	return &HealthService{
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+// MarkNotReady fails readiness with reason until MarkReady, whatever the dependencies say
+func (h *HealthService) MarkNotReady(reason string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.notReadyReason = reason
+}
+
+// MarkReady lets readiness follow the dependencies again
+func (h *HealthService) MarkReady() {
+	h.MarkNotReady("")
+}
+
+// Ready checks every dependency concurrently and reports whether the instance is ready
+func (h *HealthService) Ready(ctx context.Context) (*response.ReadinessResponse, bool) {
+	statuses := make([]response.DependencyStatus, len(h.checks))
+	var wait sync.WaitGroup
+	for i, check := range h.checks {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			statuses[i] = h.run(ctx, check)
+		}()
+	}
+	wait.Wait()
+
+	resp := &response.ReadinessResponse{
+		Status:       constants.HealthStatusReady,
+		Dependencies: make(map[string]response.DependencyStatus, len(h.checks)),
+	}
+	ready := true
+	for i, check := range h.checks {
+		resp.Dependencies[check.Name] = statuses[i]
+		if check.Critical && statuses[i].Status != constants.HealthStatusUp {
+			ready = false
+		}
+	}
+
+	h.mutex.RLock()
+	resp.Reason = h.notReadyReason
+	h.mutex.RUnlock()
+	if resp.Reason != "" {
+		ready = false
+	}
+	if !ready {
+		resp.Status = constants.HealthStatusNotReady
+	}
+	return resp, ready
+}
+
+func (h *HealthService) run(ctx context.Context, check Check) response.DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Probe(ctx)
+	status := response.DependencyStatus{
+		Status:    constants.HealthStatusUp,
+		Critical:  check.Critical,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		status.Status = constants.HealthStatusDown
+		status.Error = err.Error()
+	}
+	return status
+}
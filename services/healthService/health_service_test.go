@@ -0,0 +1,77 @@
+package healthService
+
+import (
+	"context"
+	"errors"
+	"task-manager-app/constants"
+	"testing"
+	"time"
+)
+
+func up(context.Context) error { return nil }
+
+func down(context.Context) error { return errors.New("connection refused") }
+
+func TestReadyFollowsCriticalDependencies(t *testing.T) {
+	tests := []struct {
+		name      string
+		checks    []Check
+		wantReady bool
+	}{
+		{name: "all up", checks: []Check{{Name: "database", Critical: true, Probe: up}, {Name: "kafka", Probe: up}}, wantReady: true},
+		{name: "optional down", checks: []Check{{Name: "database", Critical: true, Probe: up}, {Name: "kafka", Probe: down}}, wantReady: true},
+		{name: "critical down", checks: []Check{{Name: "database", Critical: true, Probe: down}, {Name: "kafka", Probe: up}}, wantReady: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, ready := NewHealthService(time.Second, test.checks...).Ready(context.Background())
+			if ready != test.wantReady {
+				t.Fatalf("ready = %t, want %t", ready, test.wantReady)
+			}
+			wantStatus := constants.HealthStatusReady
+			if !test.wantReady {
+				wantStatus = constants.HealthStatusNotReady
+			}
+			if resp.Status != wantStatus || len(resp.Dependencies) != len(test.checks) {
+				t.Fatalf("response = %+v, want %s with %d dependencies", resp, wantStatus, len(test.checks))
+			}
+			for _, check := range test.checks {
+				status := resp.Dependencies[check.Name]
+				failed := check.Probe(context.Background()) != nil
+				if (status.Status == constants.HealthStatusDown) != failed || (status.Error != "") != failed || status.Critical != check.Critical {
+					t.Errorf("%s reported as %+v", check.Name, status)
+				}
+			}
+		})
+	}
+}
+
+func TestReadyBoundsSlowChecks(t *testing.T) {
+	hanging := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	start := time.Now()
+	resp, ready := NewHealthService(20*time.Millisecond, Check{Name: "database", Critical: true, Probe: hanging}).Ready(context.Background())
+	if ready || resp.Dependencies["database"].Status != constants.HealthStatusDown {
+		t.Fatalf("a hanging check reported %+v", resp)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("readiness took %v", elapsed)
+	}
+}
+
+func TestMarkNotReadyOverridesDependencies(t *testing.T) {
+	health := NewHealthService(time.Second, Check{Name: "database", Critical: true, Probe: up})
+
+	health.MarkNotReady("shutting down")
+	resp, ready := health.Ready(context.Background())
+	if ready || resp.Reason != "shutting down" || resp.Dependencies["database"].Status != constants.HealthStatusUp {
+		t.Fatalf("marked not ready, got ready=%t %+v", ready, resp)
+	}
+
+	health.MarkReady()
+	if resp, ready := health.Ready(context.Background()); !ready || resp.Reason != "" {
+		t.Fatalf("marked ready again, got ready=%t %+v", ready, resp)
+	}
+}
@@ -0,0 +1,168 @@
+package idempotencyService
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"task-manager-app/constants"
+	"task-manager-app/exceptions"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/models"
+	"task-manager-app/repo"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// purgeInterval bounds how often expired keys are cleaned up
+const purgeInterval = time.Minute
+
+// maxReserveAttempts bounds how often Begin retries when the stored key changes under it
+const maxReserveAttempts = 3
+
+type IdempotencyService interface {
+	Begin(scope, key, fingerprint string) (*models.IdempotencyKey, *errors.TaskManagerError)
+	Complete(reservation *models.IdempotencyKey, responseCode int, responseBody []byte) *errors.TaskManagerError
+	Release(reservation *models.IdempotencyKey)
+}
+
+type idempotencyService struct {
+	repo      repo.IdempotencyRepository
+	ttl       time.Duration
+	lease     time.Duration
+	now       func() time.Time
+	logger    *zap.SugaredLogger
+	mutex     sync.Mutex
+	lastPurge time.Time
+}
+
+// NewIdempotencyService keeps completed keys for ttl. A request holds its key for at most
+// lease; after that a retry takes the key over, e.g. when the process handling it died.
+// Expiry is measured with now; failures are logged to logger.
+func NewIdempotencyService(repository repo.IdempotencyRepository, ttl, lease time.Duration, now func() time.Time, logger *zap.SugaredLogger) IdempotencyService {
+	return &idempotencyService{
+		repo:   repository,
+		ttl:    ttl,
+		lease:  lease,
+		now:    now,
+		logger: logger,
+	}
+}
+
+// Scope identifies the caller and the route a key belongs to, so that the same key sent by
+// different callers or to different routes does not collide. The caller is hashed along
+// with the rest, so credentials used to identify it are never stored.
+func Scope(caller, method, route string) string {
+	sum := sha256.New()
+	sum.Write([]byte(caller))
+	sum.Write([]byte{0})
+	sum.Write([]byte(method))
+	sum.Write([]byte{0})
+	sum.Write([]byte(route))
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// Fingerprint hashes the parts of a request that must match for a replay
+func Fingerprint(method, path string, body []byte) string {
+	sum := sha256.New()
+	sum.Write([]byte(method))
+	sum.Write([]byte{0})
+	sum.Write([]byte(path))
+	sum.Write([]byte{0})
+	sum.Write(body)
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// Begin reserves the key for a new request. It returns either the completed record of an
+// earlier request, whose response should be replayed, or the reservation of this request,
+// which the caller must pass to Complete or Release once it has been processed.
+func (s *idempotencyService) Begin(scope, key, fingerprint string) (*models.IdempotencyKey, *errors.TaskManagerError) {
+	if key == "" || len(key) > constants.MaxIdempotencyKeyLength {
+		return nil, exceptions.NewBadRequestException(constants.ErrInvalidIdempotencyKey)
+	}
+	s.purgeExpired()
+
+	for attempt := 0; attempt < maxReserveAttempts; attempt++ {
+		now := s.now()
+		reservation := &models.IdempotencyKey{
+			Scope:       scope,
+			Key:         key,
+			RequestHash: fingerprint,
+			LeaseToken:  uuid.New().String(),
+			ExpiresAt:   now.Add(s.lease),
+		}
+		reserved, taskErr := s.repo.Reserve(reservation)
+		if taskErr != nil {
+			return nil, taskErr
+		}
+		if reserved {
+			return reservation, nil
+		}
+
+		existing, taskErr := s.repo.GetByKey(scope, key)
+		if taskErr != nil {
+			return nil, taskErr
+		}
+		if existing == nil {
+			// Released since Reserve, so try again
+			continue
+		}
+		if existing.ExpiresAt.After(now) {
+			if existing.RequestHash != fingerprint {
+				return nil, exceptions.UnprocessableEntityException(constants.ErrIdempotencyKeyReused)
+			}
+			if !existing.IsCompleted() {
+				return nil, exceptions.ConflictException(constants.ErrIdempotencyKeyInFlight)
+			}
+			return existing, nil
+		}
+
+		// The replay window has passed, or the request holding the lease never finished
+		taken, taskErr := s.repo.TakeOver(reservation, now)
+		if taskErr != nil {
+			return nil, taskErr
+		}
+		if taken {
+			return reservation, nil
+		}
+	}
+	// Other requests keep winning the key, so this one is as good as concurrent with them
+	return nil, exceptions.ConflictException(constants.ErrIdempotencyKeyInFlight)
+}
+
+// Complete stores the response that retries with the same key will receive for the replay
+// window. When the lease ran out and another request took the key over, the response is
+// not stored and the retries get the response of that request instead.
+func (s *idempotencyService) Complete(reservation *models.IdempotencyKey, responseCode int, responseBody []byte) *errors.TaskManagerError {
+	stored, taskErr := s.repo.Complete(reservation, responseCode, responseBody, s.now().Add(s.ttl))
+	if taskErr != nil {
+		return taskErr
+	}
+	if !stored {
+		s.logger.Warnf("Idempotency key %s was taken over before its response was stored", reservation.Key)
+	}
+	return nil
+}
+
+// Release drops a reservation so that the request can be retried, e.g. after a server error
+func (s *idempotencyService) Release(reservation *models.IdempotencyKey) {
+	if taskErr := s.repo.Release(reservation); taskErr != nil {
+		s.logger.Errorf("Failed to release idempotency key %s: %s", reservation.Key, taskErr.Message)
+	}
+}
+
+func (s *idempotencyService) purgeExpired() {
+	s.mutex.Lock()
+	now := s.now()
+	if now.Sub(s.lastPurge) < purgeInterval {
+		s.mutex.Unlock()
+		return
+	}
+	s.lastPurge = now
+	s.mutex.Unlock()
+
+	if taskErr := s.repo.DeleteExpired(now); taskErr != nil {
+		s.logger.Errorf("Failed to purge expired idempotency keys: %s", taskErr.Message)
+	}
+}
@@ -0,0 +1,212 @@
+package idempotencyService
+
+import (
+	"net/http"
+	"os"
+	"sync"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/models"
+	"task-manager-app/utils"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	utils.Sugar = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
+
+// memoryRepository keeps keys like the idempotency_keys table, with (scope, key) unique
+type memoryRepository struct {
+	mutex   sync.Mutex
+	records map[[2]string]models.IdempotencyKey
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{records: make(map[[2]string]models.IdempotencyKey)}
+}
+
+func (r *memoryRepository) Reserve(record *models.IdempotencyKey) (bool, *errors.TaskManagerError) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	id := [2]string{record.Scope, record.Key}
+	if _, ok := r.records[id]; ok {
+		return false, nil
+	}
+	r.records[id] = *record
+	return true, nil
+}
+
+func (r *memoryRepository) TakeOver(record *models.IdempotencyKey, now time.Time) (bool, *errors.TaskManagerError) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	id := [2]string{record.Scope, record.Key}
+	if stored, ok := r.records[id]; !ok || stored.ExpiresAt.After(now) {
+		return false, nil
+	}
+	r.records[id] = *record
+	return true, nil
+}
+
+func (r *memoryRepository) GetByKey(scope, key string) (*models.IdempotencyKey, *errors.TaskManagerError) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stored, ok := r.records[[2]string{scope, key}]
+	if !ok {
+		return nil, nil
+	}
+	return &stored, nil
+}
+
+func (r *memoryRepository) Complete(record *models.IdempotencyKey, responseCode int, responseBody []byte, expiresAt time.Time) (bool, *errors.TaskManagerError) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	id := [2]string{record.Scope, record.Key}
+	stored, ok := r.records[id]
+	if !ok || stored.LeaseToken != record.LeaseToken || stored.IsCompleted() {
+		return false, nil
+	}
+	stored.ResponseCode, stored.ResponseBody, stored.ExpiresAt = responseCode, responseBody, expiresAt
+	r.records[id] = stored
+	return true, nil
+}
+
+func (r *memoryRepository) Release(record *models.IdempotencyKey) *errors.TaskManagerError {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	id := [2]string{record.Scope, record.Key}
+	if stored, ok := r.records[id]; ok && stored.LeaseToken == record.LeaseToken && !stored.IsCompleted() {
+		delete(r.records, id)
+	}
+	return nil
+}
+
+func (r *memoryRepository) DeleteExpired(before time.Time) *errors.TaskManagerError {
+	return nil
+}
+
+// newTestService returns a service with a one hour replay window and a one minute lease
+// whose clock only moves when the returned function is called
+func newTestService(repository *memoryRepository) (*idempotencyService, func(time.Duration)) {
+	now := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	service := NewIdempotencyService(repository, time.Hour, time.Minute, time.Now, zap.NewNop().Sugar()).(*idempotencyService)
+	service.now = func() time.Time { return now }
+	return service, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestBeginReplaysCompletedResponse(t *testing.T) {
+	service, advance := newTestService(newMemoryRepository())
+	scope := Scope("ip:10.0.0.1", http.MethodPost, "/tasks")
+
+	reservation, taskErr := service.Begin(scope, "key-1", "hash")
+	if taskErr != nil || reservation.IsCompleted() {
+		t.Fatalf("first Begin = %+v, %v, want a new reservation", reservation, taskErr)
+	}
+	if taskErr := service.Complete(reservation, http.StatusCreated, []byte(`{"uuid":"t1"}`)); taskErr != nil {
+		t.Fatalf("Complete returned %v", taskErr)
+	}
+
+	// Well past the lease, but within the replay window
+	advance(30 * time.Minute)
+	replay, taskErr := service.Begin(scope, "key-1", "hash")
+	if taskErr != nil || !replay.IsCompleted() {
+		t.Fatalf("retry Begin = %+v, %v, want the stored response", replay, taskErr)
+	}
+	if replay.ResponseCode != http.StatusCreated || string(replay.ResponseBody) != `{"uuid":"t1"}` {
+		t.Fatalf("replayed %d %s, want 201 with the first body", replay.ResponseCode, replay.ResponseBody)
+	}
+}
+
+func TestBeginRejectsKeyReusedWithDifferentBody(t *testing.T) {
+	service, _ := newTestService(newMemoryRepository())
+	scope := Scope("ip:10.0.0.1", http.MethodPost, "/tasks")
+	reservation, _ := service.Begin(scope, "key-1", "hash")
+	service.Complete(reservation, http.StatusCreated, []byte(`{}`))
+
+	if _, taskErr := service.Begin(scope, "key-1", "other hash"); taskErr == nil || taskErr.ResponseCode != http.StatusUnprocessableEntity {
+		t.Fatalf("Begin with another body = %v, want 422", taskErr)
+	}
+}
+
+func TestBeginRejectsRetryWhileInFlight(t *testing.T) {
+	service, advance := newTestService(newMemoryRepository())
+	scope := Scope("ip:10.0.0.1", http.MethodPost, "/tasks")
+	if _, taskErr := service.Begin(scope, "key-1", "hash"); taskErr != nil {
+		t.Fatalf("Begin returned %v", taskErr)
+	}
+
+	advance(59 * time.Second)
+	if _, taskErr := service.Begin(scope, "key-1", "hash"); taskErr == nil || taskErr.ResponseCode != http.StatusConflict {
+		t.Fatalf("retry within the lease = %v, want 409", taskErr)
+	}
+}
+
+func TestBeginTakesOverExpiredLease(t *testing.T) {
+	service, advance := newTestService(newMemoryRepository())
+	scope := Scope("ip:10.0.0.1", http.MethodPost, "/tasks")
+	abandoned, _ := service.Begin(scope, "key-1", "hash")
+
+	// The request holding the key never finishes, e.g. because its process died
+	advance(time.Minute)
+	takeover, taskErr := service.Begin(scope, "key-1", "hash")
+	if taskErr != nil || takeover.IsCompleted() || takeover.LeaseToken == abandoned.LeaseToken {
+		t.Fatalf("retry after the lease = %+v, %v, want a new reservation", takeover, taskErr)
+	}
+
+	// A late response of the first request must not replace the one of the retry
+	service.Complete(abandoned, http.StatusCreated, []byte(`{"uuid":"late"}`))
+	service.Release(abandoned)
+	service.Complete(takeover, http.StatusCreated, []byte(`{"uuid":"retry"}`))
+	replay, _ := service.Begin(scope, "key-1", "hash")
+	if replay == nil || string(replay.ResponseBody) != `{"uuid":"retry"}` {
+		t.Fatalf("replay = %+v, want the response of the request that took the key over", replay)
+	}
+}
+
+func TestBeginScopesKeysToCallerAndRoute(t *testing.T) {
+	service, _ := newTestService(newMemoryRepository())
+	scopes := []string{
+		Scope("ip:10.0.0.1", http.MethodPost, "/tasks"),
+		Scope("ip:10.0.0.2", http.MethodPost, "/tasks"),
+		Scope("ip:10.0.0.1", http.MethodPut, "/tasks/:uuid"),
+	}
+	for _, scope := range scopes {
+		if reservation, taskErr := service.Begin(scope, "key-1", "hash"); taskErr != nil || reservation.IsCompleted() {
+			t.Fatalf("Begin in scope %s = %+v, %v, want a reservation of its own", scope, reservation, taskErr)
+		}
+	}
+}
+
+func TestConcurrentBeginReservesKeyOnce(t *testing.T) {
+	service, _ := newTestService(newMemoryRepository())
+	scope := Scope("ip:10.0.0.1", http.MethodPost, "/tasks")
+
+	const requests = 20
+	var wg sync.WaitGroup
+	results := make(chan *errors.TaskManagerError, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, taskErr := service.Begin(scope, "key-1", "hash")
+			results <- taskErr
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	reserved := 0
+	for taskErr := range results {
+		switch {
+		case taskErr == nil:
+			reserved++
+		case taskErr.ResponseCode != http.StatusConflict:
+			t.Errorf("Begin = %v, want 409 for the requests that lost", taskErr)
+		}
+	}
+	if reserved != 1 {
+		t.Fatalf("%d requests reserved the key, want exactly 1", reserved)
+	}
+}
@@ -0,0 +1,221 @@
+package outboxService
+
+import (
+	"context"
+	"encoding/json"
+	"task-manager-app/events"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/metrics"
+	"task-manager-app/models"
+	"task-manager-app/repo"
+	"task-manager-app/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RelayOptions configures the outbox relay
+type RelayOptions struct {
+	PollInterval   time.Duration
+	BatchSize      int
+	BaseBackoff    time.Duration
+	MaxBackoff     time.Duration
+	MaxAttempts    int
+	Lease          time.Duration
+	PublishTimeout time.Duration
+	Retention      time.Duration
+	// Logger receives the relay logs, utils.Sugar when nil
+	Logger *zap.SugaredLogger
+	// Clock stamps deliveries and leases, time.Now when nil
+	Clock func() time.Time
+}
+
+// OutboxRelay publishes outbox events in order and marks them delivered. Events of one task
+// are never published out of order: when one fails, later events of that task wait for it
+// until it is delivered or dead-lettered after MaxAttempts. Events are leased while they are
+// published, so replicas can relay side by side without a transaction held open on Kafka.
+// Each claim carries its own token: an event is only settled by the relay holding it, and a
+// relay stops publishing before its lease could run out.
+type OutboxRelay struct {
+	repo      repo.OutboxRepository
+	publisher events.Publisher
+	options   RelayOptions
+	now       func() time.Time
+
+	stop      chan struct{}
+	done      chan struct{}
+	lastPurge time.Time
+}
+
+func NewOutboxRelay(repository repo.OutboxRepository, publisher events.Publisher, options RelayOptions) *OutboxRelay {
+	if options.Logger == nil {
+		options.Logger = utils.Sugar
+	}
+	if options.Clock == nil {
+		options.Clock = time.Now
+	}
+	return &OutboxRelay{
+		repo:      repository,
+		publisher: publisher,
+		options:   options,
+		now:       options.Clock,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start runs the relay in the background until Stop is called
+func (r *OutboxRelay) Start() {
+	go r.run()
+}
+
+// Stop asks the relay to finish the event it is publishing and waits for it or for ctx to
+// expire. Events claimed but not yet attempted are released for the next relay.
+func (r *OutboxRelay) Stop(ctx context.Context) error {
+	close(r.stop)
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *OutboxRelay) run() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.RelayOnce()
+		}
+	}
+}
+
+// claim is a batch of events leased by one call of RelayOnce
+type claim struct {
+	token      string
+	leaseUntil time.Time
+	batch      []models.OutboxEvent
+}
+
+// RelayOnce delivers one batch of due events and refreshes the lag metrics
+func (r *OutboxRelay) RelayOnce() {
+	now := r.now()
+	current := claim{token: uuid.New().String(), leaseUntil: now.Add(r.options.Lease)}
+	batch, taskErr := r.repo.ClaimDue(r.options.BatchSize, now, current.leaseUntil, current.token)
+	current.batch = batch
+	if taskErr != nil {
+		r.options.Logger.Errorf("Outbox relay failed to claim events: %s", taskErr.Message)
+	} else if taskErr = r.deliver(current); taskErr != nil {
+		r.options.Logger.Errorf("Outbox relay failed: %s", taskErr.Message)
+	}
+	r.recordLag()
+	r.purgeDelivered()
+}
+
+// deliver publishes the events of current in order. It stops when asked to, when a publish
+// could outlast the lease, or once the lease turns out to be lost, so that no event is
+// published while another relay may hold it.
+func (r *OutboxRelay) deliver(current claim) *errors.TaskManagerError {
+	blocked := make(map[string]bool)
+	var unattempted []uint64
+
+	for i, row := range current.batch {
+		if r.stopping() || r.now().Add(r.options.PublishTimeout).After(current.leaseUntil) {
+			for _, rest := range current.batch[i:] {
+				unattempted = append(unattempted, rest.ID)
+			}
+			break
+		}
+		// A later event of a task that just failed waits until the failed one is settled
+		if blocked[row.AggregateID] {
+			unattempted = append(unattempted, row.ID)
+			continue
+		}
+
+		var event events.TaskEvent
+		var held bool
+		var taskErr *errors.TaskManagerError
+		if err := json.Unmarshal(row.Payload, &event); err != nil {
+			held, taskErr = r.deadLetter(current.token, row, err)
+		} else if err := r.publish(&event); err != nil {
+			metrics.CountOutboxEvent(metrics.ResultFailed)
+			attempts := row.Attempts + 1
+			if r.options.MaxAttempts > 0 && attempts >= r.options.MaxAttempts {
+				held, taskErr = r.deadLetter(current.token, row, err)
+			} else {
+				blocked[row.AggregateID] = true
+				r.options.Logger.Warnf("Failed to publish outbox event %d (%s) for task %s, attempt %d: %v", row.ID, row.EventType, row.AggregateID, attempts, err)
+				nextAttemptAt := r.now().Add(utils.ExponentialBackoff(r.options.BaseBackoff, r.options.MaxBackoff, attempts))
+				held, taskErr = r.repo.MarkFailed(row.ID, current.token, err.Error(), nextAttemptAt)
+			}
+		} else {
+			held, taskErr = r.repo.MarkDelivered(row.ID, current.token)
+			if held {
+				metrics.CountOutboxEvent(metrics.ResultDelivered)
+			}
+		}
+		if taskErr != nil {
+			return taskErr
+		}
+		if !held {
+			r.options.Logger.Warnf("Outbox relay lost the lease of event %d (%s) for task %s; leaving the rest of its batch to the relay that claimed it", row.ID, row.EventType, row.AggregateID)
+			return nil
+		}
+	}
+	return r.repo.Release(unattempted, current.token, r.now())
+}
+
+func (r *OutboxRelay) publish(event *events.TaskEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.options.PublishTimeout)
+	defer cancel()
+	return r.publisher.Publish(ctx, event)
+}
+
+// deadLetter gives up on an event that cannot be read or has used up its attempts
+func (r *OutboxRelay) deadLetter(token string, row models.OutboxEvent, err error) (bool, *errors.TaskManagerError) {
+	held, taskErr := r.repo.MarkDeadLettered(row.ID, token, err.Error(), r.now())
+	if held {
+		metrics.CountOutboxEvent(metrics.ResultDeadLettered)
+		r.options.Logger.Errorf("Dead-lettered outbox event %d (%s) for task %s after %d attempts: %v", row.ID, row.EventType, row.AggregateID, row.Attempts+1, err)
+	}
+	return held, taskErr
+}
+
+func (r *OutboxRelay) stopping() bool {
+	select {
+	case <-r.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *OutboxRelay) recordLag() {
+	pending, oldest, taskErr := r.repo.Stats()
+	if taskErr != nil {
+		return
+	}
+	lag := 0.0
+	if oldest != nil {
+		lag = r.now().Sub(*oldest).Seconds()
+	}
+	metrics.SetOutboxBacklog(pending, lag)
+}
+
+func (r *OutboxRelay) purgeDelivered() {
+	now := r.now()
+	if r.options.Retention <= 0 || now.Sub(r.lastPurge) < time.Hour {
+		return
+	}
+	r.lastPurge = now
+	if taskErr := r.repo.DeleteDelivered(now.Add(-r.options.Retention)); taskErr != nil {
+		r.options.Logger.Errorf("Failed to purge delivered outbox events: %s", taskErr.Message)
+	}
+}
@@ -0,0 +1,366 @@
+package outboxService
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"task-manager-app/events"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/models"
+	"task-manager-app/utils"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	utils.Sugar = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
+
+// memoryOutbox claims events like the outbox_events table: a task's event is held back while
+// an earlier undelivered event of the task is leased or waiting for a retry
+type memoryOutbox struct {
+	mutex  sync.Mutex
+	nextID uint64
+	rows   map[uint64]*models.OutboxEvent
+}
+
+func newMemoryOutbox() *memoryOutbox {
+	return &memoryOutbox{rows: make(map[uint64]*models.OutboxEvent)}
+}
+
+func (o *memoryOutbox) Add(taskEvents ...*events.TaskEvent) *errors.TaskManagerError {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for _, event := range taskEvents {
+		payload, _ := json.Marshal(event)
+		o.addRow(event.TaskUUID, event.EventType, payload)
+	}
+	return nil
+}
+
+func (o *memoryOutbox) addRow(aggregateID, eventType string, payload []byte) uint64 {
+	o.nextID++
+	o.rows[o.nextID] = &models.OutboxEvent{ID: o.nextID, AggregateID: aggregateID, EventType: eventType, Payload: payload}
+	return o.nextID
+}
+
+func (o *memoryOutbox) ClaimDue(limit int, now, leaseUntil time.Time, token string) ([]models.OutboxEvent, *errors.TaskManagerError) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	ids := make([]uint64, 0, len(o.rows))
+	for id := range o.rows {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	waiting := make(map[string]bool)
+	var batch []models.OutboxEvent
+	for _, id := range ids {
+		row := o.rows[id]
+		if row.DeliveredAt != nil || row.FailedAt != nil {
+			continue
+		}
+		if row.NextAttemptAt.After(now) {
+			waiting[row.AggregateID] = true
+			continue
+		}
+		if waiting[row.AggregateID] || len(batch) == limit {
+			continue
+		}
+		row.NextAttemptAt = leaseUntil
+		row.LeaseToken = token
+		batch = append(batch, *row)
+	}
+	return batch, nil
+}
+
+func (o *memoryOutbox) Release(ids []uint64, token string, now time.Time) *errors.TaskManagerError {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for _, id := range ids {
+		if o.rows[id].LeaseToken == token {
+			o.rows[id].NextAttemptAt = now
+		}
+	}
+	return nil
+}
+
+func (o *memoryOutbox) MarkDelivered(id uint64, token string) (bool, *errors.TaskManagerError) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.rows[id].LeaseToken != token {
+		return false, nil
+	}
+	now := time.Now()
+	o.rows[id].DeliveredAt = &now
+	return true, nil
+}
+
+func (o *memoryOutbox) MarkFailed(id uint64, token, lastError string, nextAttemptAt time.Time) (bool, *errors.TaskManagerError) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.rows[id].LeaseToken != token {
+		return false, nil
+	}
+	o.rows[id].Attempts++
+	o.rows[id].LastError = lastError
+	o.rows[id].NextAttemptAt = nextAttemptAt
+	return true, nil
+}
+
+func (o *memoryOutbox) MarkDeadLettered(id uint64, token, lastError string, failedAt time.Time) (bool, *errors.TaskManagerError) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.rows[id].LeaseToken != token {
+		return false, nil
+	}
+	o.rows[id].Attempts++
+	o.rows[id].LastError = lastError
+	o.rows[id].FailedAt = &failedAt
+	return true, nil
+}
+
+func (o *memoryOutbox) Stats() (int64, *time.Time, *errors.TaskManagerError) {
+	return 0, nil, nil
+}
+
+func (o *memoryOutbox) DeleteDelivered(before time.Time) *errors.TaskManagerError {
+	return nil
+}
+
+func (o *memoryOutbox) row(id uint64) models.OutboxEvent {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return *o.rows[id]
+}
+
+// flakyPublisher records published events and fails those of the tasks in failing
+type flakyPublisher struct {
+	mutex     sync.Mutex
+	failing   map[string]bool
+	published []*events.TaskEvent
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, taskEvents ...*events.TaskEvent) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, event := range taskEvents {
+		if p.failing[event.TaskUUID] {
+			return fmt.Errorf("broker unavailable")
+		}
+		p.published = append(p.published, event)
+	}
+	return nil
+}
+
+func (p *flakyPublisher) Close() error {
+	return nil
+}
+
+func (p *flakyPublisher) setFailing(taskUUID string, failing bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.failing[taskUUID] = failing
+}
+
+func (p *flakyPublisher) eventTypes(taskUUID string) []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var types []string
+	for _, event := range p.published {
+		if event.TaskUUID == taskUUID {
+			types = append(types, event.EventType)
+		}
+	}
+	return types
+}
+
+func newTestRelay(outbox *memoryOutbox, publisher *flakyPublisher, maxAttempts int) (*OutboxRelay, *time.Time) {
+	now := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	relay := NewOutboxRelay(outbox, publisher, RelayOptions{
+		BatchSize:      10,
+		BaseBackoff:    time.Second,
+		MaxBackoff:     time.Minute,
+		MaxAttempts:    maxAttempts,
+		Lease:          time.Minute,
+		PublishTimeout: time.Second,
+	})
+	relay.now = func() time.Time { return now }
+	return relay, &now
+}
+
+func taskEvent(taskUUID, eventType string) *events.TaskEvent {
+	return &events.TaskEvent{SchemaVersion: events.SchemaVersion, EventType: eventType, TaskUUID: taskUUID}
+}
+
+func TestRelayHoldsBackLaterEventsOfAFailedTask(t *testing.T) {
+	outbox := newMemoryOutbox()
+	publisher := &flakyPublisher{failing: map[string]bool{"t1": true}}
+	relay, now := newTestRelay(outbox, publisher, 5)
+
+	outbox.Add(taskEvent("t1", events.TaskCreated), taskEvent("t2", events.TaskCreated), taskEvent("t1", events.TaskUpdated))
+	relay.RelayOnce()
+
+	if got := publisher.eventTypes("t2"); len(got) != 1 {
+		t.Fatalf("t2 events = %v, want its TaskCreated published despite t1 failing", got)
+	}
+	if failed := outbox.row(1); failed.Attempts != 1 || !failed.NextAttemptAt.Equal(now.Add(time.Second)) {
+		t.Errorf("failed event attempts = %d, next attempt = %v, want 1 and one second later", failed.Attempts, failed.NextAttemptAt)
+	}
+	if held := outbox.row(3); held.Attempts != 0 || held.NextAttemptAt.After(*now) {
+		t.Errorf("held-back event attempts = %d, next attempt = %v, want it released unattempted", held.Attempts, held.NextAttemptAt)
+	}
+
+	// Before the backoff ends nothing of t1 is due, not even its later event
+	relay.RelayOnce()
+	if got := publisher.eventTypes("t1"); len(got) != 0 {
+		t.Fatalf("t1 events = %v before its backoff ended", got)
+	}
+
+	publisher.setFailing("t1", false)
+	*now = now.Add(time.Second)
+	relay.RelayOnce()
+	if got := publisher.eventTypes("t1"); len(got) != 2 || got[0] != events.TaskCreated || got[1] != events.TaskUpdated {
+		t.Errorf("t1 events = %v, want TaskCreated then TaskUpdated", got)
+	}
+}
+
+func TestRelayDeadLettersAfterMaxAttempts(t *testing.T) {
+	outbox := newMemoryOutbox()
+	publisher := &flakyPublisher{failing: map[string]bool{"t1": true}}
+	relay, now := newTestRelay(outbox, publisher, 3)
+
+	outbox.Add(taskEvent("t1", events.TaskCreated))
+	for i := 0; i < 3; i++ {
+		relay.RelayOnce()
+		*now = now.Add(time.Minute)
+	}
+
+	row := outbox.row(1)
+	if row.FailedAt == nil || row.Attempts != 3 || row.LastError == "" {
+		t.Fatalf("event after 3 failures: failed_at = %v, attempts = %d, last error %q, want it dead-lettered", row.FailedAt, row.Attempts, row.LastError)
+	}
+
+	// Later events of the task are no longer held back by the dead-lettered one
+	publisher.setFailing("t1", false)
+	outbox.Add(taskEvent("t1", events.TaskUpdated))
+	relay.RelayOnce()
+	if got := publisher.eventTypes("t1"); len(got) != 1 || got[0] != events.TaskUpdated {
+		t.Errorf("t1 events = %v, want only the later TaskUpdated", got)
+	}
+}
+
+func TestRelayDeadLettersUnreadablePayloads(t *testing.T) {
+	outbox := newMemoryOutbox()
+	publisher := &flakyPublisher{failing: map[string]bool{}}
+	relay, _ := newTestRelay(outbox, publisher, 5)
+
+	outbox.mutex.Lock()
+	id := outbox.addRow("t1", events.TaskCreated, []byte("{not json"))
+	outbox.mutex.Unlock()
+	outbox.Add(taskEvent("t1", events.TaskUpdated))
+	relay.RelayOnce()
+
+	if row := outbox.row(id); row.FailedAt == nil || row.Attempts != 1 {
+		t.Errorf("unreadable event failed_at = %v, attempts = %d, want it dead-lettered on the first attempt", row.FailedAt, row.Attempts)
+	}
+	if got := publisher.eventTypes("t1"); len(got) != 1 || got[0] != events.TaskUpdated {
+		t.Errorf("t1 events = %v, want the readable TaskUpdated published", got)
+	}
+}
+
+func TestRelayReleasesClaimedEventsWhenStopping(t *testing.T) {
+	outbox := newMemoryOutbox()
+	publisher := &flakyPublisher{failing: map[string]bool{}}
+	relay, now := newTestRelay(outbox, publisher, 5)
+
+	outbox.Add(taskEvent("t1", events.TaskCreated), taskEvent("t2", events.TaskCreated))
+	close(relay.stop)
+	relay.RelayOnce()
+
+	if got := len(publisher.eventTypes("t1")) + len(publisher.eventTypes("t2")); got != 0 {
+		t.Fatalf("published %d events after stop was requested", got)
+	}
+	for _, id := range []uint64{1, 2} {
+		if row := outbox.row(id); row.NextAttemptAt.After(*now) {
+			t.Errorf("event %d is still leased until %v, want it released", id, row.NextAttemptAt)
+		}
+	}
+}
+
+// slowPublisher moves the clock of the relay on by step for every event it publishes, and
+// can hand the claim of the outbox to another relay in between
+type slowPublisher struct {
+	flakyPublisher
+	now     *time.Time
+	step    time.Duration
+	onEvent func()
+}
+
+func (p *slowPublisher) Publish(ctx context.Context, taskEvents ...*events.TaskEvent) error {
+	*p.now = p.now.Add(p.step)
+	if p.onEvent != nil {
+		p.onEvent()
+	}
+	return p.flakyPublisher.Publish(ctx, taskEvents...)
+}
+
+func TestRelayStopsBeforeItsLeaseRunsOut(t *testing.T) {
+	outbox := newMemoryOutbox()
+	relay, now := newTestRelay(outbox, nil, 5)
+	publisher := &slowPublisher{flakyPublisher: flakyPublisher{failing: map[string]bool{}}, now: now, step: 20 * time.Second}
+	relay.publisher = publisher
+
+	for _, task := range []string{"t1", "t2", "t3", "t4"} {
+		outbox.Add(taskEvent(task, events.TaskCreated))
+	}
+	start := *now
+	relay.RelayOnce()
+
+	// The lease is a minute and each publish may take a second: after 40s two events went
+	// out and the third still fits, the fourth would start at 60s
+	for id, wantDelivered := range map[uint64]bool{1: true, 2: true, 3: true, 4: false} {
+		row := outbox.row(id)
+		if delivered := row.DeliveredAt != nil; delivered != wantDelivered {
+			t.Errorf("event %d delivered = %v, want %v", id, delivered, wantDelivered)
+		}
+		if !wantDelivered && row.NextAttemptAt.After(start.Add(time.Minute)) {
+			t.Errorf("event %d is still leased until %v, want it released", id, row.NextAttemptAt)
+		}
+	}
+}
+
+func TestRelayLeavesEventsOfALostLeaseAlone(t *testing.T) {
+	outbox := newMemoryOutbox()
+	relay, now := newTestRelay(outbox, nil, 5)
+	publisher := &slowPublisher{flakyPublisher: flakyPublisher{failing: map[string]bool{}}, now: now}
+	relay.publisher = publisher
+
+	outbox.Add(taskEvent("t1", events.TaskCreated), taskEvent("t2", events.TaskCreated))
+	// While the first event is published, its lease is lost to another relay
+	publisher.onEvent = func() {
+		publisher.onEvent = nil
+		outbox.mutex.Lock()
+		for _, row := range outbox.rows {
+			row.LeaseToken = "other-relay"
+		}
+		outbox.mutex.Unlock()
+	}
+	relay.RelayOnce()
+
+	for _, id := range []uint64{1, 2} {
+		if row := outbox.row(id); row.DeliveredAt != nil || row.Attempts != 0 {
+			t.Errorf("event %d delivered at %v after %d attempts, want it left to the other relay", id, row.DeliveredAt, row.Attempts)
+		}
+	}
+	if got := publisher.eventTypes("t2"); len(got) != 0 {
+		t.Errorf("t2 events = %v, want none published after the lease was lost", got)
+	}
+}
@@ -0,0 +1,251 @@
+package streamService
+
+import (
+	"context"
+	"sync"
+	"task-manager-app/events"
+	"task-manager-app/metrics"
+	"task-manager-app/utils"
+
+	"go.uber.org/zap"
+)
+
+// StreamOptions configures the task stream
+type StreamOptions struct {
+	// ReplaySize is how many recent changes are kept to resume clients from Last-Event-ID
+	ReplaySize int
+	// ClientBuffer is how many changes a client may fall behind before it is disconnected
+	ClientBuffer int
+}
+
+// Filter selects the changes a client receives, like the query parameters of ListTasks. An
+// empty field matches everything.
+type Filter struct {
+	Status   string
+	UserID   string
+	Priority string
+}
+
+// Matches reports whether the task of event matches f before or after the change, so that a
+// client also learns about tasks leaving its view
+func (f Filter) Matches(event *events.TaskEvent) bool {
+	if event.Task == nil {
+		return false
+	}
+	after := map[string]string{
+		"status":   event.Task.Status,
+		"priority": event.Task.Priority,
+		"user_id":  utils.TaskManagerUtils.GetStringValue(event.Task.UserID),
+	}
+	if f.matches(after) {
+		return true
+	}
+
+	before := make(map[string]string, len(after))
+	for field, value := range after {
+		before[field] = value
+		if change, ok := event.Changes[field]; ok {
+			before[field] = changedValue(change.Old)
+		}
+	}
+	return f.matches(before)
+}
+
+func (f Filter) matches(task map[string]string) bool {
+	return (f.Status == "" || task["status"] == f.Status) &&
+		(f.UserID == "" || task["user_id"] == f.UserID) &&
+		(f.Priority == "" || task["priority"] == f.Priority)
+}
+
+// changedValue reads an old value of a FieldChange, which holds a *string for user_id until it
+// has been through JSON
+func changedValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case *string:
+		return utils.TaskManagerUtils.GetStringValue(v)
+	default:
+		return ""
+	}
+}
+
+// Client receives the changes matching its filter on C. C is closed when the client is
+// unsubscribed, or when it falls too far behind; it can then resume from its last change.
+type Client struct {
+	C       <-chan events.Change
+	changes chan events.Change
+	filter  Filter
+}
+
+// TaskStream fans the changes of a ChangeBus out to clients and keeps the most recent ones so
+// that clients can resume after a reconnect
+type TaskStream struct {
+	bus     events.ChangeBus
+	options StreamOptions
+
+	mutex   sync.Mutex
+	replay  []events.Change
+	clients map[*Client]struct{}
+	stopped bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewTaskStream(bus events.ChangeBus, options StreamOptions) *TaskStream {
+	return &TaskStream{
+		bus:     bus,
+		options: options,
+		clients: make(map[*Client]struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start subscribes to the bus and fans its changes out until Stop is called
+func (s *TaskStream) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	changes, err := s.bus.Subscribe(ctx)
+	if err != nil {
+		cancel()
+		return err
+	}
+	s.cancel = cancel
+	go s.run(changes)
+	return nil
+}
+
+// Stop unsubscribes from the bus and disconnects every client. Clients subscribing later are
+// disconnected straight away.
+func (s *TaskStream) Stop() {
+	s.cancel()
+	<-s.done
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.stopped = true
+	for client := range s.clients {
+		s.drop(client)
+	}
+}
+
+func (s *TaskStream) run(changes <-chan events.Change) {
+	defer close(s.done)
+	for change := range changes {
+		s.broadcast(change)
+	}
+}
+
+func (s *TaskStream) broadcast(change events.Change) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	metrics.CountStreamChange()
+
+	if s.options.ReplaySize > 0 {
+		if len(s.replay) == s.options.ReplaySize {
+			s.replay = append(s.replay[:0], s.replay[1:]...)
+		}
+		s.replay = append(s.replay, change)
+	}
+
+	for client := range s.clients {
+		if !client.filter.Matches(change.Event) {
+			continue
+		}
+		select {
+		case client.changes <- change:
+		default:
+			// Never let one slow client hold up the others
+			metrics.CountStreamDroppedClient()
+			s.drop(client)
+		}
+	}
+}
+
+// Subscribe registers a client for the changes matching filter. When lastEventID is set, the
+// buffered changes after it are returned to be sent first; complete is false when changes
+// after lastEventID have already left the buffer, and the client should reload instead.
+func (s *TaskStream) Subscribe(filter Filter, lastEventID *uint64) (client *Client, replay []events.Change, complete bool) {
+	changes := make(chan events.Change, s.options.ClientBuffer)
+	client = &Client{C: changes, changes: changes, filter: filter}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.stopped {
+		close(changes)
+		return client, nil, true
+	}
+	complete = true
+	if lastEventID != nil {
+		// With nothing buffered, for instance after a restart, missed changes cannot be ruled out
+		complete = len(s.replay) > 0 && s.replay[0].ID <= *lastEventID+1
+		for _, change := range s.replay {
+			if change.ID > *lastEventID && filter.Matches(change.Event) {
+				replay = append(replay, change)
+			}
+		}
+	}
+	s.clients[client] = struct{}{}
+	s.recordClients()
+	return client, replay, complete
+}
+
+// Unsubscribe stops sending changes to client
+func (s *TaskStream) Unsubscribe(client *Client) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.drop(client)
+}
+
+func (s *TaskStream) drop(client *Client) {
+	if _, ok := s.clients[client]; !ok {
+		return
+	}
+	delete(s.clients, client)
+	close(client.changes)
+	s.recordClients()
+}
+
+// Clients returns the number of subscribed clients
+func (s *TaskStream) Clients() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.clients)
+}
+
+func (s *TaskStream) recordClients() {
+	metrics.SetStreamClients(len(s.clients))
+}
+
+// Position returns the ID of the latest change when client has been sent every change up to
+// it, which lets an idle client resume from there instead of from its last matching change
+func (s *TaskStream) Position(client *Client) (uint64, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.replay) == 0 || len(client.changes) > 0 {
+		return 0, false
+	}
+	return s.replay[len(s.replay)-1].ID, true
+}
+
+type streamPublisher struct {
+	bus    events.ChangeBus
+	logger *zap.SugaredLogger
+}
+
+// NewStreamPublisher publishes to bus for the outbox relay. Failures are logged rather than
+// returned to logger: streams are best effort and must not hold up or duplicate other deliveries.
+func NewStreamPublisher(bus events.ChangeBus, logger *zap.SugaredLogger) events.Publisher {
+	return &streamPublisher{bus: bus, logger: logger}
+}
+
+func (p *streamPublisher) Publish(ctx context.Context, taskEvents ...*events.TaskEvent) error {
+	if err := p.bus.Publish(ctx, taskEvents...); err != nil {
+		metrics.CountStreamPublishFailure()
+		p.logger.Errorf("Failed to publish %d task events to the stream: %v", len(taskEvents), err)
+	}
+	return nil
+}
+
+func (p *streamPublisher) Close() error {
+	return p.bus.Close()
+}
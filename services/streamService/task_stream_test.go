@@ -0,0 +1,128 @@
+package streamService
+
+import (
+	"context"
+	"os"
+	"task-manager-app/events"
+	"task-manager-app/utils"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	utils.Sugar = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
+
+func startStream(t *testing.T, options StreamOptions) (*events.MemoryChangeBus, *TaskStream) {
+	bus := events.NewMemoryChangeBus()
+	stream := NewTaskStream(bus, options)
+	if err := stream.Start(); err != nil {
+		t.Fatalf("Start returned error %v", err)
+	}
+	t.Cleanup(stream.Stop)
+	return bus, stream
+}
+
+func taskEvent(eventID, status string) *events.TaskEvent {
+	return &events.TaskEvent{EventID: eventID, EventType: events.TaskUpdated, Task: &events.TaskSnapshot{Status: status, Priority: "High"}}
+}
+
+func receive(t *testing.T, client *Client) events.Change {
+	select {
+	case change, ok := <-client.C:
+		if !ok {
+			t.Fatal("client was disconnected")
+		}
+		return change
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a change")
+		return events.Change{}
+	}
+}
+
+func TestFilterMatchesTasksEnteringAndLeavingTheView(t *testing.T) {
+	alice := "alice"
+	leaving := taskEvent("e1", "Completed")
+	leaving.Changes = map[string]events.FieldChange{"status": {Old: "Pending", New: "Completed"}}
+	reassigned := taskEvent("e2", "Pending")
+	reassigned.Changes = map[string]events.FieldChange{"user_id": {Old: &alice, New: nil}}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		event  *events.TaskEvent
+		want   bool
+	}{
+		{"no filter", Filter{}, taskEvent("e0", "Pending"), true},
+		{"status after the change", Filter{Status: "Completed"}, leaving, true},
+		{"status before the change", Filter{Status: "Pending"}, leaving, true},
+		{"other status", Filter{Status: "InProgress"}, leaving, false},
+		{"previous user", Filter{UserID: "alice"}, reassigned, true},
+		{"other user", Filter{UserID: "bob"}, reassigned, false},
+		{"all fields", Filter{Status: "Pending", Priority: "Low"}, leaving, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.filter.Matches(test.event); got != test.want {
+				t.Errorf("Matches = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestStreamSendsMatchingChangesInOrder(t *testing.T) {
+	bus, stream := startStream(t, StreamOptions{ReplaySize: 10, ClientBuffer: 10})
+	client, replay, complete := stream.Subscribe(Filter{Status: "Pending"}, nil)
+	if len(replay) != 0 || !complete {
+		t.Fatalf("new client got replay %v complete %v, want nothing to replay", replay, complete)
+	}
+
+	bus.Publish(context.Background(), taskEvent("e1", "Pending"), taskEvent("e2", "Completed"), taskEvent("e3", "Pending"))
+
+	if first, second := receive(t, client), receive(t, client); first.Event.EventID != "e1" || second.Event.EventID != "e3" || second.ID != 3 {
+		t.Errorf("got %s (%d) and %s (%d), want e1 and e3 with ID 3", first.Event.EventID, first.ID, second.Event.EventID, second.ID)
+	}
+	if id, ok := stream.Position(client); !ok || id != 3 {
+		t.Errorf("Position = %d, %v, want 3 once the client has read everything", id, ok)
+	}
+}
+
+func TestStreamResumesFromTheReplayBuffer(t *testing.T) {
+	bus, stream := startStream(t, StreamOptions{ReplaySize: 3, ClientBuffer: 10})
+	watcher, _, _ := stream.Subscribe(Filter{}, nil)
+	bus.Publish(context.Background(), taskEvent("e1", "Pending"), taskEvent("e2", "Pending"), taskEvent("e3", "Completed"), taskEvent("e4", "Pending"))
+	for i := 0; i < 4; i++ {
+		receive(t, watcher)
+	}
+
+	lastEventID := uint64(1)
+	_, replay, complete := stream.Subscribe(Filter{Status: "Pending"}, &lastEventID)
+	if !complete || len(replay) != 2 || replay[0].Event.EventID != "e2" || replay[1].Event.EventID != "e4" {
+		t.Errorf("resuming after 1 replayed %v, complete %v, want e2 and e4", replay, complete)
+	}
+
+	// e2 has left the buffer of 3
+	lastEventID = 0
+	if _, _, complete := stream.Subscribe(Filter{}, &lastEventID); complete {
+		t.Error("resuming from before the buffer was reported complete")
+	}
+}
+
+func TestStreamDisconnectsSlowClients(t *testing.T) {
+	bus, stream := startStream(t, StreamOptions{ReplaySize: 10, ClientBuffer: 1})
+	slow, _, _ := stream.Subscribe(Filter{}, nil)
+	fast, _, _ := stream.Subscribe(Filter{}, nil)
+
+	bus.Publish(context.Background(), taskEvent("e1", "Pending"))
+	receive(t, fast)
+	bus.Publish(context.Background(), taskEvent("e2", "Pending"))
+	receive(t, fast)
+
+	receive(t, slow)
+	if _, ok := <-slow.C; ok {
+		t.Error("slow client was not disconnected")
+	}
+}
@@ -1,47 +1,66 @@
 package taskManagerService
 
 import (
+	"context"
 	"task-manager-app/constants"
 	"task-manager-app/constants/enums"
+	"task-manager-app/events"
 	"task-manager-app/exceptions"
 	"task-manager-app/exceptions/errors"
 	"task-manager-app/models"
 	"task-manager-app/repo"
 	"task-manager-app/request"
 	"task-manager-app/response"
+	"task-manager-app/services/userManagerServices"
 	"task-manager-app/services/validationService"
+	"task-manager-app/tracing"
 	"task-manager-app/utils"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type TaskService interface {
-	CreateTask(req *request.ReqCreateOrUpdateTasks) (*response.TaskResponse, *errors.TaskManagerError)
-	UpdateTask(uuid string, req *request.ReqCreateOrUpdateTasks) (*response.TaskResponse, *errors.TaskManagerError)
-	GetTaskByUUID(uuid string) (*response.TaskResponse, *errors.TaskManagerError)
-	DeleteTask(uuid string) *errors.TaskManagerError
-	ListTasks(status string, userID string, priority string, page, pageSize int) (*response.TaskListResponse, *errors.TaskManagerError)
+	CreateTask(ctx context.Context, req *request.ReqCreateOrUpdateTasks) (*response.TaskResponse, *errors.TaskManagerError)
+	UpdateTask(ctx context.Context, uuid string, req *request.ReqCreateOrUpdateTasks) (*response.TaskResponse, *errors.TaskManagerError)
+	GetTaskByUUID(ctx context.Context, uuid string, expandUser bool) (*response.TaskResponse, *errors.TaskManagerError)
+	DeleteTask(ctx context.Context, uuid string) *errors.TaskManagerError
+	ListTasks(ctx context.Context, status string, userIDs []string, priority string, page, pageSize int, expandUser bool) (*response.TaskListResponse, *errors.TaskManagerError)
 }
 
 type taskService struct {
 	repo              repo.TaskRepository
 	validationService validationService.ValidationService
+	userService       userManagerServices.UserService
+	now               func() time.Time
 }
 
-func NewTaskService(repository repo.TaskRepository, validationSvc validationService.ValidationService) TaskService {
+// NewTaskService creates the task service. now stamps the task events, time.Now when nil.
+func NewTaskService(repository repo.TaskRepository, validationSvc validationService.ValidationService, userService userManagerServices.UserService, now func() time.Time) TaskService {
+	if now == nil {
+		now = time.Now
+	}
 	return &taskService{
 		repo:              repository,
 		validationService: validationSvc,
+		userService:       userService,
+		now:               now,
 	}
 }
 
-func (s *taskService) CreateTask(req *request.ReqCreateOrUpdateTasks) (*response.TaskResponse, *errors.TaskManagerError) {
+func (s *taskService) CreateTask(ctx context.Context, req *request.ReqCreateOrUpdateTasks) (resp *response.TaskResponse, taskErr *errors.TaskManagerError) {
+	ctx, span := tracing.Start(ctx, "TaskService.CreateTask")
+	defer func() { tracing.End(span, taskErr) }()
+
 	// Validate request
-	if err := s.validationService.ValidateCreateTaskRequest(req); err != nil {
+	if err := s.validationService.ValidateCreateTaskRequest(ctx, req); err != nil {
 		return nil, err
 	}
 
 	// Convert request to model
 	task := &models.Task{
 		Title:       *req.Title,
+		TitleKey:    s.validationService.TitleKey(*req.Title),
 		Description: utils.TaskManagerUtils.GetStringValue(req.Description),
 		Status:      utils.TaskManagerUtils.GetStringValue(req.Status),
 		Priority:    utils.TaskManagerUtils.GetStringValue(req.Priority),
@@ -55,44 +74,75 @@ func (s *taskService) CreateTask(req *request.ReqCreateOrUpdateTasks) (*response
 		task.Priority = string(enums.PriorityMedium)
 	}
 
-	// Save
-	if taskErr := s.repo.Create(task); taskErr != nil {
+	// Save the task and its event atomically
+	taskErr = s.repo.WithContext(ctx).WithinTransaction(func(tasks repo.TaskRepository, outbox repo.OutboxRepository) *errors.TaskManagerError {
+		if taskErr := tasks.Create(task); taskErr != nil {
+			return taskErr
+		}
+		return outbox.Add(events.NewTaskCreated(task, s.now()))
+	})
+	if taskErr != nil {
 		return nil, taskErr
 	}
 	return s.toResponse(task), nil
 }
 
-func (s *taskService) GetTaskByUUID(uuid string) (*response.TaskResponse, *errors.TaskManagerError) {
-	task, taskErr := s.repo.GetByUUID(uuid)
+func (s *taskService) GetTaskByUUID(ctx context.Context, uuid string, expandUser bool) (resp *response.TaskResponse, taskErr *errors.TaskManagerError) {
+	ctx, span := tracing.Start(ctx, "TaskService.GetTaskByUUID", attribute.String("task.uuid", uuid))
+	defer func() { tracing.End(span, taskErr) }()
+
+	task, taskErr := s.repo.WithContext(ctx).GetByUUID(uuid)
 	if taskErr != nil {
 		return nil, taskErr
 	}
 	if task == nil {
 		return nil, exceptions.NotFoundException(constants.ErrTaskNotFound)
 	}
-	return s.toResponse(task), nil
-}
 
-func (s *taskService) DeleteTask(uuid string) *errors.TaskManagerError {
-	task, taskErr := s.repo.GetByUUIDForUpdate(uuid)
-	if taskErr != nil {
-		return taskErr
+	resp = s.toResponse(task)
+	if expandUser {
+		s.expandUsers(ctx, []*response.TaskResponse{resp})
 	}
-	if task == nil {
-		return exceptions.NotFoundException(constants.ErrTaskNotFound)
-	}
-	return s.repo.Delete(uuid)
+	return resp, nil
+}
+
+func (s *taskService) DeleteTask(ctx context.Context, uuid string) (taskErr *errors.TaskManagerError) {
+	ctx, span := tracing.Start(ctx, "TaskService.DeleteTask", attribute.String("task.uuid", uuid))
+	defer func() { tracing.End(span, taskErr) }()
+
+	return s.repo.WithContext(ctx).WithinTransaction(func(tasks repo.TaskRepository, outbox repo.OutboxRepository) *errors.TaskManagerError {
+		task, taskErr := tasks.GetByUUIDForUpdate(uuid)
+		if taskErr != nil {
+			return taskErr
+		}
+		if task == nil {
+			return exceptions.NotFoundException(constants.ErrTaskNotFound)
+		}
+		if taskErr := tasks.Delete(uuid); taskErr != nil {
+			return taskErr
+		}
+		return outbox.Add(events.NewTaskDeleted(task, s.now()))
+	})
 }
 
-func (s *taskService) ListTasks(status string, userID string, priority string, page, pageSize int) (*response.TaskListResponse, *errors.TaskManagerError) {
+func (s *taskService) ListTasks(ctx context.Context, status string, userIDs []string, priority string, page, pageSize int, expandUser bool) (resp *response.TaskListResponse, taskErr *errors.TaskManagerError) {
+	ctx, span := tracing.Start(ctx, "TaskService.ListTasks")
+	defer func() { tracing.End(span, taskErr) }()
+
 	if page < 1 {
 		page = 1
 	}
 	offset := (page - 1) * pageSize
 
-	// Validate filters
-	if userID != "" {
-		if err := s.validationService.ValidateUserID(userID); err != nil {
+	// Validate filters; several users are checked with one batch call
+	switch len(userIDs) {
+	case 0:
+	case 1:
+		if err := s.validationService.ValidateUserID(ctx, userIDs[0]); err != nil {
+			return nil, err
+		}
+	default:
+		if err := s.validationService.ValidateUserIDs(ctx, userIDs); err != nil {
 			return nil, err
 		}
 	}
@@ -102,7 +152,7 @@ func (s *taskService) ListTasks(status string, userID string, priority string, p
 		}
 	}
 
-	tasks, taskErr := s.repo.List(status, userID, priority, pageSize, offset)
+	tasks, taskErr := s.repo.WithContext(ctx).List(status, userIDs, priority, pageSize, offset)
 	if taskErr != nil {
 		return nil, taskErr
 	}
@@ -112,6 +162,14 @@ func (s *taskService) ListTasks(status string, userID string, priority string, p
 		taskResponses[i] = *s.toResponse(&t)
 	}
 
+	if expandUser {
+		refs := make([]*response.TaskResponse, len(taskResponses))
+		for i := range taskResponses {
+			refs[i] = &taskResponses[i]
+		}
+		s.expandUsers(ctx, refs)
+	}
+
 	return &response.TaskListResponse{
 		Tasks:    taskResponses,
 		Page:     page,
@@ -120,9 +178,32 @@ func (s *taskService) ListTasks(status string, userID string, priority string, p
 	}, nil
 }
 
-func (s *taskService) UpdateTask(uuid string, req *request.ReqCreateOrUpdateTasks) (*response.TaskResponse, *errors.TaskManagerError) {
+func (s *taskService) UpdateTask(ctx context.Context, uuid string, req *request.ReqCreateOrUpdateTasks) (resp *response.TaskResponse, taskErr *errors.TaskManagerError) {
+	ctx, span := tracing.Start(ctx, "TaskService.UpdateTask", attribute.String("task.uuid", uuid))
+	defer func() { tracing.End(span, taskErr) }()
+
+	// Validated before the transaction, so that no row lock or connection is held while the
+	// user service is called
+	if taskErr := s.validateUpdate(ctx, req); taskErr != nil {
+		return nil, taskErr
+	}
+
+	var task *models.Task
+	taskErr = s.repo.WithContext(ctx).WithinTransaction(func(tasks repo.TaskRepository, outbox repo.OutboxRepository) *errors.TaskManagerError {
+		var taskErr *errors.TaskManagerError
+		task, taskErr = s.updateTask(ctx, tasks, outbox, uuid, req)
+		return taskErr
+	})
+	if taskErr != nil {
+		return nil, taskErr
+	}
+	return s.toResponse(task), nil
+}
+
+// updateTask applies req to the locked task and records the resulting events in the same transaction
+func (s *taskService) updateTask(ctx context.Context, tasks repo.TaskRepository, outbox repo.OutboxRepository, uuid string, req *request.ReqCreateOrUpdateTasks) (*models.Task, *errors.TaskManagerError) {
 	// Check if task exists
-	task, taskErr := s.repo.GetByUUIDForUpdate(uuid)
+	task, taskErr := tasks.GetByUUIDForUpdate(uuid)
 	if taskErr != nil {
 		return nil, taskErr
 	}
@@ -130,24 +211,54 @@ func (s *taskService) UpdateTask(uuid string, req *request.ReqCreateOrUpdateTask
 		return nil, exceptions.NotFoundException(constants.ErrTaskNotFound)
 	}
 
+	before := *task
+	previousUserID := utils.TaskManagerUtils.GetStringValue(task.UserID)
+
 	// Apply updates in one place
-	changed, err := s.applyUpdates(task, req)
-	if err != nil {
-		return nil, err
+	if !s.applyUpdates(task, req) {
+		return nil, exceptions.NewBadRequestException(constants.ErrNothingToChange)
 	}
 
-	if !changed {
-		return nil, exceptions.NewBadRequestException(constants.ErrNothingToChange)
+	// Renames and reassignments must keep titles unique per user
+	titleKey := s.validationService.TitleKey(task.Title)
+	if task.UserID != nil && (titleKey != task.TitleKey || *task.UserID != previousUserID) {
+		if err := s.validationService.CheckTaskDuplicateByTitle(ctx, tasks, task.Title, *task.UserID, task.UUID); err != nil {
+			return nil, err
+		}
 	}
+	task.TitleKey = titleKey
 
-	if taskErr := s.repo.Update(task); taskErr != nil {
+	if taskErr := tasks.Update(task); taskErr != nil {
+		return nil, taskErr
+	}
+	if taskErr := outbox.Add(events.NewTaskUpdated(&before, task, s.now())...); taskErr != nil {
 		return nil, taskErr
 	}
+	return task, nil
+}
 
-	return s.toResponse(task), nil
+// validateUpdate checks the status, priority and user of req
+func (s *taskService) validateUpdate(ctx context.Context, req *request.ReqCreateOrUpdateTasks) *errors.TaskManagerError {
+	if req.Status != nil {
+		if err := s.validationService.ValidateTaskStatus(*req.Status); err != nil {
+			return err
+		}
+	}
+	if req.Priority != nil {
+		if err := s.validationService.ValidateTaskPriority(*req.Priority); err != nil {
+			return err
+		}
+	}
+	if req.UserID != nil && *req.UserID != "" {
+		if err := s.validationService.ValidateUserID(ctx, *req.UserID); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (s *taskService) applyUpdates(task *models.Task, req *request.ReqCreateOrUpdateTasks) (bool, *errors.TaskManagerError) {
+// applyUpdates copies the fields set in the validated req to task and reports whether any changed
+func (s *taskService) applyUpdates(task *models.Task, req *request.ReqCreateOrUpdateTasks) bool {
 	changed := false
 
 	// Title
@@ -161,37 +272,22 @@ func (s *taskService) applyUpdates(task *models.Task, req *request.ReqCreateOrUp
 	}
 
 	// Status
-	if req.Status != nil {
-		if err := s.validationService.ValidateTaskStatus(*req.Status); err != nil {
-			return false, err
-		}
-		if s.updateField(&task.Status, *req.Status) {
-			changed = true
-		}
+	if req.Status != nil && s.updateField(&task.Status, *req.Status) {
+		changed = true
 	}
 
 	// Priority
-	if req.Priority != nil {
-		if err := s.validationService.ValidateTaskPriority(*req.Priority); err != nil {
-			return false, err
-		}
-		if s.updateField(&task.Priority, *req.Priority) {
-			changed = true
-		}
+	if req.Priority != nil && s.updateField(&task.Priority, *req.Priority) {
+		changed = true
 	}
 
 	// UserID
-	if req.UserID != nil && *req.UserID != "" {
-		if err := s.validationService.ValidateUserID(*req.UserID); err != nil {
-			return false, err
-		}
-		if task.UserID == nil || *task.UserID != *req.UserID {
-			task.UserID = req.UserID
-			changed = true
-		}
+	if req.UserID != nil && *req.UserID != "" && (task.UserID == nil || *task.UserID != *req.UserID) {
+		task.UserID = req.UserID
+		changed = true
 	}
 
-	return changed, nil
+	return changed
 }
 
 func (s *taskService) updateField(field *string, newValue string) bool {
@@ -202,6 +298,40 @@ func (s *taskService) updateField(field *string, newValue string) bool {
 	return true
 }
 
+// expandUsers embeds the assigned user's profile into each task using one batched lookup of
+// the distinct users on the page. If the user service is unavailable the tasks are returned
+// with user_id only.
+func (s *taskService) expandUsers(ctx context.Context, tasks []*response.TaskResponse) {
+	userIDs := make([]string, 0, len(tasks))
+	seen := make(map[string]bool, len(tasks))
+	for _, t := range tasks {
+		if t.UserID != nil && !seen[*t.UserID] {
+			seen[*t.UserID] = true
+			userIDs = append(userIDs, *t.UserID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+
+	profiles, err := s.userService.GetUsers(ctx, userIDs)
+	if err != nil {
+		utils.Logger(ctx).Warnf("Returning tasks without user details: %v", err)
+	}
+	for _, t := range tasks {
+		if t.UserID == nil {
+			continue
+		}
+		if profile, ok := profiles[*t.UserID]; ok {
+			t.User = &response.UserResponse{
+				ID:    profile.UserID,
+				Name:  profile.Name,
+				Email: profile.Email,
+			}
+		}
+	}
+}
+
 func (s *taskService) toResponse(task *models.Task) *response.TaskResponse {
 	return &response.TaskResponse{
 		UUID:        task.UUID,
@@ -0,0 +1,257 @@
+package taskManagerService
+
+import (
+	"context"
+	stdErrors "errors"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"task-manager-app/events"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/models"
+	"task-manager-app/repo"
+	"task-manager-app/request"
+	"task-manager-app/services/userManagerServices"
+	"task-manager-app/services/validationService"
+	"task-manager-app/utils"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	utils.Sugar = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
+
+// listRepository serves a fixed page of tasks; the service must not call anything else
+type listRepository struct {
+	repo.TaskRepository
+	tasks   []models.Task
+	userIDs []string
+}
+
+func (r *listRepository) WithContext(ctx context.Context) repo.TaskRepository {
+	return r
+}
+
+func (r *listRepository) List(status string, userIDs []string, priority string, limit, offset int) ([]models.Task, *errors.TaskManagerError) {
+	r.userIDs = userIDs
+	return r.tasks, nil
+}
+
+// countingUserService records every GetUsers, ValidateUser and ValidateUsers call
+type countingUserService struct {
+	userManagerServices.UserService
+	calls       [][]string
+	validations [][]string
+}
+
+func (s *countingUserService) ValidateUser(ctx context.Context, userID string) (bool, error) {
+	s.validations = append(s.validations, []string{userID})
+	return userID != "unknown", nil
+}
+
+func (s *countingUserService) ValidateUsers(ctx context.Context, userIDs []string) (map[string]bool, error) {
+	s.validations = append(s.validations, userIDs)
+	results := make(map[string]bool, len(userIDs))
+	for _, userID := range userIDs {
+		results[userID] = userID != "unknown"
+	}
+	return results, nil
+}
+
+func (s *countingUserService) GetUsers(ctx context.Context, userIDs []string) (map[string]*userManagerServices.UserProfile, error) {
+	s.calls = append(s.calls, userIDs)
+	profiles := make(map[string]*userManagerServices.UserProfile)
+	for _, userID := range userIDs {
+		if userID != "unknown" {
+			profiles[userID] = &userManagerServices.UserProfile{UserID: userID, Name: "name of " + userID}
+		}
+	}
+	return profiles, nil
+}
+
+func TestListTasksExpandsUsersWithOneBatchedLookup(t *testing.T) {
+	u1, u2, unknown := "u1", "u2", "unknown"
+	repository := &listRepository{tasks: []models.Task{
+		{UUID: "t1", UserID: &u1},
+		{UUID: "t2", UserID: &u2},
+		{UUID: "t3", UserID: &u1},
+		{UUID: "t4"},
+		{UUID: "t5", UserID: &unknown},
+	}}
+	users := &countingUserService{}
+	service := NewTaskService(repository, validationService.NewValidationService(users, repository, utils.TitleNormalizer{}), users, nil)
+
+	resp, taskErr := service.ListTasks(context.Background(), "", nil, "", 1, 10, true)
+	if taskErr != nil {
+		t.Fatalf("ListTasks returned error %+v", taskErr)
+	}
+
+	if len(users.calls) != 1 {
+		t.Fatalf("GetUsers called %d times, want one call for the page", len(users.calls))
+	}
+	requested := append([]string(nil), users.calls[0]...)
+	sort.Strings(requested)
+	if want := []string{"u1", "u2", "unknown"}; len(requested) != len(want) || requested[0] != want[0] || requested[1] != want[1] || requested[2] != want[2] {
+		t.Fatalf("GetUsers requested %v, want the distinct users %v", requested, want)
+	}
+
+	for _, task := range resp.Tasks {
+		switch {
+		case task.UserID == nil || *task.UserID == unknown:
+			if task.User != nil {
+				t.Errorf("task %s: User = %+v, want none", task.UUID, task.User)
+			}
+		case task.User == nil || task.User.ID != *task.UserID:
+			t.Errorf("task %s: User = %+v, want the profile of %s", task.UUID, task.User, *task.UserID)
+		}
+	}
+}
+
+func TestListTasksValidatesUserFilters(t *testing.T) {
+	tests := []struct {
+		name        string
+		userIDs     []string
+		validations int
+		wantCode    int
+	}{
+		{name: "no filter", userIDs: nil, validations: 0},
+		{name: "one user", userIDs: []string{"u1"}, validations: 1},
+		{name: "several users in one batch", userIDs: []string{"u1", "u2", "u3"}, validations: 1},
+		{name: "unknown user", userIDs: []string{"unknown"}, validations: 1, wantCode: http.StatusNotFound},
+		{name: "unknown user in a batch", userIDs: []string{"u1", "unknown"}, validations: 1, wantCode: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := &listRepository{}
+			users := &countingUserService{}
+			service := NewTaskService(repository, validationService.NewValidationService(users, repository, utils.TitleNormalizer{}), users, nil)
+
+			_, taskErr := service.ListTasks(context.Background(), "", test.userIDs, "", 1, 10, false)
+			if len(users.validations) != test.validations {
+				t.Fatalf("validation calls = %v, want %d", users.validations, test.validations)
+			}
+			if test.wantCode != 0 {
+				if taskErr == nil || taskErr.ResponseCode != test.wantCode {
+					t.Fatalf("ListTasks returned %+v, want %d", taskErr, test.wantCode)
+				}
+				return
+			}
+			if taskErr != nil {
+				t.Fatalf("ListTasks returned error %+v", taskErr)
+			}
+			if len(repository.userIDs) != len(test.userIDs) {
+				t.Fatalf("List filtered by %v, want %v", repository.userIDs, test.userIDs)
+			}
+		})
+	}
+}
+
+// updateRepository holds one task and tells whether its transaction is open; every query
+// made in the transaction must go through the repository passed to it
+type updateRepository struct {
+	repo.TaskRepository
+	task          models.Task
+	inTransaction bool
+	existsChecks  []bool
+}
+
+func (r *updateRepository) WithContext(ctx context.Context) repo.TaskRepository {
+	return r
+}
+
+func (r *updateRepository) WithinTransaction(fn func(tasks repo.TaskRepository, outbox repo.OutboxRepository) *errors.TaskManagerError) *errors.TaskManagerError {
+	r.inTransaction = true
+	defer func() { r.inTransaction = false }()
+	return fn(&transactionRepository{updateRepository: r}, discardOutbox{})
+}
+
+func (r *updateRepository) ExistsByTitleAndUser(titleKey, userID, excludeUUID string) (bool, *errors.TaskManagerError) {
+	r.existsChecks = append(r.existsChecks, false)
+	return false, nil
+}
+
+// transactionRepository is the repository bound to the transaction of updateRepository
+type transactionRepository struct {
+	*updateRepository
+}
+
+func (r *transactionRepository) WithContext(ctx context.Context) repo.TaskRepository {
+	return r
+}
+
+func (r *transactionRepository) GetByUUIDForUpdate(uuid string) (*models.Task, *errors.TaskManagerError) {
+	task := r.task
+	return &task, nil
+}
+
+func (r *transactionRepository) ExistsByTitleAndUser(titleKey, userID, excludeUUID string) (bool, *errors.TaskManagerError) {
+	r.existsChecks = append(r.existsChecks, true)
+	return false, nil
+}
+
+func (r *transactionRepository) Update(task *models.Task) *errors.TaskManagerError {
+	r.task = *task
+	return nil
+}
+
+type discardOutbox struct {
+	repo.OutboxRepository
+}
+
+func (discardOutbox) Add(taskEvents ...*events.TaskEvent) *errors.TaskManagerError {
+	return nil
+}
+
+// transactionCheckingUserService fails validations made while the transaction is open
+type transactionCheckingUserService struct {
+	userManagerServices.UserService
+	repository *updateRepository
+}
+
+func (s *transactionCheckingUserService) ValidateUser(ctx context.Context, userID string) (bool, error) {
+	if s.repository.inTransaction {
+		return false, stdErrors.New("user validated inside the transaction")
+	}
+	return userID != "unknown", nil
+}
+
+func TestUpdateTaskValidatesBeforeTransaction(t *testing.T) {
+	owner, assignee, unknown, done := "u1", "u2", "unknown", "Completed"
+	tests := []struct {
+		name     string
+		req      request.ReqCreateOrUpdateTasks
+		wantCode int
+		checks   []bool
+	}{
+		{name: "reassign", req: request.ReqCreateOrUpdateTasks{UserID: &assignee}, checks: []bool{true}},
+		{name: "status only", req: request.ReqCreateOrUpdateTasks{Status: &done}},
+		{name: "unknown user", req: request.ReqCreateOrUpdateTasks{UserID: &unknown}, wantCode: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := &updateRepository{task: models.Task{UUID: "t1", Title: "Write", TitleKey: "Write", Status: "Pending", Priority: "Medium", UserID: &owner}}
+			users := &transactionCheckingUserService{repository: repository}
+			service := NewTaskService(repository, validationService.NewValidationService(users, repository, utils.TitleNormalizer{}), users, nil)
+
+			_, taskErr := service.UpdateTask(context.Background(), "t1", &test.req)
+			if test.wantCode != 0 {
+				if taskErr == nil || taskErr.ResponseCode != test.wantCode {
+					t.Fatalf("UpdateTask returned %+v, want %d", taskErr, test.wantCode)
+				}
+				return
+			}
+			if taskErr != nil {
+				t.Fatalf("UpdateTask returned error %+v", taskErr)
+			}
+			if !reflect.DeepEqual(repository.existsChecks, test.checks) {
+				t.Errorf("duplicate checks in transaction = %v, want %v", repository.existsChecks, test.checks)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package userEventService
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"task-manager-app/events"
+	"task-manager-app/metrics"
+	"task-manager-app/utils"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Headers set on user events forwarded to the retry and dead-letter topics
+const (
+	HeaderAttempts    = "attempts"
+	HeaderRetryAt     = "retry_at"
+	HeaderLastError   = "last_error"
+	HeaderSourceTopic = "source_topic"
+)
+
+// ConsumerOptions configures retries of user events that could not be handled
+type ConsumerOptions struct {
+	RetryTopic      string
+	DeadLetterTopic string
+	MaxAttempts     int
+	BaseBackoff     time.Duration
+	MaxBackoff      time.Duration
+	// Logger receives the consumer logs, utils.Sugar when nil
+	Logger *zap.SugaredLogger
+	// Clock stamps the retry_at header of retried events, time.Now when nil
+	Clock func() time.Time
+}
+
+// UserEventConsumer handles user events from the user events topic and from the retry topic.
+// An event that fails is forwarded to the retry topic with a retry_at header and handled again
+// once that time has come; after MaxAttempts, or straight away when it cannot be decoded, it
+// goes to the dead-letter topic. A message is committed only once it is handled or forwarded.
+type UserEventConsumer struct {
+	readers []events.MessageReader
+	writer  events.MessageWriter
+	handler UserEventHandler
+	options ConsumerOptions
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewUserEventConsumer consumes user events from reader and retried ones from retryReader,
+// which reads options.RetryTopic. writer produces to the retry and dead-letter topics.
+func NewUserEventConsumer(reader, retryReader events.MessageReader, writer events.MessageWriter, handler UserEventHandler, options ConsumerOptions) *UserEventConsumer {
+	if options.Logger == nil {
+		options.Logger = utils.Sugar
+	}
+	if options.Clock == nil {
+		options.Clock = time.Now
+	}
+	return &UserEventConsumer{
+		readers: []events.MessageReader{reader, retryReader},
+		writer:  writer,
+		handler: handler,
+		options: options,
+	}
+}
+
+// Start consumes both topics in the background until Stop is called
+func (c *UserEventConsumer) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	for _, reader := range c.readers {
+		c.wg.Add(1)
+		go c.consume(ctx, reader)
+	}
+}
+
+// Stop asks the consumer to finish the events it is handling and waits for it or for ctx to
+// expire. Events waiting for their retry time are left uncommitted and redelivered later.
+func (c *UserEventConsumer) Stop(ctx context.Context) error {
+	c.cancel()
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	var closeErr error
+	for _, reader := range c.readers {
+		if err := reader.Close(); err != nil && closeErr == nil {
+			closeErr = err
+		}
+	}
+	return closeErr
+}
+
+func (c *UserEventConsumer) consume(ctx context.Context, reader events.MessageReader) {
+	defer c.wg.Done()
+	for {
+		message, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.options.Logger.Errorf("Failed to fetch user event: %v", err)
+			if !wait(ctx, time.Second) {
+				return
+			}
+			continue
+		}
+
+		if !c.process(ctx, message) {
+			return
+		}
+		// A failed commit only means the event may be redelivered, which the inbox absorbs
+		if err := reader.CommitMessage(ctx, message); err != nil && ctx.Err() == nil {
+			c.options.Logger.Errorf("Failed to commit user event at %s/%d/%d: %v", message.Topic, message.Partition, message.Offset, err)
+		}
+	}
+}
+
+// process handles message, or forwards it to the retry or dead-letter topic, and reports
+// whether it may be committed. It is false only when ctx ends first.
+func (c *UserEventConsumer) process(ctx context.Context, message events.Message) bool {
+	attempts, _ := strconv.Atoi(message.Headers[HeaderAttempts])
+	if retryAt, err := time.Parse(time.RFC3339Nano, message.Headers[HeaderRetryAt]); err == nil {
+		if !wait(ctx, retryAt.Sub(c.options.Clock())) {
+			return false
+		}
+	}
+
+	event, err := events.DecodeUserEvent(message.Value)
+	if err != nil {
+		return c.deadLetter(ctx, message, attempts+1, err.Error())
+	}
+
+	taskErr := c.handler.Handle(event)
+	if taskErr == nil {
+		metrics.CountUserEvent(metrics.ResultProcessed)
+		return true
+	}
+
+	attempts++
+	metrics.CountUserEvent(metrics.ResultFailed)
+	if attempts >= c.options.MaxAttempts {
+		return c.deadLetter(ctx, message, attempts, taskErr.Message)
+	}
+	c.options.Logger.Warnf("Failed to handle user event %s (%s) for user %s, attempt %d: %s", event.EventID, event.EventType, event.UserID, attempts, taskErr.Message)
+	retryAt := c.options.Clock().Add(utils.ExponentialBackoff(c.options.BaseBackoff, c.options.MaxBackoff, attempts))
+	if !c.forward(ctx, c.options.RetryTopic, message, attempts, taskErr.Message, &retryAt) {
+		return false
+	}
+	metrics.CountUserEvent(metrics.ResultRetried)
+	return true
+}
+
+func (c *UserEventConsumer) deadLetter(ctx context.Context, message events.Message, attempts int, lastError string) bool {
+	c.options.Logger.Errorf("Dead-lettering user event at %s/%d/%d after %d attempts: %s", message.Topic, message.Partition, message.Offset, attempts, lastError)
+	if !c.forward(ctx, c.options.DeadLetterTopic, message, attempts, lastError, nil) {
+		return false
+	}
+	metrics.CountUserEvent(metrics.ResultDeadLettered)
+	return true
+}
+
+// forward writes a copy of message to topic, retrying until it is written or ctx ends, and
+// reports whether it was written
+func (c *UserEventConsumer) forward(ctx context.Context, topic string, message events.Message, attempts int, lastError string, retryAt *time.Time) bool {
+	headers := make(map[string]string, len(message.Headers)+4)
+	for k, v := range message.Headers {
+		headers[k] = v
+	}
+	if headers[HeaderSourceTopic] == "" {
+		headers[HeaderSourceTopic] = message.Topic
+	}
+	headers[HeaderAttempts] = strconv.Itoa(attempts)
+	headers[HeaderLastError] = lastError
+	delete(headers, HeaderRetryAt)
+	if retryAt != nil {
+		headers[HeaderRetryAt] = retryAt.UTC().Format(time.RFC3339Nano)
+	}
+	forwarded := events.Message{Topic: topic, Key: message.Key, Value: message.Value, Headers: headers}
+
+	for failures := 1; ; failures++ {
+		err := c.writer.WriteMessages(ctx, forwarded)
+		if err == nil {
+			return true
+		}
+		c.options.Logger.Errorf("Failed to forward user event to %s: %v", topic, err)
+		if !wait(ctx, utils.ExponentialBackoff(c.options.BaseBackoff, c.options.MaxBackoff, failures)) {
+			return false
+		}
+	}
+}
+
+// wait sleeps for d and reports whether it did so without ctx ending
+func wait(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,196 @@
+package userEventService
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"task-manager-app/events"
+	"task-manager-app/exceptions"
+	"task-manager-app/exceptions/errors"
+	"testing"
+	"time"
+)
+
+const (
+	userTopic  = "user-events"
+	retryTopic = "user-events-retry"
+	deadLetter = "user-events-dlq"
+)
+
+// flakyHandler fails the first failures attempts of every event, calling onFailure if set,
+// and records the handled ones
+type flakyHandler struct {
+	mutex     sync.Mutex
+	failures  int
+	onFailure func()
+	attempts  map[string]int
+	handled   []string
+}
+
+func (h *flakyHandler) Handle(event *events.UserEvent) *errors.TaskManagerError {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.attempts[event.EventID]++
+	if h.attempts[event.EventID] <= h.failures {
+		if h.onFailure != nil {
+			h.onFailure()
+		}
+		return exceptions.InternalServerException("database unavailable")
+	}
+	h.handled = append(h.handled, event.EventID)
+	return nil
+}
+
+func (h *flakyHandler) handledEvents() []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return append([]string(nil), h.handled...)
+}
+
+func startConsumer(t *testing.T, broker *events.MemoryBroker, handler UserEventHandler, maxAttempts int) {
+	consumer := NewUserEventConsumer(broker.Reader(userTopic), broker.Reader(retryTopic), broker, handler, ConsumerOptions{
+		RetryTopic:      retryTopic,
+		DeadLetterTopic: deadLetter,
+		MaxAttempts:     maxAttempts,
+		BaseBackoff:     time.Millisecond,
+		MaxBackoff:      10 * time.Millisecond,
+	})
+	consumer.Start()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := consumer.Stop(ctx); err != nil {
+			t.Errorf("Stop returned error %v", err)
+		}
+	})
+}
+
+func publishUserEvent(t *testing.T, broker *events.MemoryBroker, value []byte) {
+	if err := broker.WriteMessages(context.Background(), events.Message{Topic: userTopic, Key: []byte("gone"), Value: value}); err != nil {
+		t.Fatalf("WriteMessages returned error %v", err)
+	}
+}
+
+func userDeletedValue(eventID string) []byte {
+	value, _ := json.Marshal(deleted(eventID))
+	return value
+}
+
+func eventually(t *testing.T, what string, condition func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConsumerHandlesAndCommitsEvents(t *testing.T) {
+	broker := events.NewMemoryBroker()
+	handler := &flakyHandler{attempts: make(map[string]int)}
+	startConsumer(t, broker, handler, 3)
+
+	publishUserEvent(t, broker, userDeletedValue("e1"))
+	publishUserEvent(t, broker, userDeletedValue("e2"))
+
+	eventually(t, "both events are committed", func() bool { return broker.Committed(userTopic) == 2 })
+	if got := handler.handledEvents(); len(got) != 2 || got[0] != "e1" || got[1] != "e2" {
+		t.Errorf("handled %v, want e1 then e2", got)
+	}
+	if retried := broker.Messages(retryTopic); len(retried) != 0 {
+		t.Errorf("%d events were retried, want none", len(retried))
+	}
+}
+
+func TestConsumerRetriesFailedEventsThroughTheRetryTopic(t *testing.T) {
+	broker := events.NewMemoryBroker()
+	handler := &flakyHandler{failures: 2, attempts: make(map[string]int)}
+	startConsumer(t, broker, handler, 3)
+
+	publishUserEvent(t, broker, userDeletedValue("e1"))
+
+	eventually(t, "the event is handled", func() bool { return len(handler.handledEvents()) == 1 })
+	retried := broker.Messages(retryTopic)
+	if len(retried) != 2 {
+		t.Fatalf("event was written to the retry topic %d times, want 2", len(retried))
+	}
+	for i, message := range retried {
+		if message.Headers[HeaderAttempts] != []string{"1", "2"}[i] || message.Headers[HeaderRetryAt] == "" || message.Headers[HeaderSourceTopic] != userTopic {
+			t.Errorf("retry %d headers = %v", i, message.Headers)
+		}
+		if string(message.Key) != "gone" {
+			t.Errorf("retry %d key = %q, want the original key", i, message.Key)
+		}
+	}
+	eventually(t, "the retries are committed", func() bool { return broker.Committed(retryTopic) == 2 })
+	if broker.Committed(userTopic) != 1 {
+		t.Errorf("original event is not committed")
+	}
+	if dead := broker.Messages(deadLetter); len(dead) != 0 {
+		t.Errorf("%d events were dead-lettered, want none", len(dead))
+	}
+}
+
+func TestConsumerDeadLettersAfterMaxAttempts(t *testing.T) {
+	broker := events.NewMemoryBroker()
+	handler := &flakyHandler{failures: 10, attempts: make(map[string]int)}
+	startConsumer(t, broker, handler, 2)
+
+	publishUserEvent(t, broker, userDeletedValue("e1"))
+
+	eventually(t, "the event is dead-lettered", func() bool { return len(broker.Messages(deadLetter)) == 1 })
+	message := broker.Messages(deadLetter)[0]
+	if message.Headers[HeaderAttempts] != "2" || message.Headers[HeaderLastError] == "" || message.Headers[HeaderSourceTopic] != userTopic {
+		t.Errorf("dead-lettered headers = %v, want 2 attempts, the last error and the source topic", message.Headers)
+	}
+	if _, ok := message.Headers[HeaderRetryAt]; ok {
+		t.Errorf("dead-lettered event still has a retry_at header")
+	}
+	if len(handler.handledEvents()) != 0 {
+		t.Errorf("handled %v, want nothing", handler.handledEvents())
+	}
+}
+
+func TestConsumerDeadLettersUndecodableEvents(t *testing.T) {
+	broker := events.NewMemoryBroker()
+	handler := &flakyHandler{attempts: make(map[string]int)}
+	startConsumer(t, broker, handler, 5)
+
+	publishUserEvent(t, broker, []byte("{not json"))
+	publishUserEvent(t, broker, []byte(`{"event_type":"UserDeleted","user_id":"gone"}`))
+	publishUserEvent(t, broker, userDeletedValue("e1"))
+
+	eventually(t, "the valid event is handled", func() bool { return len(handler.handledEvents()) == 1 })
+	if dead := broker.Messages(deadLetter); len(dead) != 2 {
+		t.Errorf("%d events were dead-lettered, want the 2 undecodable ones", len(dead))
+	}
+	if retried := broker.Messages(retryTopic); len(retried) != 0 {
+		t.Errorf("undecodable events were retried %d times, want none", len(retried))
+	}
+}
+
+func TestConsumerKeepsEventsUncommittedWhileForwardingFails(t *testing.T) {
+	broker := events.NewMemoryBroker()
+	// Once the handler fails, writes fail too, so the event cannot reach the retry topic
+	handler := &flakyHandler{failures: 1, attempts: make(map[string]int), onFailure: func() {
+		broker.SetError(context.DeadlineExceeded)
+	}}
+	startConsumer(t, broker, handler, 3)
+
+	publishUserEvent(t, broker, userDeletedValue("e1"))
+
+	eventually(t, "the first attempt fails", func() bool {
+		handler.mutex.Lock()
+		defer handler.mutex.Unlock()
+		return handler.attempts["e1"] == 1
+	})
+	time.Sleep(20 * time.Millisecond)
+	if broker.Committed(userTopic) != 0 {
+		t.Fatalf("event was committed before it reached the retry topic")
+	}
+
+	broker.SetError(nil)
+	eventually(t, "the event is handled after the broker recovers", func() bool { return len(handler.handledEvents()) == 1 })
+	eventually(t, "the event is committed", func() bool { return broker.Committed(userTopic) == 1 })
+}
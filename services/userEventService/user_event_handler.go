@@ -0,0 +1,244 @@
+package userEventService
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"sync"
+	"task-manager-app/constants"
+	"task-manager-app/constants/enums"
+	"task-manager-app/events"
+	"task-manager-app/exceptions"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/models"
+	"task-manager-app/repo"
+	"task-manager-app/services/userManagerServices"
+	"task-manager-app/utils"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// UserEventHandler applies a user event to the tasks of that user
+type UserEventHandler interface {
+	Handle(event *events.UserEvent) *errors.TaskManagerError
+}
+
+// HandlerOptions configures what happens to the tasks of a deleted user
+type HandlerOptions struct {
+	// Policy is constants.UserTaskPolicyUnassign (the default), UserTaskPolicyReassign or
+	// UserTaskPolicyArchive
+	Policy string
+	// ReassignTo receives the tasks under the reassign policy
+	ReassignTo string
+	// Users checks that ReassignTo exists before tasks are first given to it; nil skips the
+	// check
+	Users userManagerServices.UserService
+	// BatchSize is how many tasks are changed per transaction,
+	// constants.DefaultUserTaskBatchSize when zero
+	BatchSize int
+	// Retention is how long handled event IDs are kept to recognise redeliveries
+	Retention time.Duration
+	// Logger receives the handler logs, utils.Sugar when nil
+	Logger *zap.SugaredLogger
+	// Clock stamps the inbox records and task events, time.Now when nil
+	Clock func() time.Time
+}
+
+type userEventHandler struct {
+	inbox   repo.InboxRepository
+	cache   userManagerServices.UserCache
+	options HandlerOptions
+	now     func() time.Time
+
+	purgeMutex sync.Mutex
+	lastPurge  time.Time
+
+	targetMutex   sync.Mutex
+	targetChecked bool
+}
+
+// NewUserEventHandler creates a handler that records every handled event in the inbox, so a
+// redelivered event is applied once. cache may be nil; otherwise the user's cached lookup is
+// dropped after each event.
+func NewUserEventHandler(inbox repo.InboxRepository, cache userManagerServices.UserCache, options HandlerOptions) (UserEventHandler, error) {
+	if options.Logger == nil {
+		options.Logger = utils.Sugar
+	}
+	if options.Clock == nil {
+		options.Clock = time.Now
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = constants.DefaultUserTaskBatchSize
+	}
+	switch options.Policy {
+	case "":
+		options.Policy = constants.UserTaskPolicyUnassign
+	case constants.UserTaskPolicyUnassign, constants.UserTaskPolicyArchive:
+	case constants.UserTaskPolicyReassign:
+		if options.ReassignTo == "" {
+			return nil, fmt.Errorf(constants.ErrMissingReassignTarget)
+		}
+	default:
+		return nil, fmt.Errorf("%s, got %q", constants.ErrInvalidUserTaskPolicy, options.Policy)
+	}
+
+	return &userEventHandler{
+		inbox:   inbox,
+		cache:   cache,
+		options: options,
+		now:     options.Clock,
+	}, nil
+}
+
+// Handle applies the task policy to every task of a deleted user, together with the
+// TaskUpdated events of the changed tasks. Other event types only refresh the cache.
+func (h *userEventHandler) Handle(event *events.UserEvent) *errors.TaskManagerError {
+	if event.EventType == events.UserDeleted {
+		processed, taskErr := h.inbox.Processed(event.EventID)
+		if taskErr != nil {
+			return taskErr
+		}
+		if processed {
+			h.options.Logger.Infof("Skipping user event %s, it was handled before", event.EventID)
+		} else if taskErr := h.releaseTasks(event); taskErr != nil {
+			return taskErr
+		}
+	}
+
+	if h.cache != nil {
+		if err := h.cache.Invalidate(event.UserID); err != nil {
+			h.options.Logger.Warnf("Failed to invalidate cached user %s: %v", event.UserID, err)
+		}
+	}
+	h.purgeProcessed()
+	return nil
+}
+
+// releaseTasks applies the policy to the tasks of the deleted user, one transaction per batch
+// of locked tasks. The event is recorded in the inbox with the last batch, so an event that
+// fails part way is applied again to the tasks left; the tasks already changed no longer
+// belong to the user or no longer change.
+func (h *userEventHandler) releaseTasks(event *events.UserEvent) *errors.TaskManagerError {
+	if h.options.Policy == constants.UserTaskPolicyReassign {
+		if taskErr := h.checkReassignTarget(); taskErr != nil {
+			return taskErr
+		}
+	}
+
+	var afterID uint
+	changed := 0
+	for done := false; !done; {
+		taskErr := h.inbox.WithinTransaction(func(inbox repo.InboxRepository, tasks repo.TaskRepository, outbox repo.OutboxRepository) *errors.TaskManagerError {
+			userTasks, taskErr := tasks.ListByUserForUpdate(event.UserID, afterID, h.options.BatchSize)
+			if taskErr != nil {
+				return taskErr
+			}
+			for i := range userTasks {
+				task := &userTasks[i]
+				before := *task
+				if taskErr := h.applyPolicy(tasks, task, event.UserID); taskErr != nil {
+					return taskErr
+				}
+				taskEvents := events.NewTaskUpdated(&before, task, h.now())
+				if len(taskEvents) == 0 {
+					continue
+				}
+				if taskErr := tasks.Update(task); taskErr != nil {
+					return taskErr
+				}
+				if taskErr := outbox.Add(taskEvents...); taskErr != nil {
+					return taskErr
+				}
+				changed++
+			}
+			if len(userTasks) == h.options.BatchSize {
+				afterID = userTasks[len(userTasks)-1].ID
+				return nil
+			}
+
+			done = true
+			recorded, taskErr := inbox.MarkProcessed(event.EventID, event.EventType, h.now())
+			if taskErr != nil {
+				return taskErr
+			}
+			if !recorded {
+				h.options.Logger.Infof("User event %s was handled concurrently", event.EventID)
+			}
+			return nil
+		})
+		if taskErr != nil {
+			return taskErr
+		}
+	}
+	h.options.Logger.Infow("Applied deleted user policy", "user_id", event.UserID, "policy", h.options.Policy, "tasks", changed)
+	return nil
+}
+
+// checkReassignTarget makes sure that the user receiving the tasks exists, once the user
+// service confirmed it. A target that does not exist fails the event, which is retried and
+// then dead-lettered, instead of handing tasks to nobody.
+func (h *userEventHandler) checkReassignTarget() *errors.TaskManagerError {
+	if h.options.Users == nil {
+		return nil
+	}
+	h.targetMutex.Lock()
+	defer h.targetMutex.Unlock()
+	if h.targetChecked {
+		return nil
+	}
+
+	valid, err := h.options.Users.ValidateUser(context.Background(), h.options.ReassignTo)
+	if err != nil {
+		if stdErrors.Is(err, userManagerServices.ErrUserServiceUnavailable) {
+			return exceptions.ServiceUnavailableException(constants.ErrUserServiceUnavailable)
+		}
+		return exceptions.InternalServerException(fmt.Sprintf("Failed to validate user: %v", err))
+	}
+	if !valid {
+		h.options.Logger.Errorf("%s: %s", constants.ErrUnknownReassignTarget, h.options.ReassignTo)
+		return exceptions.NotFoundException(constants.ErrUnknownReassignTarget)
+	}
+	h.targetChecked = true
+	return nil
+}
+
+func (h *userEventHandler) applyPolicy(tasks repo.TaskRepository, task *models.Task, userID string) *errors.TaskManagerError {
+	switch h.options.Policy {
+	case constants.UserTaskPolicyArchive:
+		task.Status = string(enums.StatusArchived)
+		return nil
+	case constants.UserTaskPolicyReassign:
+		target := h.options.ReassignTo
+		if target != userID {
+			// Titles stay unique per user; a task the new owner already has under its title
+			// is unassigned instead
+			taken, taskErr := tasks.ExistsByTitleAndUser(task.TitleKey, target, task.UUID)
+			if taskErr != nil {
+				return taskErr
+			}
+			if !taken {
+				task.UserID = &target
+				return nil
+			}
+			h.options.Logger.Warnf("Unassigning task %s instead of reassigning it to %s, who has a task with the same title", task.UUID, target)
+		}
+	}
+	task.UserID = nil
+	return nil
+}
+
+// purgeProcessed drops inbox entries older than the retention at most once an hour
+func (h *userEventHandler) purgeProcessed() {
+	h.purgeMutex.Lock()
+	defer h.purgeMutex.Unlock()
+
+	now := h.now()
+	if h.options.Retention <= 0 || now.Sub(h.lastPurge) < time.Hour {
+		return
+	}
+	h.lastPurge = now
+	if taskErr := h.inbox.DeleteProcessed(now.Add(-h.options.Retention)); taskErr != nil {
+		h.options.Logger.Errorf("Failed to purge handled user events: %s", taskErr.Message)
+	}
+}
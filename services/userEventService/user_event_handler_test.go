@@ -0,0 +1,307 @@
+package userEventService
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"task-manager-app/constants"
+	"task-manager-app/constants/enums"
+	"task-manager-app/events"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/models"
+	"task-manager-app/repo"
+	"task-manager-app/services/userManagerServices"
+	"task-manager-app/utils"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	utils.Sugar = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
+
+// memoryStore holds tasks, handled event IDs and outbox events; its transactions are not
+// isolated, which the handler does not rely on
+type memoryStore struct {
+	tasks        []models.Task
+	processed    map[string]bool
+	published    []*events.TaskEvent
+	transactions int
+}
+
+func newMemoryStore(tasks ...models.Task) *memoryStore {
+	return &memoryStore{tasks: tasks, processed: make(map[string]bool)}
+}
+
+func (s *memoryStore) MarkProcessed(eventID, eventType string, processedAt time.Time) (bool, *errors.TaskManagerError) {
+	if s.processed[eventID] {
+		return false, nil
+	}
+	s.processed[eventID] = true
+	return true, nil
+}
+
+func (s *memoryStore) Processed(eventID string) (bool, *errors.TaskManagerError) {
+	return s.processed[eventID], nil
+}
+
+func (s *memoryStore) DeleteProcessed(before time.Time) *errors.TaskManagerError {
+	return nil
+}
+
+func (s *memoryStore) WithinTransaction(fn func(inbox repo.InboxRepository, tasks repo.TaskRepository, outbox repo.OutboxRepository) *errors.TaskManagerError) *errors.TaskManagerError {
+	s.transactions++
+	return fn(s, &memoryTasks{store: s}, &memoryOutbox{store: s})
+}
+
+type memoryTasks struct {
+	repo.TaskRepository
+	store *memoryStore
+}
+
+type memoryOutbox struct {
+	repo.OutboxRepository
+	store *memoryStore
+}
+
+func (r *memoryTasks) ListByUserForUpdate(userID string, afterID uint, limit int) ([]models.Task, *errors.TaskManagerError) {
+	var result []models.Task
+	for _, task := range r.store.tasks {
+		if task.UserID != nil && *task.UserID == userID && task.ID > afterID && len(result) < limit {
+			result = append(result, task)
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryTasks) ExistsByTitleAndUser(titleKey string, userID string, excludeUUID string) (bool, *errors.TaskManagerError) {
+	for _, task := range r.store.tasks {
+		if task.TitleKey == titleKey && task.UserID != nil && *task.UserID == userID && task.UUID != excludeUUID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryTasks) Update(task *models.Task) *errors.TaskManagerError {
+	for i := range r.store.tasks {
+		if r.store.tasks[i].UUID == task.UUID {
+			r.store.tasks[i] = *task
+		}
+	}
+	return nil
+}
+
+func (o *memoryOutbox) Add(taskEvents ...*events.TaskEvent) *errors.TaskManagerError {
+	o.store.published = append(o.store.published, taskEvents...)
+	return nil
+}
+
+func (s *memoryStore) task(uuid string) models.Task {
+	for _, task := range s.tasks {
+		if task.UUID == uuid {
+			return task
+		}
+	}
+	return models.Task{}
+}
+
+// invalidatingCache records invalidated users
+type invalidatingCache struct {
+	userManagerServices.UserCache
+	invalidated []string
+}
+
+func (c *invalidatingCache) Invalidate(userID string) error {
+	c.invalidated = append(c.invalidated, userID)
+	return nil
+}
+
+func userTasks() []models.Task {
+	gone, other, heir := "gone", "other", "heir"
+	return []models.Task{
+		{ID: 1, UUID: "t1", Title: "Write docs", TitleKey: "Write docs", Status: "Pending", UserID: &gone},
+		{ID: 2, UUID: "t2", Title: "Fix bug", TitleKey: "Fix bug", Status: "InProgress", UserID: &gone},
+		{ID: 3, UUID: "t3", Title: "Review", TitleKey: "Review", Status: "Pending", UserID: &other},
+		{ID: 4, UUID: "t4", Title: "Fix bug", TitleKey: "Fix bug", Status: "Pending", UserID: &heir},
+	}
+}
+
+func deleted(eventID string) *events.UserEvent {
+	return &events.UserEvent{EventID: eventID, EventType: events.UserDeleted, UserID: "gone"}
+}
+
+func TestHandleAppliesTaskPolicy(t *testing.T) {
+	tests := []struct {
+		policy     string
+		wantUsers  map[string]string
+		wantStatus map[string]string
+	}{
+		{
+			policy:     constants.UserTaskPolicyUnassign,
+			wantUsers:  map[string]string{"t1": "", "t2": "", "t3": "other"},
+			wantStatus: map[string]string{"t1": "Pending", "t2": "InProgress"},
+		},
+		{
+			// t2 collides with a title of the new owner and is unassigned instead
+			policy:     constants.UserTaskPolicyReassign,
+			wantUsers:  map[string]string{"t1": "heir", "t2": "", "t3": "other"},
+			wantStatus: map[string]string{"t1": "Pending", "t2": "InProgress"},
+		},
+		{
+			policy:     constants.UserTaskPolicyArchive,
+			wantUsers:  map[string]string{"t1": "gone", "t2": "gone", "t3": "other"},
+			wantStatus: map[string]string{"t1": string(enums.StatusArchived), "t2": string(enums.StatusArchived)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.policy, func(t *testing.T) {
+			store := newMemoryStore(userTasks()...)
+			cache := &invalidatingCache{}
+			handler, err := NewUserEventHandler(store, cache, HandlerOptions{Policy: test.policy, ReassignTo: "heir"})
+			if err != nil {
+				t.Fatalf("NewUserEventHandler returned error %v", err)
+			}
+
+			if taskErr := handler.Handle(deleted("e1")); taskErr != nil {
+				t.Fatalf("Handle returned error %+v", taskErr)
+			}
+
+			for uuid, want := range test.wantUsers {
+				if got := utils.TaskManagerUtils.GetStringValue(store.task(uuid).UserID); got != want {
+					t.Errorf("task %s user = %q, want %q", uuid, got, want)
+				}
+			}
+			for uuid, want := range test.wantStatus {
+				if got := store.task(uuid).Status; got != want {
+					t.Errorf("task %s status = %q, want %q", uuid, got, want)
+				}
+			}
+			updated := 0
+			for _, event := range store.published {
+				if event.EventType == events.TaskUpdated {
+					updated++
+				}
+			}
+			if updated != 2 {
+				t.Errorf("got %d TaskUpdated events, want one per task of the deleted user", updated)
+			}
+			if len(cache.invalidated) != 1 || cache.invalidated[0] != "gone" {
+				t.Errorf("invalidated %v, want the deleted user", cache.invalidated)
+			}
+		})
+	}
+}
+
+func TestHandleSkipsRedeliveredEvents(t *testing.T) {
+	store := newMemoryStore(userTasks()...)
+	handler, _ := NewUserEventHandler(store, nil, HandlerOptions{Policy: constants.UserTaskPolicyReassign, ReassignTo: "heir"})
+
+	if taskErr := handler.Handle(deleted("e1")); taskErr != nil {
+		t.Fatalf("Handle returned error %+v", taskErr)
+	}
+	published := len(store.published)
+
+	// The user is given a task again; the redelivered event must not take it away
+	gone := "gone"
+	store.tasks = append(store.tasks, models.Task{ID: 5, UUID: "t5", Title: "New", TitleKey: "New", Status: "Pending", UserID: &gone})
+	if taskErr := handler.Handle(deleted("e1")); taskErr != nil {
+		t.Fatalf("Handle of the redelivery returned error %+v", taskErr)
+	}
+	if len(store.published) != published {
+		t.Errorf("redelivery published %d more events, want none", len(store.published)-published)
+	}
+	if got := utils.TaskManagerUtils.GetStringValue(store.task("t5").UserID); got != "gone" {
+		t.Errorf("task t5 user = %q after a redelivery, want it untouched", got)
+	}
+}
+
+func TestHandleIgnoresOtherEventTypes(t *testing.T) {
+	store := newMemoryStore(userTasks()...)
+	cache := &invalidatingCache{}
+	handler, _ := NewUserEventHandler(store, cache, HandlerOptions{})
+
+	if taskErr := handler.Handle(&events.UserEvent{EventID: "e1", EventType: events.UserUpdated, UserID: "gone"}); taskErr != nil {
+		t.Fatalf("Handle returned error %+v", taskErr)
+	}
+	if len(store.published) != 0 || len(store.processed) != 0 {
+		t.Errorf("UserUpdated changed tasks: %d events, %d recorded", len(store.published), len(store.processed))
+	}
+	if len(cache.invalidated) != 1 {
+		t.Errorf("invalidated %v, want the updated user dropped from the cache", cache.invalidated)
+	}
+}
+
+func TestNewUserEventHandlerRejectsInvalidPolicies(t *testing.T) {
+	if _, err := NewUserEventHandler(newMemoryStore(), nil, HandlerOptions{Policy: "delete"}); err == nil {
+		t.Error("unknown policy was accepted")
+	}
+	if _, err := NewUserEventHandler(newMemoryStore(), nil, HandlerOptions{Policy: constants.UserTaskPolicyReassign}); err == nil {
+		t.Error("reassign policy without a target was accepted")
+	}
+}
+
+func TestHandleChangesTasksInBatches(t *testing.T) {
+	gone := "gone"
+	var tasks []models.Task
+	for i := 1; i <= 5; i++ {
+		tasks = append(tasks, models.Task{ID: uint(i), UUID: fmt.Sprintf("t%d", i), Title: "Task", TitleKey: fmt.Sprintf("Task %d", i), Status: "Pending", UserID: &gone})
+	}
+	store := newMemoryStore(tasks...)
+	handler, _ := NewUserEventHandler(store, nil, HandlerOptions{Policy: constants.UserTaskPolicyArchive, BatchSize: 2})
+
+	if taskErr := handler.Handle(deleted("e1")); taskErr != nil {
+		t.Fatalf("Handle returned error %+v", taskErr)
+	}
+	for _, task := range store.tasks {
+		if task.Status != string(enums.StatusArchived) {
+			t.Errorf("task %s status = %q, want it archived", task.UUID, task.Status)
+		}
+	}
+	if store.transactions != 3 {
+		t.Errorf("used %d transactions, want one per batch of 2", store.transactions)
+	}
+	if !store.processed["e1"] {
+		t.Error("event was not recorded with the last batch")
+	}
+}
+
+// knownUsers is a user service that knows the users it holds
+type knownUsers struct {
+	userManagerServices.UserService
+	users   map[string]bool
+	lookups int
+}
+
+func (u *knownUsers) ValidateUser(ctx context.Context, userID string) (bool, error) {
+	u.lookups++
+	return u.users[userID], nil
+}
+
+func TestHandleChecksTheReassignTargetOnce(t *testing.T) {
+	store := newMemoryStore(userTasks()...)
+	users := &knownUsers{users: map[string]bool{}}
+	handler, _ := NewUserEventHandler(store, nil, HandlerOptions{Policy: constants.UserTaskPolicyReassign, ReassignTo: "heir", Users: users})
+
+	if taskErr := handler.Handle(deleted("e1")); taskErr == nil || taskErr.Message != constants.ErrUnknownReassignTarget {
+		t.Fatalf("Handle returned %+v, want the unknown target reported", taskErr)
+	}
+	if len(store.published) != 0 || store.processed["e1"] {
+		t.Errorf("tasks were changed for an unknown target: %d events", len(store.published))
+	}
+
+	users.users["heir"] = true
+	if taskErr := handler.Handle(deleted("e1")); taskErr != nil {
+		t.Fatalf("Handle returned error %+v", taskErr)
+	}
+	if taskErr := handler.Handle(deleted("e2")); taskErr != nil {
+		t.Fatalf("Handle returned error %+v", taskErr)
+	}
+	if users.lookups != 2 {
+		t.Errorf("looked the target up %d times, want it remembered once found", users.lookups)
+	}
+}
@@ -0,0 +1,66 @@
+package userManagerServices
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// userFile is the layout of a static user file. JSON files work too since YAML is a
+// superset of JSON:
+//
+//	users:
+//	  - user_id: 550e8400-e29b-41d4-a716-446655440000
+//	    name: Jane Doe
+//	    email: jane@example.com
+type userFile struct {
+	Users []struct {
+		UserID string `yaml:"user_id" json:"user_id"`
+		Name   string `yaml:"name" json:"name"`
+		Email  string `yaml:"email" json:"email"`
+	} `yaml:"users" json:"users"`
+}
+
+// fileUserProvider serves users from a YAML or JSON file loaded at startup
+type fileUserProvider struct {
+	users map[string]UserProfile
+}
+
+func NewFileUserProvider(path string) (UserProvider, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user file: %w", err)
+	}
+
+	var parsed userFile
+	if err := yaml.Unmarshal(raw, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse user file %s: %w", path, err)
+	}
+
+	users := make(map[string]UserProfile, len(parsed.Users))
+	for i, u := range parsed.Users {
+		if u.UserID == "" {
+			return nil, fmt.Errorf("user file %s: entry %d has no user_id", path, i)
+		}
+		users[u.UserID] = UserProfile{UserID: u.UserID, Name: u.Name, Email: u.Email}
+	}
+	return &fileUserProvider{users: users}, nil
+}
+
+func (p *fileUserProvider) GetUser(ctx context.Context, userID string) (*UserProfile, error) {
+	profile, ok := p.users[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &profile, nil
+}
+
+func (p *fileUserProvider) GetUsers(ctx context.Context, userIDs []string) (map[string]*UserProfile, error) {
+	profiles := make(map[string]*UserProfile, len(userIDs))
+	for _, userID := range userIDs {
+		profiles[userID], _ = p.GetUser(ctx, userID)
+	}
+	return profiles, nil
+}
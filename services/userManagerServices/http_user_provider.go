@@ -0,0 +1,69 @@
+package userManagerServices
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"task-manager-app/network/userManager"
+)
+
+// httpUserProvider looks users up in the user manager service over HTTP
+type httpUserProvider struct {
+	userClient *userManager.UserServiceClient
+}
+
+func NewHTTPUserProvider(userClient *userManager.UserServiceClient) UserProvider {
+	return &httpUserProvider{userClient: userClient}
+}
+
+// GetUser fetches a user from the user service, returning nil when it does not exist
+func (p *httpUserProvider) GetUser(ctx context.Context, userID string) (*UserProfile, error) {
+	if p.userClient == nil {
+		return nil, fmt.Errorf("user service client not initialized")
+	}
+
+	resp, clientErr := p.userClient.ValidateUserID(ctx, userID)
+	if clientErr != nil {
+		if clientErr.ResponseCode < http.StatusInternalServerError {
+			return nil, fmt.Errorf("failed to validate user ID: %s", clientErr.Message)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrUserServiceUnavailable, clientErr.Message)
+	}
+	if !resp.Valid {
+		return nil, nil
+	}
+	return toProfile(resp), nil
+}
+
+// GetUsers fetches many users from the user service in one batch
+func (p *httpUserProvider) GetUsers(ctx context.Context, userIDs []string) (map[string]*UserProfile, error) {
+	if p.userClient == nil {
+		return nil, fmt.Errorf("user service client not initialized")
+	}
+
+	results, clientErr := p.userClient.ValidateUserIDs(ctx, userIDs)
+	profiles := make(map[string]*UserProfile, len(results))
+	for userID, resp := range results {
+		if !resp.Valid {
+			profiles[userID] = nil
+			continue
+		}
+		profiles[userID] = toProfile(resp)
+	}
+
+	if clientErr != nil {
+		if clientErr.ResponseCode < http.StatusInternalServerError {
+			return profiles, fmt.Errorf("failed to validate user IDs: %s", clientErr.Message)
+		}
+		return profiles, fmt.Errorf("%w: %s", ErrUserServiceUnavailable, clientErr.Message)
+	}
+	return profiles, nil
+}
+
+func toProfile(resp *userManager.UserValidationResponse) *UserProfile {
+	return &UserProfile{
+		UserID: resp.UserID,
+		Name:   resp.Name,
+		Email:  resp.Email,
+	}
+}
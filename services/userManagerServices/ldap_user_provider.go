@@ -0,0 +1,148 @@
+package userManagerServices
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPOptions configures the LDAP user directory
+type LDAPOptions struct {
+	URL            string
+	BindDN         string
+	BindPassword   string
+	BaseDN         string
+	IDAttribute    string
+	NameAttribute  string
+	EmailAttribute string
+	Timeout        time.Duration
+}
+
+// DirectoryEntry is a user entry returned by an LDAP-style directory
+type DirectoryEntry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Get returns the first value of attribute or an empty string
+func (e DirectoryEntry) Get(attribute string) string {
+	if values := e.Attributes[attribute]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Directory finds entries whose idAttribute equals one of ids. It is implemented by a real
+// LDAP connection and by userManagerServicesFake.MemoryDirectory, an in-process stand-in.
+type Directory interface {
+	Search(idAttribute string, ids []string, attributes []string) ([]DirectoryEntry, error)
+}
+
+// ldapUserProvider looks users up in an LDAP-style directory
+type ldapUserProvider struct {
+	directory Directory
+	options   LDAPOptions
+}
+
+func NewLDAPUserProvider(directory Directory, options LDAPOptions) UserProvider {
+	if options.IDAttribute == "" {
+		options.IDAttribute = "uid"
+	}
+	if options.NameAttribute == "" {
+		options.NameAttribute = "cn"
+	}
+	if options.EmailAttribute == "" {
+		options.EmailAttribute = "mail"
+	}
+	return &ldapUserProvider{directory: directory, options: options}
+}
+
+func (p *ldapUserProvider) GetUser(ctx context.Context, userID string) (*UserProfile, error) {
+	profiles, err := p.GetUsers(ctx, []string{userID})
+	if err != nil {
+		return nil, err
+	}
+	return profiles[userID], nil
+}
+
+func (p *ldapUserProvider) GetUsers(ctx context.Context, userIDs []string) (map[string]*UserProfile, error) {
+	attributes := []string{p.options.IDAttribute, p.options.NameAttribute, p.options.EmailAttribute}
+	entries, err := p.directory.Search(p.options.IDAttribute, userIDs, attributes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUserServiceUnavailable, err)
+	}
+
+	profiles := make(map[string]*UserProfile, len(userIDs))
+	for _, userID := range userIDs {
+		profiles[userID] = nil
+	}
+	for _, entry := range entries {
+		userID := entry.Get(p.options.IDAttribute)
+		if _, requested := profiles[userID]; !requested {
+			continue
+		}
+		profiles[userID] = &UserProfile{
+			UserID: userID,
+			Name:   entry.Get(p.options.NameAttribute),
+			Email:  entry.Get(p.options.EmailAttribute),
+		}
+	}
+	return profiles, nil
+}
+
+// ldapDirectory searches a real LDAP server, opening a connection per search
+type ldapDirectory struct {
+	options LDAPOptions
+}
+
+func NewLDAPDirectory(options LDAPOptions) Directory {
+	return &ldapDirectory{options: options}
+}
+
+func (d *ldapDirectory) Search(idAttribute string, ids []string, attributes []string) ([]DirectoryEntry, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	conn, err := ldap.DialURL(d.options.URL, ldap.DialWithDialer(&net.Dialer{Timeout: d.options.Timeout}))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if d.options.Timeout > 0 {
+		conn.SetTimeout(d.options.Timeout)
+	}
+
+	if d.options.BindDN != "" {
+		if err := conn.Bind(d.options.BindDN, d.options.BindPassword); err != nil {
+			return nil, err
+		}
+	}
+
+	clauses := make([]string, len(ids))
+	for i, id := range ids {
+		clauses[i] = fmt.Sprintf("(%s=%s)", ldap.EscapeFilter(idAttribute), ldap.EscapeFilter(id))
+	}
+	request := ldap.NewSearchRequest(
+		d.options.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		"(|"+strings.Join(clauses, "")+")", attributes, nil,
+	)
+	result, err := conn.Search(request)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]DirectoryEntry, 0, len(result.Entries))
+	for _, e := range result.Entries {
+		entry := DirectoryEntry{DN: e.DN, Attributes: make(map[string][]string, len(e.Attributes))}
+		for _, attr := range e.Attributes {
+			entry.Attributes[attr.Name] = attr.Values
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
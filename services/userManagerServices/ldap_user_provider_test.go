@@ -0,0 +1,63 @@
+package userManagerServices_test
+
+import (
+	"context"
+	"errors"
+	"task-manager-app/services/userManagerServices"
+	"task-manager-app/services/userManagerServices/userManagerServicesFake"
+	"testing"
+)
+
+func newDirectory() *userManagerServicesFake.MemoryDirectory {
+	return userManagerServicesFake.NewMemoryDirectory(userManagerServices.DirectoryEntry{
+		DN: "uid=ada,ou=people,dc=example,dc=com",
+		Attributes: map[string][]string{
+			"uid":  {"ada"},
+			"cn":   {"Ada Lovelace"},
+			"mail": {"ada@example.com"},
+		},
+	})
+}
+
+func TestLDAPUserProviderGetUsers(t *testing.T) {
+	provider := userManagerServices.NewLDAPUserProvider(newDirectory(), userManagerServices.LDAPOptions{})
+
+	profiles, err := provider.GetUsers(context.Background(), []string{"ada", "grace"})
+	if err != nil {
+		t.Fatalf("GetUsers returned error %v", err)
+	}
+	if ada := profiles["ada"]; ada == nil || ada.Name != "Ada Lovelace" || ada.Email != "ada@example.com" {
+		t.Fatalf("profile of ada = %+v, want name and email from cn and mail", ada)
+	}
+	if grace, ok := profiles["grace"]; !ok || grace != nil {
+		t.Fatalf("profile of grace = %+v (present %t), want a nil entry for an unknown user", grace, ok)
+	}
+}
+
+func TestLDAPUserProviderCustomAttributes(t *testing.T) {
+	directory := userManagerServicesFake.NewMemoryDirectory(userManagerServices.DirectoryEntry{
+		Attributes: map[string][]string{"employeeID": {"e1"}, "displayName": {"Grace"}},
+	})
+	provider := userManagerServices.NewLDAPUserProvider(directory, userManagerServices.LDAPOptions{
+		IDAttribute:   "employeeID",
+		NameAttribute: "displayName",
+	})
+
+	profile, err := provider.GetUser(context.Background(), "e1")
+	if err != nil {
+		t.Fatalf("GetUser returned error %v", err)
+	}
+	if profile == nil || profile.UserID != "e1" || profile.Name != "Grace" {
+		t.Fatalf("GetUser = %+v, want e1 named Grace", profile)
+	}
+}
+
+func TestLDAPUserProviderDirectoryDown(t *testing.T) {
+	directory := newDirectory()
+	directory.SetError(errors.New("connection refused"))
+	provider := userManagerServices.NewLDAPUserProvider(directory, userManagerServices.LDAPOptions{})
+
+	if _, err := provider.GetUser(context.Background(), "ada"); !errors.Is(err, userManagerServices.ErrUserServiceUnavailable) {
+		t.Fatalf("GetUser error = %v, want ErrUserServiceUnavailable", err)
+	}
+}
@@ -0,0 +1,53 @@
+// Package userManagerServicesFake provides an in-process stand-in for an LDAP server, so the
+// LDAP user provider can be exercised locally and in tests without a directory.
+package userManagerServicesFake
+
+import (
+	"sync"
+	"task-manager-app/services/userManagerServices"
+)
+
+// MemoryDirectory implements userManagerServices.Directory over an in-memory entry list
+type MemoryDirectory struct {
+	mutex   sync.RWMutex
+	entries []userManagerServices.DirectoryEntry
+	err     error
+}
+
+func NewMemoryDirectory(entries ...userManagerServices.DirectoryEntry) *MemoryDirectory {
+	return &MemoryDirectory{entries: entries}
+}
+
+// Add stores another entry
+func (d *MemoryDirectory) Add(entry userManagerServices.DirectoryEntry) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.entries = append(d.entries, entry)
+}
+
+// SetError makes every search fail with err; pass nil to recover
+func (d *MemoryDirectory) SetError(err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.err = err
+}
+
+func (d *MemoryDirectory) Search(idAttribute string, ids []string, attributes []string) ([]userManagerServices.DirectoryEntry, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if d.err != nil {
+		return nil, d.err
+	}
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	var matches []userManagerServices.DirectoryEntry
+	for _, entry := range d.entries {
+		if wanted[entry.Get(idAttribute)] {
+			matches = append(matches, entry)
+		}
+	}
+	return matches, nil
+}
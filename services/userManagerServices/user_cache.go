@@ -0,0 +1,451 @@
+package userManagerServices
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"task-manager-app/utils"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const redisUserKeyPrefix = "task-manager:user:"
+
+// UserProfile is the user data returned by the user directory
+type UserProfile struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name,omitempty"`
+	Email  string `json:"email,omitempty"`
+}
+
+// UserLoader fetches a user from the source of truth. It returns nil for an unknown user.
+type UserLoader func(ctx context.Context, userID string) (*UserProfile, error)
+
+// UserBatchLoader fetches many users at once. Unknown users map to nil; users whose lookup
+// failed are left out of the map and reported through the error.
+type UserBatchLoader func(ctx context.Context, userIDs []string) (map[string]*UserProfile, error)
+
+// UserCache caches user lookups so repeated validations do not hit the user service. A load
+// is shared by every lookup of the same user while it runs, so it is not cancelled with the
+// lookup that started it; each lookup stops waiting for it when its own ctx is done.
+type UserCache interface {
+	Lookup(ctx context.Context, userID string, load UserLoader) (*UserProfile, error)
+	LookupMany(ctx context.Context, userIDs []string, load UserBatchLoader) (map[string]*UserProfile, error)
+	Invalidate(userID string) error
+}
+
+// UserCacheOptions configures TTLs and capacity of the user cache
+type UserCacheOptions struct {
+	PositiveTTL  time.Duration
+	NegativeTTL  time.Duration
+	MaxSize      int
+	Redis        *redis.Client
+	RedisTimeout time.Duration
+	// LoadTimeout bounds a shared load, which outlives the lookup that started it; unbounded
+	// when zero
+	LoadTimeout time.Duration
+	// Clock expires the entries, time.Now when nil
+	Clock func() time.Time
+	// Logger receives Redis failures, utils.Sugar when nil
+	Logger *zap.SugaredLogger
+}
+
+type cacheEntry struct {
+	Found     bool        `json:"found"`
+	Profile   UserProfile `json:"profile"`
+	ExpiresAt time.Time   `json:"expires_at"`
+}
+
+type userCacheStore interface {
+	get(userID string) (*cacheEntry, bool)
+	set(userID string, entry *cacheEntry)
+	delete(userID string) error
+}
+
+type userCache struct {
+	store       userCacheStore
+	loads       loadGroup
+	positiveTTL time.Duration
+	negativeTTL time.Duration
+	loadTimeout time.Duration
+	now         func() time.Time
+}
+
+// NewUserCache creates a user cache backed by Redis when opts.Redis is set and by an
+// in-process LRU otherwise. Only definitive answers are cached; errors are never stored.
+func NewUserCache(opts UserCacheOptions) UserCache {
+	if opts.Clock == nil {
+		opts.Clock = time.Now
+	}
+	if opts.Logger == nil {
+		opts.Logger = utils.Sugar
+	}
+	var store userCacheStore
+	if opts.Redis != nil {
+		store = &redisUserStore{client: opts.Redis, timeout: opts.RedisTimeout, now: opts.Clock, logger: opts.Logger}
+	} else {
+		store = newLRUUserStore(opts.MaxSize, opts.Clock)
+	}
+	return &userCache{
+		store:       store,
+		loads:       loadGroup{loads: make(map[string]*userLoad)},
+		positiveTTL: opts.PositiveTTL,
+		negativeTTL: opts.NegativeTTL,
+		loadTimeout: opts.LoadTimeout,
+		now:         opts.Clock,
+	}
+}
+
+// Lookup returns the cached result for userID or loads it, collapsing concurrent loads
+// of the same user into a single call
+func (c *userCache) Lookup(ctx context.Context, userID string, load UserLoader) (*UserProfile, error) {
+	if entry, ok := c.store.get(userID); ok {
+		return entry.profile(), nil
+	}
+
+	own, running := c.loads.claim([]string{userID})
+	if pending, ok := running[userID]; ok {
+		return pending.wait(ctx)
+	}
+	pending := own[userID]
+	go func() {
+		loadCtx, cancel := c.loadContext(ctx)
+		defer cancel()
+		profile, err := load(loadCtx, userID)
+		if err != nil {
+			c.loads.finish(userID, pending, nil, err)
+			return
+		}
+		entry := c.newEntry(profile)
+		c.save(userID, pending, entry)
+		c.loads.finish(userID, pending, entry, nil)
+	}()
+	return pending.wait(ctx)
+}
+
+// LookupMany returns cached results for userIDs and loads all misses with one call to load.
+// Misses that another Lookup or LookupMany is already loading are waited for instead.
+func (c *userCache) LookupMany(ctx context.Context, userIDs []string, load UserBatchLoader) (map[string]*UserProfile, error) {
+	results := make(map[string]*UserProfile, len(userIDs))
+	misses := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if entry, ok := c.store.get(userID); ok {
+			results[userID] = entry.profile()
+		} else {
+			misses = append(misses, userID)
+		}
+	}
+	if len(misses) == 0 {
+		return results, nil
+	}
+
+	own, running := c.loads.claim(misses)
+	if len(own) > 0 {
+		toLoad := make([]string, 0, len(own))
+		for _, userID := range misses {
+			if _, ok := own[userID]; ok {
+				toLoad = append(toLoad, userID)
+			}
+		}
+		go c.loadMany(ctx, toLoad, own, load)
+	}
+
+	var firstErr error
+	for _, userID := range misses {
+		pending, ok := own[userID]
+		if !ok {
+			pending = running[userID]
+		}
+		profile, err := pending.wait(ctx)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		results[userID] = profile
+	}
+	return results, firstErr
+}
+
+// loadMany runs the batch load of the users claimed by LookupMany and finishes their loads
+func (c *userCache) loadMany(ctx context.Context, userIDs []string, own map[string]*userLoad, load UserBatchLoader) {
+	loadCtx, cancel := c.loadContext(ctx)
+	defer cancel()
+	loaded, err := load(loadCtx, userIDs)
+	for _, userID := range userIDs {
+		profile, ok := loaded[userID]
+		if !ok {
+			missing := err
+			if missing == nil {
+				missing = fmt.Errorf("user %s was not loaded", userID)
+			}
+			c.loads.finish(userID, own[userID], nil, missing)
+			continue
+		}
+		entry := c.newEntry(profile)
+		c.save(userID, own[userID], entry)
+		c.loads.finish(userID, own[userID], entry, nil)
+	}
+}
+
+// loadContext detaches a shared load from the cancellation of the lookup that started it but
+// keeps its values, such as the trace
+func (c *userCache) loadContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if c.loadTimeout <= 0 {
+		return context.WithCancel(detached)
+	}
+	return context.WithTimeout(detached, c.loadTimeout)
+}
+
+func (c *userCache) newEntry(profile *UserProfile) *cacheEntry {
+	entry := &cacheEntry{Found: profile != nil, ExpiresAt: c.now().Add(c.negativeTTL)}
+	if profile != nil {
+		entry.Profile = *profile
+		entry.ExpiresAt = c.now().Add(c.positiveTTL)
+	}
+	return entry
+}
+
+// save stores the entry loaded by load unless userID was invalidated since the load began
+func (c *userCache) save(userID string, load *userLoad, entry *cacheEntry) {
+	if !c.loads.current(load) {
+		return
+	}
+	c.store.set(userID, entry)
+	// An invalidation that ran while the entry was written must still win
+	if !c.loads.current(load) {
+		c.store.delete(userID)
+	}
+}
+
+// Invalidate drops any cached result for userID. A load that began before is still returned
+// to its callers but is not stored.
+func (c *userCache) Invalidate(userID string) error {
+	c.loads.forget(userID)
+	return c.store.delete(userID)
+}
+
+func (e *cacheEntry) expired(now time.Time) bool {
+	return !now.Before(e.ExpiresAt)
+}
+
+func (e *cacheEntry) profile() *UserProfile {
+	if !e.Found {
+		return nil
+	}
+	profile := e.Profile
+	return &profile
+}
+
+// userLoad is a running load of one user that concurrent lookups of the user wait for
+type userLoad struct {
+	done  chan struct{}
+	entry *cacheEntry
+	err   error
+	// forgotten is set, under the mutex of the group, when the user is invalidated
+	forgotten bool
+}
+
+// wait returns the result of the load, or gives up on it when ctx is done
+func (l *userLoad) wait(ctx context.Context) (*UserProfile, error) {
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %v", ErrUserServiceUnavailable, ctx.Err())
+	}
+	if l.err != nil {
+		return nil, l.err
+	}
+	return l.entry.profile(), nil
+}
+
+// loadGroup collapses concurrent loads of the same user by Lookup and LookupMany into one.
+// Unlike a singleflight group it lets a batch join the loads already running for some of
+// its users and load the others together.
+type loadGroup struct {
+	mutex sync.Mutex
+	loads map[string]*userLoad
+}
+
+// claim returns the loads of userIDs the caller must run and finish, and the ones already
+// running that it should wait for
+func (g *loadGroup) claim(userIDs []string) (own, running map[string]*userLoad) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	own = make(map[string]*userLoad)
+	running = make(map[string]*userLoad)
+	for _, userID := range userIDs {
+		if load, ok := g.loads[userID]; ok {
+			running[userID] = load
+			continue
+		}
+		load := &userLoad{done: make(chan struct{})}
+		g.loads[userID] = load
+		own[userID] = load
+	}
+	return own, running
+}
+
+// finish hands the result of a claimed load to its waiters
+func (g *loadGroup) finish(userID string, load *userLoad, entry *cacheEntry, err error) {
+	g.mutex.Lock()
+	if g.loads[userID] == load {
+		delete(g.loads, userID)
+	}
+	g.mutex.Unlock()
+
+	load.entry, load.err = entry, err
+	close(load.done)
+}
+
+// forget makes the next lookup of userID load it again rather than wait for a running load,
+// whose result must then not be stored
+func (g *loadGroup) forget(userID string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if load, ok := g.loads[userID]; ok {
+		load.forgotten = true
+		delete(g.loads, userID)
+	}
+}
+
+// current reports whether the user of load was not invalidated since load was claimed
+func (g *loadGroup) current(load *userLoad) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return !load.forgotten
+}
+
+// lruUserStore is a size bounded in-memory store evicting the least recently used entry
+type lruUserStore struct {
+	mutex   sync.Mutex
+	maxSize int
+	now     func() time.Time
+	order   *list.List
+	items   map[string]*list.Element
+}
+
+type lruItem struct {
+	userID string
+	entry  *cacheEntry
+}
+
+func newLRUUserStore(maxSize int, now func() time.Time) *lruUserStore {
+	return &lruUserStore{
+		maxSize: maxSize,
+		now:     now,
+		order:   list.New(),
+		items:   make(map[string]*list.Element),
+	}
+}
+
+func (s *lruUserStore) get(userID string) (*cacheEntry, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	element, ok := s.items[userID]
+	if !ok {
+		return nil, false
+	}
+	item := element.Value.(*lruItem)
+	if item.entry.expired(s.now()) {
+		s.order.Remove(element)
+		delete(s.items, userID)
+		return nil, false
+	}
+	s.order.MoveToFront(element)
+	return item.entry, true
+}
+
+func (s *lruUserStore) set(userID string, entry *cacheEntry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if element, ok := s.items[userID]; ok {
+		element.Value.(*lruItem).entry = entry
+		s.order.MoveToFront(element)
+		return
+	}
+	s.items[userID] = s.order.PushFront(&lruItem{userID: userID, entry: entry})
+	for s.maxSize > 0 && s.order.Len() > s.maxSize {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*lruItem).userID)
+	}
+}
+
+func (s *lruUserStore) delete(userID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if element, ok := s.items[userID]; ok {
+		s.order.Remove(element)
+		delete(s.items, userID)
+	}
+	return nil
+}
+
+// redisUserStore shares cached users across replicas. Redis errors degrade to cache misses.
+type redisUserStore struct {
+	client  *redis.Client
+	timeout time.Duration
+	now     func() time.Time
+	logger  *zap.SugaredLogger
+}
+
+func (s *redisUserStore) context() (context.Context, context.CancelFunc) {
+	if s.timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), s.timeout)
+}
+
+func (s *redisUserStore) get(userID string) (*cacheEntry, bool) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	raw, err := s.client.Get(ctx, redisUserKeyPrefix+userID).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			s.logger.Warnf("Failed to read user %s from redis cache: %v", userID, err)
+		}
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(raw, &entry); err != nil || entry.expired(s.now()) {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (s *redisUserStore) set(userID string, entry *cacheEntry) {
+	// Redis keeps keys without a TTL forever, so an entry already expired is not written
+	ttl := entry.ExpiresAt.Sub(s.now())
+	if ttl <= 0 {
+		return
+	}
+	ctx, cancel := s.context()
+	defer cancel()
+
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := s.client.Set(ctx, redisUserKeyPrefix+userID, raw, ttl).Err(); err != nil {
+		s.logger.Warnf("Failed to write user %s to redis cache: %v", userID, err)
+	}
+}
+
+func (s *redisUserStore) delete(userID string) error {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	return s.client.Del(ctx, redisUserKeyPrefix+userID).Err()
+}
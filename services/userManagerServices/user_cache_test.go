@@ -0,0 +1,406 @@
+package userManagerServices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"task-manager-app/utils"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	utils.Sugar = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
+
+// countingLoader knows the users in known and counts how often it is called per user
+type countingLoader struct {
+	known map[string]bool
+	mutex sync.Mutex
+	calls map[string]int
+}
+
+func newCountingLoader(known ...string) *countingLoader {
+	loader := &countingLoader{known: make(map[string]bool), calls: make(map[string]int)}
+	for _, userID := range known {
+		loader.known[userID] = true
+	}
+	return loader
+}
+
+func (l *countingLoader) load(_ context.Context, userID string) (*UserProfile, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.calls[userID]++
+	if !l.known[userID] {
+		return nil, nil
+	}
+	return &UserProfile{UserID: userID, Name: "name of " + userID}, nil
+}
+
+func (l *countingLoader) callsFor(userID string) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.calls[userID]
+}
+
+func TestLookupCachesKnownAndUnknownUsers(t *testing.T) {
+	cache := NewUserCache(UserCacheOptions{PositiveTTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 10})
+	loader := newCountingLoader("u1")
+
+	for i := 0; i < 3; i++ {
+		profile, err := cache.Lookup(context.Background(), "u1", loader.load)
+		if err != nil || profile == nil || profile.Name != "name of u1" {
+			t.Fatalf("Lookup(u1) = %+v, %v, want the profile", profile, err)
+		}
+		if profile, err := cache.Lookup(context.Background(), "missing", loader.load); err != nil || profile != nil {
+			t.Fatalf("Lookup(missing) = %+v, %v, want nil", profile, err)
+		}
+	}
+	if loader.callsFor("u1") != 1 || loader.callsFor("missing") != 1 {
+		t.Fatalf("loader calls = %v, want one per user", loader.calls)
+	}
+}
+
+func TestLookupExpiresEntriesAfterTheirTTL(t *testing.T) {
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	cache := NewUserCache(UserCacheOptions{PositiveTTL: time.Hour, NegativeTTL: time.Minute, MaxSize: 10, Clock: clock})
+	loader := newCountingLoader("u1")
+	cache.Lookup(context.Background(), "u1", loader.load)
+	cache.Lookup(context.Background(), "missing", loader.load)
+
+	now = now.Add(2 * time.Minute)
+	cache.Lookup(context.Background(), "u1", loader.load)
+	cache.Lookup(context.Background(), "missing", loader.load)
+
+	if got := loader.callsFor("u1"); got != 1 {
+		t.Errorf("u1 loaded %d times, want 1 within the positive TTL", got)
+	}
+	if got := loader.callsFor("missing"); got != 2 {
+		t.Errorf("missing loaded %d times, want 2 after the negative TTL", got)
+	}
+}
+
+func TestLookupDoesNotCacheErrors(t *testing.T) {
+	cache := NewUserCache(UserCacheOptions{PositiveTTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 10})
+	calls := 0
+	failing := func(_ context.Context, userID string) (*UserProfile, error) {
+		calls++
+		return nil, fmt.Errorf("user service down")
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Lookup(context.Background(), "u1", failing); err == nil {
+			t.Fatalf("Lookup returned no error, want the loader's")
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("loader called %d times, want every lookup to retry", calls)
+	}
+}
+
+func TestLRUEvictsLeastRecentlyUsedUser(t *testing.T) {
+	cache := NewUserCache(UserCacheOptions{PositiveTTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 2})
+	loader := newCountingLoader("u1", "u2", "u3")
+
+	cache.Lookup(context.Background(), "u1", loader.load)
+	cache.Lookup(context.Background(), "u2", loader.load)
+	// u1 is now more recent than u2, so u3 evicts u2
+	cache.Lookup(context.Background(), "u1", loader.load)
+	cache.Lookup(context.Background(), "u3", loader.load)
+	cache.Lookup(context.Background(), "u1", loader.load)
+	cache.Lookup(context.Background(), "u2", loader.load)
+
+	if got := loader.callsFor("u1"); got != 1 {
+		t.Errorf("u1 loaded %d times, want 1", got)
+	}
+	if got := loader.callsFor("u2"); got != 2 {
+		t.Errorf("u2 loaded %d times, want 2 after its eviction", got)
+	}
+}
+
+func TestLookupCollapsesConcurrentLoads(t *testing.T) {
+	cache := NewUserCache(UserCacheOptions{PositiveTTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 10})
+	var calls int32
+	release := make(chan struct{})
+	slow := func(_ context.Context, userID string) (*UserProfile, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return &UserProfile{UserID: userID}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Lookup(context.Background(), "u1", slow)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("loader called %d times, want 1 for concurrent lookups", calls)
+	}
+}
+
+func TestCancelledLookupLeavesSharedLoadRunning(t *testing.T) {
+	cache := NewUserCache(UserCacheOptions{PositiveTTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 10})
+	release := make(chan struct{})
+	started := make(chan struct{})
+	slow := func(ctx context.Context, userID string) (*UserProfile, error) {
+		close(started)
+		select {
+		case <-release:
+			return &UserProfile{UserID: userID}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// The lookup that starts the load gives up on it, another one keeps waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := cache.Lookup(ctx, "u1", slow)
+		first <- err
+	}()
+	<-started
+	second := make(chan *UserProfile, 1)
+	go func() {
+		profile, _ := cache.Lookup(context.Background(), "u1", slow)
+		second <- profile
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	if err := <-first; !errors.Is(err, ErrUserServiceUnavailable) {
+		t.Fatalf("cancelled Lookup returned %v, want ErrUserServiceUnavailable", err)
+	}
+	close(release)
+	if profile := <-second; profile == nil {
+		t.Fatal("second Lookup got no profile, want the load to outlive the cancelled lookup")
+	}
+}
+
+func TestInvalidateReloadsUser(t *testing.T) {
+	cache := NewUserCache(UserCacheOptions{PositiveTTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 10})
+	loader := newCountingLoader("u1")
+	cache.Lookup(context.Background(), "u1", loader.load)
+
+	if err := cache.Invalidate("u1"); err != nil {
+		t.Fatalf("Invalidate returned %v", err)
+	}
+	cache.Lookup(context.Background(), "u1", loader.load)
+
+	if got := loader.callsFor("u1"); got != 2 {
+		t.Fatalf("u1 loaded %d times, want a reload after Invalidate", got)
+	}
+}
+
+func TestUnreachableRedisFallsBackToLoader(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 50 * time.Millisecond})
+	defer client.Close()
+	cache := NewUserCache(UserCacheOptions{PositiveTTL: time.Hour, NegativeTTL: time.Hour, Redis: client, RedisTimeout: 100 * time.Millisecond})
+	loader := newCountingLoader("u1")
+
+	for i := 0; i < 2; i++ {
+		profile, err := cache.Lookup(context.Background(), "u1", loader.load)
+		if err != nil || profile == nil || profile.UserID != "u1" {
+			t.Fatalf("Lookup = %+v, %v, want the loaded profile despite redis being down", profile, err)
+		}
+	}
+	if got := loader.callsFor("u1"); got != 2 {
+		t.Fatalf("u1 loaded %d times, want every lookup to miss", got)
+	}
+	if err := cache.Invalidate("u1"); err == nil {
+		t.Fatalf("Invalidate returned no error, want the redis error so the admin call fails")
+	}
+}
+
+func TestLookupManyLoadsOnlyMissesInOneBatch(t *testing.T) {
+	cache := NewUserCache(UserCacheOptions{PositiveTTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 10})
+	loader := newCountingLoader("u1", "u2")
+	cache.Lookup(context.Background(), "u1", loader.load)
+
+	var batches [][]string
+	profiles, err := cache.LookupMany(context.Background(), []string{"u1", "u2", "missing"}, func(ctx context.Context, userIDs []string) (map[string]*UserProfile, error) {
+		batches = append(batches, userIDs)
+		loaded := make(map[string]*UserProfile)
+		for _, userID := range userIDs {
+			loaded[userID], _ = loader.load(ctx, userID)
+		}
+		return loaded, nil
+	})
+	if err != nil {
+		t.Fatalf("LookupMany returned %v", err)
+	}
+	if len(batches) != 1 || len(batches[0]) != 2 {
+		t.Fatalf("batches = %v, want one batch of the two misses", batches)
+	}
+	if profiles["u1"] == nil || profiles["u2"] == nil || profiles["missing"] != nil {
+		t.Fatalf("profiles = %v, want u1 and u2", profiles)
+	}
+	if _, err := cache.Lookup(context.Background(), "missing", loader.load); err != nil || loader.callsFor("missing") != 1 {
+		t.Fatalf("missing loaded %d times, want the unknown user cached by the batch", loader.callsFor("missing"))
+	}
+}
+
+func TestLookupManyJoinsRunningLoads(t *testing.T) {
+	cache := NewUserCache(UserCacheOptions{PositiveTTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 10})
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var singleCalls int32
+	slow := func(_ context.Context, userID string) (*UserProfile, error) {
+		atomic.AddInt32(&singleCalls, 1)
+		close(started)
+		<-release
+		return &UserProfile{UserID: userID}, nil
+	}
+	go cache.Lookup(context.Background(), "u1", slow)
+	<-started
+
+	var mutex sync.Mutex
+	loaded := make(map[string]int)
+	batch := func(_ context.Context, userIDs []string) (map[string]*UserProfile, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		profiles := make(map[string]*UserProfile)
+		for _, userID := range userIDs {
+			loaded[userID]++
+			profiles[userID] = &UserProfile{UserID: userID}
+		}
+		return profiles, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			profiles, err := cache.LookupMany(context.Background(), []string{"u1", "u2"}, batch)
+			if err != nil || profiles["u1"] == nil || profiles["u2"] == nil {
+				t.Errorf("LookupMany = %v, %v, want both users", profiles, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if singleCalls != 1 || loaded["u1"] != 0 {
+		t.Fatalf("u1 loaded %d times alone and %d times in batches, want only the running load", singleCalls, loaded["u1"])
+	}
+	if loaded["u2"] != 1 {
+		t.Fatalf("u2 loaded %d times, want once for the concurrent batches", loaded["u2"])
+	}
+}
+
+func TestLookupManyReportsFailedUsersToWaiters(t *testing.T) {
+	cache := NewUserCache(UserCacheOptions{PositiveTTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 10})
+	profiles, err := cache.LookupMany(context.Background(), []string{"u1", "u2"}, func(_ context.Context, userIDs []string) (map[string]*UserProfile, error) {
+		return map[string]*UserProfile{"u1": {UserID: "u1"}}, fmt.Errorf("lookup of u2 failed")
+	})
+	if err == nil || profiles["u1"] == nil {
+		t.Fatalf("LookupMany = %v, %v, want u1 and the error", profiles, err)
+	}
+	if _, ok := profiles["u2"]; ok {
+		t.Fatalf("profiles = %v, want the failed user left out", profiles)
+	}
+
+	loader := newCountingLoader("u2")
+	if profile, err := cache.Lookup(context.Background(), "u2", loader.load); err != nil || profile == nil {
+		t.Fatalf("Lookup(u2) = %v, %v, want the failure not to be cached", profile, err)
+	}
+}
+
+func TestInvalidateDuringLoadDoesNotStoreStaleResult(t *testing.T) {
+	cache := NewUserCache(UserCacheOptions{PositiveTTL: time.Hour, NegativeTTL: time.Hour, MaxSize: 10})
+	loader := newCountingLoader("u1")
+
+	profile, err := cache.Lookup(context.Background(), "u1", func(_ context.Context, userID string) (*UserProfile, error) {
+		// The admin invalidates the user while its old profile is being loaded
+		if err := cache.Invalidate(userID); err != nil {
+			t.Errorf("Invalidate returned %v", err)
+		}
+		return loader.load(context.Background(), userID)
+	})
+	if err != nil || profile == nil {
+		t.Fatalf("Lookup = %+v, %v, want the loaded profile", profile, err)
+	}
+
+	cache.Lookup(context.Background(), "u1", loader.load)
+	if got := loader.callsFor("u1"); got != 2 {
+		t.Fatalf("u1 loaded %d times, want the result loaded before Invalidate not to be cached", got)
+	}
+}
+
+// redisRecorder answers GET with stored values and records SET without a Redis server
+type redisRecorder struct {
+	mutex  sync.Mutex
+	values map[string]string
+	sets   []string
+}
+
+func (r *redisRecorder) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (r *redisRecorder) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		key := fmt.Sprint(cmd.Args()[1])
+		switch cmd := cmd.(type) {
+		case *redis.StringCmd:
+			value, ok := r.values[key]
+			if !ok {
+				cmd.SetErr(redis.Nil)
+				return redis.Nil
+			}
+			cmd.SetVal(value)
+		case *redis.StatusCmd:
+			r.sets = append(r.sets, key)
+			r.values[key] = string(cmd.Args()[2].([]byte))
+			cmd.SetVal("OK")
+		}
+		return nil
+	}
+}
+
+func (r *redisRecorder) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestRedisStoreNeverKeepsExpiredEntries(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	defer client.Close()
+	recorder := &redisRecorder{values: map[string]string{
+		redisUserKeyPrefix + "stale": `{"found":false,"expires_at":"2020-01-01T00:00:00Z"}`,
+	}}
+	client.AddHook(recorder)
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &redisUserStore{client: client, timeout: time.Second, now: func() time.Time { return now }, logger: utils.Sugar}
+
+	if _, ok := store.get("stale"); ok {
+		t.Error("get returned an entry past its expiry")
+	}
+	store.set("u1", &cacheEntry{ExpiresAt: now})
+	if len(recorder.sets) != 0 {
+		t.Errorf("set wrote %v, want nothing for an entry without TTL", recorder.sets)
+	}
+	store.set("u1", &cacheEntry{Found: true, ExpiresAt: now.Add(time.Minute)})
+	if entry, ok := store.get("u1"); !ok || !entry.Found {
+		t.Errorf("get = %+v, %v, want the entry written with a TTL", entry, ok)
+	}
+}
@@ -0,0 +1,46 @@
+package userManagerServices
+
+import (
+	"context"
+	"fmt"
+	"task-manager-app/constants"
+	"task-manager-app/network/userManager"
+)
+
+// Supported values for USER_PROVIDER
+const (
+	ProviderHTTP = constants.UserDirectoryHTTP
+	ProviderFile = constants.UserDirectoryFile
+	ProviderLDAP = constants.UserDirectoryLDAP
+)
+
+// UserProvider looks users up in a user directory. Unknown users are reported as nil
+// profiles; errors wrapping ErrUserServiceUnavailable mean the directory could not answer.
+type UserProvider interface {
+	GetUser(ctx context.Context, userID string) (*UserProfile, error)
+	// GetUsers maps every requested ID to its profile (nil when unknown). IDs whose lookup
+	// failed are left out and reported through the error.
+	GetUsers(ctx context.Context, userIDs []string) (map[string]*UserProfile, error)
+}
+
+// UserProviderOptions selects and configures the user directory
+type UserProviderOptions struct {
+	Type     string
+	Client   *userManager.UserServiceClient // used by the HTTP provider
+	FilePath string
+	LDAP     LDAPOptions
+}
+
+// NewUserProvider builds the provider selected by opts.Type, defaulting to the HTTP user service
+func NewUserProvider(opts UserProviderOptions) (UserProvider, error) {
+	switch opts.Type {
+	case "", ProviderHTTP:
+		return NewHTTPUserProvider(opts.Client), nil
+	case ProviderFile:
+		return NewFileUserProvider(opts.FilePath)
+	case ProviderLDAP:
+		return NewLDAPUserProvider(NewLDAPDirectory(opts.LDAP), opts.LDAP), nil
+	default:
+		return nil, fmt.Errorf("unknown user provider %q", opts.Type)
+	}
+}
@@ -1,34 +1,105 @@
 package userManagerServices
 
 import (
-	"fmt"
-	"task-manager-app/network/userManager"
+	"context"
+	"errors"
+	"task-manager-app/constants"
+	"task-manager-app/utils"
 )
 
+// ErrUserServiceUnavailable is returned when the user service cannot give a definitive answer
+var ErrUserServiceUnavailable = errors.New("user service is unavailable")
+
 type UserService interface {
-	ValidateUser(userID string) (bool, error)
+	ValidateUser(ctx context.Context, userID string) (bool, error)
+	ValidateUsers(ctx context.Context, userIDs []string) (map[string]bool, error)
+	GetUsers(ctx context.Context, userIDs []string) (map[string]*UserProfile, error)
 }
 
 type userService struct {
-	userClient *userManager.UserServiceClient
+	provider      UserProvider
+	failurePolicy string
+	cache         UserCache
 }
 
-func NewUserService() UserService {
+// NewUserService creates a user service on top of a user directory. failurePolicy decides
+// what happens when the directory is down: constants.FailurePolicyOpen accepts the user,
+// anything else rejects the request with ErrUserServiceUnavailable. cache may be nil to
+// disable caching.
+func NewUserService(provider UserProvider, failurePolicy string, cache UserCache) UserService {
 	return &userService{
-		userClient: userManager.UserClient,
+		provider:      provider,
+		failurePolicy: failurePolicy,
+		cache:         cache,
 	}
 }
 
 // ValidateUser validates if a user ID exists in the user service
-func (s *userService) ValidateUser(userID string) (bool, error) {
-	if s.userClient == nil {
-		return false, fmt.Errorf("user service client not initialized")
+func (s *userService) ValidateUser(ctx context.Context, userID string) (bool, error) {
+	profile, err := s.lookupUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrUserServiceUnavailable) && s.failurePolicy == constants.FailurePolicyOpen {
+			utils.Logger(ctx).Warnf("Accepting user ID %s without validation: %v", userID, err)
+			return true, nil
+		}
+		return false, err
+	}
+
+	return profile != nil, nil
+}
+
+// ValidateUsers validates many users with a single batch lookup. The result maps every
+// user ID to whether it exists; the failure policy applies as in ValidateUser.
+func (s *userService) ValidateUsers(ctx context.Context, userIDs []string) (map[string]bool, error) {
+	profiles, err := s.lookupUsers(ctx, userIDs)
+	if err != nil {
+		if !errors.Is(err, ErrUserServiceUnavailable) || s.failurePolicy != constants.FailurePolicyOpen {
+			return nil, err
+		}
+		utils.Logger(ctx).Warnf("Accepting user IDs without validation: %v", err)
 	}
 
-	exists, clientErr := s.userClient.CheckUserExists(userID)
-	if clientErr != nil {
-		return false, fmt.Errorf("failed to validate user ID: %s", clientErr.Message)
+	results := make(map[string]bool, len(userIDs))
+	for _, userID := range userIDs {
+		profile, ok := profiles[userID]
+		// Users that could not be looked up are only present here under the fail-open policy
+		results[userID] = profile != nil || !ok
 	}
+	return results, nil
+}
 
-	return exists, nil
+// GetUsers returns the profiles of the given users keyed by user ID. Unknown users are
+// absent from the map. Lookups that fail are skipped and reported through the error, so
+// callers can still use the profiles that were found.
+func (s *userService) GetUsers(ctx context.Context, userIDs []string) (map[string]*UserProfile, error) {
+	profiles, err := s.lookupUsers(ctx, userIDs)
+	for userID, profile := range profiles {
+		if profile == nil {
+			delete(profiles, userID)
+		}
+	}
+	return profiles, err
+}
+
+func (s *userService) lookupUsers(ctx context.Context, userIDs []string) (map[string]*UserProfile, error) {
+	unique := make([]string, 0, len(userIDs))
+	seen := make(map[string]bool, len(userIDs))
+	for _, userID := range userIDs {
+		if userID != "" && !seen[userID] {
+			seen[userID] = true
+			unique = append(unique, userID)
+		}
+	}
+
+	if s.cache == nil {
+		return s.provider.GetUsers(ctx, unique)
+	}
+	return s.cache.LookupMany(ctx, unique, s.provider.GetUsers)
+}
+
+func (s *userService) lookupUser(ctx context.Context, userID string) (*UserProfile, error) {
+	if s.cache == nil {
+		return s.provider.GetUser(ctx, userID)
+	}
+	return s.cache.Lookup(ctx, userID, s.provider.GetUser)
 }
@@ -1,7 +1,10 @@
 package validationService
 
 import (
+	"context"
+	stdErrors "errors"
 	"fmt"
+	"strings"
 	"task-manager-app/constants"
 	"task-manager-app/constants/enums"
 	"task-manager-app/exceptions"
@@ -9,37 +12,42 @@ import (
 	"task-manager-app/repo"
 	"task-manager-app/request"
 	"task-manager-app/services/userManagerServices"
+	"task-manager-app/utils"
 )
 
 type ValidationService interface {
-	ValidateCreateTaskRequest(req *request.ReqCreateOrUpdateTasks) *errors.TaskManagerError
-	ValidateUpdateTaskRequest(req *request.ReqCreateOrUpdateTasks) *errors.TaskManagerError
-	ValidateUserID(userID string) *errors.TaskManagerError
+	ValidateCreateTaskRequest(ctx context.Context, req *request.ReqCreateOrUpdateTasks) *errors.TaskManagerError
+	ValidateUpdateTaskRequest(ctx context.Context, req *request.ReqCreateOrUpdateTasks) *errors.TaskManagerError
+	ValidateUserID(ctx context.Context, userID string) *errors.TaskManagerError
+	ValidateUserIDs(ctx context.Context, userIDs []string) *errors.TaskManagerError
 	ValidateTaskStatus(status string) *errors.TaskManagerError
 	ValidateTaskPriority(priority string) *errors.TaskManagerError
 	ValidateTaskTitle(title *string) *errors.TaskManagerError
-	CheckTaskDuplicateByTitle(title, userID string) *errors.TaskManagerError
+	CheckTaskDuplicateByTitle(ctx context.Context, tasks repo.TaskRepository, title, userID, excludeUUID string) *errors.TaskManagerError
+	TitleKey(title string) string
 }
 
 type validationService struct {
-	userService userManagerServices.UserService
-	taskRepo    repo.TaskRepository
+	userService     userManagerServices.UserService
+	taskRepo        repo.TaskRepository
+	titleNormalizer utils.TitleNormalizer
 }
 
-func NewValidationService(userService userManagerServices.UserService, taskRepo repo.TaskRepository) ValidationService {
+func NewValidationService(userService userManagerServices.UserService, taskRepo repo.TaskRepository, titleNormalizer utils.TitleNormalizer) ValidationService {
 	return &validationService{
-		userService: userService,
-		taskRepo:    taskRepo,
+		userService:     userService,
+		taskRepo:        taskRepo,
+		titleNormalizer: titleNormalizer,
 	}
 }
 
-func (v *validationService) ValidateCreateTaskRequest(req *request.ReqCreateOrUpdateTasks) *errors.TaskManagerError {
-	if err := v.validateCommonFields(req, true); err != nil {
+func (v *validationService) ValidateCreateTaskRequest(ctx context.Context, req *request.ReqCreateOrUpdateTasks) *errors.TaskManagerError {
+	if err := v.validateCommonFields(ctx, req, true); err != nil {
 		return err
 	}
 
 	if req.UserID != nil && *req.UserID != "" {
-		if err := v.CheckTaskDuplicateByTitle(*req.Title, *req.UserID); err != nil {
+		if err := v.CheckTaskDuplicateByTitle(ctx, v.taskRepo, *req.Title, *req.UserID, ""); err != nil {
 			return err
 		}
 	}
@@ -47,11 +55,11 @@ func (v *validationService) ValidateCreateTaskRequest(req *request.ReqCreateOrUp
 	return nil
 }
 
-func (v *validationService) ValidateUpdateTaskRequest(req *request.ReqCreateOrUpdateTasks) *errors.TaskManagerError {
-	return v.validateCommonFields(req, false)
+func (v *validationService) ValidateUpdateTaskRequest(ctx context.Context, req *request.ReqCreateOrUpdateTasks) *errors.TaskManagerError {
+	return v.validateCommonFields(ctx, req, false)
 }
 
-func (v *validationService) validateCommonFields(req *request.ReqCreateOrUpdateTasks, isCreate bool) *errors.TaskManagerError {
+func (v *validationService) validateCommonFields(ctx context.Context, req *request.ReqCreateOrUpdateTasks, isCreate bool) *errors.TaskManagerError {
 	if isCreate || req.Title != nil {
 		if err := v.ValidateTaskTitle(req.Title); err != nil {
 			return err
@@ -71,7 +79,7 @@ func (v *validationService) validateCommonFields(req *request.ReqCreateOrUpdateT
 	}
 
 	if req.UserID != nil && *req.UserID != "" {
-		if err := v.ValidateUserID(*req.UserID); err != nil {
+		if err := v.ValidateUserID(ctx, *req.UserID); err != nil {
 			return err
 		}
 	}
@@ -100,9 +108,12 @@ func (v *validationService) ValidateTaskPriority(priority string) *errors.TaskMa
 	return nil
 }
 
-func (v *validationService) ValidateUserID(userID string) *errors.TaskManagerError {
-	valid, err := v.userService.ValidateUser(userID)
+func (v *validationService) ValidateUserID(ctx context.Context, userID string) *errors.TaskManagerError {
+	valid, err := v.userService.ValidateUser(ctx, userID)
 	if err != nil {
+		if stdErrors.Is(err, userManagerServices.ErrUserServiceUnavailable) {
+			return exceptions.ServiceUnavailableException(constants.ErrUserServiceUnavailable)
+		}
 		return exceptions.InternalServerException(fmt.Sprintf("Failed to validate user: %v", err))
 	}
 	if !valid {
@@ -111,13 +122,47 @@ func (v *validationService) ValidateUserID(userID string) *errors.TaskManagerErr
 	return nil
 }
 
-func (v *validationService) CheckTaskDuplicateByTitle(title, userID string) *errors.TaskManagerError {
-	exists, err := v.taskRepo.ExistsByTitleAndUser(title, userID)
+// ValidateUserIDs validates several users with one batch call to the user service and
+// reports every unknown user in the error message
+func (v *validationService) ValidateUserIDs(ctx context.Context, userIDs []string) *errors.TaskManagerError {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	results, err := v.userService.ValidateUsers(ctx, userIDs)
+	if err != nil {
+		if stdErrors.Is(err, userManagerServices.ErrUserServiceUnavailable) {
+			return exceptions.ServiceUnavailableException(constants.ErrUserServiceUnavailable)
+		}
+		return exceptions.InternalServerException(fmt.Sprintf("Failed to validate users: %v", err))
+	}
+
+	var unknown []string
+	for _, userID := range userIDs {
+		if !results[userID] {
+			unknown = append(unknown, userID)
+		}
+	}
+	if len(unknown) > 0 {
+		return exceptions.NotFoundException(constants.ErrUserNotFound + ": " + strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// CheckTaskDuplicateByTitle fails with 409 when another task of the user already has the
+// title. The database unique index is the real guarantee; this only reports early. tasks is
+// the repository to query, the one of the transaction when called inside one.
+func (v *validationService) CheckTaskDuplicateByTitle(ctx context.Context, tasks repo.TaskRepository, title, userID, excludeUUID string) *errors.TaskManagerError {
+	exists, err := tasks.WithContext(ctx).ExistsByTitleAndUser(v.TitleKey(title), userID, excludeUUID)
 	if err != nil {
 		return err
 	}
 	if exists {
-		return exceptions.NewBadRequestException(constants.ErrTaskAlreadyExists)
+		return exceptions.ConflictException(constants.ErrTaskAlreadyExists)
 	}
 	return nil
 }
+
+// TitleKey returns the normalised title used for the per-user uniqueness rule
+func (v *validationService) TitleKey(title string) string {
+	return v.titleNormalizer.Normalize(title)
+}
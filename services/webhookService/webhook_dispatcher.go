@@ -0,0 +1,247 @@
+package webhookService
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"task-manager-app/constants"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/metrics"
+	"task-manager-app/models"
+	"task-manager-app/repo"
+	"task-manager-app/utils"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxErrorBody caps how much of a failed response body is kept in the delivery log
+const maxErrorBody = 512
+
+// DispatcherOptions configures the webhook dispatcher
+type DispatcherOptions struct {
+	PollInterval time.Duration
+	Concurrency  int
+	Timeout      time.Duration
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	MaxAttempts  int
+	DisableAfter int
+	Retention    time.Duration
+	// Logger receives the dispatcher logs, utils.Sugar when nil
+	Logger *zap.SugaredLogger
+	// Clock stamps attempts, retries and leases, time.Now when nil
+	Clock func() time.Time
+}
+
+// WebhookDispatcher POSTs pending deliveries to their subscriptions. A delivery that fails is
+// retried with exponential backoff and marked failed after MaxAttempts; a subscription whose
+// last DisableAfter attempts all failed is disabled. Deliveries are leased while they are
+// sent, so replicas can dispatch side by side.
+type WebhookDispatcher struct {
+	repo    repo.WebhookRepository
+	client  *http.Client
+	options DispatcherOptions
+	now     func() time.Time
+
+	stop      chan struct{}
+	done      chan struct{}
+	lastPurge time.Time
+}
+
+func NewWebhookDispatcher(repository repo.WebhookRepository, options DispatcherOptions) *WebhookDispatcher {
+	if options.Logger == nil {
+		options.Logger = utils.Sugar
+	}
+	if options.Clock == nil {
+		options.Clock = time.Now
+	}
+	return &WebhookDispatcher{
+		repo: repository,
+		client: &http.Client{
+			Timeout: options.Timeout,
+			// A redirect is reported as a failure rather than followed with the signed body
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		options: options,
+		now:     options.Clock,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start runs the dispatcher in the background until Stop is called
+func (d *WebhookDispatcher) Start() {
+	go d.run()
+}
+
+// Stop asks the dispatcher to finish the deliveries it is sending and waits for it or for ctx
+// to expire
+func (d *WebhookDispatcher) Stop(ctx context.Context) error {
+	close(d.stop)
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *WebhookDispatcher) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(d.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			d.DispatchOnce()
+		}
+	}
+}
+
+// DispatchOnce sends one batch of due deliveries in parallel and waits for them
+func (d *WebhookDispatcher) DispatchOnce() {
+	now := d.now()
+	// The lease outlasts the request timeout, so a delivery is not claimed twice while it is sent
+	batch, taskErr := d.repo.ClaimDue(d.options.Concurrency, now, now.Add(d.options.Timeout+time.Minute))
+	if taskErr != nil {
+		d.options.Logger.Errorf("Webhook dispatcher failed to claim deliveries: %s", taskErr.Message)
+		return
+	}
+
+	if len(batch) > 0 {
+		subscriptions, taskErr := d.subscriptionsOf(batch)
+		if taskErr != nil {
+			d.options.Logger.Errorf("Webhook dispatcher failed to load subscriptions: %s", taskErr.Message)
+			return
+		}
+
+		var wg sync.WaitGroup
+		for i := range batch {
+			subscription, ok := subscriptions[batch[i].SubscriptionID]
+			if !ok {
+				continue
+			}
+			wg.Add(1)
+			go func(delivery *models.WebhookDelivery) {
+				defer wg.Done()
+				d.dispatch(subscription, delivery)
+			}(&batch[i])
+		}
+		wg.Wait()
+	}
+	d.purgeFinished()
+}
+
+func (d *WebhookDispatcher) subscriptionsOf(batch []models.WebhookDelivery) (map[uint]*models.WebhookSubscription, *errors.TaskManagerError) {
+	seen := make(map[uint]bool)
+	var ids []uint
+	for _, delivery := range batch {
+		if !seen[delivery.SubscriptionID] {
+			seen[delivery.SubscriptionID] = true
+			ids = append(ids, delivery.SubscriptionID)
+		}
+	}
+	list, taskErr := d.repo.ListSubscriptionsByID(ids)
+	if taskErr != nil {
+		return nil, taskErr
+	}
+	subscriptions := make(map[uint]*models.WebhookSubscription, len(list))
+	for i := range list {
+		subscriptions[list[i].ID] = &list[i]
+	}
+	return subscriptions, nil
+}
+
+func (d *WebhookDispatcher) dispatch(subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) {
+	statusCode, err := d.post(subscription, delivery)
+	if err == nil {
+		if taskErr := d.repo.RecordSuccess(delivery, statusCode, d.now()); taskErr != nil {
+			d.options.Logger.Errorf("Failed to record webhook delivery %d: %s", delivery.ID, taskErr.Message)
+			return
+		}
+		metrics.CountWebhookDelivery(metrics.ResultDelivered)
+		return
+	}
+
+	metrics.CountWebhookDelivery(metrics.ResultFailed)
+	attempts := delivery.Attempts + 1
+	var nextAttemptAt *time.Time
+	if attempts < d.options.MaxAttempts {
+		retryAt := d.now().Add(utils.ExponentialBackoff(d.options.BaseBackoff, d.options.MaxBackoff, attempts))
+		nextAttemptAt = &retryAt
+		d.options.Logger.Warnf("Failed to deliver webhook %d (%s) to %s, attempt %d: %v", delivery.ID, delivery.EventType, subscription.URL, attempts, err)
+	} else {
+		metrics.CountWebhookDelivery(metrics.ResultGivenUp)
+		d.options.Logger.Errorf("Giving up webhook %d (%s) to %s after %d attempts: %v", delivery.ID, delivery.EventType, subscription.URL, attempts, err)
+	}
+
+	disabled, taskErr := d.repo.RecordFailure(delivery, statusCode, err.Error(), nextAttemptAt, d.options.DisableAfter)
+	if taskErr != nil {
+		d.options.Logger.Errorf("Failed to record webhook delivery %d: %s", delivery.ID, taskErr.Message)
+		return
+	}
+	if disabled {
+		metrics.CountWebhookDisabled()
+		d.options.Logger.Errorf("Disabled webhook %s to %s after %d consecutive failures", subscription.UUID, subscription.URL, d.options.DisableAfter)
+	}
+}
+
+// post sends the delivery and returns the response status, and an error unless it is 2xx
+func (d *WebhookDispatcher) post(subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	timestamp := d.now().Unix()
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(constants.HeaderWebhookID, strconv.FormatUint(delivery.ID, 10))
+	req.Header.Set(constants.HeaderWebhookEvent, delivery.EventType)
+	req.Header.Set(constants.HeaderWebhookTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(constants.HeaderWebhookSignature, Sign(subscription.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("%s: %s", resp.Status, body)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *WebhookDispatcher) purgeFinished() {
+	now := d.now()
+	if d.options.Retention <= 0 || now.Sub(d.lastPurge) < time.Hour {
+		return
+	}
+	d.lastPurge = now
+	if taskErr := d.repo.DeleteFinished(now.Add(-d.options.Retention)); taskErr != nil {
+		d.options.Logger.Errorf("Failed to purge finished webhook deliveries: %s", taskErr.Message)
+	}
+}
+
+// Sign returns the X-Webhook-Signature of body sent at timestamp: "sha256=" followed by the
+// hex HMAC-SHA256, keyed with secret, of the timestamp, a dot and the body. Receivers should
+// recompute it and reject requests with an old timestamp to prevent replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
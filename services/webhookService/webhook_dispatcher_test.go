@@ -0,0 +1,169 @@
+package webhookService
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"task-manager-app/constants"
+	"task-manager-app/models"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef"
+
+// receiver records the webhooks it is sent and answers with status
+type receiver struct {
+	mutex    sync.Mutex
+	status   int
+	received []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.received = append(r.received, req)
+	r.bodies = append(r.bodies, body)
+	w.WriteHeader(r.status)
+}
+
+func (r *receiver) setStatus(status int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.status = status
+}
+
+// newDispatcherFixture serves a subscription with a receiver and queues one delivery to it
+func newDispatcherFixture(t *testing.T, options DispatcherOptions) (*memoryWebhooks, *receiver, *WebhookDispatcher, *time.Time) {
+	target := &receiver{status: http.StatusOK}
+	server := httptest.NewServer(target)
+	t.Cleanup(server.Close)
+
+	store := &memoryWebhooks{}
+	store.CreateSubscription(&models.WebhookSubscription{URL: server.URL, Secret: testSecret, Active: true})
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.CreateDelivery(&models.WebhookDelivery{SubscriptionID: 1, EventID: "e1", EventType: "TaskCreated", Payload: []byte(`{"event_id":"e1"}`), Status: models.WebhookDeliveryPending, NextAttemptAt: now})
+
+	options.Concurrency = 10
+	options.Timeout = time.Second
+	dispatcher := NewWebhookDispatcher(store, options)
+	dispatcher.now = func() time.Time { return now }
+	return store, target, dispatcher, &now
+}
+
+func TestDispatcherSendsSignedDeliveries(t *testing.T) {
+	store, target, dispatcher, now := newDispatcherFixture(t, DispatcherOptions{MaxAttempts: 3})
+
+	dispatcher.DispatchOnce()
+
+	if len(target.received) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(target.received))
+	}
+	req := target.received[0]
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	if req.Header.Get(constants.HeaderWebhookTimestamp) != timestamp || req.Header.Get(constants.HeaderWebhookEvent) != "TaskCreated" || req.Header.Get(constants.HeaderWebhookID) != "1" {
+		t.Errorf("headers = %v", req.Header)
+	}
+	if got, want := req.Header.Get(constants.HeaderWebhookSignature), Sign(testSecret, now.Unix(), target.bodies[0]); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if Sign(testSecret, now.Unix()+1, target.bodies[0]) == Sign(testSecret, now.Unix(), target.bodies[0]) {
+		t.Error("signature does not cover the timestamp")
+	}
+
+	delivery := store.storedDelivery(1)
+	if delivery.Status != models.WebhookDeliveryDelivered || delivery.Attempts != 1 || delivery.LastStatusCode != http.StatusOK {
+		t.Errorf("delivery = %+v, want it delivered on the first attempt", delivery)
+	}
+
+	dispatcher.DispatchOnce()
+	if len(target.received) != 1 {
+		t.Errorf("delivered webhook was sent again")
+	}
+}
+
+func TestDispatcherRetriesWithBackoffAndGivesUp(t *testing.T) {
+	store, target, dispatcher, now := newDispatcherFixture(t, DispatcherOptions{
+		BaseBackoff: time.Second,
+		MaxBackoff:  time.Minute,
+		MaxAttempts: 3,
+	})
+	target.setStatus(http.StatusServiceUnavailable)
+
+	dispatcher.DispatchOnce()
+	delivery := store.storedDelivery(1)
+	if delivery.Status != models.WebhookDeliveryPending || delivery.Attempts != 1 || delivery.LastStatusCode != http.StatusServiceUnavailable || !delivery.NextAttemptAt.After(*now) {
+		t.Fatalf("delivery = %+v, want a pending retry after a 503", delivery)
+	}
+
+	// Not due yet
+	dispatcher.DispatchOnce()
+	if len(target.received) != 1 {
+		t.Fatalf("retry was sent before it was due")
+	}
+
+	for i := 0; i < 2; i++ {
+		*now = store.storedDelivery(1).NextAttemptAt
+		dispatcher.DispatchOnce()
+	}
+	delivery = store.storedDelivery(1)
+	if delivery.Status != models.WebhookDeliveryFailed || delivery.Attempts != 3 || delivery.LastError == "" {
+		t.Errorf("delivery = %+v, want it failed after 3 attempts", delivery)
+	}
+}
+
+func TestDispatcherDisablesFailingSubscriptions(t *testing.T) {
+	store, target, dispatcher, now := newDispatcherFixture(t, DispatcherOptions{
+		BaseBackoff:  time.Second,
+		MaxBackoff:   time.Second,
+		MaxAttempts:  10,
+		DisableAfter: 2,
+	})
+	target.setStatus(http.StatusInternalServerError)
+
+	dispatcher.DispatchOnce()
+	*now = now.Add(time.Second)
+	dispatcher.DispatchOnce()
+
+	subscription := store.storedSubscription(1)
+	if subscription.Active || subscription.DisabledReason == "" {
+		t.Fatalf("subscription = %+v, want it disabled after 2 failures", subscription)
+	}
+
+	// Deliveries of a disabled subscription wait until it is enabled again
+	*now = now.Add(time.Hour)
+	dispatcher.DispatchOnce()
+	if len(target.received) != 2 {
+		t.Errorf("disabled subscription received %d requests, want 2", len(target.received))
+	}
+	if delivery := store.storedDelivery(1); delivery.Status != models.WebhookDeliveryPending {
+		t.Errorf("delivery status = %q, want it kept pending", delivery.Status)
+	}
+}
+
+func TestDispatcherDoesNotFollowRedirects(t *testing.T) {
+	redirected := false
+	elsewhere := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		redirected = true
+	}))
+	defer elsewhere.Close()
+
+	store, _, dispatcher, _ := newDispatcherFixture(t, DispatcherOptions{BaseBackoff: time.Second, MaxBackoff: time.Second, MaxAttempts: 3})
+	redirector := httptest.NewServer(http.RedirectHandler(elsewhere.URL, http.StatusTemporaryRedirect))
+	defer redirector.Close()
+	store.subscriptions[0].URL = redirector.URL
+
+	dispatcher.DispatchOnce()
+
+	if redirected {
+		t.Error("dispatcher followed the redirect")
+	}
+	if delivery := store.storedDelivery(1); delivery.LastStatusCode != http.StatusTemporaryRedirect || delivery.Status != models.WebhookDeliveryPending {
+		t.Errorf("delivery = %+v, want a failed attempt with the redirect status", delivery)
+	}
+}
@@ -0,0 +1,67 @@
+package webhookService
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"task-manager-app/events"
+	"task-manager-app/models"
+	"task-manager-app/repo"
+	"time"
+)
+
+type webhookPublisher struct {
+	repo repo.WebhookRepository
+	now  func() time.Time
+}
+
+// NewWebhookPublisher queues a delivery of every event to each active subscription that wants
+// it; the WebhookDispatcher sends them. An event queued before for a subscription is skipped,
+// so the outbox relay may publish it again.
+func NewWebhookPublisher(repository repo.WebhookRepository, now func() time.Time) events.Publisher {
+	return &webhookPublisher{repo: repository, now: now}
+}
+
+func (p *webhookPublisher) Publish(ctx context.Context, taskEvents ...*events.TaskEvent) error {
+	subscriptions, taskErr := p.repo.ListActiveSubscriptions()
+	if taskErr != nil {
+		return fmt.Errorf("%s", taskErr.Message)
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	now := p.now()
+	var deliveries []models.WebhookDelivery
+	for _, event := range taskEvents {
+		var payload []byte
+		for i := range subscriptions {
+			if !Matches(&subscriptions[i], event) {
+				continue
+			}
+			if payload == nil {
+				var err error
+				if payload, err = json.Marshal(event); err != nil {
+					return err
+				}
+			}
+			deliveries = append(deliveries, models.WebhookDelivery{
+				SubscriptionID: subscriptions[i].ID,
+				EventID:        event.EventID,
+				EventType:      event.EventType,
+				Payload:        payload,
+				Status:         models.WebhookDeliveryPending,
+				NextAttemptAt:  now,
+			})
+		}
+	}
+
+	if taskErr := p.repo.AddDeliveries(deliveries); taskErr != nil {
+		return fmt.Errorf("%s", taskErr.Message)
+	}
+	return nil
+}
+
+func (p *webhookPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,335 @@
+package webhookService
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"task-manager-app/constants"
+	"task-manager-app/constants/enums"
+	"task-manager-app/events"
+	"task-manager-app/exceptions"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/models"
+	"task-manager-app/repo"
+	"task-manager-app/request"
+	"task-manager-app/response"
+	"time"
+)
+
+// Keys accepted in webhook filters
+const (
+	FilterUserID   = "user_id"
+	FilterStatus   = "status"
+	FilterPriority = "priority"
+)
+
+type WebhookService interface {
+	CreateWebhook(req *request.ReqCreateOrUpdateWebhook) (*response.WebhookResponse, *errors.TaskManagerError)
+	GetWebhook(uuid string) (*response.WebhookResponse, *errors.TaskManagerError)
+	ListWebhooks() (*response.WebhookListResponse, *errors.TaskManagerError)
+	UpdateWebhook(uuid string, req *request.ReqCreateOrUpdateWebhook) (*response.WebhookResponse, *errors.TaskManagerError)
+	DeleteWebhook(uuid string) *errors.TaskManagerError
+	ListDeliveries(uuid string, status string, page, pageSize int) (*response.WebhookDeliveryListResponse, *errors.TaskManagerError)
+	// Redeliver queues the event of a logged delivery again as a new delivery
+	Redeliver(uuid string, deliveryID uint64) (*response.WebhookDeliveryResponse, *errors.TaskManagerError)
+}
+
+type webhookService struct {
+	repo repo.WebhookRepository
+	now  func() time.Time
+}
+
+func NewWebhookService(repository repo.WebhookRepository, now func() time.Time) WebhookService {
+	return &webhookService{repo: repository, now: now}
+}
+
+func (s *webhookService) CreateWebhook(req *request.ReqCreateOrUpdateWebhook) (*response.WebhookResponse, *errors.TaskManagerError) {
+	if req.URL == nil {
+		return nil, exceptions.NewBadRequestException(constants.ErrInvalidWebhookURL)
+	}
+
+	subscription := &models.WebhookSubscription{
+		EventTypes: []string{},
+		Filters:    map[string]string{},
+		Active:     true,
+	}
+	secretGiven := req.Secret != nil
+	if req.Secret == nil {
+		secret, err := newSecret()
+		if err != nil {
+			return nil, exceptions.InternalServerException(constants.ErrFailedToSaveWebhook + ": " + err.Error())
+		}
+		req.Secret = &secret
+	}
+	if taskErr := applyWebhookUpdates(subscription, req); taskErr != nil {
+		return nil, taskErr
+	}
+
+	if taskErr := s.repo.CreateSubscription(subscription); taskErr != nil {
+		return nil, taskErr
+	}
+	resp := toWebhookResponse(subscription)
+	// The caller needs a generated secret to verify signatures; a given one is not echoed
+	if !secretGiven {
+		resp.Secret = subscription.Secret
+	}
+	return resp, nil
+}
+
+func (s *webhookService) GetWebhook(uuid string) (*response.WebhookResponse, *errors.TaskManagerError) {
+	subscription, taskErr := s.getSubscription(uuid)
+	if taskErr != nil {
+		return nil, taskErr
+	}
+	return toWebhookResponse(subscription), nil
+}
+
+func (s *webhookService) ListWebhooks() (*response.WebhookListResponse, *errors.TaskManagerError) {
+	subscriptions, taskErr := s.repo.ListSubscriptions()
+	if taskErr != nil {
+		return nil, taskErr
+	}
+	webhooks := make([]response.WebhookResponse, len(subscriptions))
+	for i := range subscriptions {
+		webhooks[i] = *toWebhookResponse(&subscriptions[i])
+	}
+	return &response.WebhookListResponse{Webhooks: webhooks, Count: len(webhooks)}, nil
+}
+
+// UpdateWebhook changes the given fields. Re-activating a disabled subscription clears its
+// failure count, and its pending deliveries are sent again.
+func (s *webhookService) UpdateWebhook(uuid string, req *request.ReqCreateOrUpdateWebhook) (*response.WebhookResponse, *errors.TaskManagerError) {
+	subscription, taskErr := s.getSubscription(uuid)
+	if taskErr != nil {
+		return nil, taskErr
+	}
+
+	wasActive := subscription.Active
+	if taskErr := applyWebhookUpdates(subscription, req); taskErr != nil {
+		return nil, taskErr
+	}
+	if subscription.Active && !wasActive {
+		subscription.ConsecutiveFailures = 0
+		subscription.DisabledReason = ""
+	}
+
+	if taskErr := s.repo.UpdateSubscription(subscription); taskErr != nil {
+		return nil, taskErr
+	}
+	return toWebhookResponse(subscription), nil
+}
+
+func (s *webhookService) DeleteWebhook(uuid string) *errors.TaskManagerError {
+	subscription, taskErr := s.getSubscription(uuid)
+	if taskErr != nil {
+		return taskErr
+	}
+	return s.repo.DeleteSubscription(subscription.ID)
+}
+
+func (s *webhookService) ListDeliveries(uuid string, status string, page, pageSize int) (*response.WebhookDeliveryListResponse, *errors.TaskManagerError) {
+	subscription, taskErr := s.getSubscription(uuid)
+	if taskErr != nil {
+		return nil, taskErr
+	}
+	switch status {
+	case "", models.WebhookDeliveryPending, models.WebhookDeliveryDelivered, models.WebhookDeliveryFailed:
+	default:
+		return nil, exceptions.NewBadRequestException(constants.ErrInvalidDeliveryStatus)
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = constants.DefaultPageSize
+	}
+
+	deliveries, taskErr := s.repo.ListDeliveries(subscription.ID, status, pageSize, (page-1)*pageSize)
+	if taskErr != nil {
+		return nil, taskErr
+	}
+	log := make([]response.WebhookDeliveryResponse, len(deliveries))
+	for i := range deliveries {
+		log[i] = *toDeliveryResponse(&deliveries[i])
+	}
+	return &response.WebhookDeliveryListResponse{
+		Deliveries: log,
+		Page:       page,
+		PageSize:   pageSize,
+		Count:      len(log),
+	}, nil
+}
+
+func (s *webhookService) Redeliver(uuid string, deliveryID uint64) (*response.WebhookDeliveryResponse, *errors.TaskManagerError) {
+	subscription, taskErr := s.getSubscription(uuid)
+	if taskErr != nil {
+		return nil, taskErr
+	}
+	original, taskErr := s.repo.GetDelivery(subscription.ID, deliveryID)
+	if taskErr != nil {
+		return nil, taskErr
+	}
+	if original == nil {
+		return nil, exceptions.NotFoundException(constants.ErrDeliveryNotFound)
+	}
+
+	delivery := &models.WebhookDelivery{
+		SubscriptionID: subscription.ID,
+		EventID:        original.EventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		Status:         models.WebhookDeliveryPending,
+		NextAttemptAt:  s.now(),
+		RedeliveryOf:   &original.ID,
+	}
+	if taskErr := s.repo.CreateDelivery(delivery); taskErr != nil {
+		return nil, taskErr
+	}
+	return toDeliveryResponse(delivery), nil
+}
+
+func (s *webhookService) getSubscription(uuid string) (*models.WebhookSubscription, *errors.TaskManagerError) {
+	subscription, taskErr := s.repo.GetSubscription(uuid)
+	if taskErr != nil {
+		return nil, taskErr
+	}
+	if subscription == nil {
+		return nil, exceptions.NotFoundException(constants.ErrWebhookNotFound)
+	}
+	return subscription, nil
+}
+
+// applyWebhookUpdates validates and copies the given fields of req onto subscription
+func applyWebhookUpdates(subscription *models.WebhookSubscription, req *request.ReqCreateOrUpdateWebhook) *errors.TaskManagerError {
+	if req.URL != nil {
+		parsed, err := url.Parse(*req.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return exceptions.NewBadRequestException(constants.ErrInvalidWebhookURL)
+		}
+		subscription.URL = *req.URL
+	}
+
+	if req.EventTypes != nil {
+		for _, eventType := range *req.EventTypes {
+			switch eventType {
+			case events.TaskCreated, events.TaskUpdated, events.TaskDeleted, events.TaskStatusChanged:
+			default:
+				return exceptions.NewBadRequestException(constants.ErrInvalidWebhookEvent)
+			}
+		}
+		subscription.EventTypes = append([]string{}, *req.EventTypes...)
+	}
+
+	if req.Filters != nil {
+		for key, value := range *req.Filters {
+			valid := false
+			switch key {
+			case FilterUserID:
+				valid = value != ""
+			case FilterStatus:
+				valid = enums.TaskStatus(value).IsValid()
+			case FilterPriority:
+				valid = enums.TaskPriority(value).IsValid()
+			}
+			if !valid {
+				return exceptions.NewBadRequestException(constants.ErrInvalidWebhookFilter)
+			}
+		}
+		filters := make(map[string]string, len(*req.Filters))
+		for key, value := range *req.Filters {
+			filters[key] = value
+		}
+		subscription.Filters = filters
+	}
+
+	if req.Secret != nil {
+		if len(*req.Secret) < constants.MinWebhookSecretLength {
+			return exceptions.NewBadRequestException(constants.ErrWebhookSecretTooShort)
+		}
+		subscription.Secret = *req.Secret
+	}
+
+	if req.Active != nil {
+		subscription.Active = *req.Active
+	}
+	return nil
+}
+
+// Matches reports whether subscription wants event
+func Matches(subscription *models.WebhookSubscription, event *events.TaskEvent) bool {
+	if len(subscription.EventTypes) > 0 {
+		wanted := false
+		for _, eventType := range subscription.EventTypes {
+			if eventType == event.EventType {
+				wanted = true
+				break
+			}
+		}
+		if !wanted {
+			return false
+		}
+	}
+
+	for key, value := range subscription.Filters {
+		if event.Task == nil {
+			return false
+		}
+		var actual string
+		switch key {
+		case FilterUserID:
+			if event.Task.UserID != nil {
+				actual = *event.Task.UserID
+			}
+		case FilterStatus:
+			actual = event.Task.Status
+		case FilterPriority:
+			actual = event.Task.Priority
+		}
+		if actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+func newSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+func toWebhookResponse(subscription *models.WebhookSubscription) *response.WebhookResponse {
+	return &response.WebhookResponse{
+		UUID:                subscription.UUID,
+		URL:                 subscription.URL,
+		EventTypes:          subscription.EventTypes,
+		Filters:             subscription.Filters,
+		Active:              subscription.Active,
+		ConsecutiveFailures: subscription.ConsecutiveFailures,
+		DisabledReason:      subscription.DisabledReason,
+		CreatedAt:           subscription.CreatedAt,
+		UpdatedAt:           subscription.UpdatedAt,
+	}
+}
+
+func toDeliveryResponse(delivery *models.WebhookDelivery) *response.WebhookDeliveryResponse {
+	resp := &response.WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		RedeliveryOf:   delivery.RedeliveryOf,
+		DeliveredAt:    delivery.DeliveredAt,
+		CreatedAt:      delivery.CreatedAt,
+	}
+	if delivery.Status == models.WebhookDeliveryPending {
+		nextAttemptAt := delivery.NextAttemptAt
+		resp.NextAttemptAt = &nextAttemptAt
+	}
+	return resp
+}
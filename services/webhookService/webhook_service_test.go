@@ -0,0 +1,419 @@
+package webhookService
+
+import (
+	"context"
+	"os"
+	"sort"
+	"sync"
+	"task-manager-app/constants"
+	"task-manager-app/events"
+	"task-manager-app/exceptions/errors"
+	"task-manager-app/models"
+	"task-manager-app/request"
+	"task-manager-app/utils"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	utils.Sugar = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
+
+// memoryWebhooks keeps subscriptions and deliveries in memory. Claimed deliveries are leased
+// by moving their next attempt, as the database repository does.
+type memoryWebhooks struct {
+	mutex         sync.Mutex
+	subscriptions []*models.WebhookSubscription
+	deliveries    []*models.WebhookDelivery
+}
+
+func (r *memoryWebhooks) CreateSubscription(subscription *models.WebhookSubscription) *errors.TaskManagerError {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	subscription.ID = uint(len(r.subscriptions) + 1)
+	subscription.UUID = uuid.New().String()
+	copied := *subscription
+	r.subscriptions = append(r.subscriptions, &copied)
+	return nil
+}
+
+func (r *memoryWebhooks) GetSubscription(uuid string) (*models.WebhookSubscription, *errors.TaskManagerError) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, subscription := range r.subscriptions {
+		if subscription.UUID == uuid {
+			copied := *subscription
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryWebhooks) ListSubscriptions() ([]models.WebhookSubscription, *errors.TaskManagerError) {
+	return r.findSubscriptions(func(*models.WebhookSubscription) bool { return true }), nil
+}
+
+func (r *memoryWebhooks) ListActiveSubscriptions() ([]models.WebhookSubscription, *errors.TaskManagerError) {
+	return r.findSubscriptions(func(s *models.WebhookSubscription) bool { return s.Active }), nil
+}
+
+func (r *memoryWebhooks) ListSubscriptionsByID(ids []uint) ([]models.WebhookSubscription, *errors.TaskManagerError) {
+	return r.findSubscriptions(func(s *models.WebhookSubscription) bool {
+		for _, id := range ids {
+			if s.ID == id {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+func (r *memoryWebhooks) findSubscriptions(match func(*models.WebhookSubscription) bool) []models.WebhookSubscription {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var result []models.WebhookSubscription
+	for _, subscription := range r.subscriptions {
+		if match(subscription) {
+			result = append(result, *subscription)
+		}
+	}
+	return result
+}
+
+func (r *memoryWebhooks) UpdateSubscription(subscription *models.WebhookSubscription) *errors.TaskManagerError {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i, existing := range r.subscriptions {
+		if existing.ID == subscription.ID {
+			copied := *subscription
+			r.subscriptions[i] = &copied
+		}
+	}
+	return nil
+}
+
+func (r *memoryWebhooks) DeleteSubscription(id uint) *errors.TaskManagerError {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var subscriptions []*models.WebhookSubscription
+	for _, subscription := range r.subscriptions {
+		if subscription.ID != id {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	var deliveries []*models.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.SubscriptionID != id {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	r.subscriptions, r.deliveries = subscriptions, deliveries
+	return nil
+}
+
+func (r *memoryWebhooks) AddDeliveries(deliveries []models.WebhookDelivery) *errors.TaskManagerError {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i := range deliveries {
+		queued := false
+		for _, existing := range r.deliveries {
+			if existing.SubscriptionID == deliveries[i].SubscriptionID && existing.EventID == deliveries[i].EventID && existing.RedeliveryOf == nil {
+				queued = true
+			}
+		}
+		if !queued {
+			r.add(&deliveries[i])
+		}
+	}
+	return nil
+}
+
+func (r *memoryWebhooks) CreateDelivery(delivery *models.WebhookDelivery) *errors.TaskManagerError {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.add(delivery)
+	return nil
+}
+
+func (r *memoryWebhooks) add(delivery *models.WebhookDelivery) {
+	delivery.ID = uint64(len(r.deliveries) + 1)
+	copied := *delivery
+	r.deliveries = append(r.deliveries, &copied)
+}
+
+func (r *memoryWebhooks) GetDelivery(subscriptionID uint, id uint64) (*models.WebhookDelivery, *errors.TaskManagerError) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, delivery := range r.deliveries {
+		if delivery.SubscriptionID == subscriptionID && delivery.ID == id {
+			copied := *delivery
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryWebhooks) ListDeliveries(subscriptionID uint, status string, limit, offset int) ([]models.WebhookDelivery, *errors.TaskManagerError) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var result []models.WebhookDelivery
+	for i := len(r.deliveries) - 1; i >= 0; i-- {
+		delivery := r.deliveries[i]
+		if delivery.SubscriptionID == subscriptionID && (status == "" || delivery.Status == status) {
+			result = append(result, *delivery)
+		}
+	}
+	if offset >= len(result) {
+		return nil, nil
+	}
+	result = result[offset:]
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (r *memoryWebhooks) ClaimDue(limit int, now, leaseUntil time.Time) ([]models.WebhookDelivery, *errors.TaskManagerError) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var batch []models.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if len(batch) == limit {
+			break
+		}
+		if delivery.Status != models.WebhookDeliveryPending || delivery.NextAttemptAt.After(now) || !r.subscription(delivery.SubscriptionID).Active {
+			continue
+		}
+		delivery.NextAttemptAt = leaseUntil
+		batch = append(batch, *delivery)
+	}
+	return batch, nil
+}
+
+func (r *memoryWebhooks) RecordSuccess(delivery *models.WebhookDelivery, statusCode int, deliveredAt time.Time) *errors.TaskManagerError {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stored := r.delivery(delivery.ID)
+	stored.Status = models.WebhookDeliveryDelivered
+	stored.Attempts++
+	stored.LastStatusCode = statusCode
+	stored.LastError = ""
+	stored.DeliveredAt = &deliveredAt
+	r.subscription(delivery.SubscriptionID).ConsecutiveFailures = 0
+	return nil
+}
+
+func (r *memoryWebhooks) RecordFailure(delivery *models.WebhookDelivery, statusCode int, lastError string, nextAttemptAt *time.Time, disableAfter int) (bool, *errors.TaskManagerError) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stored := r.delivery(delivery.ID)
+	stored.Attempts++
+	stored.LastStatusCode = statusCode
+	stored.LastError = lastError
+	if nextAttemptAt != nil {
+		stored.NextAttemptAt = *nextAttemptAt
+	} else {
+		stored.Status = models.WebhookDeliveryFailed
+	}
+
+	subscription := r.subscription(delivery.SubscriptionID)
+	subscription.ConsecutiveFailures++
+	if disableAfter <= 0 || !subscription.Active || subscription.ConsecutiveFailures < disableAfter {
+		return false, nil
+	}
+	subscription.Active = false
+	subscription.DisabledReason = constants.ErrWebhookDisabled + ": " + lastError
+	return true, nil
+}
+
+func (r *memoryWebhooks) DeleteFinished(before time.Time) *errors.TaskManagerError {
+	return nil
+}
+
+func (r *memoryWebhooks) subscription(id uint) *models.WebhookSubscription {
+	for _, subscription := range r.subscriptions {
+		if subscription.ID == id {
+			return subscription
+		}
+	}
+	return &models.WebhookSubscription{}
+}
+
+func (r *memoryWebhooks) delivery(id uint64) *models.WebhookDelivery {
+	for _, delivery := range r.deliveries {
+		if delivery.ID == id {
+			return delivery
+		}
+	}
+	return &models.WebhookDelivery{}
+}
+
+func (r *memoryWebhooks) storedDelivery(id uint64) models.WebhookDelivery {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return *r.delivery(id)
+}
+
+func (r *memoryWebhooks) storedSubscription(id uint) models.WebhookSubscription {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return *r.subscription(id)
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func createWebhook(t *testing.T, service WebhookService, req *request.ReqCreateOrUpdateWebhook) string {
+	resp, taskErr := service.CreateWebhook(req)
+	if taskErr != nil {
+		t.Fatalf("CreateWebhook returned error %+v", taskErr)
+	}
+	return resp.UUID
+}
+
+func TestCreateWebhookValidatesRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     request.ReqCreateOrUpdateWebhook
+		wantErr string
+	}{
+		{"missing url", request.ReqCreateOrUpdateWebhook{}, constants.ErrInvalidWebhookURL},
+		{"relative url", request.ReqCreateOrUpdateWebhook{URL: strPtr("/hooks")}, constants.ErrInvalidWebhookURL},
+		{"ftp url", request.ReqCreateOrUpdateWebhook{URL: strPtr("ftp://example.com/hooks")}, constants.ErrInvalidWebhookURL},
+		{"unknown event", request.ReqCreateOrUpdateWebhook{URL: strPtr("https://example.com"), EventTypes: &[]string{"TaskArchived"}}, constants.ErrInvalidWebhookEvent},
+		{"unknown filter", request.ReqCreateOrUpdateWebhook{URL: strPtr("https://example.com"), Filters: &map[string]string{"title": "x"}}, constants.ErrInvalidWebhookFilter},
+		{"invalid status", request.ReqCreateOrUpdateWebhook{URL: strPtr("https://example.com"), Filters: &map[string]string{"status": "Done"}}, constants.ErrInvalidWebhookFilter},
+		{"short secret", request.ReqCreateOrUpdateWebhook{URL: strPtr("https://example.com"), Secret: strPtr("short")}, constants.ErrWebhookSecretTooShort},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, taskErr := NewWebhookService(&memoryWebhooks{}, time.Now).CreateWebhook(&test.req)
+			if taskErr == nil || taskErr.Message != test.wantErr {
+				t.Errorf("CreateWebhook returned %+v, want %q", taskErr, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookReturnsOnlyGeneratedSecrets(t *testing.T) {
+	service := NewWebhookService(&memoryWebhooks{}, time.Now)
+
+	generated, taskErr := service.CreateWebhook(&request.ReqCreateOrUpdateWebhook{URL: strPtr("https://example.com/hooks")})
+	if taskErr != nil {
+		t.Fatalf("CreateWebhook returned error %+v", taskErr)
+	}
+	if len(generated.Secret) != 64 || !generated.Active {
+		t.Errorf("got secret %q active %v, want a generated 64 character secret on an active webhook", generated.Secret, generated.Active)
+	}
+
+	given, _ := service.CreateWebhook(&request.ReqCreateOrUpdateWebhook{URL: strPtr("https://example.com/hooks"), Secret: strPtr("0123456789abcdef")})
+	if given.Secret != "" {
+		t.Errorf("given secret was echoed back")
+	}
+	fetched, _ := service.GetWebhook(generated.UUID)
+	if fetched.Secret != "" {
+		t.Errorf("GetWebhook returned the secret")
+	}
+}
+
+func TestUpdateWebhookReenablesDisabledWebhooks(t *testing.T) {
+	store := &memoryWebhooks{}
+	service := NewWebhookService(store, time.Now)
+	id := createWebhook(t, service, &request.ReqCreateOrUpdateWebhook{URL: strPtr("https://example.com/hooks")})
+	store.subscriptions[0].Active = false
+	store.subscriptions[0].ConsecutiveFailures = 20
+	store.subscriptions[0].DisabledReason = constants.ErrWebhookDisabled
+
+	active := true
+	resp, taskErr := service.UpdateWebhook(id, &request.ReqCreateOrUpdateWebhook{Active: &active})
+	if taskErr != nil {
+		t.Fatalf("UpdateWebhook returned error %+v", taskErr)
+	}
+	if !resp.Active || resp.ConsecutiveFailures != 0 || resp.DisabledReason != "" {
+		t.Errorf("got %+v, want an active webhook with its failures cleared", resp)
+	}
+	if resp.URL != "https://example.com/hooks" {
+		t.Errorf("url = %q, want it unchanged", resp.URL)
+	}
+
+	if _, taskErr := service.UpdateWebhook(uuid.New().String(), &request.ReqCreateOrUpdateWebhook{}); taskErr == nil || taskErr.Message != constants.ErrWebhookNotFound {
+		t.Errorf("updating an unknown webhook returned %+v", taskErr)
+	}
+}
+
+func TestRedeliverQueuesACopyOfTheDelivery(t *testing.T) {
+	store := &memoryWebhooks{}
+	service := NewWebhookService(store, time.Now)
+	id := createWebhook(t, service, &request.ReqCreateOrUpdateWebhook{URL: strPtr("https://example.com/hooks")})
+	store.CreateDelivery(&models.WebhookDelivery{SubscriptionID: 1, EventID: "e1", EventType: events.TaskCreated, Payload: []byte(`{}`), Status: models.WebhookDeliveryFailed, Attempts: 8})
+
+	resp, taskErr := service.Redeliver(id, 1)
+	if taskErr != nil {
+		t.Fatalf("Redeliver returned error %+v", taskErr)
+	}
+	if resp.Status != models.WebhookDeliveryPending || resp.Attempts != 0 || resp.RedeliveryOf == nil || *resp.RedeliveryOf != 1 || resp.EventID != "e1" {
+		t.Errorf("got %+v, want a new pending delivery of e1", resp)
+	}
+
+	log, _ := service.ListDeliveries(id, "", 1, 10)
+	if log.Count != 2 || log.Deliveries[0].ID != resp.ID {
+		t.Errorf("delivery log = %+v, want the redelivery first", log.Deliveries)
+	}
+	failed, _ := service.ListDeliveries(id, models.WebhookDeliveryFailed, 1, 10)
+	if failed.Count != 1 {
+		t.Errorf("got %d failed deliveries, want 1", failed.Count)
+	}
+	if _, taskErr := service.ListDeliveries(id, "lost", 1, 10); taskErr == nil {
+		t.Error("unknown status was accepted")
+	}
+	if _, taskErr := service.Redeliver(id, 42); taskErr == nil || taskErr.Message != constants.ErrDeliveryNotFound {
+		t.Errorf("redelivering an unknown delivery returned %+v", taskErr)
+	}
+}
+
+func TestPublisherQueuesMatchingEventsOnce(t *testing.T) {
+	store := &memoryWebhooks{}
+	service := NewWebhookService(store, time.Now)
+	createWebhook(t, service, &request.ReqCreateOrUpdateWebhook{URL: strPtr("https://example.com/all")})
+	createWebhook(t, service, &request.ReqCreateOrUpdateWebhook{
+		URL:        strPtr("https://example.com/alice"),
+		EventTypes: &[]string{events.TaskCreated},
+		Filters:    &map[string]string{FilterUserID: "alice", FilterPriority: "High"},
+	})
+	inactive := false
+	createWebhook(t, service, &request.ReqCreateOrUpdateWebhook{URL: strPtr("https://example.com/off"), Active: &inactive})
+
+	alice := "alice"
+	created := &events.TaskEvent{EventID: "e1", EventType: events.TaskCreated, Task: &events.TaskSnapshot{UserID: &alice, Priority: "High"}}
+	lowPriority := &events.TaskEvent{EventID: "e2", EventType: events.TaskCreated, Task: &events.TaskSnapshot{UserID: &alice, Priority: "Low"}}
+	deleted := &events.TaskEvent{EventID: "e3", EventType: events.TaskDeleted, Task: &events.TaskSnapshot{UserID: &alice, Priority: "High"}}
+
+	publisher := NewWebhookPublisher(store, time.Now)
+	for i := 0; i < 2; i++ {
+		if err := publisher.Publish(context.Background(), created, lowPriority, deleted); err != nil {
+			t.Fatalf("Publish returned error %v", err)
+		}
+	}
+
+	var queued []string
+	for _, delivery := range store.deliveries {
+		queued = append(queued, store.subscription(delivery.SubscriptionID).URL+" "+delivery.EventID)
+	}
+	sort.Strings(queued)
+	want := []string{"https://example.com/alice e1", "https://example.com/all e1", "https://example.com/all e2", "https://example.com/all e3"}
+	if len(queued) != len(want) {
+		t.Fatalf("queued %v, want %v", queued, want)
+	}
+	for i := range want {
+		if queued[i] != want[i] {
+			t.Errorf("queued %v, want %v", queued, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"task-manager-app/constants"
+	"task-manager-app/exceptions/errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation names the tracer of the spans created by this service
+const instrumentation = "task-manager-app"
+
+// Options selects where spans are exported
+type Options struct {
+	// Exporter is none, stdout or otlp. The OTLP exporter sends to OTEL_EXPORTER_OTLP_ENDPOINT
+	// over HTTP and reads the other OTEL_EXPORTER_OTLP_* variables.
+	Exporter       string
+	ServiceName    string
+	ServiceVersion string
+}
+
+// Init installs the global tracer provider and the W3C trace context propagator, and returns
+// the function that flushes the spans still buffered. With the none exporter spans are not
+// recorded, but incoming trace context is still passed on to the user service.
+func Init(options Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch options.Exporter {
+	case constants.TracingExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case constants.TracingExporterStdout:
+		exporter, err = stdouttrace.New()
+	case constants.TracingExporterOTLP:
+		exporter, err = otlptracehttp.New(context.Background())
+	default:
+		return nil, fmt.Errorf("%s", constants.ErrInvalidTracingExporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := NewTracerProvider(sdktrace.NewBatchSpanProcessor(exporter), options)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewTracerProvider builds a provider that hands spans to processor. Tests pass a
+// SimpleSpanProcessor around a tracetest.InMemoryExporter. The sampler follows
+// OTEL_TRACES_SAMPLER and defaults to sampling every trace that is not sampled out upstream.
+func NewTracerProvider(processor sdktrace.SpanProcessor, options Options) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName(options.ServiceName),
+			semconv.ServiceVersion(options.ServiceVersion),
+		)),
+	)
+}
+
+// Start starts a span of the service layer as a child of the span in ctx
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End ends span, marking it failed when taskErr is a server error. Client errors such as a
+// missing task are recorded as an attribute only, since the service worked as intended.
+func End(span trace.Span, taskErr *errors.TaskManagerError) {
+	if taskErr != nil {
+		span.SetAttributes(attribute.Int("error.response_code", taskErr.ResponseCode))
+		if taskErr.ResponseCode >= 500 {
+			span.SetStatus(codes.Error, taskErr.Message)
+		}
+	}
+	span.End()
+}
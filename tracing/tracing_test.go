@@ -0,0 +1,117 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"task-manager-app/exceptions"
+	"task-manager-app/utils"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	utils.Sugar = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
+
+// useMemoryExporter records the spans of the test in memory until it ends
+func useMemoryExporter(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewTracerProvider(sdktrace.NewSimpleSpanProcessor(exporter), Options{ServiceName: "task-manager-test"})
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func responseCode(span tracetest.SpanStub) (int64, bool) {
+	for _, attr := range span.Attributes {
+		if attr.Key == "error.response_code" {
+			return attr.Value.AsInt64(), true
+		}
+	}
+	return 0, false
+}
+
+func TestEndMarksOnlyServerErrorsAsFailed(t *testing.T) {
+	exporter := useMemoryExporter(t)
+
+	_, ok := Start(context.Background(), "ok")
+	End(ok, nil)
+	_, notFound := Start(context.Background(), "not found")
+	End(notFound, exceptions.NotFoundException("task not found"))
+	_, failed := Start(context.Background(), "failed")
+	End(failed, exceptions.InternalServerException("database is down"))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("recorded %d spans, want 3", len(spans))
+	}
+	tests := []struct {
+		span       tracetest.SpanStub
+		wantStatus codes.Code
+		wantCode   int64
+	}{
+		{span: spans[0], wantStatus: codes.Unset},
+		{span: spans[1], wantStatus: codes.Unset, wantCode: 404},
+		{span: spans[2], wantStatus: codes.Error, wantCode: 500},
+	}
+	for _, test := range tests {
+		if test.span.Status.Code != test.wantStatus {
+			t.Errorf("span %q has status %v, want %v", test.span.Name, test.span.Status.Code, test.wantStatus)
+		}
+		if code, _ := responseCode(test.span); code != test.wantCode {
+			t.Errorf("span %q has error.response_code %d, want %d", test.span.Name, code, test.wantCode)
+		}
+	}
+}
+
+func TestStartNestsSpansAndKeepsAttributes(t *testing.T) {
+	exporter := useMemoryExporter(t)
+
+	ctx, parent := Start(context.Background(), "TaskService.GetTaskByUUID", attribute.String("task.uuid", "t1"))
+	_, child := Start(ctx, "gorm.query")
+	End(child, nil)
+	End(parent, nil)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	childStub, parentStub := spans[0], spans[1]
+	if childStub.Parent.SpanID() != parentStub.SpanContext.SpanID() {
+		t.Fatalf("child span is not a child of %q", parentStub.Name)
+	}
+	if childStub.SpanContext.TraceID() != parentStub.SpanContext.TraceID() {
+		t.Fatal("child span is in another trace")
+	}
+	if len(parentStub.Attributes) != 1 || parentStub.Attributes[0] != attribute.String("task.uuid", "t1") {
+		t.Fatalf("parent attributes = %v, want task.uuid=t1", parentStub.Attributes)
+	}
+	if got := parentStub.Resource.Attributes(); len(got) == 0 {
+		t.Fatal("spans carry no service resource")
+	}
+}
+
+func TestInitRejectsUnknownExporter(t *testing.T) {
+	if _, err := Init(Options{Exporter: "jaeger"}); err == nil {
+		t.Fatal("Init accepted an unknown exporter")
+	}
+	shutdown, err := Init(Options{Exporter: "none"})
+	if err != nil {
+		t.Fatalf("Init with the none exporter failed: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+}
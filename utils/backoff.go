@@ -0,0 +1,16 @@
+package utils
+
+import "time"
+
+// ExponentialBackoff returns the delay before retrying something that failed attempts times:
+// base after the first failure, doubling with each further one, capped at max
+func ExponentialBackoff(base, max time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
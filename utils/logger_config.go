@@ -1,39 +1,93 @@
 package utils
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/natefinch/lumberjack"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"os"
 )
 
 var (
 	Sugar *zap.SugaredLogger
+	// level is shared by every logger InitLogger builds, so SetLogLevel changes them all
+	level = zap.NewAtomicLevel()
+)
+
+// Log formats
+const (
+	LogFormatConsole = "console"
+	LogFormatJSON    = "json"
 )
 
-func InitLogger() *zap.Logger {
-	writeSyncer := getLogWriter()
-	encoder := getEncoder()
-	core := zapcore.NewTee(zapcore.NewCore(encoder, writeSyncer, zapcore.ErrorLevel),
-		zapcore.NewCore(encoder, zapcore.AddSync(os.Stdout), zapcore.ErrorLevel))
+// LogOptions configures the logger
+type LogOptions struct {
+	// Level is debug, info, warn or error
+	Level string
+	// Format is LogFormatConsole or LogFormatJSON
+	Format string
+	// File is rotated once it reaches MaxSizeMB; MaxBackups rotated files are kept for at
+	// most MaxAgeDays, with 0 keeping them all
+	File       string
+	MaxSizeMB  int
+	MaxBackups int
+	MaxAgeDays int
+}
+
+// InitLogger logs at the level of options and above, to stdout and to the rotated log file
+func InitLogger(options LogOptions) (*zap.Logger, error) {
+	if err := SetLogLevel(options.Level); err != nil {
+		return nil, err
+	}
+	encoder, err := getEncoder(options.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	writeSyncer := getLogWriter(options)
+	core := zapcore.NewTee(zapcore.NewCore(encoder, writeSyncer, level),
+		zapcore.NewCore(encoder, zapcore.AddSync(os.Stdout), level))
 	logger := zap.New(core, zap.AddCaller()).Named("[taskManager]")
 	Sugar = logger.Sugar()
-	return logger
+	return logger, nil
+}
+
+// LogLevel is the level shared by the loggers InitLogger builds
+func LogLevel() *zap.AtomicLevel {
+	return &level
 }
 
-func getEncoder() zapcore.Encoder {
+// SetLogLevel changes the level of the logger while it runs
+func SetLogLevel(name string) error {
+	parsed, err := zapcore.ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.SetLevel(parsed)
+	return nil
+}
+
+func getEncoder(format string) (zapcore.Encoder, error) {
 	encoderConfig := zap.NewProductionEncoderConfig()
 	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
-	return zapcore.NewConsoleEncoder(encoderConfig)
+	switch format {
+	case LogFormatConsole:
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		return zapcore.NewConsoleEncoder(encoderConfig), nil
+	case LogFormatJSON:
+		return zapcore.NewJSONEncoder(encoderConfig), nil
+	default:
+		return nil, fmt.Errorf("unrecognized log format: %q", format)
+	}
 }
 
-func getLogWriter() zapcore.WriteSyncer {
+func getLogWriter(options LogOptions) zapcore.WriteSyncer {
 	lumberJackLogger := &lumberjack.Logger{
-		Filename:   "./logs/task_manager.log",
-		MaxSize:    100,
-		MaxBackups: 5,
-		MaxAge:     30,
+		Filename:   options.File,
+		MaxSize:    options.MaxSizeMB,
+		MaxBackups: options.MaxBackups,
+		MaxAge:     options.MaxAgeDays,
 		Compress:   false,
 	}
 	return zapcore.AddSync(lumberJackLogger)
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestIDKey
+)
+
+// WithLogger returns a copy of ctx carrying logger, which Logger returns from then on
+func WithLogger(ctx context.Context, logger *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// Logger returns the request-scoped logger of ctx, or Sugar outside of a request
+func Logger(ctx context.Context) *zap.SugaredLogger {
+	return LoggerOr(ctx, Sugar)
+}
+
+// LoggerOr returns the request-scoped logger of ctx, or fallback outside of a request
+func LoggerOr(ctx context.Context, fallback *zap.SugaredLogger) *zap.SugaredLogger {
+	if logger, ok := ctx.Value(loggerKey).(*zap.SugaredLogger); ok {
+		return logger
+	}
+	return fallback
+}
+
+// WithRequestID returns a copy of ctx carrying the ID of the request it serves
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestID returns the ID of the request ctx serves, or an empty string
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
@@ -1,6 +1,10 @@
 package utils
 
-import "strconv"
+import (
+	"os"
+	"strconv"
+	"strings"
+)
 
 var (
 	TaskManagerUtils = &taskManagerUtils{}
@@ -20,6 +24,51 @@ func (t *taskManagerUtils) ParseStringToInt(secret string) int {
 	return v
 }
 
+// ParseStringToIntWithDefault parses value, falling back to def when it is empty or malformed
+func (t *taskManagerUtils) ParseStringToIntWithDefault(value string, def int) int {
+	if value == "" {
+		return def
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		Sugar.Errorw(err.Error())
+		return def
+	}
+	return v
+}
+
+// ParseStringToBool parses value, falling back to def when it is empty or malformed
+func (t *taskManagerUtils) ParseStringToBool(value string, def bool) bool {
+	if value == "" {
+		return def
+	}
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		Sugar.Errorw(err.Error())
+		return def
+	}
+	return v
+}
+
+// GetEnvOrDefault returns the environment variable key, or def when it is unset or empty
+func (t *taskManagerUtils) GetEnvOrDefault(key string, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// SplitAndTrim splits a comma separated value, dropping blanks around and between items
+func (t *taskManagerUtils) SplitAndTrim(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func (t *taskManagerUtils) GetStringValue(s *string) string {
 	if s == nil {
 		return ""
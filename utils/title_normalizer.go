@@ -0,0 +1,20 @@
+package utils
+
+import "strings"
+
+// TitleNormalizer derives the key used to enforce unique task titles per user
+type TitleNormalizer struct {
+	CaseInsensitive    bool
+	CollapseWhitespace bool
+}
+
+// Normalize returns the uniqueness key for title according to the configured rules
+func (n TitleNormalizer) Normalize(title string) string {
+	if n.CollapseWhitespace {
+		title = strings.Join(strings.Fields(title), " ")
+	}
+	if n.CaseInsensitive {
+		title = strings.ToLower(title)
+	}
+	return title
+}